@@ -0,0 +1,208 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+)
+
+// instanceRef is the tier argument that stands for the current tier of the
+// -instance, fetched from the Cloud SQL Admin API. Commands that take a
+// current tier use it when the tier is left out.
+const instanceRef = "@instance"
+
+// apiTimeout bounds each call: credentials, token, and the request itself.
+// It never covers a wait on the operator, such as the -apply confirmation.
+var apiTimeout = 20 * time.Second
+
+// instanceSource looks up a Cloud SQL instance. adminAPI is the real one;
+// anything else that returns the Admin API instance resource will do.
+type instanceSource interface {
+	instance(ctx context.Context, project, name string) (*gcloudInstance, error)
+}
+
+// adminClient is the Cloud SQL Admin API client.
+var adminClient = &adminAPI{client: http.DefaultClient, base: "https://sqladmin.googleapis.com/v1"}
+
+// instances is the source -instance lookups use: the Admin API, through the
+// cache.
+var instances instanceSource = cachedInstances{adminClient}
+
+// adminAPI reads instances from the Cloud SQL Admin API with Application
+// Default Credentials.
+type adminAPI struct {
+	client *http.Client
+	base   string
+}
+
+func (a *adminAPI) instance(ctx context.Context, project, name string) (*gcloudInstance, error) {
+	client, err := adcClient(ctx, a.client)
+	if err != nil {
+		return nil, err
+	}
+	u := fmt.Sprintf("%s/projects/%s/instances/%s", a.base, url.PathEscape(project), url.PathEscape(name))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := callAPI(client, "Cloud SQL Admin API", req, opts.maxRetries)
+	if err != nil {
+		return nil, err
+	}
+	switch resp.status {
+	case http.StatusOK:
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return nil, newAPIError(ErrAPIAuth, "Cloud SQL Admin API: permission denied reading %s:%s (needs cloudsql.instances.get): %s; %s", project, name, apiMessage(resp.body), authHint)
+	case http.StatusNotFound:
+		return nil, newAPIError(ErrAPINotFound, "Cloud SQL Admin API: instance %s:%s not found", project, name)
+	default:
+		return nil, resp.failure("Cloud SQL Admin API")
+	}
+	var gi gcloudInstance
+	if err := json.Unmarshal(resp.body, &gi); err != nil {
+		return nil, fmt.Errorf("Cloud SQL Admin API: invalid instance: %w", err)
+	}
+	return &gi, nil
+}
+
+// apiMessage returns the message of a Google API error body, or the body.
+func apiMessage(body []byte) string {
+	var e struct {
+		Error struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if json.Unmarshal(body, &e) == nil && e.Error.Message != "" {
+		return e.Error.Message
+	}
+	return strings.TrimSpace(string(body))
+}
+
+// adminScope is the OAuth scope go-calc asks for; the Admin and Monitoring
+// APIs both accept it.
+const adminScope = "https://www.googleapis.com/auth/cloud-platform"
+
+// adcClient returns a client that sends requests through base with a token
+// from the Application Default Credentials: $GOOGLE_APPLICATION_CREDENTIALS,
+// the gcloud login, or the GCE metadata server. The token is fetched up
+// front so a credentials failure is reported as one rather than retried as
+// a failure to connect.
+func adcClient(ctx context.Context, base *http.Client) (*http.Client, error) {
+	ctx = context.WithValue(ctx, oauth2.HTTPClient, base)
+	creds, err := google.FindDefaultCredentials(ctx, adminScope)
+	if err != nil {
+		return nil, newAPIError(ErrAPIAuth, "no Application Default Credentials: %v; run 'gcloud auth application-default login' or set GOOGLE_APPLICATION_CREDENTIALS", err)
+	}
+	if _, err := creds.TokenSource.Token(); err != nil {
+		var re *oauth2.RetrieveError
+		if errors.As(err, &re) {
+			return nil, newAPIError(ErrAPIAuth, "credentials: token request failed: %v (run 'gcloud auth application-default login' again?)", err)
+		}
+		return nil, newAPIError(ErrAPITransient, "credentials: %v", err)
+	}
+	return oauth2.NewClient(ctx, creds.TokenSource), nil
+}
+
+// instanceNamePattern and projectIDPattern are the syntax of Cloud SQL
+// instance names and project IDs, domain-scoped ones included. -instance and
+// -project are checked against them because they go into commands meant to
+// be pasted into a shell.
+var (
+	instanceNamePattern = regexp.MustCompile(`^[a-z][a-z0-9-]*$`)
+	projectIDPattern    = regexp.MustCompile(`^([a-z][a-z0-9.-]*[a-z0-9]:)?[a-z][a-z0-9-]*$`)
+)
+
+// checkInstanceFlags checks an -instance, given alone or as
+// project:instance, and a -project.
+func checkInstanceFlags(instance, project string) error {
+	if project != "" && !projectIDPattern.MatchString(project) {
+		return fmt.Errorf("invalid -project %q: use a project ID such as my-project", project)
+	}
+	if instance == "" {
+		return nil
+	}
+	p, name := "", instance
+	if i := strings.LastIndex(instance, ":"); i >= 0 {
+		p, name = instance[:i], instance[i+1:]
+	}
+	if p != "" && !projectIDPattern.MatchString(p) || !instanceNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid -instance %q: use an instance name of lower-case letters, digits, and hyphens, as <instance> or <project>:<instance>", instance)
+	}
+	return nil
+}
+
+// splitInstance splits an -instance of project:instance, or takes the
+// project from -project. The project is everything before the last colon,
+// so a domain-scoped project such as example.com:my-project is kept whole.
+func splitInstance(ref, project string) (string, string, error) {
+	if i := strings.LastIndex(ref, ":"); i >= 0 {
+		return ref[:i], ref[i+1:], nil
+	}
+	if project == "" {
+		return "", "", fmt.Errorf("-instance %s needs a project: use -instance <project>:<instance> or -project", ref)
+	}
+	return project, ref, nil
+}
+
+// instanceTier fetches the current tier of -instance and selects the rules
+// of its engine and edition, unless -engine or -edition was given on fs. It
+// leaves -instance and -project naming the instance for -gcloud.
+func instanceTier(fs *flag.FlagSet) (string, error) {
+	project, name, err := splitInstance(opts.instance, opts.project)
+	if err != nil {
+		return "", err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), apiTimeout)
+	defer cancel()
+	gi, err := instances.instance(ctx, project, name)
+	if err != nil {
+		return "", err
+	}
+	if gi.Settings.Tier == "" {
+		return "", fmt.Errorf("instance %s:%s has no tier", project, name)
+	}
+	engine, edition := rules.Engine, rules.Edition
+	if !flagSet(fs, "engine") {
+		if engine, err = engineFor(gi.DatabaseVersion); err != nil {
+			return "", fmt.Errorf("instance %s:%s: %w", project, name, err)
+		}
+	}
+	if !flagSet(fs, "edition") {
+		edition = editionFor(gi.Settings.Edition)
+	}
+	if err = selectRules(fs, engine, edition); err != nil {
+		return "", fmt.Errorf("instance %s:%s: %w", project, name, err)
+	}
+	opts.project, opts.instance = project, name
+	fmt.Fprintf(os.Stderr, "Note: %s:%s is %s (%s, %s %s)\n", project, name, gi.Settings.Tier, gi.DatabaseVersion, rules.Name, rules.editionName())
+	return gi.Settings.Tier, nil
+}
+
+// resolveInstanceRef replaces a leading @instance in *arg with the current
+// tier of -instance.
+func resolveInstanceRef(fs *flag.FlagSet, arg *string) error {
+	rest, ok := strings.CutPrefix(strings.TrimSpace(*arg), instanceRef)
+	if !ok {
+		return nil
+	}
+	if opts.instance == "" {
+		return fmt.Errorf("%s needs -instance <project>:<instance>", instanceRef)
+	}
+	tier, err := instanceTier(fs)
+	if err != nil {
+		return err
+	}
+	*arg = tier + rest
+	return nil
+}
@@ -0,0 +1,271 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// fakeInstances is an instanceSource of canned instances keyed by
+// project:instance.
+type fakeInstances map[string]*gcloudInstance
+
+func (f fakeInstances) instance(ctx context.Context, project, name string) (*gcloudInstance, error) {
+	if gi, ok := f[project+":"+name]; ok {
+		return gi, nil
+	}
+	return nil, fmt.Errorf("Cloud SQL Admin API: instance %s:%s not found", project, name)
+}
+
+func fakeInstance(version, tier, edition string) *gcloudInstance {
+	gi := &gcloudInstance{DatabaseVersion: version}
+	gi.Settings.Tier, gi.Settings.Edition = tier, edition
+	return gi
+}
+
+// useInstances makes f the -instance source, with -instance and -project
+// set, for the rest of the test.
+func useInstances(t *testing.T, f instanceSource, instance, project string) {
+	saved, savedRules, savedOpts := instances, rules, opts
+	instances, opts.instance, opts.project = f, instance, project
+	t.Cleanup(func() { instances, rules, opts = saved, savedRules, savedOpts })
+}
+
+func TestInstanceTier(t *testing.T) {
+	fake := fakeInstances{
+		"prod:orders":  fakeInstance("MYSQL_8_0", "db-custom-8-30720", "ENTERPRISE"),
+		"prod:ledger":  fakeInstance("POSTGRES_16", "db-perf-optimized-N-8", "ENTERPRISE_PLUS"),
+		"prod:legacy":  fakeInstance("MYSQL_5_7", "db-n1-highmem-4", ""),
+		"prod:empty":   fakeInstance("MYSQL_8_0", "", "ENTERPRISE"),
+		"prod:unknown": fakeInstance("ORACLE_19", "db-custom-4-16384", "ENTERPRISE"),
+	}
+	tests := []struct {
+		instance, project string
+		flags             []string
+		want              string
+		engine, edition   string
+		wantErr           string
+	}{
+		{"prod:orders", "", nil, "db-custom-8-30720", "mysql", "enterprise", ""},
+		{"orders", "prod", nil, "db-custom-8-30720", "mysql", "enterprise", ""},
+		{"prod:ledger", "", nil, "db-perf-optimized-N-8", "postgres", "enterprise-plus", ""},
+		{"prod:legacy", "", nil, "db-n1-highmem-4", "mysql", "enterprise", ""},
+		{"prod:orders", "", []string{"-edition", "enterprise-plus"}, "db-custom-8-30720", "mysql", "enterprise-plus", ""},
+		{"prod:unknown", "", []string{"-engine", "postgres"}, "db-custom-4-16384", "postgres", "enterprise", ""},
+		{"orders", "", nil, "", "", "", "needs a project"},
+		{"prod:missing", "", nil, "", "", "", "not found"},
+		{"prod:empty", "", nil, "", "", "", "has no tier"},
+		{"prod:unknown", "", nil, "", "", "", "unknown databaseVersion"},
+	}
+	for _, tt := range tests {
+		useInstances(t, fake, tt.instance, tt.project)
+		fs := flag.NewFlagSet("test", flag.ContinueOnError)
+		fs.StringVar(&opts.engine, "engine", "mysql", "")
+		fs.StringVar(&opts.edition, "edition", defaultEdition, "")
+		if err := fs.Parse(tt.flags); err != nil {
+			t.Fatal(err)
+		}
+		rules = mustLookupRules(opts.engine, opts.edition)
+		got, err := instanceTier(fs)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("-instance %s: error = %v, want %q", tt.instance, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("-instance %s %q: %v", tt.instance, tt.flags, err)
+			continue
+		}
+		if got != tt.want || rules.Engine != tt.engine || rules.Edition != tt.edition {
+			t.Errorf("-instance %s %q = %s under %s %s, want %s under %s %s", tt.instance, tt.flags, got, rules.Engine, rules.Edition, tt.want, tt.engine, tt.edition)
+		}
+		if opts.project != "prod" || strings.Contains(opts.instance, ":") {
+			t.Errorf("-instance %s left -project %q and -instance %q, want them split for -gcloud", tt.instance, opts.project, opts.instance)
+		}
+	}
+}
+
+// An explicit tier does not look the instance up, but the gcloud commands
+// still name it as gcloud takes it: the instance, with --project.
+func TestGcloudCommandSplitsInstance(t *testing.T) {
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"next", "db-custom-4-15360", "-gcloud", "-instance", "p1:mydb"}, "gcloud sql instances patch mydb --tier=db-custom-4-26624 --project=p1\n"},
+		{[]string{"next", "db-custom-4-15360", "-gcloud", "-instance", "mydb", "-project", "p1"}, "gcloud sql instances patch mydb --tier=db-custom-4-26624 --project=p1\n"},
+		{[]string{"next", "db-custom-4-15360", "-gcloud"}, "gcloud sql instances patch <INSTANCE> --tier=db-custom-4-26624\n"},
+		{[]string{"plan", "db-custom-2-7680", "db-custom-4-15360", "-gcloud", "-instance", "p1:mydb"}, "gcloud sql instances patch mydb --tier=db-custom-4-15360 --project=p1\n"},
+		{[]string{"-t", "db-custom-4-15360", "-mysql-config", "-instance", "p1:mydb"}, "gcloud sql instances patch mydb --database-flags="},
+	}
+	for _, tt := range tests {
+		out, code := run(t, tt.args...)
+		if code != exitOK || !strings.Contains(out, tt.want) {
+			t.Errorf("go-calc %q (exit %d) = %q, want %q", tt.args, code, out, tt.want)
+		}
+		if strings.Contains(out, "p1:mydb") {
+			t.Errorf("go-calc %q = %q, want no project:instance in the command", tt.args, out)
+		}
+	}
+}
+
+// -instance and -project go into commands meant for a shell, so anything
+// that is not a Cloud SQL instance name or project ID is a usage error.
+func TestInstanceFlagsChecked(t *testing.T) {
+	tests := []struct {
+		args []string
+		ok   bool
+	}{
+		{[]string{"-instance", "mydb"}, true},
+		{[]string{"-instance", "p1:my-db-2"}, true},
+		{[]string{"-instance", "example.com:my-project:mydb"}, true},
+		{[]string{"-instance", "mydb", "-project", "example.com:my-project"}, true},
+		{[]string{"-instance", "my db;rm"}, false},
+		{[]string{"-instance", "MyDB"}, false},
+		{[]string{"-instance", "p1:"}, false},
+		{[]string{"-instance", "p 1:mydb"}, false},
+		{[]string{"-instance", "mydb", "-project", "$(id)"}, false},
+	}
+	for _, tt := range tests {
+		args := append([]string{"next", "db-custom-4-15360", "-gcloud"}, tt.args...)
+		out, code := run(t, args...)
+		if tt.ok && code != exitOK || !tt.ok && (code != exitUsage || !strings.Contains(out, "invalid -")) {
+			t.Errorf("go-calc %q = exit %d, %q; want ok %t", args, code, out, tt.ok)
+		}
+	}
+	if got := gcloudPatchCommand("example.com:my-project:mydb", "", "db-custom-4-15360"); got != "gcloud sql instances patch mydb --tier=db-custom-4-15360 --project=example.com:my-project" {
+		t.Errorf("domain-scoped -instance command = %q", got)
+	}
+}
+
+func TestResolveInstanceRef(t *testing.T) {
+	fake := fakeInstances{"prod:orders": fakeInstance("MYSQL_8_0", "db-custom-8-30720", "ENTERPRISE")}
+	tests := []struct {
+		instance, arg, want string
+		wantErr             bool
+	}{
+		{"prod:orders", "@instance", "db-custom-8-30720", false},
+		{"prod:orders", " @instance,db-custom-4-15360", "db-custom-8-30720,db-custom-4-15360", false},
+		{"prod:orders", "db-custom-4-15360", "db-custom-4-15360", false},
+		{"", "db-custom-4-15360", "db-custom-4-15360", false},
+		{"", "@instance", "", true},
+		{"prod:missing", "@instance", "", true},
+	}
+	for _, tt := range tests {
+		useInstances(t, fake, tt.instance, "")
+		arg := tt.arg
+		err := resolveInstanceRef(flag.NewFlagSet("test", flag.ContinueOnError), &arg)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("-instance %q %q: error = %v, want error %t", tt.instance, tt.arg, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && arg != tt.want {
+			t.Errorf("-instance %q %q = %q, want %q", tt.instance, tt.arg, arg, tt.want)
+		}
+	}
+}
+
+// useFakeCredentials points Application Default Credentials at a service
+// account key whose token_uri is tokenURL, for the rest of the test.
+func useFakeCredentials(t *testing.T, tokenURL string) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	creds, err := json.Marshal(map[string]string{
+		"type": "service_account", "client_email": "calc@prod.iam.gserviceaccount.com",
+		"private_key": string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})), "token_uri": tokenURL,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "credentials.json")
+	if err := os.WriteFile(path, creds, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("GOOGLE_APPLICATION_CREDENTIALS", path)
+}
+
+// TestAdminAPI runs adminAPI against a fake token endpoint and Admin API,
+// with a service account key whose token_uri points at the fake.
+func TestAdminAPI(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /token", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"access_token":"fake-token","token_type":"Bearer","expires_in":3600}`)
+	})
+	mux.HandleFunc("GET /projects/{project}/instances/{name}", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer fake-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.PathValue("name") {
+		case "orders":
+			fmt.Fprint(w, `{"name":"orders","databaseVersion":"MYSQL_8_0","settings":{"tier":"db-custom-8-30720","edition":"ENTERPRISE"}}`)
+		case "secret":
+			w.WriteHeader(http.StatusForbidden)
+			fmt.Fprint(w, `{"error":{"code":403,"message":"The client is not authorized to make this request."}}`)
+		case "broken":
+			w.WriteHeader(http.StatusInternalServerError)
+			fmt.Fprint(w, `{"error":{"code":500,"message":"backend error"}}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	useFakeCredentials(t, srv.URL+"/token")
+
+	api := &adminAPI{client: srv.Client(), base: srv.URL}
+	tests := []struct {
+		name, want, wantErr string
+	}{
+		{"orders", "db-custom-8-30720", ""},
+		{"secret", "", "permission denied reading prod:secret (needs cloudsql.instances.get): The client is not authorized"},
+		{"missing", "", "instance prod:missing not found"},
+		{"broken", "", "500 Internal Server Error: backend error"},
+	}
+	for _, tt := range tests {
+		gi, err := api.instance(context.Background(), "prod", tt.name)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("instance %s: error = %v, want %q", tt.name, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil || gi.Settings.Tier != tt.want {
+			t.Errorf("instance %s = %+v, %v, want tier %s", tt.name, gi, err, tt.want)
+		}
+	}
+
+	t.Setenv("GOOGLE_APPLICATION_CREDENTIALS", filepath.Join(t.TempDir(), "missing.json"))
+	if _, err := api.instance(context.Background(), "prod", "orders"); !errors.Is(err, ErrAPIAuth) {
+		t.Errorf("instance with a missing credentials file: error = %v, want a credentials error", err)
+	}
+
+	useFakeCredentials(t, srv.URL+"/revoked")
+	mux.HandleFunc("POST /revoked", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, `{"error":"invalid_grant","error_description":"Invalid JWT Signature."}`)
+	})
+	if _, err := api.instance(context.Background(), "prod", "orders"); !errors.Is(err, ErrAPIAuth) || !strings.Contains(err.Error(), "token request failed") {
+		t.Errorf("instance with a rejected token request: error = %v, want a token error", err)
+	}
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// instanceAdmin reads and changes Cloud SQL instances. adminAPI is the real
+// one. -apply reads the live tier through it, not through the cache.
+type instanceAdmin interface {
+	instanceSource
+	patchTier(ctx context.Context, project, name, tier string) (string, error)
+}
+
+// admin is the client -apply uses.
+var admin instanceAdmin = adminClient
+
+// patchTier asks the Admin API to move an instance to tier and returns the
+// name of the operation doing it. It is not retried: a retried patch the API
+// had already accepted would be queued twice.
+func (a *adminAPI) patchTier(ctx context.Context, project, name, tier string) (string, error) {
+	client, err := adcClient(ctx, a.client)
+	if err != nil {
+		return "", err
+	}
+	body, err := json.Marshal(map[string]any{"settings": map[string]string{"tier": tier}})
+	if err != nil {
+		return "", err
+	}
+	u := fmt.Sprintf("%s/projects/%s/instances/%s", a.base, url.PathEscape(project), url.PathEscape(name))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPatch, u, bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := callAPI(client, "Cloud SQL Admin API", req, 0)
+	if err != nil {
+		return "", err
+	}
+	switch resp.status {
+	case http.StatusOK:
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return "", newAPIError(ErrAPIAuth, "Cloud SQL Admin API: permission denied changing %s:%s (needs cloudsql.instances.update): %s; %s", project, name, apiMessage(resp.body), authHint)
+	case http.StatusNotFound:
+		return "", newAPIError(ErrAPINotFound, "Cloud SQL Admin API: instance %s:%s not found", project, name)
+	case http.StatusConflict:
+		return "", newAPIError(ErrAPIRequest, "Cloud SQL Admin API: %s:%s is busy with another operation: %s", project, name, apiMessage(resp.body))
+	default:
+		return "", resp.failure("Cloud SQL Admin API")
+	}
+	var op struct {
+		Name string `json:"name"`
+	}
+	if err := json.Unmarshal(resp.body, &op); err != nil {
+		return "", fmt.Errorf("Cloud SQL Admin API: invalid operation: %w", err)
+	}
+	return op.Name, nil
+}
+
+// errApplyMode is the error of -apply in a mode that does not recommend a
+// tier to move to.
+var errApplyMode = errors.New("-apply needs a mode that recommends a tier, such as next, prev, rightsize, or check-downgrade")
+
+// Apply is the outcome of -apply: the tier change of an instance, and
+// whether it was made, left as a dry run, declined, or refused.
+type Apply struct {
+	Instance  string `json:"instance"`
+	Current   string `json:"current_tier,omitempty"`
+	Target    string `json:"target_tier,omitempty"`
+	Restart   string `json:"restart,omitempty"`
+	Applied   bool   `json:"applied"`
+	DryRun    bool   `json:"dry_run,omitempty"`
+	Declined  bool   `json:"declined,omitempty"`
+	Refused   string `json:"refused,omitempty"`
+	Operation string `json:"operation,omitempty"`
+}
+
+// confirmApply shows plan on the terminal and asks whether to go ahead.
+// Without a terminal to ask on, the answer is no.
+var confirmApply = func(plan string) (answered, yes bool) {
+	if !isTerminal(os.Stdin) {
+		return false, false
+	}
+	fmt.Fprint(os.Stderr, plan, "Apply this change? [y/N] ")
+	line, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	switch strings.ToLower(strings.TrimSpace(line)) {
+	case "y", "yes":
+		return true, true
+	}
+	return true, false
+}
+
+// restartNote describes the restart a tier change causes on an edition.
+func restartNote(edition string) string {
+	if editionFor(edition) == "enterprise-plus" {
+		return "Cloud SQL restarts the instance to change its tier; Enterprise Plus keeps the downtime near zero"
+	}
+	return "Cloud SQL restarts the instance to change its tier: expect a few minutes of downtime"
+}
+
+// addApply moves -instance to the target tier of res with the Admin API.
+// It refuses a result worked out from an invalid tier, without a valid
+// target, or that violates the policy, and checks that the instance is still on the tier res was worked
+// out from. The change is only made with -yes or when confirmed on the
+// terminal; otherwise it is a dry run.
+func addApply(res *Result) error {
+	if res.Mode == "validate" {
+		return errApplyMode
+	}
+	if opts.instance == "" {
+		return fmt.Errorf("-apply needs -instance <project>:<instance>")
+	}
+	project, name, err := splitInstance(opts.instance, opts.project)
+	if err != nil {
+		return err
+	}
+	a := &Apply{Instance: project + ":" + name, Target: res.targetTier()}
+	res.Apply = a
+	refuse := func(format string, args ...any) error {
+		a.Refused = fmt.Sprintf(format, args...)
+		res.printf("Not applying to %s: %s\n", a.Instance, a.Refused)
+		return nil
+	}
+	if res.TierInfo != nil && !res.Valid {
+		return refuse("%s is not a valid tier to start from", res.InputTier)
+	}
+	if a.Target == "" {
+		return refuse("there is no valid tier to apply")
+	}
+	target, err := ParseTier(a.Target)
+	if err != nil {
+		return refuse("%v", err)
+	}
+	if info := describe(target); !info.Valid {
+		return refuse("%s is not a valid %s tier: %s", a.Target, rules.Name, strings.Join(info.Reasons, "; "))
+	}
+	if len(res.PolicyViolations) > 0 {
+		return refuse("%s violates the policy: %s", a.Target, strings.Join(res.PolicyViolations, "; "))
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), apiTimeout)
+	gi, err := admin.instance(ctx, project, name)
+	cancel()
+	if err != nil {
+		return err
+	}
+	a.Current, a.Restart = gi.Settings.Tier, restartNote(gi.Settings.Edition)
+	current, err := ParseTier(a.Current)
+	if err != nil {
+		return refuse("its current tier %s: %v", a.Current, err)
+	}
+	if res.TierInfo != nil && res.InputTier != "" {
+		if from, err := ParseTier(res.InputTier); err == nil && from != current {
+			return refuse("it is on %s, not the %s this was worked out from", a.Current, res.InputTier)
+		}
+	}
+	if current == target {
+		res.printf("%s is already on %s; nothing to apply\n", a.Instance, a.Target)
+		return nil
+	}
+	plan := fmt.Sprintf("Apply to %s:\n  Tier: %s → %s\n  %s\n", a.Instance, a.Current, a.Target, a.Restart)
+	res.printf("%s", plan)
+	if !opts.yes {
+		switch answered, yes := confirmApply(plan); {
+		case !answered:
+			a.DryRun = true
+			res.printf("Dry run: nothing was changed; add -yes to apply\n")
+			return nil
+		case !yes:
+			a.Declined = true
+			res.printf("Not applied: declined\n")
+			return nil
+		}
+	}
+	// The confirmation can take as long as the operator likes, so the patch
+	// gets a timeout of its own
+	ctx, cancel = context.WithTimeout(context.Background(), apiTimeout)
+	defer cancel()
+	if a.Operation, err = admin.patchTier(ctx, project, name, a.Target); err != nil {
+		return err
+	}
+	a.Applied = true
+	cacheDrop("instances", project+"/"+name)
+	res.printf("Applied: %s is moving to %s (operation %s; follow it with 'gcloud sql operations describe %s --project=%s')\n",
+		a.Instance, a.Target, a.Operation, a.Operation, project)
+	return nil
+}
@@ -0,0 +1,229 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeAdmin is an instanceAdmin over canned instances that records the
+// tiers it was asked to patch to.
+type fakeAdmin struct {
+	fakeInstances
+	patched  []string
+	patchErr error
+}
+
+func (f *fakeAdmin) patchTier(ctx context.Context, project, name, tier string) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	if f.patchErr != nil {
+		return "", f.patchErr
+	}
+	f.patched = append(f.patched, project+":"+name+" "+tier)
+	return "op-1", nil
+}
+
+// useAdmin makes f the -apply client, answering confirmations with answered
+// and yes, for the rest of the test.
+func useAdmin(t *testing.T, f instanceAdmin, answered, yes bool) {
+	saved, savedConfirm, savedOpts := admin, confirmApply, opts
+	admin = f
+	confirmApply = func(string) (bool, bool) { return answered, yes }
+	t.Cleanup(func() { admin, confirmApply, opts = saved, savedConfirm, savedOpts })
+}
+
+func TestApply(t *testing.T) {
+	const from, to = "db-custom-8-53248", "db-custom-4-16384"
+	tests := []struct {
+		name            string
+		current         string // the live tier of prod:orders
+		recommended     string
+		yes             bool
+		answered, agree bool
+		patchErr        error
+		want            Apply
+		wantErr         string
+	}{
+		{"with -yes", from, to, true, false, false, nil, Apply{Target: to, Applied: true, Operation: "op-1"}, ""},
+		{"confirmed", from, to, false, true, true, nil, Apply{Target: to, Applied: true, Operation: "op-1"}, ""},
+		{"declined", from, to, false, true, false, nil, Apply{Target: to, Declined: true}, ""},
+		{"no terminal", from, to, false, false, false, nil, Apply{Target: to, DryRun: true}, ""},
+		{"moved since", "db-custom-16-106496", to, true, false, false, nil, Apply{Target: to, Refused: "it is on db-custom-16-106496, not the db-custom-8-53248 this was worked out from"}, ""},
+		{"invalid downgrade", from, "db-custom-16-106496", true, false, false, nil, Apply{Refused: "there is no valid tier to apply"}, ""},
+		{"patch fails", from, to, true, false, false, errors.New("Cloud SQL Admin API: busy"), Apply{}, "busy"},
+	}
+	for _, tt := range tests {
+		f := &fakeAdmin{fakeInstances: fakeInstances{"prod:orders": fakeInstance("MYSQL_8_0", tt.current, "ENTERPRISE")}, patchErr: tt.patchErr}
+		useAdmin(t, f, tt.answered, tt.agree)
+		opts.apply, opts.yes, opts.instance = true, tt.yes, "prod:orders"
+		res, err := runCheckDowngrade(from, tt.recommended)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = addApply(res)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("%s: error = %v, want %q", tt.name, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		want := tt.want
+		want.Instance = "prod:orders"
+		if tt.recommended == to {
+			want.Current, want.Restart = tt.current, restartNote("ENTERPRISE")
+		}
+		if *res.Apply != want {
+			t.Errorf("%s = %+v, want %+v", tt.name, *res.Apply, want)
+		}
+		if wantPatch := want.Applied; (len(f.patched) == 1) != wantPatch || wantPatch && f.patched[0] != "prod:orders "+to {
+			t.Errorf("%s patched %q, want a patch %t", tt.name, f.patched, wantPatch)
+		}
+		if wantCode := exitOK; want.Refused != "" && res.exitCode() != exitInvalid || want.Refused == "" && res.exitCode() != wantCode {
+			t.Errorf("%s exit = %d, want %d unless refused", tt.name, res.exitCode(), wantCode)
+		}
+	}
+}
+
+// An operator who takes longer than apiTimeout to confirm still gets the
+// change: the patch does not inherit the lookup's deadline.
+func TestApplySlowConfirm(t *testing.T) {
+	f := &fakeAdmin{fakeInstances: fakeInstances{"prod:orders": fakeInstance("MYSQL_8_0", "db-custom-8-53248", "ENTERPRISE")}}
+	useAdmin(t, f, true, true)
+	saved := apiTimeout
+	apiTimeout = 20 * time.Millisecond
+	t.Cleanup(func() { apiTimeout = saved })
+	confirmApply = func(string) (bool, bool) {
+		time.Sleep(5 * apiTimeout)
+		return true, true
+	}
+	opts.apply, opts.instance = true, "prod:orders"
+	res, err := runCheckDowngrade("db-custom-8-53248", "db-custom-4-16384")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := addApply(res); err != nil || !res.Apply.Applied || len(f.patched) != 1 {
+		t.Errorf("apply confirmed after %s = %+v, %v, patched %q, want it applied", 5*apiTimeout, res.Apply, err, f.patched)
+	}
+}
+
+func TestApplyAlreadyOnTarget(t *testing.T) {
+	f := &fakeAdmin{fakeInstances: fakeInstances{"prod:orders": fakeInstance("POSTGRES_16", "db-custom-4-16384", "ENTERPRISE_PLUS")}}
+	useAdmin(t, f, true, true)
+	opts.apply, opts.yes, opts.instance = true, true, "prod:orders"
+	res, err := runCPUMem(4, "16384")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := addApply(res); err != nil {
+		t.Fatal(err)
+	}
+	want := Apply{Instance: "prod:orders", Current: "db-custom-4-16384", Target: "db-custom-4-16384", Restart: restartNote("ENTERPRISE_PLUS")}
+	if *res.Apply != want || len(f.patched) != 0 || !strings.Contains(res.humanText(), "already on db-custom-4-16384; nothing to apply") {
+		t.Errorf("apply to the tier the instance is on = %+v, patched %q, want nothing to apply", *res.Apply, f.patched)
+	}
+}
+
+func TestApplyErrors(t *testing.T) {
+	useAdmin(t, &fakeAdmin{fakeInstances: fakeInstances{}}, false, false)
+	opts.apply = true
+	res, err := runTier("db-custom-4-16384")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := addApply(res); err == nil || !strings.Contains(err.Error(), "needs -instance") {
+		t.Errorf("-apply without -instance: error = %v, want it to need -instance", err)
+	}
+	opts.instance = "prod:missing"
+	if err := addApply(res); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("-apply to a missing instance: error = %v, want not found", err)
+	}
+	validate, err := runValidate("db-custom-4-16384")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := addApply(validate); !errors.Is(err, errApplyMode) {
+		t.Errorf("-apply to -validate: error = %v, want %v", err, errApplyMode)
+	}
+	for _, args := range [][]string{
+		{"next", "-yes", "db-custom-4-16384"},
+		{"list-tiers", "-apply", "-instance", "prod:orders"},
+	} {
+		if _, code := run(t, args...); code != exitUsage {
+			t.Errorf("go-calc %q exited %d, want %d", args, code, exitUsage)
+		}
+	}
+}
+
+// TestPatchTier runs patchTier against a fake Admin API. A patch is never
+// retried, since the API may already have queued it.
+func TestPatchTier(t *testing.T) {
+	var patches int
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /token", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"access_token":"fake-token","token_type":"Bearer","expires_in":3600}`)
+	})
+	mux.HandleFunc("PATCH /projects/{project}/instances/{name}", func(w http.ResponseWriter, r *http.Request) {
+		patches++
+		body, _ := io.ReadAll(r.Body)
+		if r.Header.Get("Authorization") != "Bearer fake-token" || string(body) != `{"settings":{"tier":"db-custom-4-16384"}}` {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		switch r.PathValue("name") {
+		case "orders":
+			fmt.Fprint(w, `{"name":"op-123","operationType":"UPDATE"}`)
+		case "busy":
+			w.WriteHeader(http.StatusConflict)
+			fmt.Fprint(w, `{"error":{"code":409,"message":"Operation failed because another operation was already in progress."}}`)
+		case "secret":
+			w.WriteHeader(http.StatusForbidden)
+		case "flaky":
+			w.WriteHeader(http.StatusServiceUnavailable)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+	useFakeCredentials(t, srv.URL+"/token")
+	defer func(n int) { opts.maxRetries = n }(opts.maxRetries)
+	opts.maxRetries = 3
+
+	api := &adminAPI{client: srv.Client(), base: srv.URL}
+	tests := []struct {
+		name, want, wantErr string
+		kind                error
+	}{
+		{"orders", "op-123", "", nil},
+		{"busy", "", "prod:busy is busy with another operation", ErrAPIRequest},
+		{"secret", "", "needs cloudsql.instances.update", ErrAPIAuth},
+		{"missing", "", "instance prod:missing not found", ErrAPINotFound},
+		{"flaky", "", "503 Service Unavailable", ErrAPITransient},
+	}
+	for _, tt := range tests {
+		patches = 0
+		op, err := api.patchTier(context.Background(), "prod", tt.name, "db-custom-4-16384")
+		if tt.wantErr != "" {
+			if !errors.Is(err, tt.kind) || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("patch %s: error = %v, want %v: %q", tt.name, err, tt.kind, tt.wantErr)
+			}
+		} else if err != nil || op != tt.want {
+			t.Errorf("patch %s = %q, %v, want operation %s", tt.name, op, err, tt.want)
+		}
+		if patches != 1 {
+			t.Errorf("patch %s was sent %d times, want once", tt.name, patches)
+		}
+	}
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// BatchSummary counts the outcomes of a batch run.
+type BatchSummary struct {
+	Total   int `json:"total"`
+	Valid   int `json:"valid"`
+	Invalid int `json:"invalid"`
+	Errors  int `json:"errors"`
+}
+
+// BatchResult is the outcome of validating a list of tiers.
+type BatchResult struct {
+	Mode    string       `json:"mode"`
+	Source  string       `json:"source"` // file name, "-" for stdin, or "arguments"
+	Records []*Result    `json:"records"`
+	Summary BatchSummary `json:"summary"`
+	Error   string       `json:"error,omitempty"`
+	Version *BuildInfo   `json:"version,omitempty"`
+
+	warned int   // records with warnings, including streamed ones
+	err    error // the first -o ndjson write error
+}
+
+func (b *BatchResult) setError(err error) {
+	b.Error = err.Error()
+}
+
+func (b *BatchResult) setVersion(bi *BuildInfo) {
+	b.Version = bi
+}
+
+func (b *BatchResult) humanText() string {
+	label := "line"
+	if b.Source == argsSource {
+		label = "arg"
+	}
+	var sb strings.Builder
+	for _, r := range b.Records {
+		switch {
+		case r.Error != "":
+			fmt.Fprintf(&sb, "%s %d: %s: error: %s\n", label, r.Line, r.InputTier, r.Error)
+		case r.Valid:
+			fmt.Fprintf(&sb, "%s %d: %s: valid\n", label, r.Line, r.InputTier)
+		default:
+			fmt.Fprintf(&sb, "%s %d: %s: invalid (%s)", label, r.Line, r.InputTier, strings.Join(r.Reasons, "; "))
+			if r.SuggestedTier != "" {
+				fmt.Fprintf(&sb, ", suggested %s", r.SuggestedTier)
+			}
+			sb.WriteString("\n")
+		}
+	}
+	s := b.Summary
+	fmt.Fprintf(&sb, "Checked %d tiers: %d valid, %d invalid, %d errors\n", s.Total, s.Valid, s.Invalid, s.Errors)
+	return sb.String()
+}
+
+func (b *BatchResult) csvRecords() [][]string {
+	records := [][]string{resultCSVHeader}
+	for _, r := range b.Records {
+		records = append(records, r.csvRow())
+	}
+	return records
+}
+
+// warnings returns the warnings of every record, prefixed with its line.
+func (b *BatchResult) warnings() []string {
+	var ws []string
+	for _, r := range b.Records {
+		for _, w := range r.Warnings {
+			ws = append(ws, fmt.Sprintf("line %d: %s", r.Line, w))
+		}
+	}
+	return ws
+}
+
+// exitCode is exitParse if any line failed to parse, exitInvalid if any
+// tier was invalid (or, with -strict, had a warning), and exitOK otherwise.
+func (b *BatchResult) exitCode() int {
+	switch {
+	case b.Summary.Errors > 0:
+		return exitParse
+	case b.Summary.Invalid > 0:
+		return exitInvalid
+	case opts.strict && b.warned > 0:
+		return exitInvalid
+	}
+	return exitOK
+}
+
+// runBatch validates one tier per line from path, or stdin when path is "-".
+func runBatch(path string) (*BatchResult, error) {
+	b := &BatchResult{Mode: "batch", Source: path, Records: []*Result{}}
+	if err := readLines(path, b.add); err != nil {
+		return b, err
+	}
+	return b, b.err
+}
+
+// runBatchMode runs -batch on path: validation, or with -normalize the
+// canonical form of each tier.
+func runBatchMode(path string) (report, error) {
+	if opts.normalize {
+		return runNormalizeFile(path)
+	}
+	return runBatch(path)
+}
+
+// maxLineBytes bounds the length of an input line.
+const maxLineBytes = 1 << 20
+
+// readLines calls add with each line of path, or of stdin when path is "-",
+// and its line number, reading one line at a time. Blank lines and lines
+// starting with # are skipped.
+func readLines(path string, add func(line int, text string)) error {
+	var in io.Reader = os.Stdin
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		in = f
+	}
+	scanner := bufio.NewScanner(in)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLineBytes)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		add(line, text)
+	}
+	if errors.Is(scanner.Err(), bufio.ErrTooLong) {
+		return fmt.Errorf("line %d is longer than %d bytes", line+1, maxLineBytes)
+	}
+	return scanner.Err()
+}
+
+// argsSource is the BatchResult source of tiers given as arguments.
+const argsSource = "arguments"
+
+// runTierArgs validates each tier argument; Line is the argument position.
+func runTierArgs(args []string) (*BatchResult, error) {
+	b := &BatchResult{Mode: "batch", Source: argsSource, Records: []*Result{}}
+	for i, arg := range args {
+		b.add(i+1, arg)
+	}
+	return b, b.err
+}
+
+// add validates one tier and records the outcome. With -o ndjson the record
+// is written out instead of kept.
+func (b *BatchResult) add(line int, text string) {
+	res, err := runTier(text)
+	res.Line = line
+	b.Summary.Total++
+	switch {
+	case err != nil:
+		res.setError(err)
+		b.Summary.Errors++
+	case res.Valid:
+		b.Summary.Valid++
+	default:
+		b.Summary.Invalid++
+	}
+	if len(res.Warnings) > 0 {
+		b.warned++
+	}
+	if streaming() {
+		if b.err == nil {
+			b.err = writeJSONLine(streamOut, ndjsonRecord{"record", res})
+		}
+		return
+	}
+	b.Records = append(b.Records, res)
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// cacheSchema is the version of the cache entry format. Entries of another
+// version are ignored and rewritten.
+const cacheSchema = 1
+
+// cacheEntry is one cached API response, stored under its key.
+type cacheEntry struct {
+	Schema int             `json:"schema"`
+	Key    string          `json:"key"`
+	Stored time.Time       `json:"stored"`
+	Data   json.RawMessage `json:"data"`
+}
+
+// cacheDir is where lookups are cached: go-calc under the user cache
+// directory.
+func cacheDir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "go-calc"), nil
+}
+
+// cachePath is the file of key in the cache of kind.
+func cachePath(kind, key string) (string, error) {
+	dir, err := cacheDir()
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(dir, kind, hex.EncodeToString(sum[:8])+".json"), nil
+}
+
+// cacheGet decodes the cached value of key into v. It reports false, leaving
+// the entry to be rewritten, when the cache is off with -no-cache, or the
+// entry is missing, older than -cache-ttl, of another schema, or corrupt.
+func cacheGet(kind, key string, v any) bool {
+	if opts.noCache {
+		return false
+	}
+	path, err := cachePath(kind, key)
+	if err != nil {
+		return false
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return false
+	}
+	var e cacheEntry
+	if json.Unmarshal(data, &e) != nil || e.Schema != cacheSchema || e.Key != key {
+		return false
+	}
+	if age := time.Since(e.Stored); age < 0 || age >= opts.cacheTTL {
+		return false
+	}
+	return json.Unmarshal(e.Data, v) == nil
+}
+
+// cachePut stores v as the cached value of key. The cache is only an
+// optimisation, so failing to write it is not an error.
+func cachePut(kind, key string, v any) {
+	if opts.noCache {
+		return
+	}
+	path, err := cachePath(kind, key)
+	if err != nil {
+		return
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return
+	}
+	entry, err := json.Marshal(cacheEntry{Schema: cacheSchema, Key: key, Stored: time.Now().UTC(), Data: data})
+	if err != nil {
+		return
+	}
+	if os.MkdirAll(filepath.Dir(path), 0o700) != nil {
+		return
+	}
+	// Written aside and renamed, so a concurrent run never reads half an entry
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".entry-*")
+	if err != nil {
+		return
+	}
+	_, err = tmp.Write(entry)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+	}
+}
+
+// cacheDrop removes the cached value of key, once it is known to be stale.
+func cacheDrop(kind, key string) {
+	if path, err := cachePath(kind, key); err == nil {
+		os.Remove(path)
+	}
+}
+
+// cachedInstances serves instance lookups from the cache, keyed by project
+// and instance, and caches what next returns.
+type cachedInstances struct {
+	next instanceSource
+}
+
+func (c cachedInstances) instance(ctx context.Context, project, name string) (*gcloudInstance, error) {
+	key := project + "/" + name
+	var gi gcloudInstance
+	if cacheGet("instances", key, &gi) {
+		return &gi, nil
+	}
+	got, err := c.next.instance(ctx, project, name)
+	if err != nil {
+		return nil, err
+	}
+	cachePut("instances", key, got)
+	return got, nil
+}
+
+// CacheResult is the outcome of a cache action.
+type CacheResult struct {
+	Action  string `json:"action"`
+	Dir     string `json:"dir"`
+	Removed int    `json:"removed"`
+	Error   string `json:"error,omitempty"`
+}
+
+func (c *CacheResult) humanText() string {
+	return fmt.Sprintf("Removed %d cached entries from %s\n", c.Removed, c.Dir)
+}
+
+func (c *CacheResult) exitCode() int      { return exitOK }
+func (c *CacheResult) setError(err error) { c.Error = err.Error() }
+
+// runCache runs a cache action. clear removes every cached lookup.
+func runCache(action string) (*CacheResult, error) {
+	c := &CacheResult{Action: action}
+	if action != "clear" {
+		return c, fmt.Errorf("unknown cache action %q: use clear", action)
+	}
+	dir, err := cacheDir()
+	if err != nil {
+		return c, err
+	}
+	c.Dir = dir
+	err = filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if err != nil || d.IsDir() || !strings.HasSuffix(path, ".json") {
+			return err
+		}
+		c.Removed++
+		return nil
+	})
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return c, err
+	}
+	return c, os.RemoveAll(dir)
+}
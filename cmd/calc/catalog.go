@@ -0,0 +1,221 @@
+package main
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// defaultTiers is the embedded known tier catalog used unless -tiers-file
+// replaces it. It has the same CSV format as a -tiers-file.
+//
+//go:embed tiers.csv
+var defaultTiers []byte
+
+// knownTiers are the standard shapes -t, -downgrade, and the sizing modes
+// step between, sorted by vCPUs then memory without duplicates; the lookups
+// binary-search it. Entries that are not valid
+// under the selected rules (Enterprise Plus shapes under Enterprise, say)
+// are skipped by the lookups rather than removed.
+var knownTiers = mustParseTiers(defaultTiers, "tiers.csv")
+
+// tierEntry is a catalog entry, the line of the file it came from, and why
+// it could not be read, if it could not.
+type tierEntry struct {
+	Tier
+	line int
+	err  error
+}
+
+// parseTiers reads a tier catalog: CSV of cpus,ram_mb pairs, with an
+// optional header and # comments, or a JSON array such as
+//
+//	[{"cpus": 2, "ram_mb": 7680}, {"cpus": 4, "ram_mb": 15360}]
+//
+// name is used in errors; a .json name, or data starting with [, is JSON.
+func parseTiers(data []byte, name string) ([]tierEntry, error) {
+	if strings.EqualFold(filepath.Ext(name), ".json") || bytes.HasPrefix(bytes.TrimSpace(data), []byte("[")) {
+		return parseTiersJSON(data)
+	}
+	return parseTiersCSV(data)
+}
+
+func parseTiersCSV(data []byte) ([]tierEntry, error) {
+	r := csv.NewReader(bytes.NewReader(data))
+	r.Comment = '#'
+	r.FieldsPerRecord = -1
+	r.TrimLeadingSpace = true
+	var entries []tierEntry
+	for first := true; ; first = false {
+		rec, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		line, _ := r.FieldPos(0)
+		if first && len(rec) == 2 && strings.EqualFold(rec[0], "cpus") {
+			continue
+		}
+		if len(rec) != 2 {
+			entries = append(entries, tierEntry{line: line, err: fmt.Errorf("expected cpus,ram_mb, got %d fields", len(rec))})
+			continue
+		}
+		cpu, cerr := strconv.Atoi(strings.TrimSpace(rec[0]))
+		ram, rerr := strconv.Atoi(strings.TrimSpace(rec[1]))
+		if cerr != nil || rerr != nil {
+			entries = append(entries, tierEntry{line: line, err: fmt.Errorf("expected whole numbers cpus,ram_mb, got %q", strings.Join(rec, ","))})
+			continue
+		}
+		entries = append(entries, tierEntry{Tier: Tier{CPUs: cpu, RAMMB: ram}, line: line})
+	}
+	return entries, nil
+}
+
+func parseTiersJSON(data []byte) ([]tierEntry, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('[') {
+		return nil, fmt.Errorf("expected a JSON array of {\"cpus\", \"ram_mb\"} objects")
+	}
+	var entries []tierEntry
+	for dec.More() {
+		line := lineAt(data, dec.InputOffset())
+		var e struct {
+			CPUs  *int `json:"cpus"`
+			RAMMB *int `json:"ram_mb"`
+		}
+		if err := dec.Decode(&e); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		if e.CPUs == nil || e.RAMMB == nil {
+			entries = append(entries, tierEntry{line: line, err: errors.New("expected both cpus and ram_mb")})
+			continue
+		}
+		entries = append(entries, tierEntry{Tier: Tier{CPUs: *e.CPUs, RAMMB: *e.RAMMB}, line: line})
+	}
+	return entries, nil
+}
+
+// lineAt returns the line of the first value at or after offset, skipping
+// the whitespace and comma the JSON decoder stops before.
+func lineAt(data []byte, offset int64) int {
+	i := int(offset)
+	for i < len(data) && strings.IndexByte(" \t\r\n,", data[i]) >= 0 {
+		i++
+	}
+	return 1 + bytes.Count(data[:i], []byte("\n"))
+}
+
+// sortTiers orders tiers by vCPUs then memory and drops duplicates.
+func sortTiers(ts []Tier) []Tier {
+	slices.SortFunc(ts, func(a, b Tier) int {
+		if a.Less(b) {
+			return -1
+		}
+		if b.Less(a) {
+			return 1
+		}
+		return 0
+	})
+	return slices.Compact(ts)
+}
+
+// mustParseTiers reads the embedded catalog. Its entries span editions, so
+// each must be valid under at least one engine and edition rather than under
+// the selected rules, which are not known yet.
+func mustParseTiers(data []byte, name string) []Tier {
+	entries, err := parseTiers(data, name)
+	if err != nil {
+		panic(fmt.Sprintf("%s: %v", name, err))
+	}
+	ts := make([]Tier, len(entries))
+	for i, e := range entries {
+		if e.err == nil && !validSomewhere(e.Tier) {
+			e.err = fmt.Errorf("%s is not valid under any engine and edition", e.Tier)
+		}
+		if e.err != nil {
+			panic(fmt.Sprintf("%s: line %d: %v", name, e.line, e.err))
+		}
+		ts[i] = e.Tier
+	}
+	return sortTiers(ts)
+}
+
+// validSomewhere reports whether t is a valid custom tier for some engine
+// and edition.
+func validSomewhere(t Tier) bool {
+	for _, engine := range sortedKeys(engineRules) {
+		for _, edition := range sortedKeys(editions) {
+			if len(mustLookupRules(engine, edition).violations(t)) == 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// parseSomewhere parses name under the first engine and edition whose
+// limits it is within, so catalogs spanning editions keep every tier.
+func parseSomewhere(name string) (Tier, error) {
+	t, err := ParseTier(name)
+	if err == nil {
+		return t, nil
+	}
+	for _, engine := range sortedKeys(engineRules) {
+		for _, edition := range sortedKeys(editions) {
+			if t, perr := mustLookupRules(engine, edition).parseTier(name); perr == nil {
+				return t, nil
+			}
+		}
+	}
+	return Tier{}, err
+}
+
+// loadTiersFile reads a -tiers-file and returns the catalog to use: the
+// file's tiers alone, or merged into the built-in ones. Every entry must be a
+// valid custom tier under the selected rules; all bad entries are reported
+// with their line numbers.
+func loadTiersFile(path string, merge bool) ([]Tier, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := parseTiers(data, path)
+	if err != nil {
+		return nil, fmt.Errorf("invalid tiers file %s: %w", path, err)
+	}
+	var ts []Tier
+	var errs []error
+	for _, e := range entries {
+		if e.err == nil {
+			if verr := e.Validate(); verr != nil {
+				e.err = fmt.Errorf("%s: %w", e.Tier, verr)
+			}
+		}
+		if e.err != nil {
+			errs = append(errs, fmt.Errorf("line %d: %w", e.line, e.err))
+			continue
+		}
+		ts = append(ts, e.Tier)
+	}
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("invalid tiers file %s:\n%w", path, errors.Join(errs...))
+	}
+	if len(ts) == 0 && !merge {
+		return nil, fmt.Errorf("invalid tiers file %s: no tiers", path)
+	}
+	if merge {
+		ts = append(slices.Clone(knownTiers), ts...)
+	}
+	return sortTiers(ts), nil
+}
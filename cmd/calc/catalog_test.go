@@ -0,0 +1,128 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+)
+
+// linearNextKnownTier and linearPreviousKnownTier are the linear scans the
+// binary searches replaced, kept as the reference behavior.
+func linearNextKnownTier(t Tier) (Tier, bool) {
+	for _, k := range knownTiers {
+		if t.Less(k) && k.Valid() {
+			return k, true
+		}
+	}
+	return Tier{}, false
+}
+
+func linearPreviousKnownTier(t Tier) (Tier, bool) {
+	for i := len(knownTiers) - 1; i >= 0; i-- {
+		k := knownTiers[i]
+		if k.Less(t) && k.Valid() {
+			return k, true
+		}
+	}
+	return Tier{}, false
+}
+
+// useRules selects the rules of engine and edition until the test ends.
+func useRules(t testing.TB, engine, edition string) {
+	saved := rules
+	rules = mustLookupRules(engine, edition)
+	t.Cleanup(func() { rules = saved })
+}
+
+// boundaryTiers are the known tiers and their neighbours a vCPU, a memory
+// step, and a megabyte away, plus the ends of the range.
+func boundaryTiers() []Tier {
+	ts := []Tier{{CPUs: 0, RAMMB: 0}, {CPUs: 1, RAMMB: 0}, {CPUs: 1000, RAMMB: 1 << 30}}
+	for _, k := range knownTiers {
+		for _, dc := range []int{-1, 0, 1} {
+			for _, dr := range []int{-256, -1, 0, 1, 256} {
+				ts = append(ts, Tier{CPUs: k.CPUs + dc, RAMMB: k.RAMMB + dr})
+			}
+		}
+	}
+	return ts
+}
+
+func TestKnownTierSearchMatchesLinearScan(t *testing.T) {
+	for _, engine := range sortedKeys(engineRules) {
+		for _, edition := range sortedKeys(editions) {
+			useRules(t, engine, edition)
+			for _, in := range boundaryTiers() {
+				got, gotOK := findNextKnownTier(in)
+				want, wantOK := linearNextKnownTier(in)
+				if got != want || gotOK != wantOK {
+					t.Errorf("%s %s: findNextKnownTier(%s) = %s, %t, want %s, %t", engine, edition, in, got, gotOK, want, wantOK)
+				}
+				got, gotOK = findPreviousKnownTier(in)
+				want, wantOK = linearPreviousKnownTier(in)
+				if got != want || gotOK != wantOK {
+					t.Errorf("%s %s: findPreviousKnownTier(%s) = %s, %t, want %s, %t", engine, edition, in, got, gotOK, want, wantOK)
+				}
+			}
+		}
+	}
+}
+
+func TestKnownTiersSortedAndValid(t *testing.T) {
+	if !slices.IsSortedFunc(knownTiers, func(a, b Tier) int {
+		switch {
+		case a.Less(b):
+			return -1
+		case b.Less(a):
+			return 1
+		}
+		return 0
+	}) {
+		t.Error("knownTiers is not sorted by vCPUs then memory")
+	}
+	for i, k := range knownTiers {
+		if i > 0 && knownTiers[i-1] == k {
+			t.Errorf("knownTiers has %s twice", k)
+		}
+		if !validSomewhere(k) {
+			t.Errorf("known tier %s is not valid under any engine and edition", k)
+		}
+	}
+}
+
+// allValidTiers returns every valid custom tier under the selected rules, in
+// catalog order.
+func allValidTiers() []Tier {
+	var ts []Tier
+	for cpu := rules.MinCPUs; cpu <= rules.MaxCPUs; cpu++ {
+		for ram := rules.MinRAMMB; ram <= rules.maxRAMFor(cpu); ram += rules.RAMStepMB {
+			if t := (Tier{CPUs: cpu, RAMMB: ram}); t.Valid() {
+				ts = append(ts, t)
+			}
+		}
+	}
+	return ts
+}
+
+// BenchmarkFindNextKnownTier looks up the top of catalogs of growing size;
+// the time grows with log n, where the linear scan grows with n.
+func BenchmarkFindNextKnownTier(b *testing.B) {
+	useRules(b, "mysql", "enterprise-plus")
+	all := allValidTiers()
+	saved := knownTiers
+	b.Cleanup(func() { knownTiers = saved })
+	for _, n := range []int{100, 1000, 10000, len(all)} {
+		knownTiers = all[:n]
+		in := knownTiers[n-2]
+		b.Run(fmt.Sprintf("binary/n=%d", n), func(b *testing.B) {
+			for range b.N {
+				findNextKnownTier(in)
+			}
+		})
+		b.Run(fmt.Sprintf("linear/n=%d", n), func(b *testing.B) {
+			for range b.N {
+				linearNextKnownTier(in)
+			}
+		})
+	}
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"math"
+	"slices"
+)
+
+// cheapestAlternatives is how many runners-up -cheapest lists.
+const cheapestAlternatives = 5
+
+// Cheapest is how a -cheapest recommendation was ranked.
+type Cheapest struct {
+	Basis        string      `json:"basis"` // "cost" or "resources"
+	Candidates   int         `json:"candidates"`
+	Alternatives []*TierInfo `json:"alternatives"`
+}
+
+// cheapestCandidates returns, for every legal vCPU count from the smallest
+// that covers cpu, the smallest valid tier with at least memMB of memory
+// that the policy allows. More memory on the same vCPUs only costs more, so
+// there is at most one candidate per vCPU count.
+func cheapestCandidates(cpu, memMB float64) []Tier {
+	var ts []Tier
+	need := max(roundUp256(int(math.Ceil(memMB))), rules.MinRAMMB)
+	for c := rules.legalCPUAtLeast(int(math.Ceil(max(cpu, 1)))); c <= rules.MaxCPUs; c = rules.legalCPUAtLeast(c + 1) {
+		if float64(c) < cpu {
+			break // legalCPUAtLeast stops at MaxCPUs
+		}
+		t := Tier{CPUs: c, RAMMB: max(need, roundUp256(rules.minRAMFor(c)))}
+		if t.RAMMB <= rules.maxRAMFor(c) && t.Valid() && len(policy.violations(t)) == 0 {
+			ts = append(ts, t)
+		}
+		if c == rules.MaxCPUs {
+			break
+		}
+	}
+	return ts
+}
+
+// resourceScore is the size of t relative to the request: 2 is an exact
+// fit, and each resource adds its overshoot.
+func resourceScore(t Tier, cpu, memMB float64) float64 {
+	return float64(t.CPUs)/max(cpu, 1) + float64(t.RAMMB)/max(memMB, float64(rules.MinRAMMB))
+}
+
+// runCheapest finds the valid tier with at least cpu vCPUs and mem that
+// costs least under the loaded prices, or without -cost the one that
+// overshoots the request least, and lists the next best.
+func runCheapest(cpu float64, mem string) (*Result, error) {
+	res := newResult("cheapest")
+	res.RequestedCPUs = cpu
+	var memMB float64
+	if mem != "" {
+		var err error
+		if memMB, err = parseMem(mem); err != nil {
+			return res, fmt.Errorf("invalid mem format: %w", err)
+		}
+	}
+	res.RequestedMemMB = memMB
+	memMB = res.padMem(res.addConnections(memMB))
+	cpu = res.padCPU(cpu)
+	if cpu <= 0 && memMB <= 0 {
+		return res, fmt.Errorf("-cheapest needs -cpu, -mem, or both")
+	}
+	request := fmt.Sprintf("%g vCPUs and %.0f MB RAM", cpu, memMB)
+	switch {
+	case memMB <= 0:
+		request = fmt.Sprintf("%g vCPUs", cpu)
+	case cpu <= 0:
+		request = fmt.Sprintf("%.0f MB RAM", memMB)
+	}
+	ts := cheapestCandidates(cpu, memMB)
+	if len(ts) == 0 {
+		return res, fmt.Errorf("no valid %s %s tier has at least %s", rules.Name, rules.editionName(), request)
+	}
+	infos := make([]*TierInfo, len(ts))
+	for i, t := range ts {
+		infos[i] = describe(t)
+	}
+	ch := &Cheapest{Basis: "resources", Candidates: len(ts)}
+	if prices != nil {
+		ch.Basis = "cost"
+	}
+	slices.SortStableFunc(infos, func(a, b *TierInfo) int {
+		if ch.Basis == "cost" {
+			if c := cmp.Compare(a.Cost.Monthly, b.Cost.Monthly); c != 0 {
+				return c
+			}
+		}
+		ta, tb := Tier{CPUs: a.CPUs, RAMMB: a.RAMMB}, Tier{CPUs: b.CPUs, RAMMB: b.RAMMB}
+		return cmp.Compare(resourceScore(ta, cpu, memMB), resourceScore(tb, cpu, memMB))
+	})
+	res.TierInfo = infos[0]
+	ch.Alternatives = infos[1:min(len(infos), cheapestAlternatives+1)]
+	res.Cheapest = ch
+	basis := "monthly cost"
+	if ch.Basis == "resources" {
+		basis = "least vCPUs and memory over the request (use -cost to rank by price)"
+	}
+	res.printf("Cheapest CloudSQL %s tier with at least %s, by %s, of %d candidates:\n", rules.Name, request, basis, ch.Candidates)
+	res.printConnections()
+	res.printPadding()
+	res.printf("  - Tier: %s\n", res.TierInfo.summary())
+	for i, alt := range ch.Alternatives {
+		if i == 0 {
+			res.println("Alternatives:")
+		}
+		line := alt.summary()
+		if ch.Basis == "cost" {
+			line += fmt.Sprintf(" (%s)", prices.deltaText(alt.Cost.Monthly-res.Cost.Monthly))
+		}
+		res.printf("  %d. %s\n", i+1, line)
+	}
+	best := Tier{CPUs: res.CPUs, RAMMB: res.RAMMB}
+	res.checkConnections(best)
+	return res, nil
+}
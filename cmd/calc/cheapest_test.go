@@ -0,0 +1,128 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+// legalCPUCounts is how many vCPU counts the current rules allow, the most
+// candidates -cheapest may enumerate.
+func legalCPUCounts() int {
+	n := 0
+	for c := rules.legalCPUAtLeast(1); ; c = rules.legalCPUAtLeast(c + 1) {
+		n++
+		if c == rules.MaxCPUs {
+			return n
+		}
+	}
+}
+
+func TestCheapestCandidatesBounds(t *testing.T) {
+	for _, engine := range sortedKeys(engineRules) {
+		for _, edition := range sortedKeys(editions) {
+			useRules(t, engine, edition)
+			limit := legalCPUCounts()
+			for _, cpu := range []float64{0, 0.5, 1, 3, 6, 17, 64, float64(rules.MaxCPUs)} {
+				for _, memMB := range []float64{0, 1000, 3840, 20480, 20000.5, 106496, float64(rules.MaxRAMMB)} {
+					ts := cheapestCandidates(cpu, memMB)
+					if len(ts) > limit {
+						t.Fatalf("%s %s: %g vCPUs, %g MB: %d candidates, more than the %d legal vCPU counts", engine, edition, cpu, memMB, len(ts), limit)
+					}
+					for i, c := range ts {
+						if !c.Valid() || float64(c.CPUs) < cpu || float64(c.RAMMB) < memMB {
+							t.Fatalf("%s %s: %g vCPUs, %g MB: candidate %s does not meet the request", engine, edition, cpu, memMB, c)
+						}
+						if i > 0 && c.CPUs <= ts[i-1].CPUs {
+							t.Fatalf("%s %s: %g vCPUs, %g MB: candidate %s after %s, want one per vCPU count in order", engine, edition, cpu, memMB, c, ts[i-1])
+						}
+						if less := (Tier{CPUs: c.CPUs, RAMMB: c.RAMMB - 256}); less.Valid() && float64(less.RAMMB) >= memMB {
+							t.Fatalf("%s %s: %g vCPUs, %g MB: candidate %s, but %s also meets the request", engine, edition, cpu, memMB, c, less)
+						}
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestCheapestCandidates(t *testing.T) {
+	useRules(t, "mysql", "enterprise")
+	tests := []struct {
+		cpu, memMB  float64
+		n           int
+		first, last Tier
+	}{
+		{0, 0, 49, Tier{CPUs: 1, RAMMB: 3840}, Tier{CPUs: 96, RAMMB: 88576}},
+		{6, 20480, 46, Tier{CPUs: 6, RAMMB: 20480}, Tier{CPUs: 96, RAMMB: 88576}},
+		{1, 20000, 47, Tier{CPUs: 4, RAMMB: 20224}, Tier{CPUs: 96, RAMMB: 88576}},
+		{0, 614400, 2, Tier{CPUs: 94, RAMMB: 614400}, Tier{CPUs: 96, RAMMB: 614400}},
+		{95, 0, 1, Tier{CPUs: 96, RAMMB: 88576}, Tier{CPUs: 96, RAMMB: 88576}},
+		{97, 0, 0, Tier{}, Tier{}},
+	}
+	for _, tt := range tests {
+		ts := cheapestCandidates(tt.cpu, tt.memMB)
+		if len(ts) != tt.n {
+			t.Errorf("cheapestCandidates(%g, %g) = %d candidates, want %d", tt.cpu, tt.memMB, len(ts), tt.n)
+			continue
+		}
+		if tt.n > 0 && (ts[0] != tt.first || ts[len(ts)-1] != tt.last) {
+			t.Errorf("cheapestCandidates(%g, %g) = %s to %s, want %s to %s", tt.cpu, tt.memMB, ts[0], ts[len(ts)-1], tt.first, tt.last)
+		}
+	}
+}
+
+func TestCheapestRanking(t *testing.T) {
+	for _, cost := range []bool{false, true} {
+		args := []string{"suggest", "-o", "json", "-cheapest", "-cpu", "1", "-mem", "20G"}
+		if cost {
+			args = append(args, "-cost")
+		}
+		out, code := run(t, args...)
+		var res struct {
+			Tier string `json:"tier"`
+			Cost struct {
+				Monthly float64 `json:"monthly"`
+			} `json:"cost"`
+			Cheapest struct {
+				Basis        string `json:"basis"`
+				Candidates   int    `json:"candidates"`
+				Alternatives []struct {
+					Tier string `json:"tier"`
+					Cost struct {
+						Monthly float64 `json:"monthly"`
+					} `json:"cost"`
+				} `json:"alternatives"`
+			} `json:"cheapest"`
+		}
+		if err := json.Unmarshal([]byte(out), &res); err != nil {
+			t.Fatalf("go-calc %q: %v\n%s", args, err, out)
+		}
+		ch := res.Cheapest
+		if code != exitOK || res.Tier != "db-custom-4-20480" || ch.Candidates != 47 || len(ch.Alternatives) != cheapestAlternatives {
+			t.Errorf("go-calc %q = %s of %d candidates with %d alternatives (exit %d), want db-custom-4-20480 of 47 with %d", args, res.Tier, ch.Candidates, len(ch.Alternatives), code, cheapestAlternatives)
+		}
+		if want := map[bool]string{false: "resources", true: "cost"}[cost]; ch.Basis != want {
+			t.Errorf("go-calc %q ranked by %s, want %s", args, ch.Basis, want)
+		}
+		prev := res.Cost.Monthly
+		for _, alt := range ch.Alternatives {
+			if alt.Cost.Monthly < prev {
+				t.Errorf("go-calc %q lists %s at %g/mo after one at %g/mo", args, alt.Tier, alt.Cost.Monthly, prev)
+			}
+			prev = alt.Cost.Monthly
+		}
+	}
+	for _, args := range [][]string{{"-cheapest"}, {"-cheapest", "-cpu", "97"}} {
+		if _, code := run(t, append([]string{"suggest"}, args...)...); code == exitOK {
+			t.Errorf("suggest %q exited %d, want an error", args, code)
+		}
+	}
+}
+
+// BenchmarkCheapestCandidates is the widest enumeration: every vCPU count.
+func BenchmarkCheapestCandidates(b *testing.B) {
+	useRules(b, "mysql", "enterprise-plus")
+	for range b.N {
+		cheapestCandidates(1, 0)
+	}
+}
@@ -0,0 +1,647 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// command is a go-calc subcommand. Each command parses its own flag set, so
+// only the flags that apply to it are accepted.
+type command struct {
+	name    string
+	args    string // usage of the positional arguments
+	summary string
+	nargs   int // number of positional arguments, or -1 for one or more
+	flags   []func(*flag.FlagSet)
+	run     func(args []string) (report, error)
+}
+
+// commands lists the subcommands in the order help shows them.
+var commands = []*command{
+	{"validate", "<tier>", "Validate a tier and show the nearest valid tier if it is not", 1, nil,
+		func(a []string) (report, error) { return runValidate(a[0]) }},
+	{"next", "<tier>", "Show the next known tier up from a tier", 1, []func(*flag.FlagSet){stepsFlags, nearestFlags, scaleFlags},
+		func(a []string) (report, error) { return runTier(a[0]) }},
+	{"prev", "<tier>", "Suggest a downgrade tier", 1, []func(*flag.FlagSet){stepsFlags, strategyFlags},
+		func(a []string) (report, error) { return runDowngrade(a[0]) }},
+	{"bump-mem", "<tier>", "Raise memory to -to-ratio GB/vCPU, keeping vCPUs", 1, []func(*flag.FlagSet){bumpMemFlags},
+		func(a []string) (report, error) { return runBumpMem(a[0]) }},
+	{"bump-cpu", "<tier>", "Raise vCPUs to the next legal count, keeping memory", 1, nil,
+		func(a []string) (report, error) { return runBumpCPU(a[0]) }},
+	{"suggest", "", "Size a tier from -cpu, -mem, both, -data-size, or -connections", 0, []func(*flag.FlagSet){suggestFlags},
+		func([]string) (report, error) { return runSuggest(opts.cpu, opts.mem) }},
+	{"check-downgrade", "<current> <recommended>", "Check that recommended is a valid downgrade from current", 2, []func(*flag.FlagSet){checkFlags, mixedFlags, correctedFlags},
+		func(a []string) (report, error) { return runCheckPair(strings.Join(a, " "), false) }},
+	{"check-upgrade", "<current> <recommended>", "Check that recommended is a valid upgrade from current", 2, []func(*flag.FlagSet){mixedFlags, correctedFlags},
+		func(a []string) (report, error) { return runCheckPair(strings.Join(a, " "), true) }},
+	{"diff", "<tier-a> <tier-b>", "Compare two tiers side by side", 2, nil,
+		func(a []string) (report, error) { return runDiff(a[0], a[1]) }},
+	{"plan", "<current> <target>", "Plan the resizes from current to target, none more than -max-factor times", 2, []func(*flag.FlagSet){planFlags},
+		func(a []string) (report, error) { return runPlan(a[0], a[1]) }},
+	{"replica", "<primary>", "Size a read replica for a primary tier and total the pair", 1, []func(*flag.FlagSet){replicaFlags},
+		func(a []string) (report, error) { return runReplica(a[0]) }},
+	{"rightsize", "<tier>", "Recommend a tier for observed utilization", 1, []func(*flag.FlagSet){rightsizeFlags},
+		func(a []string) (report, error) { return runRightsize(a[0], opts.usage) }},
+	{"growth", "<tier>", "Project the tier needed as load grows", 1, []func(*flag.FlagSet){growthFlags},
+		func(a []string) (report, error) { return runGrowth(a[0], opts.growth) }},
+	{"from-rds", "<class>", "Find the smallest tier with at least the vCPUs and memory of an RDS instance class", 1, nil,
+		func(a []string) (report, error) { return runFromRDS(a[0]) }},
+	{"cpu-range", "<vCPUs>...", "Show the legal memory range of each vCPU count (e.g. 8,16,32)", -1, nil,
+		func(a []string) (report, error) { return runCPURange(a) }},
+	{"mem-range", "<memory>", "Show the vCPU counts that can carry an amount of memory (e.g. 200G)", 1, nil,
+		func(a []string) (report, error) { return runMemRange(a[0]) }},
+	{"matrix", "", "List every tier at 3.75 and 6.5 GB/vCPU and check the known tiers against them", 0, nil,
+		func([]string) (report, error) { return runMatrix() }},
+	{"list-tiers", "", "List the known tiers", 0, []func(*flag.FlagSet){listFlags},
+		func([]string) (report, error) { return runListTiers(opts.filter) }},
+	{"instances", "<file>", "Report on a gcloud instance list JSON file (- for stdin)", 1, []func(*flag.FlagSet){monitorFlags, fleetFlags, reportFlags},
+		func(a []string) (report, error) { return runFleet(a[0]) }},
+	{"recommender", "<file>", "Check every tier change in a Cloud SQL rightsizing recommender JSON export (- for stdin)", 1, nil,
+		func(a []string) (report, error) { return runRecommender(a[0]) }},
+	{"batch", "<file>", "Validate one tier per line (- for stdin)", 1, []func(*flag.FlagSet){batchFlags, reportFlags},
+		func(a []string) (report, error) { return runBatchMode(a[0]) }},
+	{"normalize", "<tier>...", "Print the canonical db-custom-<cpus>-<ram_mb> form of each tier (- reads stdin)", -1, nil,
+		func(a []string) (report, error) { return runNormalize(a) }},
+	{"tiers", "export", "Write the known tier catalog (from -tiers-from or -tiers-file) as a -tiers-file", 1, nil,
+		func(a []string) (report, error) { return runTiers(a[0]) }},
+	{"watch", "", "Compare the live tiers of the instances in a -desired file with their desired tiers, every -interval", 0, []func(*flag.FlagSet){watchFlags},
+		func([]string) (report, error) { return runWatch() }},
+	{"serve", "", "Serve validate, next, suggest, and check-downgrade as a JSON HTTP API on -listen", 0, []func(*flag.FlagSet){checkFlags, mixedFlags, correctedFlags, serveFlags},
+		func([]string) (report, error) { return runServe() }},
+	{"cache", "clear", "Remove the cached Cloud SQL Admin API lookups", 1, nil,
+		func(a []string) (report, error) { return runCache(a[0]) }},
+	{"version", "", "Print the build version and the tier rules revision", 0, nil,
+		func([]string) (report, error) { return runVersion() }},
+}
+
+func lookupCommand(name string) *command {
+	for _, c := range commands {
+		if c.name == name {
+			return c
+		}
+	}
+	return nil
+}
+
+// newFlagSet builds the flag set of a command: its own flags plus the flags
+// shared by every command.
+func (c *command) newFlagSet() *flag.FlagSet {
+	fs := flag.NewFlagSet("go-calc "+c.name, flag.ContinueOnError)
+	for _, register := range c.flags {
+		register(fs)
+	}
+	commonFlags(fs)
+	fs.Usage = func() {
+		w := fs.Output()
+		fmt.Fprintf(w, "Usage: go-calc %s [flags] %s\n\n%s.\n\nFlags:\n", c.name, c.args, c.summary)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// takesCurrent reports whether the first argument of c is a current tier,
+// which -instance can supply from the Cloud SQL Admin API.
+func (c *command) takesCurrent() bool {
+	for _, a := range []string{"<tier>", "<current>", "<primary>"} {
+		if strings.HasPrefix(c.args, a) && c.nargs > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// runCommand runs a subcommand and exits. "help [command]" prints usage.
+func runCommand(name string, args []string) {
+	if name == "help" {
+		if len(args) > 0 {
+			if c := lookupCommand(args[0]); c != nil {
+				fs := c.newFlagSet()
+				fs.SetOutput(os.Stdout)
+				fs.Usage()
+				os.Exit(exitOK)
+			}
+			fmt.Printf("Unknown command %q\n", args[0])
+			os.Exit(exitUsage)
+		}
+		flag.CommandLine.SetOutput(os.Stdout)
+		usage()
+		os.Exit(exitOK)
+	}
+	c := lookupCommand(name)
+	fs := c.newFlagSet()
+	pos, err := parseInterspersed(fs, args)
+	if errors.Is(err, flag.ErrHelp) {
+		os.Exit(exitOK)
+	}
+	if err != nil {
+		os.Exit(exitUsage)
+	}
+	if c.takesCurrent() && opts.instance != "" && len(pos) == c.nargs-1 {
+		pos = append([]string{instanceRef}, pos...)
+	}
+	if c.nargs >= 0 && len(pos) != c.nargs || c.nargs < 0 && len(pos) == 0 {
+		fmt.Fprintf(fs.Output(), "Usage: go-calc %s [flags] %s\n", c.name, c.args)
+		os.Exit(exitUsage)
+	}
+	setup(fs)
+	if c.takesCurrent() {
+		if err := resolveInstanceRef(fs, &pos[0]); err != nil {
+			finish(newResult(c.name), err)
+		}
+	}
+	res, err := c.run(pos)
+	finish(res, err)
+}
+
+// parseInterspersed parses flags that may appear before, between, or after
+// the positional arguments, and returns the positional arguments.
+func parseInterspersed(fs *flag.FlagSet, args []string) ([]string, error) {
+	var pos []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			return pos, nil
+		}
+		pos = append(pos, args[0])
+		args = args[1:]
+	}
+}
+
+// flagSet reports whether the named flag was given to fs.
+func flagSet(fs *flag.FlagSet, name string) bool {
+	set := false
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+	return set
+}
+
+// commonFlags registers the output, rules, and annotation flags every mode
+// accepts.
+func commonFlags(fs *flag.FlagSet) {
+	fs.StringVar(&opts.config, "config", "", "Config file of flag defaults and policy (default $XDG_CONFIG_HOME/go-calc/config.yaml)")
+	fs.StringVar(&opts.tiersFile, "tiers-file", "", "Known tier catalog, CSV or JSON of cpus,ram_mb pairs, to use instead of the built-in one")
+	fs.StringVar(&opts.tiersFrom, "tiers-from", "", "Known tier catalog from a 'gcloud sql tiers list --format=json' file (- for stdin), to use instead of the built-in one")
+	fs.BoolVar(&opts.tiersMerge, "tiers-merge", false, "Add the -tiers-file or -tiers-from tiers to the built-in catalog instead of replacing it")
+	fs.StringVar(&opts.output, "o", "text", "Output format: text, json, yaml, terraform, csv, k8s, gha (GitHub Actions annotations), slack (Block Kit payload), or ndjson (batch and instances records streamed one per line)")
+	fs.StringVar(&opts.notifyURL, "notify-url", "", "With -o slack, also post the payload to this Slack webhook URL")
+	fs.BoolVar(&opts.k8s, "k8s", false, "Print Kubernetes resource requests for the resulting tiers (same as -o k8s)")
+	fs.StringVar(&opts.k8sOverhead, "k8s-overhead", "", "With -k8s, memory to subtract for the OS and agents (e.g., 1Gi)")
+	fs.BoolVar(&opts.quiet, "q", false, "Print only the resulting tier; warnings and errors go to stderr")
+	fs.BoolVar(&opts.quiet, "quiet", false, "Same as -q")
+	fs.StringVar(&opts.format, "format", "", "Go text/template for the output, or @tier-only / @oneline (overrides -o)")
+	fs.Float64Var(&opts.headroom, "headroom", 0, "Percentage to add to the memory requirement before sizing; for rightsize, percentage of capacity to keep free (default 20 there)")
+	fs.Float64Var(&opts.cpuHeadroom, "cpu-headroom", 0, "Percentage to add to the vCPU requirement before sizing; for rightsize, replaces -headroom for vCPUs")
+	fs.BoolVar(&opts.usable, "usable", false, "Show the estimated memory the engine can use, after the OS and agent overhead, and size -data-size and -mysql-config from it")
+	fs.IntVar(&opts.overheadMB, "overhead-mb", 0, "With -usable, fixed overhead in MB (default: the engine's estimate, 1024 for MySQL and PostgreSQL, 2048 for SQL Server)")
+	fs.Float64Var(&opts.overheadPct, "overhead-pct", 0, "With -usable, overhead as a percentage of instance memory (default: the engine's estimate, 5)")
+	fs.StringVar(&opts.round, "round", "", "Snap vCPUs and memory up, down, or nearest (default: each mode's own rounding)")
+	fs.BoolVar(&opts.gcloud, "gcloud", false, "Also print the gcloud command that applies the resulting tier")
+	fs.StringVar(&opts.instance, "instance", "", "Instance name used in generated commands; as <project>:<instance>, or with -project, stands for its current tier when the tier is left out or given as @instance")
+	fs.StringVar(&opts.project, "project", "", "Project used in generated commands")
+	fs.BoolVar(&opts.apply, "apply", false, "Move -instance to the resulting tier with the Cloud SQL Admin API (a dry run without -yes or confirmation)")
+	fs.BoolVar(&opts.yes, "yes", false, "With -apply, make the change without asking")
+	fs.BoolVar(&opts.noCache, "no-cache", false, "Look up -instance in the Cloud SQL Admin API even if it is cached")
+	fs.DurationVar(&opts.cacheTTL, "cache-ttl", time.Hour, "How long a cached -instance lookup is used for")
+	fs.IntVar(&opts.maxRetries, "max-retries", 3, "Times to retry a Cloud SQL Admin or Cloud Monitoring API call that was rate limited or failed on the server side")
+	fs.StringVar(&opts.engine, "engine", "mysql", "Database engine whose tier rules apply: "+strings.Join(sortedKeys(engineRules), ", "))
+	fs.StringVar(&opts.edition, "edition", defaultEdition, "CloudSQL edition whose limits apply: "+strings.Join(sortedKeys(editions), ", "))
+	fs.BoolVar(&opts.cost, "cost", false, "Print estimated monthly cost for the tiers involved")
+	fs.Func("min-tier", "Policy floor: never suggest a tier with fewer vCPUs or less memory than this (config min_tier)", func(v string) error { return policy.set("min_tier", v) })
+	fs.Func("max-tier", "Policy ceiling: never suggest a tier with more vCPUs or memory than this (config max_tier)", func(v string) error { return policy.set("max_tier", v) })
+	fs.BoolVar(&opts.ha, "ha", false, "Treat the instance as regional (HA): double the -cost compute and check the policy ha_min_tier")
+	fs.StringVar(&opts.region, "region", "us-central1", "Region used for cost estimates")
+	fs.StringVar(&opts.prices, "prices", "", "Price table JSON file to use instead of the embedded one")
+	fs.StringVar(&opts.commitment, "commitment", noCommitment, "With -cost, price at this committed use discount: none, 1yr, or 3yr")
+	fs.IntVar(&opts.committedCPUs, "committed-cpus", 0, "vCPUs already under a commitment; warn when a downgrade leaves fewer")
+	fs.StringVar(&opts.committedRAM, "committed-ram", "", "Memory already under a commitment (e.g. 64G); warn when a downgrade leaves less")
+	fs.Float64Var(&opts.ratio, "ratio", defaultGBPerCPU, "Memory per vCPU in GB used to size -cpu, -mem, and suggested tiers")
+	fs.BoolVar(&opts.explain, "explain", false, "Show every rule check and sizing step, with the numbers involved")
+	fs.BoolVar(&opts.strict, "strict", false, "Treat warnings (invalid or adjusted calculated tiers, clamped memory or ratio, aggressive downgrades) as errors (exit code 2)")
+	fs.BoolVar(&opts.tfPlaceholders, "tf-placeholders", false, "Include availability_type and disk_size placeholders in terraform output")
+	fs.BoolVar(&opts.mysqlConfig, "mysql-config", false, "Recommend innodb_buffer_pool_size, innodb_log_file_size, and max_connections for the resulting tier")
+	fs.Float64Var(&opts.bufferPoolPct, "buffer-pool-pct", 75, "With -mysql-config, percentage of memory for the InnoDB buffer pool")
+	fs.Float64Var(&opts.perConnKB, "per-conn-kb", 2048, "With -mysql-config, memory per connection in KB")
+	fs.BoolVar(&opts.equivalents, "equivalents", false, "List the GCE machine types closest to the resulting tier")
+	fs.BoolVar(&opts.toRDS, "to-rds", false, "List the AWS RDS instance classes closest to the resulting tier")
+	fs.StringVar(&opts.flagsFile, "flags-file", "", "Check that the memory flags in this JSON or key=value file fit the resulting tier")
+	fs.Float64Var(&opts.memBudgetPct, "mem-budget-pct", 90, "With -flags-file, percentage of memory the flags may use")
+}
+
+func suggestFlags(fs *flag.FlagSet) {
+	fs.Var(cpuFlag{&opts.cpu, &opts.cpuInput}, "cpu", "Number of `vCPUs` (e.g., 24, 48, or millicores such as 4000m)")
+	fs.StringVar(&opts.mem, "mem", "", "Memory (e.g., 6G, 6144M, 6144, 1.5T, 52Gi, 6442450944B)")
+	fs.BoolVar(&opts.cheapest, "cheapest", false, "Find the valid tier meeting -cpu and -mem that costs least (with -cost), and the 5 next cheapest")
+	fs.StringVar(&opts.dataSize, "data-size", "", "Size the tier from this data volume (e.g., 500G) instead of -cpu and -mem")
+	fs.Float64Var(&opts.workingSet, "working-set", 0.2, "With -data-size, fraction of the data that is hot and should fit in the buffer pool")
+	fs.IntVar(&opts.connections, "connections", 0, "Add the memory of this many client connections to the request (alone, or with -cpu, -mem, or -data-size)")
+	fs.Float64Var(&opts.connMemKB, "conn-mem-kb", 12288, "With -connections, memory per connection in KB")
+	fs.Float64Var(&opts.bufferPoolFraction, "buffer-pool-fraction", 0.72, "With -data-size, fraction of instance memory given to the buffer pool")
+}
+
+func batchFlags(fs *flag.FlagSet) {
+	fs.BoolVar(&opts.normalize, "normalize", false, "Print the canonical form of each tier instead of validating it")
+}
+
+func stepsFlags(fs *flag.FlagSet) {
+	fs.IntVar(&opts.steps, "steps", 0, "List the next N known tiers in that direction")
+}
+
+func nearestFlags(fs *flag.FlagSet) {
+	fs.IntVar(&opts.nearest, "nearest", 0, "List the N known tiers closest in vCPUs and memory")
+	fs.Float64Var(&opts.cpuWeight, "cpu-weight", 1, "Weight of the vCPU difference in the -nearest distance")
+	fs.Float64Var(&opts.memWeight, "mem-weight", 1, "Weight of the memory difference in the -nearest distance")
+}
+
+func scaleFlags(fs *flag.FlagSet) {
+	fs.Float64Var(&opts.scale, "scale", 0, "Multiply the vCPUs and memory of the tier by this factor (e.g., 2 or 0.5) and snap to a valid tier")
+	fs.BoolVar(&opts.clamp, "clamp", false, "With -scale, stop at the smallest or largest tier instead of failing")
+}
+
+func strategyFlags(fs *flag.FlagSet) {
+	fs.StringVar(&opts.strategy, "strategy", "balanced", "Downgrade strategy: mem-first, cpu-first, balanced, or all")
+	fs.Float64Var(&opts.savings.TargetPct, "target-savings", 0, "Pick the downgrade whose resource (or, with -cost, cost) savings are closest to this percentage")
+	fs.Float64Var(&opts.savings.TolerancePct, "tolerance", 5, "Largest distance in percentage points between -target-savings and the savings achieved")
+	fs.BoolVar(&opts.savings.AnyShape, "any-shape", false, "Consider every valid custom shape for -target-savings, not just the known tiers")
+}
+
+func bumpMemFlags(fs *flag.FlagSet) {
+	fs.Float64Var(&opts.toRatio, "to-ratio", 0, "Target memory per vCPU in GB for -bump-mem (default: the engine maximum)")
+}
+
+func checkFlags(fs *flag.FlagSet) {
+	fs.Float64Var(&opts.maxStepPct, "max-step-pct", 50, "Flag downgrades that drop more than this percentage of vCPUs or memory in one step")
+}
+
+func mixedFlags(fs *flag.FlagSet) {
+	fs.BoolVar(&opts.allowMixed, "allow-mixed", false, "Accept a change where one of vCPUs and memory moves the other way")
+}
+
+func correctedFlags(fs *flag.FlagSet) {
+	fs.BoolVar(&opts.assumeCorrected, "assume-corrected", false, "When the current tier is not valid, check against its nearest valid tier instead of stopping")
+}
+
+func planFlags(fs *flag.FlagSet) {
+	fs.Float64Var(&opts.maxFactor, "max-factor", 2, "Largest factor one step of a plan may change vCPUs or memory by")
+}
+
+func replicaFlags(fs *flag.FlagSet) {
+	fs.IntVar(&opts.replicaOffset, "replica-offset", 1, "Known tiers below the primary to suggest for the replica (0 is the same tier)")
+	fs.StringVar(&opts.replicaTier, "replica-tier", "", "Check this replica tier instead of suggesting one")
+	fs.Float64Var(&opts.maxShrinkPct, "max-shrink-pct", 50, "Largest percentage the replica may be below the primary in vCPUs or memory")
+}
+
+func rightsizeFlags(fs *flag.FlagSet) {
+	fs.Float64Var(&opts.usage.CPUPct, "cpu-util", 0, "Observed peak CPU utilization in percent")
+	fs.Float64Var(&opts.usage.MemPct, "mem-util", 0, "Observed peak memory utilization in percent")
+	fs.StringVar(&opts.usageCSV, "usage-csv", "", "CSV of timestamped CPU percent and memory bytes samples to size from at -percentile (- for stdin)")
+	monitorFlags(fs)
+}
+
+func monitorFlags(fs *flag.FlagSet) {
+	fs.BoolVar(&opts.monitor, "monitor", false, "Read -cpu-util and -mem-util from Cloud Monitoring for -instance (for each instance of an instance list)")
+	fs.StringVar(&opts.window, "window", "14d", "With -monitor, how far back to read utilization (e.g. 14d, 36h)")
+	opts.percentile = 95
+	fs.Var(percentileFlag{&opts.percentile}, "percentile", "With -monitor or -usage-csv, percentile of the utilization samples to size for, or max")
+	fs.Var(percentileFlag{&opts.cpuPercentile}, "cpu-percentile", "With -monitor or -usage-csv, percentile to size vCPUs for, or max, instead of -percentile (e.g. 99 for bursts)")
+	fs.Var(percentileFlag{&opts.memPercentile}, "mem-percentile", "With -monitor or -usage-csv, percentile to size memory for, or max, instead of -percentile (e.g. 50 for the steady working set)")
+}
+
+func fleetFlags(fs *flag.FlagSet) {
+	fs.IntVar(&opts.concurrency, "concurrency", 4, "With -monitor, instances of an instance list to look up at once")
+}
+
+func watchFlags(fs *flag.FlagSet) {
+	fs.StringVar(&opts.desired, "desired", "", "YAML file of the instances to watch and their desired tiers")
+	fs.DurationVar(&opts.interval, "interval", 10*time.Minute, "Time between watch passes")
+	fs.BoolVar(&opts.once, "once", false, "Run a single watch pass and exit, with exit code 2 on any finding")
+}
+
+func serveFlags(fs *flag.FlagSet) {
+	fs.StringVar(&opts.listen, "listen", ":8080", "Address serve listens on")
+	fs.BoolVar(&opts.metrics, "metrics", true, "Export Prometheus metrics of the requests and the tier catalog on /metrics")
+}
+
+func growthFlags(fs *flag.FlagSet) {
+	fs.Float64Var(&opts.growth.MemPct, "mem-growth", 0, "Monthly memory growth in percent")
+	fs.Float64Var(&opts.growth.CPUPct, "cpu-growth", 0, "Monthly vCPU growth in percent")
+	fs.IntVar(&opts.growth.Months, "months", 12, "Projection horizon in months")
+	fs.IntVar(&opts.growth.Every, "every", 3, "Months between milestones")
+}
+
+func listFlags(fs *flag.FlagSet) {
+	fs.IntVar(&opts.filter.MinCPUs, "min-cpu", 0, "Only tiers with at least this many vCPUs")
+	fs.IntVar(&opts.filter.MaxCPUs, "max-cpu", 0, "Only tiers with at most this many vCPUs")
+	fs.StringVar(&opts.minMem, "min-mem", "", "Only tiers with at least this much memory (e.g., 16G)")
+	fs.StringVar(&opts.maxMem, "max-mem", "", "Only tiers with at most this much memory (e.g., 64G)")
+	fs.StringVar(&opts.filter.RatioClass, "ratio-class", "", "Only standard (3.75 GB/vCPU) or highmem (6.5 GB/vCPU) tiers")
+}
+
+// setup validates the parsed flags of fs and prepares the selected rules,
+// prices, and output format. It exits on a usage error.
+func setup(fs *flag.FlagSet) {
+	fail := func(msg any) {
+		fmt.Println(msg)
+		os.Exit(exitUsage)
+	}
+	var err error
+	if path := opts.config; path != "" {
+		err = loadConfig(fs, path, true)
+	} else if path = defaultConfigPath(); path != "" {
+		err = loadConfig(fs, path, false)
+	}
+	if err != nil {
+		fail(err)
+	}
+	if opts.quiet {
+		opts.output = "quiet"
+	}
+	if opts.k8s {
+		opts.output = "k8s"
+	}
+	if opts.format != "" {
+		if outputTemplate, err = parseFormat(opts.format); err != nil {
+			fail(err)
+		}
+		// With -o slack the template renders the payload instead
+		if opts.output != "slack" {
+			opts.output = "template"
+		}
+	}
+	if err = calibrateOverhead(fs); err != nil {
+		fail(err)
+	}
+	if err = selectRules(fs, opts.engine, opts.edition); err != nil {
+		fail(err)
+	}
+	if opts.strategy == "" {
+		opts.strategy = "balanced"
+	}
+	switch {
+	case opts.tiersFile != "" && opts.tiersFrom != "":
+		fail("-tiers-file and -tiers-from cannot be combined")
+	case opts.tiersFile != "":
+		if knownTiers, err = loadTiersFile(opts.tiersFile, opts.tiersMerge); err != nil {
+			fail(err)
+		}
+	case opts.tiersFrom != "":
+		if knownTiers, err = loadGcloudTiers(opts.tiersFrom, opts.tiersMerge); err != nil {
+			fail(err)
+		}
+	case opts.tiersMerge:
+		fail("-tiers-merge requires -tiers-file or -tiers-from")
+	}
+	if opts.strategy != "all" && downgradeStrategy(opts.strategy) == nil {
+		fail(fmt.Sprintf("Unknown strategy %q: use mem-first, cpu-first, balanced, or all", opts.strategy))
+	}
+	if opts.savings.TargetPct != 0 && flagSet(fs, "strategy") {
+		fail("-target-savings cannot be combined with -strategy")
+	}
+	if (opts.savings.AnyShape || flagSet(fs, "tolerance")) && opts.savings.TargetPct == 0 {
+		fail("-any-shape and -tolerance require -target-savings")
+	}
+	if flagSet(fs, "cpu-weight") || flagSet(fs, "mem-weight") {
+		if opts.cpuWeight < 0 || opts.memWeight < 0 || opts.cpuWeight+opts.memWeight == 0 {
+			fail("-cpu-weight and -mem-weight must be non-negative and not both zero")
+		}
+	}
+	if flagSet(fs, "scale") && opts.scale <= 0 {
+		fail("-scale must be above 0")
+	}
+	if opts.clamp && opts.scale == 0 {
+		fail("-clamp requires -scale")
+	}
+	if opts.report != "" && opts.report != "html" {
+		fail(fmt.Sprintf("Unknown -report format %q: use html", opts.report))
+	}
+	switch opts.round {
+	case "", roundUp, roundDown, roundNearest:
+	default:
+		fail(fmt.Sprintf("Unknown -round %q: use up, down, or nearest", opts.round))
+	}
+	if opts.headroom < 0 || opts.cpuHeadroom < 0 {
+		fail("-headroom and -cpu-headroom must not be negative")
+	}
+	opts.usage.HeadroomPct = 20
+	if flagSet(fs, "headroom") {
+		opts.usage.HeadroomPct = opts.headroom
+	}
+	if flagSet(fs, "cpu-headroom") {
+		opts.usage.CPUHeadroomPct = &opts.cpuHeadroom
+	}
+	if opts.connections < 0 || opts.connections > 0 && opts.connMemKB <= 0 {
+		fail("-connections must not be negative and -conn-mem-kb must be positive")
+	}
+	if opts.monitor && (flagSet(fs, "cpu-util") || flagSet(fs, "mem-util")) {
+		fail("-monitor cannot be combined with -cpu-util or -mem-util")
+	}
+	if opts.usageCSV != "" && (opts.monitor || flagSet(fs, "cpu-util") || flagSet(fs, "mem-util")) {
+		fail("-usage-csv cannot be combined with -monitor, -cpu-util, or -mem-util")
+	}
+	if !opts.monitor && flagSet(fs, "window") {
+		fail("-window requires -monitor")
+	}
+	if !opts.monitor && opts.usageCSV == "" && (flagSet(fs, "percentile") || flagSet(fs, "cpu-percentile") || flagSet(fs, "mem-percentile")) {
+		fail("-percentile, -cpu-percentile, and -mem-percentile require -monitor or -usage-csv")
+	}
+	if opts.monitor {
+		if _, err := parseWindow(opts.window); err != nil {
+			fail(err)
+		}
+	}
+	if opts.yes && !opts.apply {
+		fail("-yes requires -apply")
+	}
+	if err := checkInstanceFlags(opts.instance, opts.project); err != nil {
+		fail(err)
+	}
+	if flagSet(fs, "interval") && opts.interval <= 0 {
+		fail("-interval must be positive")
+	}
+	if opts.cacheTTL < 0 {
+		fail("-cache-ttl must not be negative")
+	}
+	if opts.maxRetries < 0 {
+		fail("-max-retries must not be negative")
+	}
+	if flagSet(fs, "concurrency") && opts.concurrency < 1 {
+		fail("-concurrency must be at least 1")
+	}
+	if opts.bufferPoolPct <= 0 || opts.bufferPoolPct >= 100 || opts.perConnKB <= 0 {
+		fail("-buffer-pool-pct must be between 0 and 100 and -per-conn-kb must be positive")
+	}
+	if opts.filter.MinRAMMB, err = parseOptionalMem(opts.minMem); err != nil {
+		fail(err)
+	}
+	if opts.filter.MaxRAMMB, err = parseOptionalMem(opts.maxMem); err != nil {
+		fail(err)
+	}
+	if mb, err := parseOptionalMem(opts.committedRAM); err != nil {
+		fail(fmt.Sprintf("Invalid -committed-ram: %v", err))
+	} else if opts.committedCPUs < 0 {
+		fail("-committed-cpus must not be negative")
+	} else {
+		opts.committedRAMMB = int(mb)
+	}
+	if mb, err := parseOptionalMem(opts.k8sOverhead); err != nil {
+		fail(fmt.Sprintf("Invalid -k8s-overhead: %v", err))
+	} else {
+		opts.k8sOverheadMB = int(mb)
+	}
+	if opts.cost {
+		if prices, err = loadPrices(opts.prices); err != nil {
+			fail(err)
+		}
+		if _, err = prices.region(opts.region); err != nil {
+			fail(err)
+		}
+		if _, err = prices.commitment(opts.commitment); err != nil {
+			fail(err)
+		}
+	} else if flagSet(fs, "commitment") {
+		fail("-commitment requires -cost")
+	}
+	switch opts.output {
+	case "text", "json", "yaml", "terraform", "csv", "k8s", "gha", "slack", "ndjson", "template", "quiet":
+	default:
+		fail(fmt.Sprintf("Unknown output format %q: use text, json, yaml, terraform, csv, k8s, gha, slack, or ndjson", opts.output))
+	}
+	if opts.report != "" && opts.output == "ndjson" {
+		fail("-report does not work with -o ndjson, which keeps no records")
+	}
+	if opts.notifyURL != "" && opts.output != "slack" {
+		fail("-notify-url requires -o slack")
+	}
+}
+
+// selectRules makes the tier rules of engine and edition current and fits
+// the sizing ratios given on fs, or their defaults, to them.
+func selectRules(fs *flag.FlagSet, engine, edition string) error {
+	var err error
+	if rules, err = lookupRules(engine, edition); err != nil {
+		return err
+	}
+	if flagSet(fs, "ratio") {
+		if err = rules.checkRatio(opts.ratio); err != nil {
+			return err
+		}
+	} else if r := rules.clampRatio(opts.ratio); r != opts.ratio {
+		opts.ratioNote = fmt.Sprintf("sizing ratio adjusted from %g to %g GB/vCPU to fit the %s %s range of %s", opts.ratio, r, rules.Name, rules.editionName(), rules.ratioRange())
+		opts.ratio = r
+	}
+	if min := policy.ForbidRatiosBelow; opts.ratio < min {
+		if flagSet(fs, "ratio") {
+			return fmt.Errorf("ratio %g GB/vCPU is below the policy forbid_ratios_below %g", opts.ratio, min)
+		}
+		opts.ratioNote = fmt.Sprintf("sizing ratio adjusted from %g to %g GB/vCPU by the policy forbid_ratios_below", opts.ratio, min)
+		opts.ratio = min
+	}
+	if flagSet(fs, "to-ratio") {
+		if err = rules.checkRatio(opts.toRatio); err != nil {
+			return err
+		}
+	} else {
+		opts.toRatio = rules.MaxGBPerCPU
+	}
+	return nil
+}
+
+// finish writes the outcome of a mode and exits with its exit code. JSON and
+// YAML output include the build info.
+func finish(res report, err error) {
+	if v, ok := res.(versioned); ok && (opts.output == "json" || opts.output == "yaml" || opts.output == "ndjson") {
+		v.setVersion(buildInfo())
+	}
+	if err != nil {
+		res.setError(err)
+		switch opts.output {
+		case "json", "yaml", "csv", "ndjson":
+			emit(os.Stdout, opts.output, res)
+		case "quiet":
+			fmt.Fprintln(os.Stderr, err)
+		case "gha":
+			fmt.Println(annotation{level: "error", message: err.Error()})
+		default:
+			fmt.Println(err)
+		}
+		os.Exit(exitCodeFor(err))
+	}
+	if _, ok := res.(*Result); !ok && opts.apply {
+		fmt.Fprintln(os.Stderr, errApplyMode)
+		os.Exit(exitUsage)
+	}
+	if r, ok := res.(*Result); ok {
+		if err := annotate(r); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(exitUsage)
+		}
+	}
+	if err := emit(os.Stdout, opts.output, res); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(exitUsage)
+	}
+	if opts.notifyURL != "" {
+		if err := notifySlack(res); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(exitUsage)
+		}
+	}
+	if opts.report != "" {
+		if err := saveReport(res); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(exitUsage)
+		}
+		fmt.Fprintf(os.Stderr, "Report written to %s\n", opts.reportOut)
+	}
+	writeWarnings(res)
+	os.Exit(res.exitCode())
+}
+
+// writeWarnings sends the warnings of res to stderr, keeping stdout for the
+// result. JSON, YAML, and NDJSON carry them in the warnings field instead. With
+// -strict they are reported as errors. GitHub Actions annotations include
+// them as well.
+func writeWarnings(res report) {
+	if opts.output == "json" || opts.output == "yaml" || opts.output == "ndjson" || opts.output == "gha" {
+		return
+	}
+	var ws []string
+	label := "Warning"
+	if opts.strict {
+		label = "Error"
+	}
+	switch r := res.(type) {
+	case *Result:
+		ws = r.Warnings
+	case *BatchResult:
+		ws = r.warnings()
+	case *FleetResult:
+		ws = r.warnings()
+	case *NormalizeResult:
+		ws, label = r.warnings(), "Error"
+	}
+	for _, w := range ws {
+		fmt.Fprintf(os.Stderr, "%s: %s\n", label, w)
+	}
+}
+
+// printExitCodes documents the process exit codes.
+func printExitCodes(w io.Writer) {
+	fmt.Fprintln(w, "Exit codes:")
+	fmt.Fprintln(w, "  0  tier is valid (or the downgrade/upgrade is valid)")
+	fmt.Fprintln(w, "  1  usage error")
+	fmt.Fprintln(w, "  2  tier parsed but failed validation (or the downgrade/upgrade is not valid)")
+	fmt.Fprintln(w, "  3  input could not be parsed")
+	fmt.Fprintln(w, "  4  the current tier of a downgrade/upgrade check failed validation")
+	fmt.Fprintln(w, "  5  the resulting tier is outside the -min-tier/-max-tier policy")
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+)
+
+// Delta is the change in resources from one tier to another.
+type Delta struct {
+	CPUs   float64 `json:"cpus"`
+	CPUPct float64 `json:"cpus_pct"`
+	RAMMB  int     `json:"ram_mb"`
+	RAMPct float64 `json:"ram_pct"`
+}
+
+// compareTiers returns the change from a to b.
+func compareTiers(a, b Tier) Delta {
+	d := Delta{
+		CPUs:  b.VCPUs() - a.VCPUs(),
+		RAMMB: b.RAMMB - a.RAMMB,
+	}
+	if a.VCPUs() != 0 {
+		d.CPUPct = d.CPUs / a.VCPUs() * 100
+	}
+	if a.RAMMB != 0 {
+		d.RAMPct = float64(d.RAMMB) / float64(a.RAMMB) * 100
+	}
+	return d
+}
+
+// String formats the delta, e.g. "-8 vCPUs (-50%), -26624 MB (-50%)".
+func (d Delta) String() string {
+	return fmt.Sprintf("%+g vCPUs (%+.0f%%), %+d MB (%+.0f%%)", d.CPUs, d.CPUPct, d.RAMMB, d.RAMPct)
+}
+
+// maxDropPct returns the larger of the CPU and memory reductions, as a
+// positive percentage.
+func (d Delta) maxDropPct() float64 {
+	return max(-d.CPUPct, -d.RAMPct, 0)
+}
+
+// distance is the weighted mean of the absolute CPU and memory changes, as a
+// fraction: 0 is the same shape and 1 is, on average, a 100% difference.
+func (d Delta) distance(cpuWeight, memWeight float64) float64 {
+	return (cpuWeight*math.Abs(d.CPUPct) + memWeight*math.Abs(d.RAMPct)) / (cpuWeight + memWeight) / 100
+}
+
+// direction classifies the change: "upgrade" when nothing shrinks,
+// "downgrade" when nothing grows, "same" when nothing changes, and
+// "sideways" when one resource grows while the other shrinks.
+func (d Delta) direction() string {
+	switch {
+	case d.CPUs == 0 && d.RAMMB == 0:
+		return "same"
+	case d.CPUs >= 0 && d.RAMMB >= 0:
+		return "upgrade"
+	case d.CPUs <= 0 && d.RAMMB <= 0:
+		return "downgrade"
+	}
+	return "sideways"
+}
+
+// Verdict is the per-resource direction of a change: each of CPU and RAM is
+// "decreased", "increased", or "unchanged", and Change is "lower" when
+// nothing increases, "higher" when nothing decreases, "same" when nothing
+// changes, and "mixed" when one resource increases while the other decreases.
+type Verdict struct {
+	CPU    string `json:"cpu"`
+	RAM    string `json:"ram"`
+	Change string `json:"change"`
+}
+
+// verdict classifies the change in each resource and overall.
+func (d Delta) verdict() Verdict {
+	dir := func(change float64) string {
+		switch {
+		case change < 0:
+			return "decreased"
+		case change > 0:
+			return "increased"
+		}
+		return "unchanged"
+	}
+	change := map[string]string{"same": "same", "upgrade": "higher", "downgrade": "lower", "sideways": "mixed"}[d.direction()]
+	return Verdict{CPU: dir(d.CPUs), RAM: dir(float64(d.RAMMB)), Change: change}
+}
+
+// Neighbour is a known tier ranked by its distance from another tier.
+type Neighbour struct {
+	*TierInfo
+	Distance  float64 `json:"distance"`
+	Direction string  `json:"direction"`
+}
+
+// nearestKnownTiers returns the n valid known tiers closest to t, nearest
+// first. Ties keep the known tier order.
+func nearestKnownTiers(t Tier, n int, cpuWeight, memWeight float64) []*Neighbour {
+	var ns []*Neighbour
+	for _, k := range knownTiers {
+		if !k.Valid() {
+			continue
+		}
+		d := compareTiers(t, k)
+		ns = append(ns, &Neighbour{TierInfo: describe(k), Distance: d.distance(cpuWeight, memWeight), Direction: d.direction()})
+	}
+	sort.SliceStable(ns, func(i, j int) bool { return ns[i].Distance < ns[j].Distance })
+	return ns[:min(n, len(ns))]
+}
+
+// KnownNeighbour is the known tier next to another tier, and how that tier
+// compares with it.
+type KnownNeighbour struct {
+	*TierInfo
+	Delta Delta `json:"delta"` // from this known tier to the other tier
+}
+
+// KnownNeighbours are the valid known tiers just below and above a tier in
+// the catalog order.
+type KnownNeighbours struct {
+	Exact bool            `json:"exact"`
+	Below *KnownNeighbour `json:"below,omitempty"`
+	Above *KnownNeighbour `json:"above,omitempty"`
+}
+
+// knownNeighbours returns the known tiers on either side of t.
+func knownNeighbours(t Tier) *KnownNeighbours {
+	ns := &KnownNeighbours{Exact: t.Valid() && slices.Contains(knownTiers, t)}
+	if k, ok := findPreviousKnownTier(t); ok {
+		ns.Below = &KnownNeighbour{TierInfo: describe(k), Delta: compareTiers(k, t)}
+	}
+	if k, ok := findNextKnownTier(t); ok {
+		ns.Above = &KnownNeighbour{TierInfo: describe(k), Delta: compareTiers(k, t)}
+	}
+	return ns
+}
+
+// relative describes a percentage change of a resource, e.g. "12% more RAM"
+// or, with less "fewer", "50% fewer vCPUs".
+func relative(pct float64, resource, less string) string {
+	switch {
+	case math.Round(pct) > 0:
+		return fmt.Sprintf("%.0f%% more %s", pct, resource)
+	case math.Round(pct) < 0:
+		return fmt.Sprintf("%.0f%% %s %s", -pct, less, resource)
+	}
+	return "same " + resource
+}
+
+// text describes how the other tier compares with the neighbour.
+func (n *KnownNeighbour) text() string {
+	return fmt.Sprintf("%s, %s than %s", relative(n.Delta.CPUPct, "vCPUs", "fewer"), relative(n.Delta.RAMPct, "RAM", "less"), n.Tier)
+}
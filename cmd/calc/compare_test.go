@@ -0,0 +1,165 @@
+package main
+
+import (
+	"encoding/json"
+	"math"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestDistance(t *testing.T) {
+	tests := []struct {
+		from, to             Tier
+		cpuWeight, memWeight float64
+		want                 float64
+		direction            string
+	}{
+		{Tier{CPUs: 4, RAMMB: 16384}, Tier{CPUs: 4, RAMMB: 16384}, 1, 1, 0, "same"},
+		{Tier{CPUs: 4, RAMMB: 16384}, Tier{CPUs: 8, RAMMB: 32768}, 1, 1, 1, "upgrade"},
+		{Tier{CPUs: 4, RAMMB: 16384}, Tier{CPUs: 8, RAMMB: 16384}, 1, 1, 0.5, "upgrade"},
+		{Tier{CPUs: 4, RAMMB: 16384}, Tier{CPUs: 8, RAMMB: 16384}, 3, 1, 0.75, "upgrade"},
+		{Tier{CPUs: 4, RAMMB: 16384}, Tier{CPUs: 8, RAMMB: 16384}, 0, 1, 0, "upgrade"},
+		{Tier{CPUs: 4, RAMMB: 16384}, Tier{CPUs: 8, RAMMB: 16384}, 1, 0, 1, "upgrade"},
+		{Tier{CPUs: 8, RAMMB: 32768}, Tier{CPUs: 4, RAMMB: 8192}, 1, 1, 0.625, "downgrade"},
+		{Tier{CPUs: 8, RAMMB: 32768}, Tier{CPUs: 4, RAMMB: 8192}, 1, 3, 0.6875, "downgrade"},
+		{Tier{CPUs: 2, RAMMB: 8192}, Tier{CPUs: 4, RAMMB: 4096}, 1, 1, 0.75, "sideways"},
+		{Tier{CPUs: 16, RAMMB: 61440}, Tier{CPUs: 16, RAMMB: 106496}, 1, 1, 0.3667, "upgrade"},
+	}
+	for _, tt := range tests {
+		d := compareTiers(tt.from, tt.to)
+		if got := d.distance(tt.cpuWeight, tt.memWeight); math.Abs(got-tt.want) > 1e-4 {
+			t.Errorf("distance %s -> %s at %g:%g = %g, want %g", tt.from, tt.to, tt.cpuWeight, tt.memWeight, got, tt.want)
+		}
+		if got := d.direction(); got != tt.direction {
+			t.Errorf("direction %s -> %s = %s, want %s", tt.from, tt.to, got, tt.direction)
+		}
+	}
+}
+
+func TestNearestKnownTiers(t *testing.T) {
+	got := nearestKnownTiers(Tier{CPUs: 8, RAMMB: 32768}, 3, 1, 1)
+	want := []string{"db-custom-8-30720", "db-custom-10-38400", "db-custom-6-39936"}
+	if len(got) != len(want) {
+		t.Fatalf("nearestKnownTiers returned %d tiers, want %d", len(got), len(want))
+	}
+	for i, n := range got {
+		if n.Tier != want[i] {
+			t.Errorf("nearestKnownTiers[%d] = %s (distance %g), want %s", i, n.Tier, n.Distance, want[i])
+		}
+		if i > 0 && n.Distance < got[i-1].Distance {
+			t.Errorf("nearestKnownTiers not sorted: %g after %g", n.Distance, got[i-1].Distance)
+		}
+	}
+}
+
+func TestVerdict(t *testing.T) {
+	tests := []struct {
+		name     string
+		from, to Tier
+		want     Verdict
+	}{
+		{"both lower", Tier{CPUs: 8, RAMMB: 53248}, Tier{CPUs: 4, RAMMB: 16384}, Verdict{"decreased", "decreased", "lower"}},
+		{"both higher", Tier{CPUs: 4, RAMMB: 16384}, Tier{CPUs: 8, RAMMB: 53248}, Verdict{"increased", "increased", "higher"}},
+		{"fewer vCPUs, more memory", Tier{CPUs: 8, RAMMB: 30720}, Tier{CPUs: 6, RAMMB: 39936}, Verdict{"decreased", "increased", "mixed"}},
+		{"more vCPUs, less memory", Tier{CPUs: 6, RAMMB: 39936}, Tier{CPUs: 8, RAMMB: 30720}, Verdict{"increased", "decreased", "mixed"}},
+		{"less memory only", Tier{CPUs: 8, RAMMB: 53248}, Tier{CPUs: 8, RAMMB: 30720}, Verdict{"unchanged", "decreased", "lower"}},
+		{"more vCPUs only", Tier{CPUs: 4, RAMMB: 16384}, Tier{CPUs: 6, RAMMB: 16384}, Verdict{"increased", "unchanged", "higher"}},
+		{"same", Tier{CPUs: 4, RAMMB: 16384}, Tier{CPUs: 4, RAMMB: 16384}, Verdict{"unchanged", "unchanged", "same"}},
+	}
+	for _, tt := range tests {
+		if got := compareTiers(tt.from, tt.to).verdict(); got != tt.want {
+			t.Errorf("%s: verdict %s -> %s = %+v, want %+v", tt.name, tt.from, tt.to, got, tt.want)
+		}
+	}
+}
+
+// -allow-mixed accepts a mixed change either way but does not turn a clean
+// upgrade into a downgrade or the reverse.
+func TestCheckQuadrants(t *testing.T) {
+	tests := []struct {
+		name      string
+		from, to  string
+		downgrade int
+		upgrade   int
+		mixed     bool
+	}{
+		{"both lower", "db-custom-8-53248", "db-custom-4-16384", exitOK, exitInvalid, false},
+		{"both higher", "db-custom-4-16384", "db-custom-8-53248", exitInvalid, exitOK, false},
+		{"fewer vCPUs, more memory", "db-custom-8-30720", "db-custom-6-39936", exitInvalid, exitInvalid, true},
+		{"more vCPUs, less memory", "db-custom-6-39936", "db-custom-8-30720", exitInvalid, exitInvalid, true},
+	}
+	for _, tt := range tests {
+		for _, check := range []struct {
+			name string
+			want int
+		}{{"check-downgrade", tt.downgrade}, {"check-upgrade", tt.upgrade}} {
+			if _, code := run(t, check.name, tt.from, tt.to); code != check.want {
+				t.Errorf("%s: %s exited %d, want %d", tt.name, check.name, code, check.want)
+			}
+			want := check.want
+			if tt.mixed {
+				want = exitOK
+			}
+			if _, code := run(t, check.name, "-allow-mixed", tt.from, tt.to); code != want {
+				t.Errorf("%s: %s -allow-mixed exited %d, want %d", tt.name, check.name, code, want)
+			}
+		}
+	}
+}
+
+// An invalid current tier stops a change check with its own exit code, and
+// -assume-corrected checks from its nearest valid tier instead.
+func TestInvalidCurrentTier(t *testing.T) {
+	tests := []struct {
+		name      string
+		args      []string
+		want      int
+		corrected string
+		checked   string // valid_downgrade or valid_upgrade, when checked
+	}{
+		{"both invalid", []string{"check-downgrade", "db-custom-8-53249", "db-custom-3-16384"}, exitCurrent, "db-custom-10-53504", ""},
+		{"current invalid only", []string{"check-downgrade", "db-custom-7-53248", "db-custom-4-16384"}, exitCurrent, "db-custom-8-53248", ""},
+		{"recommended invalid only", []string{"check-downgrade", "db-custom-8-53248", "db-custom-3-16384"}, exitInvalid, "", "false"},
+		{"both valid", []string{"check-downgrade", "db-custom-8-53248", "db-custom-4-16384"}, exitOK, "", "true"},
+		{"upgrade from an invalid tier", []string{"check-upgrade", "db-custom-3-16384", "db-custom-8-53248"}, exitCurrent, "db-custom-4-16384", ""},
+		{"current invalid only, corrected", []string{"check-downgrade", "-assume-corrected", "db-custom-7-53248", "db-custom-4-16384"}, exitOK, "db-custom-8-53248", "true"},
+		{"both invalid, corrected", []string{"check-downgrade", "-assume-corrected", "db-custom-8-53249", "db-custom-3-16384"}, exitInvalid, "db-custom-10-53504", "false"},
+		{"upgrade, corrected", []string{"check-upgrade", "-assume-corrected", "db-custom-3-16384", "db-custom-8-53248"}, exitOK, "db-custom-4-16384", "true"},
+	}
+	for _, tt := range tests {
+		out, code := run(t, append(tt.args, "-o", "json")...)
+		var res struct {
+			Corrected *TierInfo `json:"corrected_current"`
+			Downgrade *bool     `json:"valid_downgrade"`
+			Upgrade   *bool     `json:"valid_upgrade"`
+			Warnings  []string  `json:"warnings"`
+		}
+		if err := json.Unmarshal([]byte(out), &res); err != nil {
+			t.Fatalf("%s: %v\n%s", tt.name, err, out)
+		}
+		corrected, checked := "", ""
+		if res.Corrected != nil {
+			corrected = res.Corrected.Tier
+		}
+		for _, v := range []*bool{res.Downgrade, res.Upgrade} {
+			if v != nil {
+				checked = strconv.FormatBool(*v)
+			}
+		}
+		if code != tt.want || corrected != tt.corrected || checked != tt.checked {
+			t.Errorf("%s: exit %d, corrected %q, checked %q; want exit %d, corrected %q, checked %q", tt.name, code, corrected, checked, tt.want, tt.corrected, tt.checked)
+		}
+		assumed := strings.Contains(strings.Join(res.Warnings, "\n"), "(-assume-corrected)")
+		if assumed != (tt.corrected != "" && tt.checked != "") {
+			t.Errorf("%s: warnings = %q, want the correction noted only with -assume-corrected", tt.name, res.Warnings)
+		}
+	}
+
+	out, _ := run(t, "check-downgrade", "db-custom-7-53248", "db-custom-4-16384")
+	for _, want := range []string{"Current tier is not valid, so the downgrade was not checked.", "Nearest valid current tier: db-custom-8-53248", "Use -assume-corrected"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("check-downgrade from an invalid tier = %q, want %q", out, want)
+		}
+	}
+}
@@ -0,0 +1,423 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+)
+
+// completionShells are the shells `go-calc completion` generates scripts for.
+var completionShells = []string{"bash", "zsh", "fish"}
+
+func init() {
+	// Appended here rather than listed in commands: runCompletion reads the
+	// commands table, which would otherwise be an initialization cycle.
+	commands = append(commands, &command{"completion", "<shell>", "Print a bash, zsh, or fish completion script", 1, nil,
+		func(a []string) (report, error) { return runCompletion(a[0]) }})
+}
+
+// Flags whose values are completed with known tiers or file names.
+var (
+	tierFlags = []string{"t", "bump-mem", "bump-cpu", "rightsize", "growth", "downgrade", "replica-tier"}
+	fileFlags = []string{"batch", "instances", "recommender", "prices", "flags-file", "tiers-file", "tiers-from", "config", "report-out", "desired"}
+)
+
+// flagChoices returns the fixed values a flag accepts, if any.
+func flagChoices(name string) []string {
+	switch name {
+	case "o":
+		return []string{"text", "json", "yaml", "terraform", "csv", "k8s", "gha", "slack", "ndjson"}
+	case "engine":
+		return sortedKeys(engineRules)
+	case "edition":
+		return sortedKeys(editions)
+	case "strategy":
+		var names []string
+		for _, s := range downgradeStrategies {
+			names = append(names, s.name)
+		}
+		return append(names, "all")
+	case "ratio-class":
+		return sortedKeys(ratioClasses)
+	case "report":
+		return []string{"html"}
+	case "format":
+		var names []string
+		for _, p := range sortedKeys(formatPresets) {
+			names = append(names, "@"+p)
+		}
+		return names
+	}
+	return nil
+}
+
+// completionFlag is a flag as the completion scripts see it.
+type completionFlag struct {
+	name, usage string
+	isBool      bool
+}
+
+// completionSpec is the CLI surface the scripts complete: the flags every
+// command shares, the flags of each command, and those of the deprecated
+// flag form.
+type completionSpec struct {
+	common []completionFlag
+	own    map[string][]completionFlag // by command name, without common
+	legacy []completionFlag            // without common
+	all    []completionFlag            // every flag, once
+}
+
+// flagsOf lists the flags fs defines, sorted by name.
+func flagsOf(fs *flag.FlagSet) []completionFlag {
+	var fl []completionFlag
+	fs.VisitAll(func(f *flag.Flag) {
+		b, ok := f.Value.(interface{ IsBoolFlag() bool })
+		fl = append(fl, completionFlag{f.Name, f.Usage, ok && b.IsBoolFlag()})
+	})
+	return fl
+}
+
+func newCompletionSpec() *completionSpec {
+	// Registering flags resets opts to the flag defaults.
+	saved := opts
+	defer func() { opts = saved }()
+
+	fs := flag.NewFlagSet("", flag.ContinueOnError)
+	commonFlags(fs)
+	spec := &completionSpec{common: flagsOf(fs), own: map[string][]completionFlag{}}
+	isCommon := map[string]bool{}
+	for _, f := range spec.common {
+		isCommon[f.name] = true
+	}
+	without := func(fl []completionFlag) []completionFlag {
+		var out []completionFlag
+		for _, f := range fl {
+			if !isCommon[f.name] {
+				out = append(out, f)
+			}
+		}
+		return out
+	}
+	for _, c := range commands {
+		spec.own[c.name] = without(flagsOf(c.newFlagSet()))
+	}
+	fs = flag.NewFlagSet("", flag.ContinueOnError)
+	new(legacyFlags).register(fs)
+	spec.all = flagsOf(fs)
+	spec.legacy = without(spec.all)
+	return spec
+}
+
+// commandsWith returns the commands that define the non-common flag name.
+func (s *completionSpec) commandsWith(name string) []string {
+	var names []string
+	for _, c := range commands {
+		for _, f := range s.own[c.name] {
+			if f.name == name {
+				names = append(names, c.name)
+			}
+		}
+	}
+	return names
+}
+
+// argKind is what the positional arguments of c complete to: "tier",
+// "file", "shell", or "".
+func argKind(c *command) string {
+	switch {
+	case strings.Contains(c.args, "<tier>"), strings.Contains(c.args, "<current>"):
+		return "tier"
+	case strings.Contains(c.args, "<file>"):
+		return "file"
+	case strings.Contains(c.args, "<shell>"):
+		return "shell"
+	}
+	return ""
+}
+
+// flagKind is what the value of a flag completes to: "tier", "file",
+// "choice", or "" for free-form values.
+func flagKind(name string) string {
+	switch {
+	case contains(tierFlags, name):
+		return "tier"
+	case contains(fileFlags, name):
+		return "file"
+	case flagChoices(name) != nil:
+		return "choice"
+	}
+	return ""
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func commandNames() []string {
+	var names []string
+	for _, c := range commands {
+		names = append(names, c.name)
+	}
+	return names
+}
+
+func knownTierNames() []string {
+	var names []string
+	for _, t := range knownTiers {
+		names = append(names, t.String())
+	}
+	return names
+}
+
+func dashed(fl []completionFlag) string {
+	var names []string
+	for _, f := range fl {
+		names = append(names, "-"+f.name)
+	}
+	return strings.Join(names, " ")
+}
+
+// flagPattern is a shell case pattern matching -name and --name for each flag.
+func flagPattern(names []string) string {
+	var alts []string
+	for _, n := range names {
+		alts = append(alts, "-"+n, "--"+n)
+	}
+	return strings.Join(alts, "|")
+}
+
+// valueFlags groups the flags that take a value by flagKind, in name order.
+func (s *completionSpec) valueFlags() map[string][]string {
+	groups := map[string][]string{}
+	for _, f := range s.all {
+		if !f.isBool {
+			k := flagKind(f.name)
+			groups[k] = append(groups[k], f.name)
+		}
+	}
+	return groups
+}
+
+func (s *completionSpec) bash() string {
+	var sb strings.Builder
+	sb.WriteString("# bash completion for go-calc. Generated by 'go-calc completion bash'.\n")
+	sb.WriteString("_go_calc() {\n")
+	sb.WriteString("    local cur=${COMP_WORDS[COMP_CWORD]} prev=${COMP_WORDS[COMP_CWORD-1]}\n")
+	fmt.Fprintf(&sb, "    local tiers=%q\n", strings.Join(knownTierNames(), " "))
+	fmt.Fprintf(&sb, "    local common=%q\n", dashed(s.common))
+	sb.WriteString("    case $prev in\n")
+	groups := s.valueFlags()
+	for _, name := range groups["choice"] {
+		fmt.Fprintf(&sb, "        %s) COMPREPLY=($(compgen -W %q -- \"$cur\")); return ;;\n", flagPattern([]string{name}), strings.Join(flagChoices(name), " "))
+	}
+	fmt.Fprintf(&sb, "        %s) COMPREPLY=($(compgen -W \"$tiers\" -- \"$cur\")); return ;;\n", flagPattern(groups["tier"]))
+	fmt.Fprintf(&sb, "        %s) COMPREPLY=($(compgen -f -- \"$cur\")); return ;;\n", flagPattern(groups["file"]))
+	fmt.Fprintf(&sb, "        %s) return ;;\n", flagPattern(groups[""]))
+	sb.WriteString("    esac\n")
+	sb.WriteString("    local flags cmd\n")
+	sb.WriteString("    (( COMP_CWORD > 1 )) && cmd=${COMP_WORDS[1]}\n")
+	sb.WriteString("    case $cmd in\n")
+	fmt.Fprintf(&sb, "        help) COMPREPLY=($(compgen -W %q -- \"$cur\")); return ;;\n", strings.Join(commandNames(), " "))
+	for _, c := range commands {
+		fmt.Fprintf(&sb, "        %s)\n", c.name)
+		fmt.Fprintf(&sb, "            flags=%q\n", dashed(s.own[c.name]))
+		switch argKind(c) {
+		case "tier":
+			sb.WriteString("            [[ $cur == -* ]] || { COMPREPLY=($(compgen -W \"$tiers\" -- \"$cur\")); return; } ;;\n")
+		case "file":
+			sb.WriteString("            [[ $cur == -* ]] || { COMPREPLY=($(compgen -f -- \"$cur\")); return; } ;;\n")
+		case "shell":
+			fmt.Fprintf(&sb, "            [[ $cur == -* ]] || { COMPREPLY=($(compgen -W %q -- \"$cur\")); return; } ;;\n", strings.Join(completionShells, " "))
+		default:
+			sb.WriteString("            ;;\n")
+		}
+	}
+	sb.WriteString("        *)\n")
+	fmt.Fprintf(&sb, "            flags=%q\n", dashed(s.legacy))
+	sb.WriteString("            if [[ $cur != -* ]]; then\n")
+	sb.WriteString("                local words=$tiers\n")
+	fmt.Fprintf(&sb, "                (( COMP_CWORD == 1 )) && words=%q\" $tiers\"\n", strings.Join(append(commandNames(), "help"), " "))
+	sb.WriteString("                COMPREPLY=($(compgen -W \"$words\" -- \"$cur\")); return\n")
+	sb.WriteString("            fi ;;\n")
+	sb.WriteString("    esac\n")
+	sb.WriteString("    COMPREPLY=($(compgen -W \"$flags $common\" -- \"$cur\"))\n")
+	sb.WriteString("}\n")
+	sb.WriteString("complete -F _go_calc go-calc\n")
+	return sb.String()
+}
+
+// zshQuote quotes s for a single-quoted zsh word.
+func zshQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// zshDescribed formats flags as _describe entries, "-name:usage".
+func zshDescribed(fl []completionFlag) string {
+	var entries []string
+	for _, f := range fl {
+		entries = append(entries, zshQuote("-"+f.name+":"+f.usage))
+	}
+	return strings.Join(entries, " ")
+}
+
+func (s *completionSpec) zsh() string {
+	var sb strings.Builder
+	sb.WriteString("#compdef go-calc\n")
+	sb.WriteString("# zsh completion for go-calc. Generated by 'go-calc completion zsh'.\n")
+	sb.WriteString("_go_calc() {\n")
+	sb.WriteString("    local cur=${words[CURRENT]} prev=${words[CURRENT-1]}\n")
+	fmt.Fprintf(&sb, "    local -a tiers=(%s)\n", strings.Join(knownTierNames(), " "))
+	var cmds []string
+	for _, c := range commands {
+		cmds = append(cmds, zshQuote(c.name+":"+c.summary))
+	}
+	fmt.Fprintf(&sb, "    local -a commands=(%s)\n", strings.Join(cmds, " "))
+	fmt.Fprintf(&sb, "    local -a common=(%s)\n", zshDescribed(s.common))
+	sb.WriteString("    case $prev in\n")
+	groups := s.valueFlags()
+	for _, name := range groups["choice"] {
+		fmt.Fprintf(&sb, "        (%s) compadd -- %s; return ;;\n", flagPattern([]string{name}), strings.Join(flagChoices(name), " "))
+	}
+	fmt.Fprintf(&sb, "        (%s) compadd -a tiers; return ;;\n", flagPattern(groups["tier"]))
+	fmt.Fprintf(&sb, "        (%s) _files; return ;;\n", flagPattern(groups["file"]))
+	fmt.Fprintf(&sb, "        (%s) return ;;\n", flagPattern(groups[""]))
+	sb.WriteString("    esac\n")
+	sb.WriteString("    local -a flags\n")
+	sb.WriteString("    local cmd\n")
+	sb.WriteString("    (( CURRENT > 2 )) && cmd=${words[2]}\n")
+	sb.WriteString("    case $cmd in\n")
+	sb.WriteString("        (help) _describe command commands; return ;;\n")
+	for _, c := range commands {
+		fmt.Fprintf(&sb, "        (%s)\n", c.name)
+		fmt.Fprintf(&sb, "            flags=(%s)\n", zshDescribed(s.own[c.name]))
+		switch argKind(c) {
+		case "tier":
+			sb.WriteString("            [[ $cur == -* ]] || { compadd -a tiers; return } ;;\n")
+		case "file":
+			sb.WriteString("            [[ $cur == -* ]] || { _files; return } ;;\n")
+		case "shell":
+			fmt.Fprintf(&sb, "            [[ $cur == -* ]] || { compadd -- %s; return } ;;\n", strings.Join(completionShells, " "))
+		default:
+			sb.WriteString("            ;;\n")
+		}
+	}
+	sb.WriteString("        (*)\n")
+	fmt.Fprintf(&sb, "            flags=(%s)\n", zshDescribed(s.legacy))
+	sb.WriteString("            if [[ $cur != -* ]]; then\n")
+	sb.WriteString("                (( CURRENT == 2 )) && _describe command commands\n")
+	sb.WriteString("                compadd -a tiers; return\n")
+	sb.WriteString("            fi ;;\n")
+	sb.WriteString("    esac\n")
+	sb.WriteString("    _describe flag flags -- common\n")
+	sb.WriteString("}\n")
+	sb.WriteString("if [[ $funcstack[1] == _go_calc ]]; then\n")
+	sb.WriteString("    _go_calc \"$@\"\n")
+	sb.WriteString("else\n")
+	sb.WriteString("    compdef _go_calc go-calc\n")
+	sb.WriteString("fi\n")
+	return sb.String()
+}
+
+// fishQuote quotes s for a single-quoted fish word.
+func fishQuote(s string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(s) + "'"
+}
+
+// fishFlag is the complete line for one flag under condition cond.
+func fishFlag(f completionFlag, cond string) string {
+	line := "complete -c go-calc"
+	if cond != "" {
+		line += " -n " + fishQuote(cond)
+	}
+	line += " -o " + f.name
+	if !f.isBool {
+		switch flagKind(f.name) {
+		case "tier":
+			line += ` -x -a "$tiers"`
+		case "file":
+			line += " -r -F"
+		case "choice":
+			line += " -x -a " + fishQuote(strings.Join(flagChoices(f.name), " "))
+		default:
+			line += " -x"
+		}
+	}
+	return line + " -d " + fishQuote(f.usage) + "\n"
+}
+
+func (s *completionSpec) fish() string {
+	var sb strings.Builder
+	sb.WriteString("# fish completion for go-calc. Generated by 'go-calc completion fish'.\n")
+	fmt.Fprintf(&sb, "set -l commands %s\n", strings.Join(append(commandNames(), "help"), " "))
+	fmt.Fprintf(&sb, "set -l tiers %s\n", strings.Join(knownTierNames(), " "))
+	sb.WriteString("complete -c go-calc -f\n")
+	for _, c := range commands {
+		fmt.Fprintf(&sb, "complete -c go-calc -n '__fish_use_subcommand' -a %s -d %s\n", c.name, fishQuote(c.summary))
+	}
+	sb.WriteString("complete -c go-calc -n '__fish_use_subcommand' -a help -d 'Show the usage of go-calc or of a command'\n")
+	sb.WriteString("complete -c go-calc -n '__fish_use_subcommand' -a \"$tiers\"\n")
+	sb.WriteString("complete -c go-calc -n '__fish_seen_subcommand_from help' -a \"$commands\"\n")
+	byKind := map[string][]string{}
+	for _, c := range commands {
+		if k := argKind(c); k != "" {
+			byKind[k] = append(byKind[k], c.name)
+		}
+	}
+	kinds := sortedKeys(byKind)
+	for _, k := range kinds {
+		line := "complete -c go-calc -n " + fishQuote("__fish_seen_subcommand_from "+strings.Join(byKind[k], " "))
+		switch k {
+		case "tier":
+			line += ` -a "$tiers"`
+		case "file":
+			line += " -F"
+		case "shell":
+			line += " -a " + fishQuote(strings.Join(completionShells, " "))
+		}
+		sb.WriteString(line + "\n")
+	}
+	for _, f := range s.common {
+		sb.WriteString(fishFlag(f, ""))
+	}
+	for _, f := range s.legacy {
+		cond := "__fish_use_subcommand"
+		if with := s.commandsWith(f.name); len(with) > 0 {
+			cond += "; or __fish_seen_subcommand_from " + strings.Join(with, " ")
+		}
+		sb.WriteString(fishFlag(f, cond))
+	}
+	return sb.String()
+}
+
+// ScriptResult is the output of the completion command.
+type ScriptResult struct {
+	Shell  string `json:"shell"`
+	Script string `json:"script"`
+	Error  string `json:"error,omitempty"`
+}
+
+func (s *ScriptResult) humanText() string  { return s.Script }
+func (s *ScriptResult) exitCode() int      { return exitOK }
+func (s *ScriptResult) setError(err error) { s.Error = err.Error() }
+
+// runCompletion generates the completion script for shell.
+func runCompletion(shell string) (*ScriptResult, error) {
+	res := &ScriptResult{Shell: shell}
+	spec := newCompletionSpec()
+	switch shell {
+	case "bash":
+		res.Script = spec.bash()
+	case "zsh":
+		res.Script = spec.zsh()
+	case "fish":
+		res.Script = spec.fish()
+	default:
+		return res, fmt.Errorf("unknown shell %q: use %s", shell, strings.Join(completionShells, ", "))
+	}
+	return res, nil
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// TestCompletionGolden compares each completion script with its golden file.
+// Run go test -run Completion -update after changing commands or flags.
+func TestCompletionGolden(t *testing.T) {
+	for _, shell := range completionShells {
+		t.Run(shell, func(t *testing.T) {
+			res, err := runCompletion(shell)
+			if err != nil {
+				t.Fatal(err)
+			}
+			golden := filepath.Join("testdata", "completion."+shell)
+			if *update {
+				if err := os.WriteFile(golden, []byte(res.Script), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if res.Script != string(want) {
+				t.Errorf("%s completion differs from %s; rerun with -update if the change is intended", shell, golden)
+			}
+			again, _ := runCompletion(shell)
+			if again.Script != res.Script {
+				t.Errorf("%s completion is not deterministic", shell)
+			}
+		})
+	}
+}
+
+func TestCompletionBashSyntax(t *testing.T) {
+	bash, err := exec.LookPath("bash")
+	if err != nil {
+		t.Skip("bash not installed")
+	}
+	res, err := runCompletion("bash")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cmd := exec.Command(bash, "-n")
+	cmd.Stdin = strings.NewReader(res.Script)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Errorf("bash -n: %v\n%s", err, out)
+	}
+}
+
+func TestCompletionUnknownShell(t *testing.T) {
+	if _, err := runCompletion("powershell"); err == nil {
+		t.Error("runCompletion(powershell) succeeded, want an error")
+	}
+}
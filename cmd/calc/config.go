@@ -0,0 +1,184 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Policy holds the organization's limits on the tiers go-calc may suggest.
+type Policy struct {
+	MinTier           string  `json:"min_tier,omitempty"`
+	MaxTier           string  `json:"max_tier,omitempty"`
+	ForbidRatiosBelow float64 `json:"forbid_ratios_below,omitempty"`
+	HAMinTier         string  `json:"ha_min_tier,omitempty"`
+
+	min, max, haMin *Tier
+}
+
+// policy is the policy loaded from the config file.
+var policy Policy
+
+// policyKeys are the config file keys that set the policy rather than a
+// flag default.
+var policyKeys = map[string]bool{"min_tier": true, "max_tier": true, "forbid_ratios_below": true, "ha_min_tier": true}
+
+// defaultConfigPath returns $XDG_CONFIG_HOME/go-calc/config.yaml, or
+// ~/.config/go-calc/config.yaml when XDG_CONFIG_HOME is not set.
+func defaultConfigPath() string {
+	dir := os.Getenv("XDG_CONFIG_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return ""
+		}
+		dir = filepath.Join(home, ".config")
+	}
+	return filepath.Join(dir, "go-calc", "config.yaml")
+}
+
+// loadConfig reads the config file at path and applies it: policy keys set
+// the policy, every other key is the default for the flag of that name.
+// Flags given on the command line keep their values. A missing file is only
+// an error when the path was given with -config.
+func loadConfig(fs *flag.FlagSet, path string, explicit bool) error {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) && !explicit {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+	var values map[string]any
+	if err := yaml.Unmarshal(data, &values); err != nil {
+		return fmt.Errorf("config %s: %w", path, err)
+	}
+	known := flag.NewFlagSet("", flag.ContinueOnError)
+	saved := opts
+	new(legacyFlags).register(known)
+	opts = saved
+	for _, key := range sortedKeys(values) {
+		value := fmt.Sprint(values[key])
+		switch {
+		case policyKeys[key] && flagSet(fs, strings.ReplaceAll(key, "_", "-")):
+			// Set by -min-tier or -max-tier on the command line.
+		case policyKeys[key]:
+			if err := policy.set(key, value); err != nil {
+				return fmt.Errorf("config %s: %s: %w", path, key, err)
+			}
+		case known.Lookup(key) == nil || key == "config":
+			return fmt.Errorf("config %s: unknown setting %q", path, key)
+		case fs.Lookup(key) == nil, flagSet(fs, key):
+			// Not a flag of this command, or overridden on the command line.
+		default:
+			if err := fs.Set(key, value); err != nil {
+				return fmt.Errorf("config %s: %s: %w", path, key, err)
+			}
+		}
+	}
+	return nil
+}
+
+// set applies one policy key.
+func (p *Policy) set(key, value string) error {
+	switch key {
+	case "min_tier", "max_tier", "ha_min_tier":
+		// The engine and edition are not selected yet
+		t, err := parseSomewhere(value)
+		if err != nil {
+			return err
+		}
+		switch key {
+		case "min_tier":
+			p.MinTier, p.min = t.String(), &t
+		case "max_tier":
+			p.MaxTier, p.max = t.String(), &t
+		default:
+			p.HAMinTier, p.haMin = t.String(), &t
+		}
+	case "forbid_ratios_below":
+		var r float64
+		if _, err := fmt.Sscan(value, &r); err != nil || r <= 0 {
+			return fmt.Errorf("invalid ratio %q", value)
+		}
+		p.ForbidRatiosBelow = r
+	}
+	if p.min != nil && p.max != nil && (p.max.CPUs < p.min.CPUs || p.max.RAMMB < p.min.RAMMB) {
+		return fmt.Errorf("max_tier %s is below min_tier %s", p.MaxTier, p.MinTier)
+	}
+	return nil
+}
+
+// violations returns the policy rules t breaks. A tier is below min_tier
+// when it has fewer vCPUs or less memory, and above max_tier when it has
+// more of either; a tier equal to either is within policy.
+func (p Policy) violations(t Tier) []string {
+	vs, _ := p.check(t)
+	return vs
+}
+
+// check is violations with the Code* of each rule t breaks.
+func (p Policy) check(t Tier) (vs, codes []string) {
+	if p.below(t) {
+		vs, codes = append(vs, fmt.Sprintf("%s is below the policy min_tier %s", t, p.MinTier)), append(codes, CodePolicyBelowMin)
+	}
+	if p.above(t) {
+		vs, codes = append(vs, fmt.Sprintf("%s is above the policy max_tier %s", t, p.MaxTier)), append(codes, CodePolicyAboveMax)
+	}
+	if p.ForbidRatiosBelow > 0 && !t.Shared() && t.Ratio() < p.ForbidRatiosBelow {
+		vs, codes = append(vs, fmt.Sprintf("%s has %.2f GB/vCPU, below the policy forbid_ratios_below %g", t, t.Ratio(), p.ForbidRatiosBelow)), append(codes, CodePolicyRatio)
+	}
+	return vs, codes
+}
+
+// below reports whether t has fewer vCPUs or less memory than min_tier.
+func (p Policy) below(t Tier) bool {
+	return p.min != nil && (t.CPUs < p.min.CPUs || t.RAMMB < p.min.RAMMB)
+}
+
+// above reports whether t has more vCPUs or memory than max_tier.
+func (p Policy) above(t Tier) bool {
+	return p.max != nil && (t.CPUs > p.max.CPUs || t.RAMMB > p.max.RAMMB)
+}
+
+// haViolation returns why t is too small for a regional (HA) primary under
+// ha_min_tier, or "" when it is not.
+func (p Policy) haViolation(t Tier) string {
+	if p.haMin != nil && (t.CPUs < p.haMin.CPUs || t.RAMMB < p.haMin.RAMMB) {
+		return fmt.Sprintf("%s is below the policy ha_min_tier %s for regional (HA) instances", t, p.HAMinTier)
+	}
+	return ""
+}
+
+// addPolicyCheck reports the policy rules the target tier of res breaks.
+func addPolicyCheck(res *Result) {
+	target := res.targetTier()
+	if target == "" && res.Mode == "validate" && res.TierInfo != nil {
+		target = res.Tier
+	}
+	if target == "" {
+		return
+	}
+	t, err := ParseTier(target)
+	if err != nil {
+		return
+	}
+	vs, codes := policy.check(t)
+	for _, v := range vs {
+		res.printf("Policy violation: %s\n", v)
+	}
+	res.PolicyViolations = append(res.PolicyViolations, vs...)
+	res.PolicyCodes = append(res.PolicyCodes, codes...)
+}
+
+// noneWithinPolicy reports that next, the tier res would suggest, is above
+// max_tier, so there is no suggestion.
+func (r *Result) noneWithinPolicy(next Tier) {
+	r.Message = "no higher tier within policy"
+	r.printf("No higher tier within policy: %s.\n", strings.Join(policy.violations(next), "; "))
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPolicyViolations(t *testing.T) {
+	var p Policy
+	if err := p.set("min_tier", "db-custom-2-7680"); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.set("max_tier", "db-custom-64-425984"); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		tier  Tier
+		codes string
+	}{
+		{Tier{CPUs: 2, RAMMB: 7680}, ""},
+		{Tier{CPUs: 64, RAMMB: 425984}, ""},
+		{Tier{CPUs: 2, RAMMB: 7424}, CodePolicyBelowMin},
+		{Tier{CPUs: 1, RAMMB: 3840}, CodePolicyBelowMin},
+		{Tier{CPUs: 64, RAMMB: 426240}, CodePolicyAboveMax},
+		{Tier{CPUs: 96, RAMMB: 393216}, CodePolicyAboveMax},
+		{Tier{CPUs: 1, RAMMB: 638976}, CodePolicyBelowMin + "," + CodePolicyAboveMax},
+	}
+	for _, tt := range tests {
+		vs, codes := p.check(tt.tier)
+		if got := strings.Join(codes, ","); got != tt.codes || len(vs) != len(codes) {
+			t.Errorf("check(%s) = %q, %q, want %q", tt.tier, vs, codes, tt.codes)
+		}
+	}
+}
+
+// TestPolicyBoundaries runs suggestions that land on or just past the
+// -min-tier and -max-tier boundaries: a tier on the boundary is suggested,
+// one past it is not.
+func TestPolicyBoundaries(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		want []string
+		not  string
+		code int
+	}{
+		{"next on the ceiling", []string{"next", "-max-tier", "db-custom-4-26624", "db-custom-4-15360"},
+			[]string{"Next known working custom tier: db-custom-4-26624"}, "within policy", exitOK},
+		{"next past the ceiling", []string{"next", "-max-tier", "db-custom-4-26368", "db-custom-4-15360"},
+			[]string{"No higher tier within policy: db-custom-4-26624 is above the policy max_tier db-custom-4-26368."}, "Next known working", exitOK},
+		{"steps up to the ceiling", []string{"next", "-steps", "4", "-max-tier", "db-custom-6-26624", "db-custom-4-15360"},
+			[]string{"1. db-custom-4-26624", "2. db-custom-6-23040", "At the policy max_tier db-custom-6-26624: no known tier above db-custom-6-23040 within policy."}, "3. ", exitOK},
+		{"steps down to the floor", []string{"prev", "-steps", "6", "-min-tier", "db-custom-4-15360", "db-custom-8-30720"},
+			[]string{"4. db-custom-4-15360", "At the policy min_tier db-custom-4-15360: no known tier below db-custom-4-15360 within policy."}, "5. ", exitOK},
+		{"downgrade to the floor", []string{"prev", "-min-tier", "db-custom-2-7680", "db-custom-2-13312"},
+			[]string{"Suggested downgrade tier: db-custom-2-7680"}, "within policy", exitOK},
+		{"downgrade past the floor", []string{"prev", "-min-tier", "db-custom-4-26624", "db-custom-4-26624"},
+			[]string{"No downgrade within policy: db-custom-4-15360 is below the policy min_tier db-custom-4-26624."}, "Suggested downgrade", exitOK},
+		{"check-downgrade to the floor", []string{"check-downgrade", "-min-tier", "db-custom-2-7680", "db-custom-8-30720", "db-custom-2-7680"},
+			[]string{"Valid downgrade: Yes"}, "Policy violation", exitOK},
+		{"check-downgrade past the floor", []string{"check-downgrade", "-min-tier", "db-custom-2-7680", "db-custom-8-30720", "db-custom-1-3840"},
+			[]string{"Valid downgrade: Yes", "Policy violation: db-custom-1-3840 is below the policy min_tier db-custom-2-7680"}, "", exitPolicy},
+		{"check-upgrade to the ceiling", []string{"check-upgrade", "-max-tier", "db-custom-8-30720", "db-custom-4-15360", "db-custom-8-30720"},
+			[]string{"Valid upgrade: Yes"}, "Policy violation", exitOK},
+		{"check-upgrade past the ceiling", []string{"check-upgrade", "-max-tier", "db-custom-8-30720", "db-custom-4-15360", "db-custom-8-30976"},
+			[]string{"Policy violation: db-custom-8-30976 is above the policy max_tier db-custom-8-30720"}, "", exitPolicy},
+	}
+	for _, tt := range tests {
+		out, code := run(t, tt.args...)
+		for _, want := range tt.want {
+			if !strings.Contains(out, want) {
+				t.Errorf("%s: output = %q, want %q", tt.name, out, want)
+			}
+		}
+		if tt.not != "" && strings.Contains(out, tt.not) {
+			t.Errorf("%s: output = %q, want no %q", tt.name, out, tt.not)
+		}
+		if code != tt.code {
+			t.Errorf("%s: exit = %d, want %d", tt.name, code, tt.code)
+		}
+	}
+
+	out, _ := run(t, "check-downgrade", "-o", "gha", "-min-tier", "db-custom-2-7680", "db-custom-8-30720", "db-custom-1-3840")
+	if !strings.Contains(out, "::error::[POLICY_BELOW_MIN_TIER] Policy violation: ") {
+		t.Errorf("-o gha = %q, want the policy violation with its code", out)
+	}
+	if _, code := run(t, "next", "-max-tier", "db-custom-four", "db-custom-4-15360"); code == exitOK {
+		t.Errorf("-max-tier of a malformed tier exited %d, want a failure", code)
+	}
+}
@@ -0,0 +1,48 @@
+package main
+
+// connsPerVCPU is roughly how many client connections one vCPU serves before
+// context switching and lock contention dominate; beyond it a pooler in front
+// of the instance does better than a bigger tier.
+const connsPerVCPU = 200
+
+// Connections is the memory a -connections count adds to a sizing request.
+type Connections struct {
+	Count     int     `json:"count"`
+	PerConnKB float64 `json:"per_conn_kb"`
+	MemMB     float64 `json:"mem_mb"`
+}
+
+// addConnections records the -connections memory on r and returns memMB
+// with it added. Without -connections it returns memMB unchanged.
+func (r *Result) addConnections(memMB float64) float64 {
+	if opts.connections == 0 {
+		return memMB
+	}
+	r.Connections = &Connections{Count: opts.connections, PerConnKB: opts.connMemKB, MemMB: float64(opts.connections) * opts.connMemKB / 1024}
+	return memMB + r.Connections.MemMB
+}
+
+// printConnections shows the connection memory included in the request.
+func (r *Result) printConnections() {
+	if c := r.Connections; c != nil {
+		r.printf("  - Connections: %d × %g KB = %.0f MB included in memory\n", c.Count, c.PerConnKB, c.MemMB)
+	}
+}
+
+// checkConnections warns when the -connections count is more than tier t can
+// realistically serve, which calls for connection pooling rather than a
+// larger tier.
+func (r *Result) checkConnections(t Tier) {
+	c := r.Connections
+	if c == nil {
+		return
+	}
+	if c.Count > maxConnections {
+		r.warnf(CodeConnectionsLimit, "%d connections is above the Cloud SQL max_connections limit of %d", c.Count, maxConnections)
+	}
+	if limit := int(max(t.VCPUs(), 1) * connsPerVCPU); c.Count > limit {
+		r.Message = "needs connection pooling"
+		r.warnf(CodeConnectionsPooling, "%d connections is more than %s can realistically serve (about %d, %d per vCPU); this needs connection pooling such as ProxySQL or PgBouncer rather than a larger tier",
+			c.Count, t, limit, connsPerVCPU)
+	}
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestConnections(t *testing.T) {
+	tests := []struct {
+		args    []string
+		tier    string
+		connMB  float64
+		pooling bool
+	}{
+		{[]string{"-connections", "2000"}, "db-custom-16-24064", 24000, false},
+		{[]string{"-connections", "1000", "-conn-mem-kb", "4096"}, "db-custom-4-4096", 4000, true},
+		{[]string{"-cpu", "4", "-connections", "500"}, "db-custom-4-12288", 6000, false},
+		{[]string{"-mem", "16G", "-connections", "100"}, "db-custom-12-17664", 1200, false},
+		{[]string{"-cpu", "2", "-connections", "1000"}, "db-custom-4-15104", 12000, true},
+		{[]string{"-cpu", "2", "-connections", "1000", "-conn-mem-kb", "512"}, "db-custom-2-3840", 500, true},
+		{[]string{"-connections", "50000"}, "db-custom-96-600064", 600000, true},
+		{[]string{"-connections", "50000", "-conn-mem-kb", "1024"}, "db-custom-34-50176", 50000, true},
+		{[]string{"-connections", "100000", "-conn-mem-kb", "256"}, "db-custom-16-25088", 25000, true},
+	}
+	for _, tt := range tests {
+		args := append([]string{"suggest", "-o", "json", "-mysql-config"}, tt.args...)
+		out, code := run(t, args...)
+		var res struct {
+			Tier        string   `json:"tier"`
+			Warnings    []string `json:"warnings"`
+			Message     string   `json:"message"`
+			Connections struct {
+				Count int     `json:"count"`
+				MemMB float64 `json:"mem_mb"`
+			} `json:"connections"`
+			MySQLConfig struct {
+				MaxConnections int `json:"max_connections"`
+			} `json:"mysql_config"`
+		}
+		if err := json.Unmarshal([]byte(out), &res); err != nil {
+			t.Fatalf("go-calc %q: %v\n%s", args, err, out)
+		}
+		if code != exitOK || res.Tier != tt.tier || res.Connections.MemMB != tt.connMB {
+			t.Errorf("go-calc %q = %s with %g MB for connections (exit %d), want %s with %g MB", tt.args, res.Tier, res.Connections.MemMB, code, tt.tier, tt.connMB)
+		}
+		if res.MySQLConfig.MaxConnections != res.Connections.Count {
+			t.Errorf("go-calc %q max_connections = %d, want the %d connections asked for", tt.args, res.MySQLConfig.MaxConnections, res.Connections.Count)
+		}
+		pooling := strings.Contains(strings.Join(res.Warnings, "\n"), "needs connection pooling")
+		if pooling != tt.pooling || pooling != (res.Message == "needs connection pooling") {
+			t.Errorf("go-calc %q warnings = %q, message %q, want pooling warning %t", tt.args, res.Warnings, res.Message, tt.pooling)
+		}
+	}
+	if _, code := run(t, "suggest", "-connections", "50000", "-strict"); code != exitInvalid {
+		t.Errorf("-connections 50000 -strict exited %d, want %d", code, exitInvalid)
+	}
+	for _, args := range [][]string{{"-connections", "-1"}, {"-connections", "10", "-conn-mem-kb", "0"}} {
+		if _, code := run(t, append([]string{"suggest"}, args...)...); code != exitUsage {
+			t.Errorf("suggest %q exited %d, want %d", args, code, exitUsage)
+		}
+	}
+}
@@ -0,0 +1,312 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// Constraints is the rule set a custom tier must satisfy for one database
+// engine. Keeping the rules as data means adding an engine is a table entry
+// rather than more branches in the validation code.
+type Constraints struct {
+	Engine      string  // flag value, e.g. "mysql"
+	Name        string  // display name, e.g. "MySQL"
+	Edition     string  // edition flag value, e.g. "enterprise"
+	MinCPUs     int     // smallest vCPU count; 1 is the only odd count allowed
+	MaxCPUs     int     // largest vCPU count
+	MinRAMMB    int     // absolute memory floor
+	MaxRAMMB    int     // absolute memory ceiling, set by the edition
+	RAMStepMB   int     // memory must be a multiple of this
+	MinGBPerCPU float64 // lower bound of memory per vCPU
+	MaxGBPerCPU float64 // upper bound of memory per vCPU
+	SharedCore  bool    // db-f1-micro and db-g1-small are available
+	Shapes      []ShapeLimit
+	Overhead    Overhead // memory the engine cannot use, for -usable
+}
+
+// ShapeLimit is a memory ceiling for one vCPU count that is tighter than
+// MaxGBPerCPU allows, for shapes the API treats specially.
+type ShapeLimit struct {
+	CPUs     int
+	MaxRAMMB int
+}
+
+// oneVCPUShape caps the 1 vCPU shape: the API rejects memory above 3840 MB
+// there even though 6.5 GB/vCPU would allow 6656 MB.
+var oneVCPUShape = ShapeLimit{CPUs: 1, MaxRAMMB: 3840}
+
+// engineRules holds the custom tier constraints per engine, per the
+// CloudSQL machine series documentation.
+var engineRules = map[string]Constraints{
+	"mysql": {
+		Engine: "mysql", Name: "MySQL",
+		MinCPUs: 1, MaxCPUs: 96, MinRAMMB: 3840, RAMStepMB: 256,
+		MinGBPerCPU: 0.9, MaxGBPerCPU: 6.5, SharedCore: true,
+		Shapes:   []ShapeLimit{oneVCPUShape},
+		Overhead: Overhead{FixedMB: 1024, Pct: 5},
+	},
+	"postgres": {
+		Engine: "postgres", Name: "PostgreSQL",
+		MinCPUs: 1, MaxCPUs: 96, MinRAMMB: 3840, RAMStepMB: 256,
+		MinGBPerCPU: 0.9, MaxGBPerCPU: 6.5, SharedCore: true,
+		Shapes:   []ShapeLimit{oneVCPUShape},
+		Overhead: Overhead{FixedMB: 1024, Pct: 5},
+	},
+	"sqlserver": {
+		Engine: "sqlserver", Name: "SQL Server",
+		MinCPUs: 2, MaxCPUs: 96, MinRAMMB: 3840, RAMStepMB: 256,
+		MinGBPerCPU: 0.9, MaxGBPerCPU: 6.5,
+		Overhead: Overhead{FixedMB: 2048, Pct: 5},
+	},
+	"sqlserver-enterprise": {
+		Engine: "sqlserver-enterprise", Name: "SQL Server Enterprise",
+		MinCPUs: 2, MaxCPUs: 96, MinRAMMB: 10240, RAMStepMB: 256,
+		MinGBPerCPU: 3.75, MaxGBPerCPU: 6.5,
+		Overhead: Overhead{FixedMB: 2048, Pct: 5},
+	},
+}
+
+// Edition describes the limits an edition places on top of the engine rules.
+type Edition struct {
+	Name        string  // display name, e.g. "Enterprise Plus"
+	MaxCPUs     int     // largest vCPU count
+	MaxRAMMB    int     // largest instance memory
+	MaxGBPerCPU float64 // upper bound of memory per vCPU
+	SharedCore  bool    // shared-core tiers are offered
+}
+
+// editions maps the -edition flag values to their limits.
+var editions = map[string]Edition{
+	"enterprise":      {Name: "Enterprise", MaxCPUs: 96, MaxRAMMB: 638976, MaxGBPerCPU: 6.5, SharedCore: true},
+	"enterprise-plus": {Name: "Enterprise Plus", MaxCPUs: 128, MaxRAMMB: 884736, MaxGBPerCPU: 8},
+}
+
+const defaultEdition = "enterprise"
+
+// defaultGBPerCPU is the memory per vCPU used to size recommendations when
+// -ratio is not given.
+const defaultGBPerCPU = 1.5
+
+// rules is the constraint set selected with -engine.
+var rules = mustLookupRules("mysql", defaultEdition)
+
+// lookupRules returns the constraints for an engine under an edition.
+func lookupRules(engine, edition string) (Constraints, error) {
+	c, ok := engineRules[strings.ToLower(engine)]
+	if !ok {
+		return Constraints{}, fmt.Errorf("unknown engine %q: use one of %s", engine, strings.Join(sortedKeys(engineRules), ", "))
+	}
+	e, ok := editions[strings.ToLower(edition)]
+	if !ok {
+		return Constraints{}, fmt.Errorf("unknown edition %q: use one of %s", edition, strings.Join(sortedKeys(editions), ", "))
+	}
+	c.Edition = strings.ToLower(edition)
+	c.MaxCPUs = e.MaxCPUs
+	c.MaxRAMMB = e.MaxRAMMB
+	c.MaxGBPerCPU = e.MaxGBPerCPU
+	c.SharedCore = c.SharedCore && e.SharedCore
+	return c, nil
+}
+
+func mustLookupRules(engine, edition string) Constraints {
+	c, err := lookupRules(engine, edition)
+	if err != nil {
+		panic(err)
+	}
+	return c
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// editionName returns the display name of the selected edition.
+func (c Constraints) editionName() string {
+	return editions[c.Edition].Name
+}
+
+// cpuAllowed reports whether n is a legal vCPU count.
+func (c Constraints) cpuAllowed(n int) bool {
+	if n < c.MinCPUs || n > c.MaxCPUs {
+		return false
+	}
+	return n == 1 || n%2 == 0
+}
+
+// legalCPUAtLeast returns the smallest legal vCPU count that is at least n,
+// or MaxCPUs when n is above the cap.
+func (c Constraints) legalCPUAtLeast(n int) int {
+	if n < c.MinCPUs {
+		n = c.MinCPUs
+	}
+	if n != 1 && n%2 != 0 {
+		n++
+	}
+	if n > c.MaxCPUs {
+		n = c.MaxCPUs
+	}
+	return n
+}
+
+// legalCPUBelow returns the largest legal vCPU count below n, if any.
+func (c Constraints) legalCPUBelow(n int) (int, bool) {
+	n = min(n-1, c.MaxCPUs)
+	if n != 1 && n%2 != 0 {
+		n--
+	}
+	if n < c.MinCPUs {
+		return 0, false
+	}
+	return n, true
+}
+
+// minRAMFor returns the smallest whole MB allowed by the ratio for cpu. The
+// bound is rounded up: 0.9 GB × 96 vCPUs is 88473.6 MB, so 88473 MB is below
+// it, which truncating float math used to accept.
+func (c Constraints) minRAMFor(cpu int) int {
+	return ratioMBCeil(c.MinGBPerCPU, cpu)
+}
+
+// maxRAMFor returns the largest whole MB allowed for cpu: the ratio ceiling,
+// or the shape's own limit or the edition's ceiling when that is lower.
+func (c Constraints) maxRAMFor(cpu int) int {
+	m := min(ratioMBFloor(c.MaxGBPerCPU, cpu), c.MaxRAMMB)
+	if s, ok := c.shapeLimit(cpu); ok {
+		m = min(m, s.MaxRAMMB)
+	}
+	return m
+}
+
+// maxTier returns the largest valid custom tier under c.
+func (c Constraints) maxTier() Tier {
+	return Tier{CPUs: c.MaxCPUs, RAMMB: roundDown256(c.maxRAMFor(c.MaxCPUs))}
+}
+
+// shapeLimit returns the shape-specific limit for cpu, if there is one.
+func (c Constraints) shapeLimit(cpu int) (ShapeLimit, bool) {
+	for _, s := range c.Shapes {
+		if s.CPUs == cpu {
+			return s, true
+		}
+	}
+	return ShapeLimit{}, false
+}
+
+// gbMilli returns a GB/vCPU ratio in thousandths of a GB, so memory for a
+// ratio can be computed in exact integer MB. The engine ratios have at most
+// two decimals; a -ratio is used to the nearest 0.001 GB.
+func gbMilli(gb float64) int {
+	return int(math.Round(gb * 1000))
+}
+
+// ratioMBFloor returns cpu vCPUs at gb GB/vCPU in MB, rounded down.
+func ratioMBFloor(gb float64, cpu int) int {
+	return cpu * gbMilli(gb) * 1024 / 1000
+}
+
+// ratioMBCeil returns cpu vCPUs at gb GB/vCPU in MB, rounded up.
+func ratioMBCeil(gb float64, cpu int) int {
+	return (cpu*gbMilli(gb)*1024 + 999) / 1000
+}
+
+// roundUp256 rounds mb up to the 256 MB custom tier memory step.
+func roundUp256(mb int) int {
+	return (mb + 255) / 256 * 256
+}
+
+// roundDown256 rounds mb down to the 256 MB custom tier memory step.
+func roundDown256(mb int) int {
+	return mb / 256 * 256
+}
+
+// clampRatio limits a GB/vCPU ratio to the engine's allowed band.
+func (c Constraints) clampRatio(r float64) float64 {
+	return min(max(r, c.MinGBPerCPU), c.MaxGBPerCPU)
+}
+
+// checkRatio reports an error if r is outside the engine's GB/vCPU band.
+func (c Constraints) checkRatio(r float64) error {
+	if r < c.MinGBPerCPU || r > c.MaxGBPerCPU {
+		return fmt.Errorf("ratio %g GB/vCPU is outside the %s range for %s %s", r, c.ratioRange(), c.Name, c.editionName())
+	}
+	return nil
+}
+
+// ratioRange formats the memory-per-vCPU band, e.g. "0.9-6.5 GB".
+func (c Constraints) ratioRange() string {
+	return fmt.Sprintf("%g-%g GB", c.MinGBPerCPU, c.MaxGBPerCPU)
+}
+
+// violations returns every rule t fails, in evaluation order.
+func (c Constraints) violations(t Tier) []*TierError {
+	if t.Shared() {
+		if c.SharedCore {
+			return nil
+		}
+		return []*TierError{newTierError(ErrCPUCount, t.String(), "shared-core tier %s is not available for %s %s", t, c.Name, c.editionName()).withCode(CodeSharedCore)}
+	}
+	var errs []*TierError
+	// vCPUs must be 1 or an even number within the engine's range
+	if t.CPUs < c.MinCPUs || t.CPUs > c.MaxCPUs {
+		errs = append(errs, newTierError(ErrCPUCount, t.CPUs, "vCPUs must be between %d and %d for %s, got %d", c.MinCPUs, c.MaxCPUs, c.Name, t.CPUs).withCode(CodeCPURange))
+	} else if !c.cpuAllowed(t.CPUs) {
+		errs = append(errs, newTierError(ErrCPUCount, t.CPUs, "vCPUs must be 1 or an even number, got %d", t.CPUs).withCode(CodeCPUOdd))
+	}
+	// Memory must be a multiple of the step and at least the floor
+	if t.RAMMB%c.RAMStepMB != 0 {
+		errs = append(errs, newTierError(ErrRAMAlignment, t.RAMMB, "memory must be a multiple of %d MB, got %d MB", c.RAMStepMB, t.RAMMB))
+	}
+	if t.RAMMB < c.MinRAMMB {
+		errs = append(errs, newTierError(ErrRAMTooLow, t.RAMMB, "memory must be at least %d MB for %s, got %d MB", c.MinRAMMB, c.Name, t.RAMMB))
+	}
+	// Memory must be within the per-vCPU band
+	if t.CPUs >= 1 {
+		minRam, maxRam := c.minRAMFor(t.CPUs), ratioMBFloor(c.MaxGBPerCPU, t.CPUs)
+		if t.RAMMB < minRam || t.RAMMB > maxRam {
+			code := CodeRatioTooLow
+			if t.RAMMB > maxRam {
+				code = CodeRatioTooHigh
+			}
+			errs = append(errs, newTierError(ErrRatioOutOfRange, t.Ratio(), "memory must be %g to %g GB per vCPU (%d-%d MB for %d vCPUs), got %.2f GB/vCPU", c.MinGBPerCPU, c.MaxGBPerCPU, minRam, maxRam, t.CPUs, t.Ratio()).withCode(code))
+		} else if s, ok := c.shapeLimit(t.CPUs); ok && t.RAMMB > s.MaxRAMMB {
+			errs = append(errs, newTierError(ErrRAMTooHigh, t.RAMMB, "memory must be at most %d MB for %d vCPU %s tiers, got %d MB", s.MaxRAMMB, s.CPUs, c.Name, t.RAMMB).withCode(CodeAboveShapeRAM))
+		}
+	}
+	if t.RAMMB > c.MaxRAMMB {
+		errs = append(errs, newTierError(ErrRAMTooHigh, t.RAMMB, "memory exceeds the Cloud SQL maximum of %d MB for %s %s, got %d MB", c.MaxRAMMB, c.Name, c.editionName(), t.RAMMB))
+	}
+	return errs
+}
+
+// checks evaluates every rule against t, passing or not, with the numbers
+// involved. It backs -explain; violations reports the failures as errors.
+func (c Constraints) checks(t Tier) []Check {
+	if t.Shared() {
+		offered := "offered"
+		if !c.SharedCore {
+			offered = "not offered"
+		}
+		return []Check{newCheck("shared-core", c.SharedCore, "shared-core tier %s is %s for %s %s", t, offered, c.Name, c.editionName())}
+	}
+	minRAM, maxRAM := c.minRAMFor(t.CPUs), ratioMBFloor(c.MaxGBPerCPU, t.CPUs)
+	checks := []Check{
+		newCheck("vcpu-range", t.CPUs >= c.MinCPUs && t.CPUs <= c.MaxCPUs, "%d vCPUs, allowed %d-%d", t.CPUs, c.MinCPUs, c.MaxCPUs),
+		newCheck("vcpu-parity", t.CPUs == 1 || t.CPUs%2 == 0, "%d vCPUs must be 1 or even", t.CPUs),
+		newCheck("ram-alignment", t.RAMMB%c.RAMStepMB == 0, "%d MB %% %d = %d", t.RAMMB, c.RAMStepMB, t.RAMMB%c.RAMStepMB),
+		newCheck("ram-floor", t.RAMMB >= c.MinRAMMB, "%d MB >= %d MB", t.RAMMB, c.MinRAMMB),
+		newCheck("ram-per-vcpu-min", t.RAMMB >= minRAM, "%d MB >= %g GB × %d vCPUs = %d MB", t.RAMMB, c.MinGBPerCPU, t.CPUs, minRAM),
+		newCheck("ram-per-vcpu-max", t.RAMMB <= maxRAM, "%d MB <= %g GB × %d vCPUs = %d MB", t.RAMMB, c.MaxGBPerCPU, t.CPUs, maxRAM),
+	}
+	checks = append(checks, newCheck("ram-ceiling", t.RAMMB <= c.MaxRAMMB, "%d MB <= %d MB (%s %s maximum)", t.RAMMB, c.MaxRAMMB, c.Name, c.editionName()))
+	if s, ok := c.shapeLimit(t.CPUs); ok {
+		checks = append(checks, newCheck("ram-shape-max", t.RAMMB <= s.MaxRAMMB, "%d MB <= %d MB for %d vCPU tiers", t.RAMMB, s.MaxRAMMB, s.CPUs))
+	}
+	return checks
+}
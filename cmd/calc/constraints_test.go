@@ -0,0 +1,108 @@
+package main
+
+import (
+	"math/big"
+	"strconv"
+	"testing"
+)
+
+// exactRatioMB returns cpu vCPUs at gb GB/vCPU in MB as an exact fraction.
+func exactRatioMB(gb float64, cpu int) *big.Rat {
+	r, _ := new(big.Rat).SetString(strconv.FormatFloat(gb, 'f', -1, 64))
+	return r.Mul(r, big.NewRat(int64(cpu)*1024, 1))
+}
+
+// ratFloor and ratCeil round an exact fraction to whole MB.
+func ratFloor(r *big.Rat) int {
+	return int(new(big.Int).Quo(r.Num(), r.Denom()).Int64())
+}
+
+func ratCeil(r *big.Rat) int {
+	if r.IsInt() {
+		return ratFloor(r)
+	}
+	return ratFloor(r) + 1
+}
+
+func TestRAMBoundsAtEdges(t *testing.T) {
+	for _, engine := range sortedKeys(engineRules) {
+		for _, edition := range sortedKeys(editions) {
+			useRules(t, engine, edition)
+			for _, cpu := range []int{1, 2, 4, 6, 8, 16, 32, 48, 64, 80, 96, 128} {
+				if !rules.cpuAllowed(cpu) {
+					continue
+				}
+				minExact, maxExact := exactRatioMB(rules.MinGBPerCPU, cpu), exactRatioMB(rules.MaxGBPerCPU, cpu)
+				if got, want := rules.minRAMFor(cpu), ratCeil(minExact); got != want {
+					t.Errorf("%s %s: minRAMFor(%d) = %d, want %d", engine, edition, cpu, got, want)
+				}
+				want := min(ratFloor(maxExact), rules.MaxRAMMB)
+				if s, ok := rules.shapeLimit(cpu); ok {
+					want = min(want, s.MaxRAMMB)
+				}
+				if got := rules.maxRAMFor(cpu); got != want {
+					t.Errorf("%s %s: maxRAMFor(%d) = %d, want %d", engine, edition, cpu, got, want)
+				}
+				// The float version truncated both bounds. It agrees with the
+				// integer one whenever the bound is a whole MB; otherwise it
+				// was one MB too low for the floor.
+				floatMin := int(rules.MinGBPerCPU * float64(cpu) * 1024)
+				if minExact.IsInt() && floatMin != rules.minRAMFor(cpu) {
+					t.Errorf("%s %s: float min %d MB differs from %d MB at %d vCPUs", engine, edition, floatMin, rules.minRAMFor(cpu), cpu)
+				}
+				if !minExact.IsInt() && floatMin != rules.minRAMFor(cpu)-1 {
+					t.Errorf("%s %s: float min %d MB, want one below %d MB at %d vCPUs", engine, edition, floatMin, rules.minRAMFor(cpu), cpu)
+				}
+
+				lo := max(roundUp256(rules.minRAMFor(cpu)), rules.MinRAMMB)
+				hi := roundDown256(rules.maxRAMFor(cpu))
+				if lo > hi {
+					continue
+				}
+				for _, ram := range []int{lo, hi} {
+					if tier := (Tier{CPUs: cpu, RAMMB: ram}); !tier.Valid() {
+						t.Errorf("%s %s: %s at the memory bound is not valid: %v", engine, edition, tier, tier.Validate())
+					}
+				}
+				for _, ram := range []int{lo - 256, hi + 256} {
+					if tier := (Tier{CPUs: cpu, RAMMB: ram}); tier.Valid() {
+						t.Errorf("%s %s: %s outside the memory bound is valid", engine, edition, tier)
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestRatioMB(t *testing.T) {
+	tests := []struct {
+		gb          float64
+		cpu         int
+		floor, ceil int
+	}{
+		{0.9, 96, 88473, 88474},
+		{0.9, 1, 921, 922},
+		{0.9, 10, 9216, 9216},
+		{6.5, 96, 638976, 638976},
+		{6.5, 1, 6656, 6656},
+		{3.75, 2, 7680, 7680},
+		{1.5, 3, 4608, 4608},
+		{0.333, 3, 1022, 1023},
+	}
+	for _, tt := range tests {
+		if got := ratioMBFloor(tt.gb, tt.cpu); got != tt.floor {
+			t.Errorf("ratioMBFloor(%g, %d) = %d, want %d", tt.gb, tt.cpu, got, tt.floor)
+		}
+		if got := ratioMBCeil(tt.gb, tt.cpu); got != tt.ceil {
+			t.Errorf("ratioMBCeil(%g, %d) = %d, want %d", tt.gb, tt.cpu, got, tt.ceil)
+		}
+	}
+	for mb, want := range map[int][2]int{0: {0, 0}, 1: {0, 256}, 255: {0, 256}, 256: {256, 256}, 3841: {3840, 4096}} {
+		if got := roundDown256(mb); got != want[0] {
+			t.Errorf("roundDown256(%d) = %d, want %d", mb, got, want[0])
+		}
+		if got := roundUp256(mb); got != want[1] {
+			t.Errorf("roundUp256(%d) = %d, want %d", mb, got, want[1])
+		}
+	}
+}
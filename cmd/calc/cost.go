@@ -0,0 +1,338 @@
+package main
+
+import (
+	"cmp"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"text/tabwriter"
+)
+
+// defaultPrices is the embedded price table used unless -prices is given.
+//
+//go:embed prices.json
+var defaultPrices []byte
+
+// PriceTable is the schema of the embedded price table and of files passed
+// with -prices. Rates are on-demand list prices in Currency per hour:
+//
+//	{
+//	  "version": "2026-01",
+//	  "currency": "USD",
+//	  "hours_per_month": 730,
+//	  "ha_multiplier": 2,
+//	  "commitments": {"1yr": 25, "3yr": 52},
+//	  "regions": {
+//	    "us-central1": {
+//	      "vcpu_hour": 0.0413,
+//	      "gb_ram_hour": 0.0070,
+//	      "shared_core_hour": {"db-f1-micro": 0.0105, "db-g1-small": 0.0350}
+//	    }
+//	  }
+//	}
+type PriceTable struct {
+	Version       string                 `json:"version"`
+	Currency      string                 `json:"currency"`
+	HoursPerMonth float64                `json:"hours_per_month"`
+	HAMultiplier  float64                `json:"ha_multiplier"`
+	Commitments   map[string]float64     `json:"commitments"` // discount in percent
+	Regions       map[string]RegionPrice `json:"regions"`
+}
+
+// RegionPrice holds the rates for one region.
+type RegionPrice struct {
+	VCPUHour       float64            `json:"vcpu_hour"`
+	GBRAMHour      float64            `json:"gb_ram_hour"`
+	SharedCoreHour map[string]float64 `json:"shared_core_hour"`
+}
+
+// Cost is an estimated price for one tier. For custom tiers Hourly is the
+// sum of the vCPU and RAM parts.
+type Cost struct {
+	Region     string  `json:"region"`
+	Currency   string  `json:"currency"`
+	VCPUHourly float64 `json:"vcpu_hourly,omitempty"`
+	RAMHourly  float64 `json:"ram_hourly,omitempty"`
+	Hourly     float64 `json:"hourly"`
+	Monthly    float64 `json:"monthly"`
+	Estimate   bool    `json:"estimate"`
+	HA         bool    `json:"ha,omitempty"`
+	Commitment string  `json:"commitment,omitempty"`
+}
+
+// defaultCommitments are the committed use discounts, in percent, of a price
+// table that does not list its own.
+var defaultCommitments = map[string]float64{"1yr": 25, "3yr": 52}
+
+// noCommitment is the -commitment for on-demand prices.
+const noCommitment = "none"
+
+// prices is the table loaded by main when -cost is set.
+var prices *PriceTable
+
+// loadPrices reads a price table from path, or the embedded table when path
+// is empty.
+func loadPrices(path string) (*PriceTable, error) {
+	data := defaultPrices
+	if path != "" {
+		var err error
+		if data, err = os.ReadFile(path); err != nil {
+			return nil, err
+		}
+	}
+	var pt PriceTable
+	if err := json.Unmarshal(data, &pt); err != nil {
+		return nil, fmt.Errorf("invalid price table: %w", err)
+	}
+	if pt.HoursPerMonth == 0 {
+		pt.HoursPerMonth = 730
+	}
+	if pt.HAMultiplier == 0 {
+		pt.HAMultiplier = 2
+	}
+	if pt.Currency == "" {
+		pt.Currency = "USD"
+	}
+	if pt.Commitments == nil {
+		pt.Commitments = defaultCommitments
+	}
+	if err := pt.validate(); err != nil {
+		if path == "" {
+			path = "prices.json"
+		}
+		return nil, fmt.Errorf("invalid price table %s:\n%w", path, err)
+	}
+	return &pt, nil
+}
+
+// validate reports every missing or impossible rate, so a hand-maintained
+// -prices file can be fixed in one pass.
+func (pt *PriceTable) validate() error {
+	var errs []error
+	if pt.HoursPerMonth < 0 {
+		errs = append(errs, fmt.Errorf("hours_per_month must be positive, got %g", pt.HoursPerMonth))
+	}
+	if pt.HAMultiplier < 1 {
+		errs = append(errs, fmt.Errorf("ha_multiplier must be at least 1, got %g", pt.HAMultiplier))
+	}
+	if len(pt.Regions) == 0 {
+		errs = append(errs, fmt.Errorf(`no regions: add "regions": {"<region>": {"vcpu_hour": ..., "gb_ram_hour": ...}}`))
+	}
+	for _, name := range sortedKeys(pt.Commitments) {
+		if name == noCommitment {
+			errs = append(errs, fmt.Errorf("commitment %q: the name is reserved for on-demand prices", name))
+		} else if pct := pt.Commitments[name]; pct < 0 || pct >= 100 {
+			errs = append(errs, fmt.Errorf("commitment %q: discount must be from 0 to below 100 percent, got %g", name, pct))
+		}
+	}
+	for _, name := range sortedKeys(pt.Regions) {
+		rp := pt.Regions[name]
+		if rp.VCPUHour <= 0 {
+			errs = append(errs, fmt.Errorf("region %q: vcpu_hour is missing or not positive", name))
+		}
+		if rp.GBRAMHour <= 0 {
+			errs = append(errs, fmt.Errorf("region %q: gb_ram_hour is missing or not positive", name))
+		}
+		for _, tier := range sortedKeys(rp.SharedCoreHour) {
+			if rp.SharedCoreHour[tier] <= 0 {
+				errs = append(errs, fmt.Errorf("region %q: shared_core_hour %q is not positive", name, tier))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// region returns the rates for name.
+func (pt *PriceTable) region(name string) (RegionPrice, error) {
+	rp, ok := pt.Regions[name]
+	if !ok {
+		return RegionPrice{}, fmt.Errorf("no prices for region %q: use one of %s", name, strings.Join(sortedKeys(pt.Regions), ", "))
+	}
+	return rp, nil
+}
+
+// estimate prices a tier described by info in region at the -commitment.
+func (pt *PriceTable) estimate(info *TierInfo, region string) (*Cost, error) {
+	return pt.estimateAt(info, region, opts.commitment)
+}
+
+// commitment checks a -commitment name and returns its discount in percent.
+func (pt *PriceTable) commitment(name string) (float64, error) {
+	if name == noCommitment || name == "" {
+		return 0, nil
+	}
+	pct, ok := pt.Commitments[name]
+	if !ok {
+		return 0, fmt.Errorf("unknown commitment %q: use one of %s", name, strings.Join(pt.commitmentNames(), ", "))
+	}
+	return pct, nil
+}
+
+// commitmentNames lists none and the table's commitments, shortest first.
+func (pt *PriceTable) commitmentNames() []string {
+	names := sortedKeys(pt.Commitments)
+	slices.SortStableFunc(names, func(a, b string) int { return cmp.Compare(pt.Commitments[a], pt.Commitments[b]) })
+	return append([]string{noCommitment}, names...)
+}
+
+// estimateAt prices a tier described by info in region at a commitment.
+// With -ha the compute is charged ha_multiplier times, for the primary and
+// its standby. Committed use discounts do not cover shared-core tiers.
+func (pt *PriceTable) estimateAt(info *TierInfo, region, commitment string) (*Cost, error) {
+	discount, err := pt.commitment(commitment)
+	if err != nil {
+		return nil, err
+	}
+	rp, err := pt.region(region)
+	if err != nil {
+		return nil, err
+	}
+	m := 1.0
+	if opts.ha {
+		m = pt.HAMultiplier
+	}
+	c := &Cost{Region: region, Currency: pt.Currency, Estimate: true, HA: opts.ha}
+	if info.SharedCore {
+		rate, ok := rp.SharedCoreHour[info.Tier]
+		if !ok {
+			return nil, fmt.Errorf("no price for %s in region %q: add it to shared_core_hour", info.Tier, region)
+		}
+		c.Hourly = rate * m
+	} else {
+		m *= 1 - discount/100
+		c.VCPUHourly = float64(info.CPUs) * rp.VCPUHour * m
+		c.RAMHourly = info.RAMGB * rp.GBRAMHour * m
+		c.Hourly = c.VCPUHourly + c.RAMHourly
+		if discount > 0 {
+			c.Commitment = commitment
+		}
+	}
+	c.Monthly = c.Hourly * pt.HoursPerMonth
+	return c, nil
+}
+
+// addCosts prints the estimated cost of the primary tier and, when there is
+// a tier to compare against, records and prints the monthly difference.
+// describe has already priced every tier in res.
+func addCosts(res *Result) error {
+	if res.TierInfo == nil {
+		return nil
+	}
+	if res.Cost == nil {
+		_, err := prices.estimate(res.TierInfo, opts.region)
+		return err
+	}
+	kind := "on-demand"
+	if opts.commitment != noCommitment {
+		kind = fmt.Sprintf("%s commitment, %g%% off", opts.commitment, prices.Commitments[opts.commitment])
+	}
+	if opts.ha {
+		kind += ", HA"
+	}
+	res.printf("Estimated cost (%s, %s, estimate): %s/mo for %s\n",
+		opts.region, kind, prices.money(res.Cost.Monthly), res.Tier)
+	res.printf("  %s: %s\n", res.Tier, prices.breakdown(res.Cost))
+	other := res.comparisonTier()
+	if other == nil || other.Cost == nil {
+		return nil
+	}
+	delta := other.Cost.Monthly - res.Cost.Monthly
+	res.MonthlyDelta = &delta
+	res.printf("  %s: %s (%s)\n", other.Tier, prices.breakdown(other.Cost), prices.deltaText(delta))
+	return addCommitmentCosts(res, other)
+}
+
+// CommitmentCost is the monthly cost of the tier of a result and of the tier
+// it is compared with under one commitment level.
+type CommitmentCost struct {
+	Commitment string  `json:"commitment"`
+	Monthly    float64 `json:"monthly"`
+	Compared   float64 `json:"compared_monthly"`
+	Delta      float64 `json:"monthly_delta"`
+}
+
+// addCommitmentCosts lists the monthly costs of the tier of res and of other
+// side by side for every commitment level.
+func addCommitmentCosts(res *Result, other *TierInfo) error {
+	var sb strings.Builder
+	tw := tabwriter.NewWriter(&sb, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "    Commitment\t%s\t%s\tChange\n", res.Tier, other.Tier)
+	for _, name := range prices.commitmentNames() {
+		from, err := prices.estimateAt(res.TierInfo, opts.region, name)
+		if err != nil {
+			return err
+		}
+		to, err := prices.estimateAt(other, opts.region, name)
+		if err != nil {
+			return err
+		}
+		cc := &CommitmentCost{Commitment: name, Monthly: from.Monthly, Compared: to.Monthly, Delta: to.Monthly - from.Monthly}
+		res.CommitmentCosts = append(res.CommitmentCosts, cc)
+		label := name
+		if name == opts.commitment {
+			label += " *"
+		}
+		fmt.Fprintf(tw, "    %s\t%s/mo\t%s/mo\t%s\n", label, prices.money(cc.Monthly), prices.money(cc.Compared), prices.deltaText(cc.Delta))
+	}
+	tw.Flush()
+	res.println("  By commitment (* selected):")
+	res.printf("%s", sb.String())
+	return nil
+}
+
+// breakdown formats the parts of c, e.g. "vCPUs $0.3304/h + RAM $0.2100/h =
+// $0.5404/h, $394.49/mo".
+func (pt *PriceTable) breakdown(c *Cost) string {
+	total := fmt.Sprintf("%s/h, %s/mo", pt.rate(c.Hourly), pt.money(c.Monthly))
+	if c.VCPUHourly == 0 && c.RAMHourly == 0 {
+		return "shared core " + total
+	}
+	return fmt.Sprintf("vCPUs %s/h + RAM %s/h = %s", pt.rate(c.VCPUHourly), pt.rate(c.RAMHourly), total)
+}
+
+// money formats an amount in the table's currency.
+func (pt *PriceTable) money(v float64) string {
+	if pt.Currency == "USD" {
+		return fmt.Sprintf("$%.2f", v)
+	}
+	return fmt.Sprintf("%.2f %s", v, pt.Currency)
+}
+
+// rate formats an hourly amount, which needs more precision than money.
+func (pt *PriceTable) rate(v float64) string {
+	if pt.Currency == "USD" {
+		return fmt.Sprintf("$%.4f", v)
+	}
+	return fmt.Sprintf("%.4f %s", v, pt.Currency)
+}
+
+// deltaText describes a monthly cost change, e.g. "saves ~$412/mo".
+func (pt *PriceTable) deltaText(delta float64) string {
+	switch {
+	case delta < 0:
+		return fmt.Sprintf("saves ~%s/mo", pt.money(-delta))
+	case delta > 0:
+		return fmt.Sprintf("costs ~%s/mo more", pt.money(delta))
+	}
+	return "no change"
+}
+
+// checkCommitment warns when the tier a result moves to has fewer vCPUs or
+// less memory than -committed-cpus or -committed-ram: a commitment is billed
+// for its full term whether or not the instance uses it.
+func checkCommitment(res *Result) {
+	to := res.comparisonTier()
+	if to == nil {
+		return
+	}
+	if c := opts.committedCPUs; c > 0 && to.CPUs < c {
+		res.warnf(CodeCommitmentStranded, "%s strands %d of the %d committed vCPUs; the commitment is still billed", to.Tier, c-to.CPUs, c)
+	}
+	if c := opts.committedRAMMB; c > 0 && to.RAMMB < c {
+		res.warnf(CodeCommitmentStranded, "%s strands %d MB of the %d MB committed memory; the commitment is still billed", to.Tier, c-to.RAMMB, c)
+	}
+}
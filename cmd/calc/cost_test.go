@@ -0,0 +1,72 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestHAMatrix runs check-downgrade under every combination of -ha, -cost,
+// and an ha_min_tier policy, to a recommended tier above and below the floor.
+func TestHAMatrix(t *testing.T) {
+	config := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(config, []byte("ha_min_tier: db-custom-4-15360\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	const curr = "db-custom-8-30720"
+	zonal := map[string]float64{}
+	for _, rec := range []string{"db-custom-4-15360", "db-custom-2-7680"} {
+		for _, ha := range []bool{false, true} {
+			for _, cost := range []bool{false, true} {
+				for _, withPolicy := range []bool{false, true} {
+					args := []string{"check-downgrade", "-o", "json"}
+					if ha {
+						args = append(args, "-ha")
+					}
+					if cost {
+						args = append(args, "-cost")
+					}
+					if withPolicy {
+						args = append(args, "-config", config)
+					}
+					args = append(args, curr, rec)
+					out, code := run(t, args...)
+					var res struct {
+						HA   bool `json:"ha"`
+						Cost *struct {
+							Monthly float64 `json:"monthly"`
+							HA      bool    `json:"ha"`
+						} `json:"cost"`
+						Warnings []string `json:"warnings"`
+					}
+					if err := json.Unmarshal([]byte(out), &res); err != nil {
+						t.Fatalf("go-calc %q: %v\n%s", args, err, out)
+					}
+					if code != exitOK || res.HA != ha {
+						t.Errorf("go-calc %q: exit %d, ha %t, want exit %d and ha %t", args, code, res.HA, exitOK, ha)
+					}
+					if (res.Cost != nil) != cost {
+						t.Errorf("go-calc %q: cost = %+v, want a cost %t", args, res.Cost, cost)
+					} else if cost && !ha {
+						zonal[rec] = res.Cost.Monthly
+					} else if cost && (!res.Cost.HA || res.Cost.Monthly != 2*zonal[rec]) {
+						t.Errorf("go-calc %q: cost = %+v, want HA at twice the zonal %g", args, *res.Cost, zonal[rec])
+					}
+					below := rec == "db-custom-2-7680"
+					warned := strings.Contains(strings.Join(res.Warnings, "\n"), "ha_min_tier")
+					if want := ha && withPolicy && below; warned != want {
+						t.Errorf("go-calc %q: warnings = %q, want the ha_min_tier warning %t", args, res.Warnings, want)
+					}
+					if !ha || !withPolicy || !below {
+						continue
+					}
+					if _, code := run(t, append([]string{"check-downgrade", "-strict"}, args[1:]...)...); code != exitInvalid {
+						t.Errorf("go-calc -strict %q exited %d, want %d", args, code, exitInvalid)
+					}
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// DataSizing is the chain of reasoning behind a -data-size recommendation:
+// the hot fraction of the data becomes the buffer pool target, and the
+// buffer pool is a fixed fraction of instance memory. With -usable that is
+// the usable memory, and the instance memory adds the overhead to it.
+type DataSizing struct {
+	DataMB             float64   `json:"data_mb"`
+	WorkingSet         float64   `json:"working_set"`
+	BufferPoolMB       float64   `json:"buffer_pool_mb"`
+	BufferPoolFraction float64   `json:"buffer_pool_fraction"`
+	InstanceMemMB      float64   `json:"instance_mem_mb"`
+	UsableMemMB        float64   `json:"usable_mem_mb,omitempty"`
+	Overhead           *Overhead `json:"overhead,omitempty"`
+	Shards             int       `json:"shards,omitempty"`
+}
+
+// runDataSize sizes a tier from a data volume: working set × data is the
+// buffer pool, the buffer pool over -buffer-pool-fraction is the instance
+// memory, and the vCPUs follow from the memory at -ratio.
+func runDataSize(data string) (*Result, error) {
+	res := newResult("data-size")
+	res.sizeAt(opts.ratio)
+	dataMB, err := parseSize(data)
+	if err != nil {
+		return res, fmt.Errorf("invalid -data-size: %w", err)
+	}
+	if opts.workingSet <= 0 || opts.workingSet > 1 {
+		return res, fmt.Errorf("-working-set must be above 0 and at most 1, got %g", opts.workingSet)
+	}
+	if opts.bufferPoolFraction <= 0 || opts.bufferPoolFraction >= 1 {
+		return res, fmt.Errorf("-buffer-pool-fraction must be between 0 and 1, got %g", opts.bufferPoolFraction)
+	}
+	ds := &DataSizing{DataMB: dataMB, WorkingSet: opts.workingSet, BufferPoolFraction: opts.bufferPoolFraction}
+	ds.BufferPoolMB = dataMB * ds.WorkingSet
+	ds.InstanceMemMB = ds.BufferPoolMB / ds.BufferPoolFraction
+	res.Data = ds
+	memMB := res.padMem(res.addConnections(ds.InstanceMemMB))
+	memory := "Instance memory"
+	if opts.usable {
+		memory = "Usable memory"
+	}
+	res.printf("Recommended CloudSQL %s tier for %s of data:\n", rules.Name, strings.TrimSpace(data))
+	res.printf("  - Data: %.0f MB (%.2f GB)\n", dataMB, dataMB/1024)
+	res.printf("  - Working set: %g%% = %.0f MB (%.2f GB), the buffer pool target\n", ds.WorkingSet*100, ds.BufferPoolMB, ds.BufferPoolMB/1024)
+	res.printf("  - %s: %.0f MB / %g = %.0f MB (%.2f GB)\n", memory, ds.BufferPoolMB, ds.BufferPoolFraction, ds.InstanceMemMB, ds.InstanceMemMB/1024)
+	if res.Connections != nil {
+		res.printConnections()
+		res.printf("  - Total memory: %.0f MB (%.2f GB)\n", memMB, memMB/1024)
+	}
+	res.printPadding()
+	if opts.usable {
+		o := rules.Overhead
+		ds.UsableMemMB, ds.Overhead = memMB, &o
+		memMB = o.raw(memMB)
+		ds.InstanceMemMB = memMB
+		res.printf("  - Instance memory: %.0f MB usable + %s overhead = %.0f MB (%.2f GB)\n", ds.UsableMemMB, o, memMB, memMB/1024)
+	}
+	res.RequestedMemMB = memMB
+
+	if largest := rules.maxTier(); memMB > float64(largest.RAMMB) {
+		ds.Shards = int(math.Ceil(memMB / float64(largest.RAMMB)))
+		res.TierInfo = describe(largest)
+		res.Message = "working set exceeds the largest tier"
+		res.warnf(CodeWorkingSetTooLarge, "%.0f MB of instance memory is more than the largest tier %s (%d MB) holds; split the working set across %d instances with read replicas or sharding",
+			memMB, largest, largest.RAMMB, ds.Shards)
+		res.printf("  - Tier: %s (largest, %d MB)\n", largest, largest.RAMMB)
+		res.checkConnections(largest)
+		return res, nil
+	}
+
+	cpu := memMB / 1024 / opts.ratio
+	res.RequestedCPUs = cpu
+	if cpu > float64(rules.MaxCPUs) {
+		res.warnf(CodeCPUClamped, "%.2f vCPUs at %g GB/vCPU is more than %s %s allows; sized at %d vCPUs", cpu, opts.ratio, rules.Name, rules.editionName(), rules.MaxCPUs)
+		cpu = float64(rules.MaxCPUs)
+	}
+	tier, binding, err := smallestTierFor(cpu, memMB)
+	if err != nil {
+		return res, fmt.Errorf("cannot size %s of data: %w", strings.TrimSpace(data), err)
+	}
+	res.TierInfo = describe(tier)
+	res.Binding = binding
+	res.printf("  - vCPUs: %.2f at %g GB/vCPU, rounded to %d\n", res.RequestedCPUs, opts.ratio, tier.CPUs)
+	res.printf("  - Tier: %s (%d MB, %.2f GB)\n", tier, tier.RAMMB, tier.RAMGB())
+	res.printf("  - Memory per vCPU: %.2f GB (valid range: %s)\n", tier.Ratio(), rules.ratioRange())
+	res.checkConnections(tier)
+	return res, nil
+}
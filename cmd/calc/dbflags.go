@@ -0,0 +1,182 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// perThreadFlags are the buffers MySQL may allocate for every connection,
+// with their MySQL 8.0 defaults in bytes.
+var perThreadFlags = map[string]int64{
+	"sort_buffer_size":     262144,
+	"join_buffer_size":     262144,
+	"read_buffer_size":     131072,
+	"read_rnd_buffer_size": 262144,
+	"thread_stack":         1048576,
+	"binlog_cache_size":    32768,
+}
+
+// Defaults for the other memory flags when a flags file does not set them.
+const (
+	defaultMaxConnections = 151
+	defaultTmpTableSize   = 16777216
+)
+
+// FlagViolation is a memory flag that does not fit in the tier's budget.
+type FlagViolation struct {
+	Flag        string `json:"flag"`
+	Value       int64  `json:"value"`
+	BudgetBytes int64  `json:"budget_bytes"`
+	Msg         string `json:"message"`
+}
+
+// loadDBFlags reads database flags from a JSON object, a gcloud-style JSON
+// list of {"name", "value"} entries, or key=value lines.
+func loadDBFlags(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	flags := map[string]string{}
+	trimmed := bytes.TrimSpace(data)
+	switch {
+	case bytes.HasPrefix(trimmed, []byte("{")):
+		var m map[string]any
+		if err := json.Unmarshal(trimmed, &m); err != nil {
+			return nil, fmt.Errorf("invalid flags file %s: %w", path, err)
+		}
+		for k, v := range m {
+			flags[k] = fmt.Sprint(v)
+		}
+	case bytes.HasPrefix(trimmed, []byte("[")):
+		var list []struct {
+			Name  string `json:"name"`
+			Value any    `json:"value"`
+		}
+		if err := json.Unmarshal(trimmed, &list); err != nil {
+			return nil, fmt.Errorf("invalid flags file %s: %w", path, err)
+		}
+		for _, f := range list {
+			flags[f.Name] = fmt.Sprint(f.Value)
+		}
+	default:
+		scanner := bufio.NewScanner(bytes.NewReader(data))
+		line := 0
+		for scanner.Scan() {
+			line++
+			text := strings.TrimSpace(scanner.Text())
+			if text == "" || strings.HasPrefix(text, "#") {
+				continue
+			}
+			k, v, ok := strings.Cut(text, "=")
+			if !ok {
+				return nil, fmt.Errorf("invalid flags file %s: line %d: expected key=value", path, line)
+			}
+			flags[strings.TrimSpace(k)] = strings.TrimSpace(v)
+		}
+	}
+	return flags, nil
+}
+
+// intFlag returns the numeric value of a flag, or def when it is not set.
+func intFlag(flags map[string]string, name string, def int64) (int64, error) {
+	v, ok := flags[name]
+	if !ok {
+		return def, nil
+	}
+	n, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		return 0, fmt.Errorf("flag %s: invalid number %q", name, v)
+	}
+	return int64(n), nil
+}
+
+// checkDBFlags fits the memory flags into budgetPct of the tier's memory, in
+// order: the buffer pool, then max_connections times the per-thread buffers,
+// then tmp_table_size. Each flag that overruns what is left is a violation.
+func checkDBFlags(t Tier, flags map[string]string, budgetPct float64) ([]FlagViolation, error) {
+	budget := int64(float64(t.RAMMB) * budgetPct / 100 * (1 << 20))
+	left := budget
+	var violations []FlagViolation
+	consume := func(flag string, value, cost int64, what string) {
+		if cost > left {
+			name := flag
+			if _, set := flags[flag]; !set {
+				name += " (default)"
+			}
+			violations = append(violations, FlagViolation{
+				Flag: flag, Value: value, BudgetBytes: max(left, 0),
+				Msg: fmt.Sprintf("%s=%d needs %s for %s, but only %s of the %g%% budget (%s) is left",
+					name, value, bytesText(cost), what, bytesText(max(left, 0)), budgetPct, bytesText(budget)),
+			})
+		}
+		left -= cost
+	}
+
+	bp, err := intFlag(flags, "innodb_buffer_pool_size", 0)
+	if err != nil {
+		return nil, err
+	}
+	consume("innodb_buffer_pool_size", bp, bp, "the buffer pool")
+
+	conns, err := intFlag(flags, "max_connections", defaultMaxConnections)
+	if err != nil {
+		return nil, err
+	}
+	var perThread int64
+	for _, name := range sortedKeys(perThreadFlags) {
+		v, err := intFlag(flags, name, perThreadFlags[name])
+		if err != nil {
+			return nil, err
+		}
+		perThread += v
+	}
+	consume("max_connections", conns, conns*perThread, fmt.Sprintf("connections at %d KB each", perThread>>10))
+
+	tmp, err := intFlag(flags, "tmp_table_size", defaultTmpTableSize)
+	if err != nil {
+		return nil, err
+	}
+	consume("tmp_table_size", tmp, tmp, "in-memory temporary tables")
+	return violations, nil
+}
+
+// bytesText formats a byte count in MB, or in KB below 1 MB.
+func bytesText(n int64) string {
+	if n < 1<<20 && n > 0 {
+		return fmt.Sprintf("%d KB", n>>10)
+	}
+	return fmt.Sprintf("%d MB", n>>20)
+}
+
+// addFlagsCheck checks the -flags-file flags against the tier the result
+// resolves to.
+func addFlagsCheck(res *Result) error {
+	flags, err := loadDBFlags(opts.flagsFile)
+	if err != nil {
+		return err
+	}
+	t, err := ParseTier(res.resolvedTier())
+	if err != nil {
+		return fmt.Errorf("-flags-file needs a resolved tier")
+	}
+	violations, err := checkDBFlags(t, flags, opts.memBudgetPct)
+	if err != nil {
+		return err
+	}
+	res.FlagViolations = violations
+	if len(violations) == 0 {
+		res.printf("Database flags in %s fit %s (%g%% memory budget).\n", opts.flagsFile, t, opts.memBudgetPct)
+		return nil
+	}
+	res.printf("Database flags in %s do not fit %s:\n", opts.flagsFile, t)
+	for _, v := range violations {
+		res.printf("  %s\n", v.Msg)
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"text/tabwriter"
+)
+
+// word is the one-word verdict of diff: downgrade, upgrade, sideways when
+// neither resource changes, or mixed.
+func (v Verdict) word() string {
+	switch v.Change {
+	case "lower":
+		return "downgrade"
+	case "higher":
+		return "upgrade"
+	case "same":
+		return "sideways"
+	}
+	return "mixed"
+}
+
+// runDiffPair runs diff on an "a b" pair, separated like a check-downgrade
+// pair.
+func runDiffPair(input string) (*Result, error) {
+	parts := splitList([]string{input})
+	if len(parts) != 2 {
+		return newResult("diff"), fmt.Errorf("usage: -diff '<tier-a> <tier-b>'")
+	}
+	return runDiff(parts[0], parts[1])
+}
+
+// runDiff compares two tiers side by side, with the same delta and verdict
+// as check-downgrade and check-upgrade but no direction expected.
+func runDiff(a, b string) (*Result, error) {
+	res := newResult("diff")
+	res.InputTier = a
+	ta, err := ParseTier(a)
+	if err != nil {
+		return res, fmt.Errorf("invalid first tier: %w", err)
+	}
+	tb, err := ParseTier(b)
+	if err != nil {
+		return res, fmt.Errorf("invalid second tier: %w", err)
+	}
+	res.TierInfo = describe(ta)
+	res.Compared = describe(tb)
+	res.noteEquivalent(a, ta)
+	res.noteEquivalent(b, tb)
+	delta := compareTiers(ta, tb)
+	verdict := delta.verdict()
+	res.Delta, res.Verdict = &delta, &verdict
+	res.Direction = verdict.word()
+
+	ia, ib := res.TierInfo, res.Compared
+	var sb strings.Builder
+	tw := tabwriter.NewWriter(&sb, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "\t%s\t%s\tChange\n", ia.Tier, ib.Tier)
+	fmt.Fprintf(tw, "  vCPUs\t%g\t%g\t%+g (%+.0f%%)\n", ta.VCPUs(), tb.VCPUs(), delta.CPUs, delta.CPUPct)
+	fmt.Fprintf(tw, "  RAM\t%d MB\t%d MB\t%+d MB (%+.0f%%)\n", ia.RAMMB, ib.RAMMB, delta.RAMMB, delta.RAMPct)
+	fmt.Fprintf(tw, "  GB/vCPU\t%.2f\t%.2f\t%+.2f\n", ia.Ratio, ib.Ratio, ib.Ratio-ia.Ratio)
+	fmt.Fprintf(tw, "  Valid\t%t\t%t\t\n", ia.Valid, ib.Valid)
+	if ia.Cost != nil && ib.Cost != nil && ia.Cost.Monthly > 0 {
+		diff := ib.Cost.Monthly - ia.Cost.Monthly
+		fmt.Fprintf(tw, "  Cost\t%s/mo\t%s/mo\t%s (%+.0f%%)\n", prices.money(ia.Cost.Monthly), prices.money(ib.Cost.Monthly),
+			prices.deltaText(diff), diff/ia.Cost.Monthly*100)
+	}
+	tw.Flush()
+	res.printf("Comparing %s with %s:\n", a, b)
+	res.printf("%s", sb.String())
+	for _, info := range []*TierInfo{ia, ib} {
+		if !info.Valid {
+			res.printf("  %s is not valid: %s\n", info.Tier, strings.Join(info.Reasons, "; "))
+		}
+	}
+	res.printf("Verdict: %s (vCPUs %s, memory %s)\n", res.Direction, verdict.CPU, verdict.RAM)
+	return res, nil
+}
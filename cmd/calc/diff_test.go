@@ -0,0 +1,47 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	tests := []struct {
+		a, b      string
+		direction string
+		cpus      float64
+		ramMB     int
+		code      int
+	}{
+		{"db-custom-4-15360", "db-custom-8-30720", "upgrade", 4, 15360, exitOK},
+		{"db-custom-8-30720", "db-custom-4-15360", "downgrade", -4, -15360, exitOK},
+		{"db-custom-4-15360", "db-custom-4-15360", "sideways", 0, 0, exitOK},
+		{"db-custom-8-30720", "db-custom-6-39936", "mixed", -2, 9216, exitOK},
+		{"db-custom-4-15360", "db-custom-3-4000", "downgrade", -1, -11360, exitInvalid},
+	}
+	for _, tt := range tests {
+		out, code := run(t, "diff", "-o", "json", tt.a, tt.b)
+		var res struct {
+			Tier      string    `json:"tier"`
+			Compared  *TierInfo `json:"compared"`
+			Delta     *Delta    `json:"delta"`
+			Direction string    `json:"direction"`
+		}
+		if err := json.Unmarshal([]byte(out), &res); err != nil {
+			t.Fatalf("diff %s %s: %v\n%s", tt.a, tt.b, err, out)
+		}
+		if res.Tier != tt.a || res.Compared == nil || res.Compared.Tier != tt.b || res.Delta == nil {
+			t.Fatalf("diff %s %s compared %s with %v", tt.a, tt.b, res.Tier, res.Compared)
+		}
+		if res.Direction != tt.direction || res.Delta.CPUs != tt.cpus || res.Delta.RAMMB != tt.ramMB || code != tt.code {
+			t.Errorf("diff %s %s = %s, %+g vCPUs, %+d MB (exit %d), want %s, %+g vCPUs, %+d MB (exit %d)",
+				tt.a, tt.b, res.Direction, res.Delta.CPUs, res.Delta.RAMMB, code, tt.direction, tt.cpus, tt.ramMB, tt.code)
+		}
+	}
+	if _, code := run(t, "diff", "db-custom-4-15360", "bogus"); code != exitParse {
+		t.Errorf("diff with an unparseable tier exited %d, want %d", code, exitParse)
+	}
+	if _, code := run(t, "-diff", "db-custom-4-15360"); code != exitUsage {
+		t.Errorf("-diff with one tier exited %d, want %d", code, exitUsage)
+	}
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Process exit codes.
+const (
+	exitOK      = 0 // tier is valid
+	exitUsage   = 1 // bad flags or arguments
+	exitInvalid = 2 // input parsed but failed validation
+	exitParse   = 3 // input could not be parsed
+	exitCurrent = 4 // current tier of a change check failed validation
+	exitPolicy  = 5 // resulting tier is outside the policy
+)
+
+// Sentinel errors identifying which parse or validation rule failed.
+// Concrete failures are returned as *TierError values wrapping one of these,
+// so callers can match with errors.Is and inspect details with errors.As.
+var (
+	ErrBadTierSyntax   = errors.New("invalid tier format")
+	ErrCPUCount        = errors.New("invalid vCPU count")
+	ErrRAMAlignment    = errors.New("memory is not a multiple of 256 MB")
+	ErrRAMTooLow       = errors.New("memory is below the minimum")
+	ErrRatioOutOfRange = errors.New("memory per vCPU is out of range")
+	ErrRAMTooHigh      = errors.New("memory is above the maximum")
+	ErrBadMemSyntax    = errors.New("invalid memory format")
+	ErrBadMemUnit      = errors.New("invalid memory unit")
+)
+
+// TierError describes a single failed rule and the value that failed it.
+type TierError struct {
+	Kind  error  // one of the Err* sentinels
+	Value any    // the offending value
+	Msg   string // human-readable explanation
+	Code  string // a Code* narrower than the code of Kind, if any
+}
+
+func (e *TierError) Error() string {
+	return e.Msg
+}
+
+func (e *TierError) Unwrap() error {
+	return e.Kind
+}
+
+func newTierError(kind error, value any, format string, args ...any) *TierError {
+	return &TierError{Kind: kind, Value: value, Msg: fmt.Sprintf(format, args...)}
+}
+
+// withCode sets the narrower code of e, such as CodeCPUOdd for an
+// ErrCPUCount failure, and returns e.
+func (e *TierError) withCode(code string) *TierError {
+	e.Code = code
+	return e
+}
+
+// Sentinel errors classifying why a Cloud SQL Admin API, Cloud Monitoring,
+// or credentials call failed. They are returned as *APIError values wrapping
+// one of these.
+var (
+	ErrAPIAuth      = errors.New("not authorized")
+	ErrAPINotFound  = errors.New("not found")
+	ErrAPITransient = errors.New("transient failure")
+	ErrAPIRequest   = errors.New("request rejected")
+)
+
+// APIError describes a failed API call. Only ErrAPITransient failures are
+// retried.
+type APIError struct {
+	Kind error  // one of the ErrAPI* sentinels
+	Msg  string // human-readable explanation
+}
+
+func (e *APIError) Error() string {
+	return e.Msg
+}
+
+func (e *APIError) Unwrap() error {
+	return e.Kind
+}
+
+func newAPIError(kind error, format string, args ...any) *APIError {
+	return &APIError{Kind: kind, Msg: fmt.Sprintf(format, args...)}
+}
+
+// exitCodeFor maps an error returned by a mode to a process exit code:
+// input that could not be parsed gives exitParse, and a parsed value out of
+// range, such as a tier above the edition maximum, gives exitInvalid.
+func exitCodeFor(err error) int {
+	var te *TierError
+	switch {
+	case errors.Is(err, ErrBadTierSyntax), errors.Is(err, ErrBadMemSyntax), errors.Is(err, ErrBadMemUnit):
+		return exitParse
+	case errors.As(err, &te):
+		return exitInvalid
+	}
+	return exitUsage
+}
+
+// Stable codes of every error, validation failure, and warning, for machines
+// reading JSON, NDJSON, CSV, or GitHub Actions output alongside the message.
+// A code keeps its meaning once released; add new codes rather than reuse one.
+const (
+	// Parse and validation failures
+	CodeTierSyntax      = "TIER_SYNTAX"
+	CodeCPUCount        = "CPU_COUNT"
+	CodeCPUOdd          = "CPU_ODD"
+	CodeCPURange        = "CPU_OUT_OF_RANGE"
+	CodeSharedCore      = "SHARED_CORE_UNAVAILABLE"
+	CodeRAMAlignment    = "RAM_NOT_256_ALIGNED"
+	CodeBelowMinRAM     = "BELOW_MIN_RAM"
+	CodeAboveMaxRAM     = "ABOVE_MAX_RAM"
+	CodeAboveShapeRAM   = "ABOVE_SHAPE_MAX_RAM"
+	CodeRatioOutOfRange = "RATIO_OUT_OF_RANGE"
+	CodeRatioTooLow     = "RATIO_TOO_LOW"
+	CodeRatioTooHigh    = "RATIO_TOO_HIGH"
+	CodeMemSyntax       = "MEM_SYNTAX"
+	CodeMemUnit         = "MEM_UNIT"
+	CodeAPIAuth         = "API_AUTH"
+	CodeAPINotFound     = "API_NOT_FOUND"
+	CodeAPITransient    = "API_TRANSIENT"
+	CodeAPIRequest      = "API_REQUEST"
+	CodeNotADowngrade   = "NOT_A_DOWNGRADE"
+	CodeNotAnUpgrade    = "NOT_AN_UPGRADE"
+	CodeCurrentInvalid  = "CURRENT_INVALID"
+	CodeReplicaTooSmall = "REPLICA_TOO_SMALL"
+	CodePolicyBelowMin  = "POLICY_BELOW_MIN_TIER"
+	CodePolicyAboveMax  = "POLICY_ABOVE_MAX_TIER"
+	CodePolicyRatio     = "POLICY_RATIO_TOO_LOW"
+
+	// Warnings
+	CodeNoValidTier         = "NO_VALID_TIER"
+	CodeMixedChange         = "MIXED_CHANGE"
+	CodeCurrentCorrected    = "CURRENT_CORRECTED"
+	CodeAggressiveDowngrade = "AGGRESSIVE_DOWNGRADE"
+	CodeHAViolation         = "HA_VIOLATION"
+	CodeRAMRaised           = "RAM_RAISED_TO_MIN"
+	CodeRAMRoundedUp        = "RAM_ROUNDED_UP"
+	CodeCPUMoved            = "CPU_MOVED"
+	CodeCPUClamped          = "CPU_CLAMPED"
+	CodeSharedCoreNoSLA     = "SHARED_CORE_NO_SLA"
+	CodeRatioAdjusted       = "RATIO_ADJUSTED"
+	CodeConnectionsLimit    = "CONNECTIONS_ABOVE_LIMIT"
+	CodeConnectionsPooling  = "CONNECTIONS_NEED_POOLING"
+	CodeCommitmentStranded  = "COMMITMENT_STRANDED"
+	CodeWorkingSetTooLarge  = "WORKING_SET_TOO_LARGE"
+	CodeGrowthBeyondLimits  = "GROWTH_BEYOND_LIMITS"
+	CodeReplicaOffsetShort  = "REPLICA_OFFSET_SHORT"
+	CodeReplicaLarger       = "REPLICA_LARGER"
+	CodeScaleClamped        = "SCALE_CLAMPED"
+)
+
+// reasonCodes are the codes of the error sentinels.
+var reasonCodes = []struct {
+	kind error
+	code string
+}{
+	{ErrBadTierSyntax, CodeTierSyntax},
+	{ErrCPUCount, CodeCPUCount},
+	{ErrRAMAlignment, CodeRAMAlignment},
+	{ErrRAMTooLow, CodeBelowMinRAM},
+	{ErrRatioOutOfRange, CodeRatioOutOfRange},
+	{ErrRAMTooHigh, CodeAboveMaxRAM},
+	{ErrBadMemSyntax, CodeMemSyntax},
+	{ErrBadMemUnit, CodeMemUnit},
+	{ErrAPIAuth, CodeAPIAuth},
+	{ErrAPINotFound, CodeAPINotFound},
+	{ErrAPITransient, CodeAPITransient},
+	{ErrAPIRequest, CodeAPIRequest},
+}
+
+// reasonCode is the code of err: the narrower code of a *TierError, or the
+// code of the sentinel err wraps, or "" for an error without one.
+func reasonCode(err error) string {
+	var te *TierError
+	if errors.As(err, &te) && te.Code != "" {
+		return te.Code
+	}
+	for _, rc := range reasonCodes {
+		if errors.Is(err, rc.kind) {
+			return rc.code
+		}
+	}
+	return ""
+}
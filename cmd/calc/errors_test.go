@@ -0,0 +1,145 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// declaredNames returns the package-level constants or variables of
+// errors.go whose names start with prefix, with their string values for
+// constants.
+func declaredNames(t *testing.T, tok token.Token, prefix string) map[string]string {
+	t.Helper()
+	f, err := parser.ParseFile(token.NewFileSet(), "errors.go", nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := map[string]string{}
+	for _, decl := range f.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != tok {
+			continue
+		}
+		for _, spec := range gen.Specs {
+			vs := spec.(*ast.ValueSpec)
+			for i, name := range vs.Names {
+				if !strings.HasPrefix(name.Name, prefix) {
+					continue
+				}
+				names[name.Name] = ""
+				if lit, ok := vs.Values[i].(*ast.BasicLit); ok && lit.Kind == token.STRING {
+					names[name.Name], _ = strconv.Unquote(lit.Value)
+				}
+			}
+		}
+	}
+	return names
+}
+
+func TestCodesUnique(t *testing.T) {
+	codes := declaredNames(t, token.CONST, "Code")
+	if len(codes) < len(reasonCodes) {
+		t.Fatalf("found %d Code constants, want at least the %d reason codes", len(codes), len(reasonCodes))
+	}
+	seen := map[string]string{}
+	for name, code := range codes {
+		if !regexp.MustCompile(`^[A-Z][A-Z0-9_]*$`).MatchString(code) {
+			t.Errorf("%s = %q, want an upper-case code", name, code)
+		}
+		if other, ok := seen[code]; ok {
+			t.Errorf("%s and %s are both %q", name, other, code)
+		}
+		seen[code] = name
+	}
+}
+
+func TestEverySentinelHasACode(t *testing.T) {
+	sentinels := declaredNames(t, token.VAR, "Err")
+	if len(sentinels) != len(reasonCodes) {
+		t.Errorf("errors.go declares %d Err sentinels and reasonCodes maps %d", len(sentinels), len(reasonCodes))
+	}
+	codes := map[string]bool{}
+	for _, code := range declaredNames(t, token.CONST, "Code") {
+		codes[code] = true
+	}
+	for _, rc := range reasonCodes {
+		if !codes[rc.code] {
+			t.Errorf("%v maps to %q, which is not a Code constant", rc.kind, rc.code)
+		}
+		wrapped := fmt.Errorf("wrapped: %w", newTierError(rc.kind, "x", "bad"))
+		if got := reasonCode(wrapped); got != rc.code {
+			t.Errorf("reasonCode of a wrapped %v = %q, want %q", rc.kind, got, rc.code)
+		}
+	}
+	if got := reasonCode((&TierError{Kind: ErrCPUCount}).withCode(CodeCPUOdd)); got != CodeCPUOdd {
+		t.Errorf("reasonCode of a narrowed vCPU error = %q, want %q", got, CodeCPUOdd)
+	}
+	if got := reasonCode(errors.New("something else")); got != "" {
+		t.Errorf("reasonCode of an untyped error = %q, want none", got)
+	}
+}
+
+func TestOutputCodes(t *testing.T) {
+	var res struct {
+		Reasons      []string `json:"reasons"`
+		ReasonCodes  []string `json:"reason_codes"`
+		Warnings     []string `json:"warnings"`
+		WarningCodes []string `json:"warning_codes"`
+		Code         string   `json:"code"`
+		ErrorCode    string   `json:"error_code"`
+	}
+	tests := []struct {
+		args                          []string
+		reasons, warnings, code, errc string
+	}{
+		{[]string{"-o", "json", "db-custom-3-4000"}, "CPU_ODD,RAM_NOT_256_ALIGNED", "", "", ""},
+		{[]string{"-o", "json", "db-custom-2-16384"}, "RATIO_TOO_HIGH", "", "", ""},
+		{[]string{"-o", "json", "nonsense"}, "", "", "", "TIER_SYNTAX"},
+		{[]string{"check-downgrade", "-o", "json", "db-custom-4-16384", "db-custom-8-53248"}, "", "", "NOT_A_DOWNGRADE", ""},
+		{[]string{"check-downgrade", "-o", "json", "db-custom-16-61440", "db-custom-4-15360"}, "", "AGGRESSIVE_DOWNGRADE", "", ""},
+	}
+	for _, tt := range tests {
+		out, _ := run(t, tt.args...)
+		res.Reasons, res.ReasonCodes, res.Warnings, res.WarningCodes, res.Code, res.ErrorCode = nil, nil, nil, nil, "", ""
+		if err := json.Unmarshal([]byte(out), &res); err != nil {
+			t.Fatalf("go-calc %q: %v\n%s", tt.args, err, out)
+		}
+		if got := strings.Join(res.ReasonCodes, ","); got != tt.reasons || len(res.ReasonCodes) != len(res.Reasons) {
+			t.Errorf("go-calc %q reason_codes = %q for %q, want %q", tt.args, res.ReasonCodes, res.Reasons, tt.reasons)
+		}
+		if got := strings.Join(res.WarningCodes, ","); got != tt.warnings || len(res.WarningCodes) != len(res.Warnings) {
+			t.Errorf("go-calc %q warning_codes = %q for %q, want %q", tt.args, res.WarningCodes, res.Warnings, tt.warnings)
+		}
+		if res.Code != tt.code || res.ErrorCode != tt.errc {
+			t.Errorf("go-calc %q code = %q, error_code = %q, want %q and %q", tt.args, res.Code, res.ErrorCode, tt.code, tt.errc)
+		}
+	}
+
+	// CSV and GitHub Actions output carry the same codes.
+	out, _ := run(t, "-o", "csv", "db-custom-3-4000")
+	rows, err := csv.NewReader(strings.NewReader(out)).ReadAll()
+	if err != nil || len(rows) != 2 || rows[0][7] != "code" || rows[1][7] != "CPU_ODD;RAM_NOT_256_ALIGNED" {
+		t.Errorf("-o csv = %q, %v, want the codes in the code column", rows, err)
+	}
+	for _, tt := range []struct {
+		args []string
+		want string
+	}{
+		{[]string{"-o", "gha", "db-custom-3-4000"}, "::error::[CPU_ODD,RAM_NOT_256_ALIGNED] db-custom-3-4000 is not a valid"},
+		{[]string{"check-downgrade", "-o", "gha", "db-custom-4-16384", "db-custom-8-53248"}, "::error::[NOT_A_DOWNGRADE] "},
+		{[]string{"check-downgrade", "-o", "gha", "db-custom-16-61440", "db-custom-4-15360"}, "::warning::[AGGRESSIVE_DOWNGRADE] "},
+	} {
+		if out, _ := run(t, tt.args...); !strings.HasPrefix(out, tt.want) {
+			t.Errorf("go-calc %q = %q, want it to start %q", tt.args, out, tt.want)
+		}
+	}
+}
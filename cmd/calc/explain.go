@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Check is one rule evaluation or sizing step reported by -explain. For a
+// sizing step, Passed means the value already satisfied the rule and was
+// left unchanged.
+type Check struct {
+	Rule   string `json:"rule"`
+	Passed bool   `json:"passed"`
+	Detail string `json:"detail"`
+
+	step bool
+}
+
+// explanation collects the checks of one result. Its methods do nothing on a
+// nil receiver, so code can record steps without testing for -explain.
+type explanation []Check
+
+func newCheck(rule string, passed bool, format string, a ...any) Check {
+	return Check{Rule: rule, Passed: passed, Detail: fmt.Sprintf(format, a...)}
+}
+
+func (e *explanation) add(rule string, passed bool, format string, a ...any) {
+	if e == nil {
+		return
+	}
+	c := newCheck(rule, passed, format, a...)
+	c.step = true
+	*e = append(*e, c)
+}
+
+// checkTier records every rule t is validated against.
+func (e *explanation) checkTier(t Tier) {
+	if e == nil {
+		return
+	}
+	*e = append(*e, rules.checks(t)...)
+}
+
+// text renders the checks as an indented list. Rule checks are marked pass or
+// FAIL, sizing steps ok or adj, when they changed the value.
+func (e explanation) text() string {
+	var sb strings.Builder
+	sb.WriteString("Explanation:\n")
+	for _, c := range e {
+		var mark string
+		switch {
+		case c.step && c.Passed:
+			mark = "ok"
+		case c.step:
+			mark = "adj"
+		case c.Passed:
+			mark = "pass"
+		default:
+			mark = "FAIL"
+		}
+		fmt.Fprintf(&sb, "  [%-4s] %s: %s\n", mark, c.Rule, c.Detail)
+	}
+	return sb.String()
+}
+
+// explainer returns the explanation of r when -explain is set, else nil.
+func (r *Result) explainer() *explanation {
+	if !opts.explain {
+		return nil
+	}
+	return &r.Explanation
+}
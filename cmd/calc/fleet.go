@@ -0,0 +1,381 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"text/tabwriter"
+)
+
+// gcloudInstance is the subset of `gcloud sql instances list --format=json`
+// that fleet analysis reads.
+type gcloudInstance struct {
+	Name            string `json:"name"`
+	Project         string `json:"project"`
+	Region          string `json:"region"`
+	DatabaseVersion string `json:"databaseVersion"`
+	Settings        struct {
+		Tier    string `json:"tier"`
+		Edition string `json:"edition"`
+	} `json:"settings"`
+}
+
+// engineFor maps a CloudSQL databaseVersion such as MYSQL_8_0 or
+// SQLSERVER_2019_ENTERPRISE to an -engine value.
+func engineFor(databaseVersion string) (string, error) {
+	v := strings.ToUpper(databaseVersion)
+	switch {
+	case strings.HasPrefix(v, "MYSQL"):
+		return "mysql", nil
+	case strings.HasPrefix(v, "POSTGRES"):
+		return "postgres", nil
+	case strings.HasPrefix(v, "SQLSERVER") && strings.HasSuffix(v, "_ENTERPRISE"):
+		return "sqlserver-enterprise", nil
+	case strings.HasPrefix(v, "SQLSERVER"):
+		return "sqlserver", nil
+	}
+	return "", fmt.Errorf("unknown databaseVersion %q", databaseVersion)
+}
+
+// editionFor maps a settings.edition value such as ENTERPRISE_PLUS to an
+// -edition value. Instances without one are Enterprise.
+func editionFor(edition string) string {
+	if edition == "" {
+		return defaultEdition
+	}
+	return strings.ReplaceAll(strings.ToLower(edition), "_", "-")
+}
+
+// FleetInstance is the analysis of one instance. The embedded Result is the
+// -t analysis of its tier under the instance's engine and edition rules,
+// with its utilization under -monitor.
+type FleetInstance struct {
+	Name            string `json:"name"`
+	Region          string `json:"region"`
+	DatabaseVersion string `json:"database_version"`
+	Known           bool   `json:"known_shape"`
+	MonitoringError string `json:"monitoring_error,omitempty"`
+	*Result
+}
+
+// FleetTotals sums the analysed instances.
+type FleetTotals struct {
+	Instances int     `json:"instances"`
+	VCPUs     float64 `json:"vcpus"`
+	RAMMB     int     `json:"ram_mb"`
+	Invalid   int     `json:"invalid"`
+	Errors    int     `json:"errors"`
+
+	MonitoringErrors int `json:"monitoring_errors,omitempty"`
+}
+
+// FleetResult is the outcome of -instances.
+type FleetResult struct {
+	Mode      string           `json:"mode"`
+	Source    string           `json:"source"`
+	Instances []*FleetInstance `json:"instances"`
+	Totals    FleetTotals      `json:"totals"`
+	Error     string           `json:"error,omitempty"`
+	Version   *BuildInfo       `json:"version,omitempty"`
+}
+
+func (f *FleetResult) setError(err error) {
+	f.Error = err.Error()
+}
+
+func (f *FleetResult) setVersion(bi *BuildInfo) {
+	f.Version = bi
+}
+
+// exitCode follows -batch: exitParse if any tier failed to parse,
+// exitInvalid if any tier was invalid (or, with -strict, its utilization
+// could not be read), and exitOK otherwise.
+func (f *FleetResult) exitCode() int {
+	switch {
+	case f.Totals.Errors > 0:
+		return exitParse
+	case f.Totals.Invalid > 0:
+		return exitInvalid
+	case opts.strict && f.Totals.MonitoringErrors > 0:
+		return exitInvalid
+	}
+	return exitOK
+}
+
+// warnings returns the utilization lookups that failed, by instance.
+func (f *FleetResult) warnings() []string {
+	var ws []string
+	for _, in := range f.Instances {
+		if in.MonitoringError != "" {
+			ws = append(ws, fmt.Sprintf("instance %s: %s", in.Name, in.MonitoringError))
+		}
+	}
+	return ws
+}
+
+func (f *FleetResult) humanText() string {
+	var sb strings.Builder
+	tw := tabwriter.NewWriter(&sb, 0, 0, 2, ' ', 0)
+	util := ""
+	if opts.monitor {
+		util = "\tCPU %\tMem %"
+	}
+	fmt.Fprintln(tw, "Instance\tRegion\tTier\tvCPUs\tRAM GB\tGB/vCPU\tValid\tShape"+util+"\tNext tier")
+	for _, in := range f.Instances {
+		if opts.monitor {
+			util = "\t\t"
+			if m := in.Monitoring; m != nil {
+				util = fmt.Sprintf("\t%.1f\t%.1f", m.CPUPct, m.MemPct)
+			}
+		}
+		if in.Error != "" {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t\t\t\terror\t%s\t%s\n", in.Name, in.Region, in.InputTier, util, in.Error)
+			continue
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%g\t%.2f\t%.2f\t%t\t%s%s\t%s\n", in.Name, in.Region, in.InputTier,
+			in.vcpus(), in.RAMGB, in.Ratio, in.Valid, in.shape(), util, in.SuggestedTier)
+	}
+	tw.Flush()
+	t := f.Totals
+	fmt.Fprintf(&sb, "%d instances: %g vCPUs, %d MB (%.2f GB) RAM, %d invalid, %d errors",
+		t.Instances, t.VCPUs, t.RAMMB, float64(t.RAMMB)/1024, t.Invalid, t.Errors)
+	if t.MonitoringErrors > 0 {
+		fmt.Fprintf(&sb, ", %d without utilization", t.MonitoringErrors)
+	}
+	sb.WriteString("\n")
+	return sb.String()
+}
+
+func (f *FleetResult) csvRecords() [][]string {
+	records := [][]string{{"name", "region", "database_version", "tier", "cpus", "ram_mb", "ratio_gb_per_cpu", "valid", "known_shape", "suggested_tier", "error"}}
+	if opts.monitor {
+		records[0] = append(records[0], "cpu_util_pct", "mem_util_pct", "monitoring_error")
+	}
+	for _, in := range f.Instances {
+		row := []string{in.Name, in.Region, in.DatabaseVersion, in.InputTier, "", "", "", "", "", "", in.Error}
+		if in.TierInfo != nil {
+			row[4] = strconv.FormatFloat(in.vcpus(), 'g', -1, 64)
+			row[5] = strconv.Itoa(in.RAMMB)
+			row[6] = strconv.FormatFloat(in.Ratio, 'f', 2, 64)
+			row[7] = strconv.FormatBool(in.Valid)
+			row[8] = strconv.FormatBool(in.Known)
+			row[9] = in.SuggestedTier
+		}
+		if opts.monitor {
+			util := []string{"", "", in.MonitoringError}
+			if m := in.Monitoring; m != nil {
+				util[0] = strconv.FormatFloat(m.CPUPct, 'f', 1, 64)
+				util[1] = strconv.FormatFloat(m.MemPct, 'f', 1, 64)
+			}
+			row = append(row, util...)
+		}
+		records = append(records, row)
+	}
+	return records
+}
+
+// vcpus returns the instance vCPUs, fractional for shared-core tiers. It is
+// read from the analysis, which ran under the instance's rules.
+func (in *FleetInstance) vcpus() float64 {
+	return in.TierInfo.tier().VCPUs()
+}
+
+func (in *FleetInstance) shape() string {
+	if in.Known {
+		return "known"
+	}
+	return "oddball"
+}
+
+// runFleet analyses every instance in a gcloud instance list JSON file, or
+// stdin when path is "-", decoding one instance at a time. With -o ndjson
+// each instance is written out as it is analysed instead of kept.
+//
+// With -monitor the utilization of up to -concurrency instances is read at
+// once, and the instances are analysed in input order as their lookups
+// finish. Ctrl-C cancels the lookups in flight and ends the run.
+func runFleet(path string) (*FleetResult, error) {
+	f := &FleetResult{Mode: "instances", Source: path, Instances: []*FleetInstance{}}
+	var in io.Reader = os.Stdin
+	if path != "-" {
+		file, err := os.Open(path)
+		if err != nil {
+			return f, err
+		}
+		defer file.Close()
+		in = file
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	pending := make(chan *fleetLookup, opts.concurrency)
+	decoded := make(chan error, 1)
+	go func() {
+		defer close(pending)
+		decoded <- lookupInstances(ctx, json.NewDecoder(in), path, pending)
+	}()
+	// Cancel and wait out the lookups before returning, however the run
+	// ends, so none outlives it
+	defer func() {
+		stop()
+		for range pending {
+		}
+	}()
+	selected := rules
+	defer func() { rules = selected }()
+	for l := range pending {
+		<-l.done
+		if ctx.Err() != nil {
+			break
+		}
+		fi := f.add(l.gi)
+		if l.usage != nil {
+			fi.Monitoring = l.usage
+		} else if l.err != nil {
+			fi.MonitoringError = l.err.Error()
+			f.Totals.MonitoringErrors++
+		}
+		if streaming() {
+			if err := writeJSONLine(streamOut, ndjsonInstance{"instance", fi}); err != nil {
+				return f, err
+			}
+			continue
+		}
+		f.Instances = append(f.Instances, fi)
+	}
+	if ctx.Err() != nil {
+		return f, fmt.Errorf("interrupted after %d instances", f.Totals.Instances)
+	}
+	return f, <-decoded
+}
+
+// fleetLookup is an instance of the list and its utilization, which done
+// is closed on once read. Without -monitor it is closed on at once.
+type fleetLookup struct {
+	gi    gcloudInstance
+	usage *Monitoring
+	err   error
+	done  chan struct{}
+}
+
+// lookupInstances decodes the instances of a list and sends them to pending
+// in order, reading the utilization of up to -concurrency of them at once
+// under -monitor. It returns once every lookup it started has ended.
+func lookupInstances(ctx context.Context, dec *json.Decoder, path string, pending chan<- *fleetLookup) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return fmt.Errorf("invalid instance list %s: %w", path, err)
+	}
+	if tok != json.Delim('[') {
+		return fmt.Errorf("invalid instance list %s: not a JSON array", path)
+	}
+	slots := make(chan struct{}, opts.concurrency)
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	for dec.More() {
+		l := &fleetLookup{done: make(chan struct{})}
+		if err := dec.Decode(&l.gi); err != nil {
+			return fmt.Errorf("invalid instance list %s: %w", path, err)
+		}
+		// The tier is parsed here, not in the lookup, and under the
+		// instance's own rules: runFleet switches rules as it analyses
+		t, err := listTier(l.gi)
+		if opts.monitor && err == nil {
+			select {
+			case slots <- struct{}{}:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+			wg.Go(func() {
+				defer func() { <-slots }()
+				defer close(l.done)
+				l.usage, l.err = instanceUsage(ctx, l.gi, t)
+			})
+		} else {
+			close(l.done)
+		}
+		select {
+		case pending <- l:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	if _, err := dec.Token(); err != nil {
+		return fmt.Errorf("invalid instance list %s: %w", path, err)
+	}
+	return nil
+}
+
+// instanceUsage reads the utilization of an instance of the list, of tier
+// t, in the project it names or -project.
+func instanceUsage(ctx context.Context, gi gcloudInstance, t Tier) (*Monitoring, error) {
+	project := gi.Project
+	if project == "" {
+		project = opts.project
+	}
+	if project == "" {
+		return nil, fmt.Errorf("no project for Cloud Monitoring: the instance list has none, so use -project")
+	}
+	ctx, cancel := context.WithTimeout(ctx, apiTimeout)
+	defer cancel()
+	return readUsage(ctx, project, gi.Name, t)
+}
+
+// add analyses one instance and counts it in the totals.
+func (f *FleetResult) add(gi gcloudInstance) *FleetInstance {
+	fi := &FleetInstance{Name: gi.Name, Region: gi.Region, DatabaseVersion: gi.DatabaseVersion}
+	f.Totals.Instances++
+	res, err := analyseInstance(gi)
+	fi.Result = res
+	if err != nil {
+		res.setError(err)
+		f.Totals.Errors++
+		return fi
+	}
+	t, _ := ParseTier(gi.Settings.Tier)
+	fi.Known = t.Shared() || slices.Contains(knownTiers, t)
+	f.Totals.VCPUs += t.VCPUs()
+	f.Totals.RAMMB += t.RAMMB
+	if !res.Valid {
+		f.Totals.Invalid++
+	}
+	return fi
+}
+
+// analyseInstance runs the -t analysis of an instance's tier under the rules
+// of its engine and edition. It leaves rules set to those rules.
+func analyseInstance(gi gcloudInstance) (*Result, error) {
+	var err error
+	if rules, err = instanceRules(gi); err != nil {
+		res := newResult("tier")
+		res.InputTier = gi.Settings.Tier
+		return res, err
+	}
+	return runTier(gi.Settings.Tier)
+}
+
+// instanceRules returns the rules of an instance's engine and edition.
+func instanceRules(gi gcloudInstance) (Constraints, error) {
+	engine, err := engineFor(gi.DatabaseVersion)
+	if err != nil {
+		return Constraints{}, err
+	}
+	return lookupRules(engine, editionFor(gi.Settings.Edition))
+}
+
+// listTier parses the tier of an instance of the list under its own rules,
+// without reading the selected ones. A tier that does not parse gets no
+// utilization; the analysis reports it.
+func listTier(gi gcloudInstance) (Tier, error) {
+	c, err := instanceRules(gi)
+	if err != nil {
+		return Tier{}, err
+	}
+	return c.parseTier(gi.Settings.Tier)
+}
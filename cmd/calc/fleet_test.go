@@ -0,0 +1,227 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// slowMetrics is a metricsSource that takes delay to answer, or with block
+// waits for its context to end. An instance named db-N reads N% CPU, and
+// instances in fail cannot be read. It is safe for concurrent lookups and
+// records how many were in flight at once.
+type slowMetrics struct {
+	delay time.Duration
+	block bool
+	fail  map[string]bool
+
+	started        chan struct{}
+	once           sync.Once
+	inFlight, peak atomic.Int32
+}
+
+func (f *slowMetrics) samples(ctx context.Context, project, instance, metric string, start, end time.Time) ([]float64, error) {
+	f.once.Do(func() { close(f.started) })
+	n := f.inFlight.Add(1)
+	defer f.inFlight.Add(-1)
+	for p := f.peak.Load(); n > p && !f.peak.CompareAndSwap(p, n); p = f.peak.Load() {
+	}
+	if _, ok := ctx.Deadline(); !ok {
+		return nil, errors.New("no deadline on the metrics context")
+	}
+	wait := time.After(f.delay)
+	if f.block {
+		wait = nil
+	}
+	select {
+	case <-wait:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	if f.fail[instance] {
+		return nil, fmt.Errorf("Cloud Monitoring API: permission denied on %s", instance)
+	}
+	if metric == memUsageMetric {
+		return []float64{6 << 30}, nil
+	}
+	pct, _ := strconv.Atoi(strings.TrimPrefix(instance, "db-"))
+	return []float64{float64(pct) / 100}, nil
+}
+
+// writeInstanceList writes a gcloud instance list of n db-custom-4-15360
+// instances named db-1 to db-n and returns its path.
+func writeInstanceList(t *testing.T, n int) string {
+	t.Helper()
+	var list []map[string]any
+	for i := 1; i <= n; i++ {
+		list = append(list, map[string]any{
+			"name": fmt.Sprintf("db-%d", i), "project": "prod", "databaseVersion": "MYSQL_8_0",
+			"settings": map[string]string{"tier": "db-custom-4-15360", "edition": "ENTERPRISE"},
+		})
+	}
+	data, err := json.Marshal(list)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "instances.json")
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestFleetMonitorConcurrency(t *testing.T) {
+	const n = 16
+	path := writeInstanceList(t, n)
+	elapsed := map[int]time.Duration{}
+	for _, concurrency := range []int{1, 8} {
+		f := &slowMetrics{delay: 20 * time.Millisecond, started: make(chan struct{})}
+		useMetrics(t, f)
+		opts.monitor, opts.window, opts.percentile, opts.concurrency = true, "14d", 95, concurrency
+		start := time.Now()
+		res, err := runFleet(path)
+		elapsed[concurrency] = time.Since(start)
+		if err != nil {
+			t.Fatalf("-concurrency %d: %v", concurrency, err)
+		}
+		if got := int(f.peak.Load()); got > concurrency || concurrency > 1 && got < 2 {
+			t.Errorf("-concurrency %d had %d lookups in flight at once", concurrency, got)
+		}
+		if len(res.Instances) != n || res.Totals.MonitoringErrors != 0 {
+			t.Fatalf("-concurrency %d = %d instances, %d monitoring errors, want %d and none", concurrency, len(res.Instances), res.Totals.MonitoringErrors, n)
+		}
+		for i, in := range res.Instances {
+			want := fmt.Sprintf("db-%d", i+1)
+			if in.Name != want || in.Monitoring == nil || in.Monitoring.CPUPct != float64(i+1) || in.Monitoring.MemPct != 40 {
+				t.Errorf("-concurrency %d instance %d = %s with %+v, want %s at %d%% CPU and 40%% memory", concurrency, i, in.Name, in.Monitoring, want, i+1)
+			}
+		}
+	}
+	if elapsed[8]*2 > elapsed[1] {
+		t.Errorf("-concurrency 8 took %s and -concurrency 1 took %s, want at least twice as fast", elapsed[8], elapsed[1])
+	}
+}
+
+func TestFleetMonitorErrors(t *testing.T) {
+	path := writeInstanceList(t, 5)
+	useMetrics(t, &slowMetrics{fail: map[string]bool{"db-2": true, "db-4": true}, started: make(chan struct{})})
+	opts.monitor, opts.window, opts.percentile, opts.concurrency = true, "14d", 95, 4
+	res, err := runFleet(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Totals.Instances != 5 || res.Totals.MonitoringErrors != 2 || res.exitCode() != exitOK {
+		t.Errorf("totals = %+v, exit %d, want 5 instances, 2 without utilization, exit %d", res.Totals, res.exitCode(), exitOK)
+	}
+	want := []string{"instance db-2: Cloud Monitoring API: permission denied on db-2", "instance db-4: Cloud Monitoring API: permission denied on db-4"}
+	if got := res.warnings(); strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("warnings = %q, want %q", got, want)
+	}
+	for _, in := range res.Instances {
+		if failed := in.Name == "db-2" || in.Name == "db-4"; failed != (in.Monitoring == nil) || !in.Valid {
+			t.Errorf("%s = %+v, error %q, want a valid tier with utilization unless its lookup failed", in.Name, in.Monitoring, in.MonitoringError)
+		}
+	}
+	if !strings.Contains(res.humanText(), "2 without utilization") {
+		t.Errorf("report = %q, want the instances without utilization counted", res.humanText())
+	}
+	opts.strict = true
+	if got := res.exitCode(); got != exitInvalid {
+		t.Errorf("-strict exit = %d, want %d", got, exitInvalid)
+	}
+}
+
+// Lookups parse each tier under its own instance's rules while the analysis
+// switches between them, so a tier only valid under Enterprise Plus still
+// gets its utilization read. Run with -race to catch lookups that read the
+// selected rules.
+func TestFleetMonitorMixedEngines(t *testing.T) {
+	list := `[
+		{"name": "db-10", "project": "prod", "databaseVersion": "MYSQL_8_0", "settings": {"tier": "db-custom-128-851968", "edition": "ENTERPRISE_PLUS"}},
+		{"name": "db-20", "project": "prod", "databaseVersion": "POSTGRES_16", "settings": {"tier": "db-custom-4-15360", "edition": "ENTERPRISE"}},
+		{"name": "db-30", "project": "prod", "databaseVersion": "SQLSERVER_2022_STANDARD", "settings": {"tier": "db-custom-8-30720"}},
+		{"name": "db-40", "project": "prod", "databaseVersion": "MYSQL_8_0", "settings": {"tier": "db-custom-96-638976", "edition": "ENTERPRISE_PLUS"}}
+	]`
+	path := filepath.Join(t.TempDir(), "instances.json")
+	if err := os.WriteFile(path, []byte(list), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	useRules(t, "mysql", "enterprise")
+	useMetrics(t, &slowMetrics{delay: time.Millisecond, started: make(chan struct{})})
+	opts.monitor, opts.window, opts.percentile, opts.concurrency = true, "14d", 95, 4
+	res, err := runFleet(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Totals.Instances != 4 || res.Totals.MonitoringErrors != 0 || res.Totals.Invalid != 0 {
+		t.Fatalf("totals = %+v, want 4 valid instances with utilization", res.Totals)
+	}
+	for _, in := range res.Instances {
+		if in.Monitoring == nil {
+			t.Errorf("%s (%s) has no utilization, error %q", in.Name, in.Tier, in.MonitoringError)
+		}
+	}
+	if got := res.Instances[0].vcpus(); got != 128 {
+		t.Errorf("%s vCPUs after the run = %g, want 128", res.Instances[0].Name, got)
+	}
+	if rules.Name != mustLookupRules("mysql", "enterprise").Name || rules.Edition != "enterprise" {
+		t.Errorf("rules after the run = %s %s, want the selected MySQL Enterprise", rules.Name, rules.Edition)
+	}
+}
+
+// A Ctrl-C while lookups are in flight cancels them and ends the run.
+func TestFleetMonitorInterrupt(t *testing.T) {
+	path := writeInstanceList(t, 8)
+	f := &slowMetrics{block: true, started: make(chan struct{})}
+	useMetrics(t, f)
+	opts.monitor, opts.window, opts.percentile, opts.concurrency = true, "14d", 95, 4
+	go func() {
+		<-f.started
+		p, err := os.FindProcess(os.Getpid())
+		if err == nil {
+			p.Signal(os.Interrupt)
+		}
+	}()
+	done := make(chan error, 1)
+	go func() {
+		_, err := runFleet(path)
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err == nil || !strings.Contains(err.Error(), "interrupted after 0 instances") {
+			t.Errorf("interrupted run error = %v, want it interrupted", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("run still going 5s after Ctrl-C")
+	}
+	for deadline := time.Now().Add(5 * time.Second); f.inFlight.Load() > 0; time.Sleep(time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatalf("%d lookups still in flight after Ctrl-C", f.inFlight.Load())
+		}
+	}
+}
+
+func TestFleetFlags(t *testing.T) {
+	path := writeInstanceList(t, 1)
+	for _, args := range [][]string{
+		{"instances", "-monitor", "-concurrency", "0", path},
+		{"instances", "-monitor", "-percentile", "0", path},
+		{"instances", "-monitor", "-window", "two weeks", path},
+		{"instances", "-concurrency", "8", "-percentile", "90", path},
+		{"rightsize", "-monitor", "-instance", "prod:orders", "-percentile", "101", "db-custom-8-30720"},
+	} {
+		if _, code := run(t, args...); code != exitUsage {
+			t.Errorf("go-calc %q exited %d, want %d", args, code, exitUsage)
+		}
+	}
+}
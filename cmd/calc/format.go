@@ -0,0 +1,70 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/template"
+)
+
+// formatPresets are the built-in -format templates, selected with @name.
+var formatPresets = map[string]string{
+	"tier-only": `{{.Tier}}`,
+	"oneline":   `{{.Tier}} → {{printf "%.1f" .RAMGB}} GB ({{.CPUs}} vCPUs, {{printf "%.2f" .Ratio}} GB/vCPU{{if not .Valid}}, invalid{{end}}){{with .Suggested}}, suggested {{.Tier}}{{end}}`,
+}
+
+// templateFuncs are the functions -format and payload templates may call:
+// json renders a value as JSON, for templates that build JSON payloads.
+var templateFuncs = template.FuncMap{
+	"json": func(v any) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+}
+
+// outputTemplate is the parsed -format template.
+var outputTemplate *template.Template
+
+// parseFormat parses a -format value: a Go text/template, or @name for a
+// preset. Parse errors include the line and column of the problem.
+func parseFormat(format string) (*template.Template, error) {
+	text := format
+	if name, ok := strings.CutPrefix(format, "@"); ok {
+		if text, ok = formatPresets[name]; !ok {
+			return nil, fmt.Errorf("unknown format preset %q: use one of @%s", format, strings.Join(sortedKeys(formatPresets), ", @"))
+		}
+	}
+	t, err := template.New("format").Funcs(templateFuncs).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid -format: %w", err)
+	}
+	return t, nil
+}
+
+// writeTemplate executes the -format template against the result, or
+// against each record of a batch, ending every execution with a newline.
+func writeTemplate(w io.Writer, r report) error {
+	var data []any
+	if b, ok := r.(*BatchResult); ok {
+		for _, rec := range b.Records {
+			data = append(data, rec)
+		}
+	} else {
+		data = append(data, r)
+	}
+	for _, d := range data {
+		var buf bytes.Buffer
+		if err := outputTemplate.Execute(&buf, d); err != nil {
+			return err
+		}
+		if !bytes.HasSuffix(buf.Bytes(), []byte("\n")) {
+			buf.WriteByte('\n')
+		}
+		if _, err := w.Write(buf.Bytes()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/csv"
+	"fmt"
+	"sort"
+	"strconv"
+)
+
+// machineTypeData is the embedded table of standard GCE machine types, as
+// name,cpus,ram_mb rows.
+//
+//go:embed machinetypes.csv
+var machineTypeData []byte
+
+// MachineType is a predefined GCE machine shape.
+type MachineType struct {
+	Name  string `json:"name"`
+	CPUs  int    `json:"cpus"`
+	RAMMB int    `json:"ram_mb"`
+}
+
+// tier returns the custom tier with the same vCPUs and memory.
+func (m MachineType) tier() Tier {
+	return Tier{CPUs: m.CPUs, RAMMB: m.RAMMB}
+}
+
+// machineTypes are the GCE machine types ParseTier accepts and -equivalents
+// matches against, in table order.
+var machineTypes = mustParseMachineTypes(machineTypeData, "machinetypes.csv")
+
+// mustParseMachineTypes reads an embedded name,cpus,ram_mb table with a
+// header row and # comments.
+func mustParseMachineTypes(data []byte, file string) []MachineType {
+	r := csv.NewReader(bytes.NewReader(data))
+	r.Comment = '#'
+	recs, err := r.ReadAll()
+	if err != nil {
+		panic(fmt.Sprintf("%s: %v", file, err))
+	}
+	var ms []MachineType
+	for _, rec := range recs[1:] {
+		cpu, cerr := strconv.Atoi(rec[1])
+		ram, rerr := strconv.Atoi(rec[2])
+		if cerr != nil || rerr != nil {
+			panic(fmt.Sprintf("%s: bad row %q", file, rec))
+		}
+		ms = append(ms, MachineType{Name: rec[0], CPUs: cpu, RAMMB: ram})
+	}
+	return ms
+}
+
+// lookupMachineType returns the GCE machine type named name, if there is one.
+func lookupMachineType(name string) (MachineType, bool) {
+	for _, m := range machineTypes {
+		if m.Name == name {
+			return m, true
+		}
+	}
+	return MachineType{}, false
+}
+
+// MachineMatch is a machine type ranked by its distance from a tier.
+type MachineMatch struct {
+	MachineType
+	Distance float64 `json:"distance"`
+	Exact    bool    `json:"exact"`
+}
+
+// closestMachines returns the n entries of table closest to t, nearest
+// first, weighing vCPUs and memory equally. Ties keep the table order.
+func closestMachines(table []MachineType, t Tier, n int) []*MachineMatch {
+	var ms []*MachineMatch
+	for _, m := range table {
+		d := compareTiers(t, m.tier()).distance(1, 1)
+		ms = append(ms, &MachineMatch{MachineType: m, Distance: d, Exact: m.tier() == t})
+	}
+	sort.SliceStable(ms, func(i, j int) bool { return ms[i].Distance < ms[j].Distance })
+	return ms[:min(n, len(ms))]
+}
+
+// matchTier returns the resolved tier of res for a machine type listing.
+func matchTier(res *Result, flag string) (Tier, error) {
+	t, err := ParseTier(res.resolvedTier())
+	if err != nil {
+		return Tier{}, fmt.Errorf("-%s needs a resolved tier", flag)
+	}
+	if t.Shared() {
+		return Tier{}, fmt.Errorf("-%s does not support shared-core tier %s", flag, t)
+	}
+	return t, nil
+}
+
+// printMatches appends a machine type listing under title.
+func (r *Result) printMatches(title string, ms []*MachineMatch) {
+	r.printf("%s:\n", title)
+	for _, m := range ms {
+		match := fmt.Sprintf("distance %.3f", m.Distance)
+		if m.Exact {
+			match = "exact match"
+		}
+		r.printf("  %s: %d vCPUs, %d MB (%.2f GB) [%s]\n", m.Name, m.CPUs, m.RAMMB, float64(m.RAMMB)/1024, match)
+	}
+}
+
+// addEquivalents lists the GCE machine types closest to the resolved tier.
+func addEquivalents(res *Result) error {
+	t, err := matchTier(res, "equivalents")
+	if err != nil {
+		return err
+	}
+	res.Equivalents = closestMachines(machineTypes, t, 3)
+	res.printMatches("GCE machine types closest to "+t.String(), res.Equivalents)
+	return nil
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseMachineType(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Tier
+		wantErr error
+	}{
+		{"n1-highmem-8", Tier{CPUs: 8, RAMMB: 53248}, nil},
+		{"n1-standard-1", Tier{CPUs: 1, RAMMB: 3840}, nil},
+		{"n2-standard-16", Tier{CPUs: 16, RAMMB: 65536}, nil},
+		{"N2-HIGHCPU-4", Tier{CPUs: 4, RAMMB: 4096}, nil},
+		{" e2-highmem-2 ", Tier{CPUs: 2, RAMMB: 16384}, nil},
+		{"n2-standard-3", Tier{}, ErrBadTierSyntax},
+		{"n2d-standard-16", Tier{}, ErrBadTierSyntax},
+		{"db-n2-standard-16", Tier{}, ErrBadTierSyntax},
+	}
+	for _, tt := range tests {
+		got, err := ParseTier(tt.in)
+		if !errors.Is(err, tt.wantErr) || tt.wantErr == nil && err != nil {
+			t.Errorf("ParseTier(%q) error = %v, want %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseTier(%q) = %+v, want %+v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestGCEEquivalents(t *testing.T) {
+	tests := []struct {
+		tier  Tier
+		want  []string
+		exact bool
+	}{
+		{Tier{CPUs: 8, RAMMB: 30720}, []string{"n1-standard-8", "n2-standard-8", "e2-standard-8"}, true},
+		{Tier{CPUs: 8, RAMMB: 53248}, []string{"n1-highmem-8", "n2-highmem-8", "e2-highmem-8"}, true},
+		{Tier{CPUs: 96, RAMMB: 638976}, []string{"n1-highmem-96"}, true},
+		{Tier{CPUs: 10, RAMMB: 38400}, []string{"n2-standard-8", "e2-standard-8", "n1-standard-8"}, false},
+		{Tier{CPUs: 6, RAMMB: 24576}, []string{"n1-highmem-4", "n1-standard-8", "n2-standard-4"}, false},
+		{Tier{CPUs: 2, RAMMB: 3840}, []string{"n2-highcpu-2", "n1-standard-1", "n1-standard-2"}, false},
+	}
+	for _, tt := range tests {
+		got := closestMachines(machineTypes, tt.tier, len(tt.want))
+		for i, m := range got {
+			if m.Name != tt.want[i] {
+				t.Errorf("closestMachines(%s)[%d] = %s, want %s", tt.tier, i, m.Name, tt.want[i])
+			}
+			if exact := tt.exact && i == 0; m.Exact != exact || exact != (m.Distance == 0) {
+				t.Errorf("closestMachines(%s)[%d] = %s, exact %t at distance %g, want exact %t", tt.tier, i, m.Name, m.Exact, m.Distance, exact)
+			}
+			if i > 0 && got[i-1].Distance > m.Distance {
+				t.Errorf("closestMachines(%s) is not nearest first: %s at %g before %s at %g", tt.tier, got[i-1].Name, got[i-1].Distance, m.Name, m.Distance)
+			}
+		}
+	}
+}
+
+func TestMachineTypesTable(t *testing.T) {
+	seen := map[string]bool{}
+	for _, m := range machineTypes {
+		if seen[m.Name] {
+			t.Errorf("machine type %s is listed twice", m.Name)
+		}
+		seen[m.Name] = true
+		if m.CPUs < 1 || m.RAMMB < 1 {
+			t.Errorf("machine type %s has %d vCPUs and %d MB", m.Name, m.CPUs, m.RAMMB)
+		}
+	}
+}
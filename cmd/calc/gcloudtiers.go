@@ -0,0 +1,209 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// gcloudTierEntry is one tier of `gcloud sql tiers list --format=json`. RAM
+// and DiskQuota are int64 values, which the API encodes as JSON strings.
+type gcloudTierEntry struct {
+	Tier      string    `json:"tier"`
+	RAM       jsonInt64 `json:"RAM"`
+	DiskQuota jsonInt64 `json:"DiskQuota"`
+	Region    []string  `json:"region"`
+}
+
+// jsonInt64 reads an int64 given as a JSON number or a string.
+type jsonInt64 int64
+
+func (n *jsonInt64) UnmarshalJSON(data []byte) error {
+	s := strings.Trim(string(data), `"`)
+	if s == "" || s == "null" {
+		*n = 0
+		return nil
+	}
+	v, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid integer %s", data)
+	}
+	*n = jsonInt64(v)
+	return nil
+}
+
+// CatalogEntry is a tier of a -tiers-from file and how it was categorized.
+// Only custom tiers that are valid under some engine and edition join the
+// known tier catalog; the rest are listed so nothing is dropped unnoticed.
+type CatalogEntry struct {
+	Tier     string   `json:"tier"`
+	Category string   `json:"category"` // custom, legacy, shared-core, invalid, or other
+	CPUs     int      `json:"cpus,omitempty"`
+	RAMMB    int      `json:"ram_mb,omitempty"`
+	Regions  []string `json:"regions,omitempty"`
+	Note     string   `json:"note,omitempty"`
+}
+
+// catalogEntries lists the tiers read by -tiers-from, for tiers export.
+var catalogEntries []*CatalogEntry
+
+// categorizeTier sorts a gcloud tier name into a catalog category.
+func categorizeTier(g gcloudTierEntry) *CatalogEntry {
+	name := strings.ToLower(strings.TrimSpace(g.Tier))
+	e := &CatalogEntry{Tier: g.Tier, Regions: g.Region}
+	if t, ok := legacyTiers[name]; ok {
+		e.Category = "legacy"
+		e.Note = "same shape as " + t.String()
+		return e
+	}
+	for _, sc := range sharedCoreTiers {
+		if name == sc.name {
+			e.Category = "shared-core"
+			return e
+		}
+	}
+	if !customTierPattern.MatchString(name) {
+		e.Category = "other"
+		e.Note = "not a db-custom tier"
+		return e
+	}
+	t, err := parseSomewhere(name)
+	if err != nil {
+		e.Category = "invalid"
+		e.Note = err.Error()
+		return e
+	}
+	e.CPUs, e.RAMMB = t.CPUs, t.RAMMB
+	if mb := int(int64(g.RAM) >> 20); g.RAM != 0 && mb != t.RAMMB {
+		e.Note = fmt.Sprintf("RAM %d bytes is %d MB, not the %d MB of the name", g.RAM, mb, t.RAMMB)
+	}
+	e.Category = "custom"
+	if !validSomewhere(t) {
+		e.Category = "invalid"
+		e.Note = fmt.Sprintf("%s is not valid under any engine and edition", t)
+	}
+	return e
+}
+
+// loadGcloudTiers reads a -tiers-from file, or stdin when path is "-", and
+// returns the catalog to use: its custom tiers alone, or merged into the
+// built-in ones. It records every entry in catalogEntries.
+func loadGcloudTiers(path string, merge bool) ([]Tier, error) {
+	var in io.Reader = os.Stdin
+	if path != "-" {
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+		in = file
+	}
+	var list []gcloudTierEntry
+	if err := json.NewDecoder(in).Decode(&list); err != nil {
+		return nil, fmt.Errorf("invalid tiers list %s: %w", path, err)
+	}
+	var ts []Tier
+	counts := map[string]int{}
+	for _, g := range list {
+		e := categorizeTier(g)
+		catalogEntries = append(catalogEntries, e)
+		counts[e.Category]++
+		if e.Category == "custom" {
+			ts = append(ts, Tier{CPUs: e.CPUs, RAMMB: e.RAMMB})
+		}
+	}
+	if len(ts) == 0 && !merge {
+		return nil, fmt.Errorf("invalid tiers list %s: no custom tiers", path)
+	}
+	if skipped := len(list) - counts["custom"]; skipped > 0 {
+		var parts []string
+		for _, c := range []string{"legacy", "shared-core", "invalid", "other"} {
+			if counts[c] > 0 {
+				parts = append(parts, fmt.Sprintf("%d %s", counts[c], c))
+			}
+		}
+		fmt.Fprintf(os.Stderr, "Note: %s: %d custom tiers used; skipped %s (see 'go-calc tiers export')\n", path, counts["custom"], strings.Join(parts, ", "))
+	}
+	if merge {
+		ts = append(ts, knownTiers...)
+	}
+	return sortTiers(ts), nil
+}
+
+// TierCatalog is the outcome of tiers export: the known tier catalog in the
+// -tiers-file format, and the -tiers-from entries left out of it.
+type TierCatalog struct {
+	Tiers   []Tier
+	Skipped []*CatalogEntry
+	Error   string
+}
+
+func (c *TierCatalog) setError(err error) {
+	c.Error = err.Error()
+}
+
+func (c *TierCatalog) exitCode() int {
+	return exitOK
+}
+
+// humanText is a -tiers-file CSV; skipped entries become # comments.
+func (c *TierCatalog) humanText() string {
+	var sb strings.Builder
+	for _, e := range c.Skipped {
+		fmt.Fprintf(&sb, "# skipped %s: %s", e.Category, e.Tier)
+		if e.Note != "" {
+			fmt.Fprintf(&sb, " (%s)", e.Note)
+		}
+		sb.WriteString("\n")
+	}
+	sb.WriteString("cpus,ram_mb\n")
+	for _, t := range c.Tiers {
+		fmt.Fprintf(&sb, "%d,%d\n", t.CPUs, t.RAMMB)
+	}
+	return sb.String()
+}
+
+func (c *TierCatalog) csvRecords() [][]string {
+	records := [][]string{{"cpus", "ram_mb"}}
+	for _, t := range c.Tiers {
+		records = append(records, []string{strconv.Itoa(t.CPUs), strconv.Itoa(t.RAMMB)})
+	}
+	return records
+}
+
+// MarshalJSON writes the JSON -tiers-file format.
+func (c *TierCatalog) MarshalJSON() ([]byte, error) {
+	type entry struct {
+		CPUs  int `json:"cpus"`
+		RAMMB int `json:"ram_mb"`
+	}
+	if c.Error != "" {
+		return json.Marshal(struct {
+			Error string `json:"error"`
+		}{c.Error})
+	}
+	entries := make([]entry, len(c.Tiers))
+	for i, t := range c.Tiers {
+		entries[i] = entry{t.CPUs, t.RAMMB}
+	}
+	return json.Marshal(entries)
+}
+
+// runTiers runs a tiers action. export writes the known tier catalog, as
+// loaded by -tiers-from or -tiers-file, in the -tiers-file format.
+func runTiers(action string) (*TierCatalog, error) {
+	c := &TierCatalog{}
+	if action != "export" {
+		return c, fmt.Errorf("unknown tiers action %q: use export", action)
+	}
+	c.Tiers = knownTiers
+	for _, e := range catalogEntries {
+		if e.Category != "custom" {
+			c.Skipped = append(c.Skipped, e)
+		}
+	}
+	return c, nil
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+)
+
+// annotation is one GitHub Actions workflow command, shown inline on the
+// pull request at file and line when they are set.
+type annotation struct {
+	level   string // error or warning
+	file    string
+	line    int
+	title   string
+	message string
+}
+
+// ghaEscaper escapes annotation messages; ghaPropertyEscaper also escapes
+// the separators of the file, line, and title properties.
+var (
+	ghaEscaper         = strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A")
+	ghaPropertyEscaper = strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C")
+)
+
+func (a annotation) String() string {
+	var props []string
+	if a.file != "" {
+		props = append(props, "file="+ghaPropertyEscaper.Replace(a.file))
+		if a.line > 0 {
+			props = append(props, fmt.Sprintf("line=%d", a.line))
+		}
+	}
+	if a.title != "" {
+		props = append(props, "title="+ghaPropertyEscaper.Replace(a.title))
+	}
+	cmd := "::" + a.level
+	if len(props) > 0 {
+		cmd += " " + strings.Join(props, ",")
+	}
+	return cmd + "::" + ghaEscaper.Replace(a.message)
+}
+
+// warningLevel is the annotation level of a warning: an error under -strict,
+// which fails the run on warnings.
+func warningLevel() string {
+	if opts.strict {
+		return "error"
+	}
+	return "warning"
+}
+
+// annotations returns the findings of a result at file and line: an error if
+// it failed, is invalid, or is not a valid change, and a warning for each of
+// its warnings. A valid result without warnings has none. Each message starts
+// with the codes of its finding in brackets, as in JSON output.
+func (r *Result) annotations(file string, line int, title string) []annotation {
+	at := func(level string, codes []string, format string, args ...any) annotation {
+		msg := fmt.Sprintf(format, args...)
+		if codes = slices.DeleteFunc(slices.Clone(codes), func(c string) bool { return c == "" }); len(codes) > 0 {
+			msg = "[" + strings.Join(codes, ",") + "] " + msg
+		}
+		return annotation{level: level, file: file, line: line, title: title, message: msg}
+	}
+	var as []annotation
+	var suggested string
+	if r.SuggestedTier != "" {
+		suggested = "; suggested " + r.SuggestedTier
+	}
+	switch {
+	case r.Error != "":
+		as = append(as, at("error", []string{r.ErrorCode}, "%s: %s", r.input(), r.Error))
+	case r.ValidDowngrade != nil && !*r.ValidDowngrade:
+		as = append(as, at("error", []string{r.Code}, "%s to %s is not a valid downgrade%s", r.Tier, r.Recommended.Tier, suggested))
+	case r.ValidUpgrade != nil && !*r.ValidUpgrade:
+		as = append(as, at("error", []string{r.Code}, "%s to %s is not a valid upgrade%s", r.Tier, r.Recommended.Tier, suggested))
+	case r.TierInfo != nil && !r.Valid:
+		as = append(as, at("error", r.Codes, "%s is not a valid %s tier: %s%s", r.input(), rules.Name, strings.Join(r.Reasons, "; "), suggested))
+	}
+	for i, v := range r.PolicyViolations {
+		as = append(as, at("error", r.PolicyCodes[i:i+1], "Policy violation: %s", v))
+	}
+	for i, w := range r.Warnings {
+		as = append(as, at(warningLevel(), r.WarningCodes[i:i+1], "%s", w))
+	}
+	return as
+}
+
+// annotationFile is the file annotations of source point at: none for stdin
+// or arguments.
+func annotationFile(source string) string {
+	if source == "-" || source == argsSource {
+		return ""
+	}
+	return source
+}
+
+// writeGHA prints the findings of r as GitHub Actions annotations. Batch
+// records point at their line of the input file, and fleet instances are
+// titled with their name.
+func writeGHA(w io.Writer, r report) error {
+	var as []annotation
+	switch r := r.(type) {
+	case *Result:
+		as = r.annotations("", 0, "")
+	case *BatchResult:
+		// Without a file, the line or argument number goes in the title
+		file, label := annotationFile(r.Source), "line"
+		if r.Source == argsSource {
+			label = "arg"
+		}
+		for _, rec := range r.Records {
+			if file == "" {
+				as = append(as, rec.annotations("", 0, fmt.Sprintf("%s %d", label, rec.Line))...)
+				continue
+			}
+			as = append(as, rec.annotations(file, rec.Line, "")...)
+		}
+	case *FleetResult:
+		for _, in := range r.Instances {
+			as = append(as, in.Result.annotations(annotationFile(r.Source), 0, in.Name)...)
+		}
+	default:
+		return fmt.Errorf("gha output is not supported for this mode")
+	}
+	for _, a := range as {
+		if _, err := fmt.Fprintln(w, a); err != nil {
+			return err
+		}
+	}
+	return nil
+}
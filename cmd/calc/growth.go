@@ -0,0 +1,70 @@
+package main
+
+import (
+	"fmt"
+	"math"
+)
+
+// Growth is a compound monthly growth projection.
+type Growth struct {
+	MemPct float64 `json:"mem_growth_pct"`
+	CPUPct float64 `json:"cpu_growth_pct"`
+	Months int     `json:"months"`
+	Every  int     `json:"every"`
+}
+
+func (g Growth) validate() error {
+	if g.MemPct <= -100 || g.CPUPct <= -100 {
+		return fmt.Errorf("-mem-growth and -cpu-growth must be above -100%%")
+	}
+	if g.Months < 1 || g.Every < 1 {
+		return fmt.Errorf("-months and -every must be at least 1")
+	}
+	return nil
+}
+
+// Milestone is the projected need at one point of a growth timeline and the
+// tier that covers it.
+type Milestone struct {
+	Month         int     `json:"month"`
+	RequiredCPUs  float64 `json:"required_cpus"`
+	RequiredMemMB float64 `json:"required_mem_mb"`
+	*TierInfo
+}
+
+// runGrowth projects the load of input forward by compound monthly growth
+// and lists the smallest valid tier needed at every g.Every months.
+func runGrowth(input string, g Growth) (*Result, error) {
+	res := newResult("growth")
+	res.InputTier = input
+	res.Growth = &g
+	curr, err := ParseTier(input)
+	if err != nil {
+		return res, fmt.Errorf("invalid tier: %w", err)
+	}
+	if err := g.validate(); err != nil {
+		return res, err
+	}
+	res.TierInfo = describe(curr)
+	res.noteEquivalent(input, curr)
+	res.Timeline = []*Milestone{}
+	res.printf("Growth projection for %s: memory %+g%%/month, vCPUs %+g%%/month, %d months\n", input, g.MemPct, g.CPUPct, g.Months)
+	for m := g.Every; m <= g.Months; m += g.Every {
+		cpu := curr.VCPUs() * math.Pow(1+g.CPUPct/100, float64(m))
+		memMB := float64(curr.RAMMB) * math.Pow(1+g.MemPct/100, float64(m))
+		t, _, err := smallestTierFor(cpu, memMB)
+		if err != nil {
+			res.Message = fmt.Sprintf("projection exceeds the tier limits at month %d", m)
+			res.warnf(CodeGrowthBeyondLimits, "month %d needs %.2f vCPUs and %.0f MB, beyond the limits: %v", m, cpu, memMB, err)
+			break
+		}
+		ms := &Milestone{Month: m, RequiredCPUs: cpu, RequiredMemMB: memMB, TierInfo: describe(t)}
+		res.Timeline = append(res.Timeline, ms)
+		res.printf("  Month %d: needs %.2f vCPUs, %.0f MB (%.2f GB) -> %s\n", m, cpu, memMB, memMB/1024, ms.summary())
+	}
+	if n := len(res.Timeline); n > 0 {
+		last := res.Timeline[n-1]
+		res.suggest(Tier{CPUs: last.CPUs, RAMMB: last.RAMMB})
+	}
+	return res, nil
+}
@@ -0,0 +1,57 @@
+package main
+
+// Padding is the -headroom and -cpu-headroom slack added to a sizing
+// request: the requirement as computed and as padded.
+type Padding struct {
+	MemPct   float64 `json:"mem_pct,omitempty"`
+	RawMemMB float64 `json:"raw_mem_mb,omitempty"`
+	MemMB    float64 `json:"mem_mb,omitempty"`
+	CPUPct   float64 `json:"cpu_pct,omitempty"`
+	RawCPUs  float64 `json:"raw_cpus,omitempty"`
+	CPUs     float64 `json:"cpus,omitempty"`
+}
+
+func (r *Result) padding() *Padding {
+	if r.Padding == nil {
+		r.Padding = &Padding{}
+	}
+	return r.Padding
+}
+
+// padMem returns memMB raised by -headroom percent. It is memMB itself when
+// there is no headroom.
+func (r *Result) padMem(memMB float64) float64 {
+	if opts.headroom == 0 || memMB <= 0 {
+		return memMB
+	}
+	p := r.padding()
+	p.MemPct, p.RawMemMB = opts.headroom, memMB
+	p.MemMB = memMB * (1 + opts.headroom/100)
+	return p.MemMB
+}
+
+// padCPU returns cpu raised by -cpu-headroom percent. It is cpu itself when
+// there is no CPU headroom.
+func (r *Result) padCPU(cpu float64) float64 {
+	if opts.cpuHeadroom == 0 || cpu <= 0 {
+		return cpu
+	}
+	p := r.padding()
+	p.CPUPct, p.RawCPUs = opts.cpuHeadroom, cpu
+	p.CPUs = cpu * (1 + opts.cpuHeadroom/100)
+	return p.CPUs
+}
+
+// printPadding shows the requirement before and after headroom.
+func (r *Result) printPadding() {
+	p := r.Padding
+	if p == nil {
+		return
+	}
+	if p.MemPct != 0 {
+		r.printf("  - Headroom: %.0f MB (%.2f GB) required + %g%% = %.0f MB (%.2f GB)\n", p.RawMemMB, p.RawMemMB/1024, p.MemPct, p.MemMB, p.MemMB/1024)
+	}
+	if p.CPUPct != 0 {
+		r.printf("  - CPU headroom: %.2f vCPUs required + %g%% = %.2f vCPUs\n", p.RawCPUs, p.CPUPct, p.CPUs)
+	}
+}
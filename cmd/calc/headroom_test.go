@@ -0,0 +1,50 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// -headroom and -cpu-headroom pad the request before sizing; how far the
+// tier then exceeds the padded request is its spare, a different thing.
+func TestHeadroomPadding(t *testing.T) {
+	tests := []struct {
+		args    []string
+		tier    string
+		padding Padding
+		spare   *Spare
+	}{
+		{[]string{"-mem", "16G", "-headroom", "25"}, "db-custom-14-20480", Padding{MemPct: 25, RawMemMB: 16384, MemMB: 20480}, nil},
+		{[]string{"-cpu", "4", "-mem", "16G", "-headroom", "25"}, "db-custom-4-20480", Padding{MemPct: 25, RawMemMB: 16384, MemMB: 20480}, &Spare{}},
+		{[]string{"-cpu", "6", "-mem", "40G", "-headroom", "20", "-cpu-headroom", "50"}, "db-custom-10-49152", Padding{MemPct: 20, RawMemMB: 40960, MemMB: 49152, CPUPct: 50, RawCPUs: 6, CPUs: 9}, &Spare{CPUs: 1}},
+	}
+	for _, tt := range tests {
+		args := append([]string{"suggest", "-o", "json"}, tt.args...)
+		out, code := run(t, args...)
+		var res struct {
+			Tier     string          `json:"tier"`
+			Padding  *Padding        `json:"padding"`
+			Spare    *Spare          `json:"spare"`
+			Headroom json.RawMessage `json:"headroom"`
+		}
+		if err := json.Unmarshal([]byte(out), &res); err != nil || code != exitOK {
+			t.Fatalf("go-calc %q: exit %d, %v\n%s", args, code, err, out)
+		}
+		if res.Tier != tt.tier || res.Padding == nil || *res.Padding != tt.padding {
+			t.Errorf("go-calc %q = %s padded %+v, want %s padded %+v", args, res.Tier, res.Padding, tt.tier, tt.padding)
+		}
+		if (res.Spare == nil) != (tt.spare == nil) || tt.spare != nil && *res.Spare != *tt.spare || res.Headroom != nil {
+			t.Errorf("go-calc %q spare = %+v, headroom %s, want spare %+v and no headroom key", args, res.Spare, res.Headroom, tt.spare)
+		}
+	}
+	out, _ := run(t, "suggest", "-cpu", "4", "-mem", "16G", "-headroom", "25")
+	for _, want := range []string{"Headroom: 16384 MB (16.00 GB) required + 25% = 20480 MB", "vCPUs: 4 (spare +0)", "spare +0 MB"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("suggest -cpu 4 -mem 16G -headroom 25 = %q, want %q", out, want)
+		}
+	}
+	if strings.Contains(out, "headroom +") {
+		t.Errorf("suggest -cpu 4 -mem 16G -headroom 25 = %q, want the spare not called headroom", out)
+	}
+}
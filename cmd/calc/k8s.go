@@ -0,0 +1,64 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// writeK8s renders each resolved tier as a Kubernetes resources block, in
+// the units pods use: whole vCPUs or millicores, and memory in Mi less the
+// -k8s-overhead reservation. Several tiers are separate YAML documents.
+func writeK8s(w io.Writer, r report) error {
+	var tiers []string
+	switch r := r.(type) {
+	case *Result:
+		if t := r.quietTier(); t != "" {
+			tiers = append(tiers, t)
+		}
+	case *BatchResult:
+		for _, rec := range r.Records {
+			if t := rec.quietTier(); rec.Error == "" && t != "" {
+				tiers = append(tiers, t)
+			}
+		}
+	case *NormalizeResult:
+		for _, rec := range r.Records {
+			if rec.Error == "" {
+				tiers = append(tiers, rec.Tier)
+			}
+		}
+	default:
+		return fmt.Errorf("k8s output is not supported for this mode")
+	}
+	if len(tiers) == 0 {
+		return fmt.Errorf("no resolved tier to render as Kubernetes resources")
+	}
+	for i, name := range tiers {
+		t, err := ParseTier(name)
+		if err != nil {
+			return err
+		}
+		memMB := t.RAMMB - opts.k8sOverheadMB
+		if memMB <= 0 {
+			return fmt.Errorf("-k8s-overhead of %d MB leaves no memory on %s", opts.k8sOverheadMB, t)
+		}
+		if i > 0 {
+			fmt.Fprintln(w, "---")
+		}
+		_, err = fmt.Fprintf(w, "# %s\nresources:\n  requests:\n    cpu: %q\n    memory: %q\n", t, k8sCPU(t.VCPUs()), strconv.Itoa(memMB)+"Mi")
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// k8sCPU formats vCPUs as a Kubernetes CPU quantity: "8", or millicores
+// such as "500m" for shared-core shares.
+func k8sCPU(vcpus float64) string {
+	if vcpus == float64(int(vcpus)) {
+		return strconv.Itoa(int(vcpus))
+	}
+	return strconv.Itoa(int(vcpus*1000)) + "m"
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// parseK8s reads back the tiers writeK8s rendered, one per YAML document.
+func parseK8s(t *testing.T, out string) []Tier {
+	t.Helper()
+	var tiers []Tier
+	for _, doc := range strings.Split(out, "---\n") {
+		var cpu, mem float64
+		for _, line := range strings.Split(doc, "\n") {
+			key, val, ok := strings.Cut(strings.TrimSpace(line), ": ")
+			if !ok {
+				continue
+			}
+			var err error
+			switch val = strings.Trim(val, `"`); key {
+			case "cpu":
+				cpu, err = parseCPU(val)
+			case "memory":
+				mem, err = parseMem(val)
+			}
+			if err != nil {
+				t.Fatalf("%s in %q: %v", line, doc, err)
+			}
+		}
+		tier := Tier{CPUs: int(cpu), RAMMB: int(mem)}
+		for _, sc := range sharedCoreTiers {
+			if sc.vCPU == cpu && sc.tier.RAMMB == int(mem) {
+				tier = sc.tier
+			}
+		}
+		tiers = append(tiers, tier)
+	}
+	return tiers
+}
+
+func TestK8sRoundTrip(t *testing.T) {
+	defer func(mb int) { opts.k8sOverheadMB = mb }(opts.k8sOverheadMB)
+	opts.k8sOverheadMB = 0
+
+	n := &NormalizeResult{}
+	var want []Tier
+	for _, sc := range sharedCoreTiers {
+		n.Records = append(n.Records, &NormalizedTier{Tier: sc.name})
+		want = append(want, sc.tier)
+	}
+	for _, tier := range knownTiers {
+		if !tier.Valid() {
+			continue
+		}
+		n.Records = append(n.Records, &NormalizedTier{Tier: tier.String()})
+		want = append(want, tier)
+	}
+	var out strings.Builder
+	if err := writeK8s(&out, n); err != nil {
+		t.Fatal(err)
+	}
+	got := parseK8s(t, out.String())
+	if len(got) != len(want) {
+		t.Fatalf("writeK8s rendered %d documents, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("%s round-tripped through k8s as %+v", n.Records[i].Tier, got[i])
+		}
+	}
+}
+
+func TestK8sOverhead(t *testing.T) {
+	defer func(mb int) { opts.k8sOverheadMB = mb }(opts.k8sOverheadMB)
+	tests := []struct {
+		tier     string
+		overhead int
+		want     string
+		wantErr  bool
+	}{
+		{"db-custom-8-53248", 0, `cpu: "8"` + "\n" + `    memory: "53248Mi"`, false},
+		{"db-custom-8-53248", 1024, `cpu: "8"` + "\n" + `    memory: "52224Mi"`, false},
+		{"db-g1-small", 512, `cpu: "500m"` + "\n" + `    memory: "1229Mi"`, false},
+		{"db-f1-micro", 614, "", true},
+		{"db-custom-1-3840", 4096, "", true},
+	}
+	for _, tt := range tests {
+		opts.k8sOverheadMB = tt.overhead
+		var out strings.Builder
+		err := writeK8s(&out, &NormalizeResult{Records: []*NormalizedTier{{Tier: tt.tier}}})
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s with %d MB overhead: error = %v, want error %t", tt.tier, tt.overhead, err, tt.wantErr)
+			continue
+		}
+		if !strings.Contains(out.String(), tt.want) {
+			t.Errorf("%s with %d MB overhead = %q, want %q", tt.tier, tt.overhead, out.String(), tt.want)
+		}
+	}
+}
+
+func TestK8sDocuments(t *testing.T) {
+	out, code := run(t, "-k8s", "normalize", "db-custom-4-16384", "nonsense", "db-n1-standard-2")
+	if got := parseK8s(t, out); len(got) != 2 || got[0] != (Tier{4, 16384}) || got[1] != (Tier{2, 7680}) {
+		t.Errorf("-k8s normalize (exit %d) = %q, want two documents for the resolved tiers", code, out)
+	}
+	if _, code := run(t, "-k8s", "-k8s-overhead", "lots", "next", "db-custom-4-16384"); code != exitUsage {
+		t.Errorf("-k8s-overhead lots exited %d, want %d", code, exitUsage)
+	}
+}
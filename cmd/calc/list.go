@@ -0,0 +1,109 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+// ratioClasses maps the -ratio-class values to their memory per vCPU in GB.
+var ratioClasses = map[string]float64{
+	"standard": 3.75,
+	"highmem":  6.5,
+}
+
+// ratioClass returns the name of the ratio class t belongs to, or "".
+func ratioClass(t Tier) string {
+	for name, r := range ratioClasses {
+		if t.RAMMB == ratioMBFloor(r, t.CPUs) {
+			return name
+		}
+	}
+	return ""
+}
+
+// TierFilter selects known tiers for -list-tiers. Zero fields do not filter.
+type TierFilter struct {
+	MinCPUs    int
+	MaxCPUs    int
+	MinRAMMB   float64
+	MaxRAMMB   float64
+	RatioClass string
+}
+
+func (f TierFilter) match(t Tier) bool {
+	switch {
+	case f.MinCPUs > 0 && t.CPUs < f.MinCPUs,
+		f.MaxCPUs > 0 && t.CPUs > f.MaxCPUs,
+		f.MinRAMMB > 0 && float64(t.RAMMB) < f.MinRAMMB,
+		f.MaxRAMMB > 0 && float64(t.RAMMB) > f.MaxRAMMB,
+		f.RatioClass != "" && ratioClass(t) != f.RatioClass:
+		return false
+	}
+	return true
+}
+
+// ListResult is the outcome of -list-tiers.
+type ListResult struct {
+	Mode    string      `json:"mode"`
+	Engine  string      `json:"engine"`
+	Edition string      `json:"edition"`
+	Tiers   []*TierInfo `json:"tiers"`
+	Error   string      `json:"error,omitempty"`
+	Version *BuildInfo  `json:"version,omitempty"`
+}
+
+func (l *ListResult) setError(err error) {
+	l.Error = err.Error()
+}
+
+func (l *ListResult) setVersion(bi *BuildInfo) {
+	l.Version = bi
+}
+
+func (l *ListResult) exitCode() int {
+	return exitOK
+}
+
+func (l *ListResult) humanText() string {
+	var sb strings.Builder
+	tw := tabwriter.NewWriter(&sb, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "Tier\tvCPUs\tRAM MB\tRAM GB\tGB/vCPU\tClass")
+	for _, info := range l.Tiers {
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%.2f\t%.2f\t%s\n", info.Tier, info.CPUs, info.RAMMB, info.RAMGB, info.Ratio, info.Class)
+	}
+	tw.Flush()
+	fmt.Fprintf(&sb, "%d known tiers for %s %s\n", len(l.Tiers), rules.Name, rules.editionName())
+	return sb.String()
+}
+
+func (l *ListResult) csvRecords() [][]string {
+	records := [][]string{{"tier", "cpus", "ram_mb", "ram_gb", "ratio_gb_per_cpu", "class"}}
+	for _, info := range l.Tiers {
+		records = append(records, []string{
+			info.Tier,
+			strconv.Itoa(info.CPUs),
+			strconv.Itoa(info.RAMMB),
+			strconv.FormatFloat(info.RAMGB, 'f', 2, 64),
+			strconv.FormatFloat(info.Ratio, 'f', 2, 64),
+			info.Class,
+		})
+	}
+	return records
+}
+
+// runListTiers lists the known tiers that are valid under the selected rules
+// and pass the filter.
+func runListTiers(f TierFilter) (*ListResult, error) {
+	res := &ListResult{Mode: "list-tiers", Engine: rules.Engine, Edition: rules.Edition, Tiers: []*TierInfo{}}
+	if _, ok := ratioClasses[f.RatioClass]; f.RatioClass != "" && !ok {
+		return res, fmt.Errorf("unknown ratio class %q: use one of %s", f.RatioClass, strings.Join(sortedKeys(ratioClasses), ", "))
+	}
+	for _, t := range knownTiers {
+		if t.Valid() && f.match(t) {
+			res.Tiers = append(res.Tiers, describe(t))
+		}
+	}
+	return res, nil
+}
@@ -1,326 +1,1121 @@
 package main
 
 import (
+	"errors"
 	"flag"
 	"fmt"
 	"math"
 	"os"
-	"regexp"
-	"strconv"
 	"strings"
+	"time"
+	"unicode"
 )
 
-func parseTier(tier string) (int, int, error) {
-	re := regexp.MustCompile(`db-custom-(\d+)-(\d+)`)
-	matches := re.FindStringSubmatch(tier)
-	if len(matches) != 3 {
-		return 0, 0, fmt.Errorf("invalid tier format")
+// runBumpMem raises the memory of a tier to -to-ratio GB/vCPU, keeping its
+// vCPUs. The default target is the engine's maximum ratio.
+func runBumpMem(input string) (*Result, error) {
+	res := newResult("bump-mem")
+	res.InputTier = input
+	t, err := ParseTier(input)
+	if err != nil {
+		return res, fmt.Errorf("invalid tier: %w", err)
 	}
-	cpu, err1 := strconv.Atoi(matches[1])
-	ram, err2 := strconv.Atoi(matches[2])
-	if err1 != nil || err2 != nil {
-		return 0, 0, fmt.Errorf("invalid tier numbers")
+	res.TierInfo = describe(t)
+	res.noteEquivalent(input, t)
+	if t.Shared() {
+		return res, fmt.Errorf("cannot bump memory for shared-core tier %s: move to a custom tier such as %s", t, knownTiers[0])
 	}
-	return cpu, ram, nil
-}
-
-func parseMem(memStr string) (float64, error) {
-	if memStr == "" {
-		return 0, fmt.Errorf("empty memory string")
-	}
-	var value float64
-	var unit string
-	n, err := fmt.Sscanf(memStr, "%f%s", &value, &unit)
-	if err != nil || n < 1 {
-		return 0, fmt.Errorf("invalid memory format")
-	}
-	switch unit {
-	case "G", "g":
-		return value * 1024, nil
-	case "M", "m", "":
-		return value, nil
-	default:
-		return 0, fmt.Errorf("invalid unit: %s", unit)
+	c, r := t.CPUs, t.RAMMB
+	target := opts.toRatio
+	res.SizingRatio = target
+	// Keep CPUs, size RAM at the target ratio in 256 MB steps without
+	// passing the engine's GB/vCPU ceiling or the shape's own limit
+	mode := rounding(roundDown)
+	sizeRAM := func(c int) int {
+		if mode == roundUp {
+			return roundUp256(ratioMBCeil(target, c))
+		}
+		return roundMem(float64(c)*target*1024, mode)
+	}
+	ram := sizeRAM(c)
+	if s, ok := rules.shapeLimit(c); ok && ram > s.MaxRAMMB && c < rules.MaxCPUs {
+		// The shape caps memory below the target: move to the next vCPU count
+		c = rules.legalCPUAtLeast(c + 1)
+		ram = sizeRAM(c)
+		res.warnf(CodeCPUMoved, "%d vCPU tiers allow at most %d MB; moved to %d vCPUs to reach %g GB/vCPU", s.CPUs, s.MaxRAMMB, c, target)
+	}
+	if ceiling := roundDown256(rules.maxRAMFor(c)); ceiling < rules.MinRAMMB && c < rules.MaxCPUs {
+		// No tier of this many vCPUs holds the engine's minimum memory
+		next := rules.legalCPUAtLeast(c + 1)
+		res.warnf(CodeCPUMoved, "%d vCPU tiers allow at most %d MB, below the %d MB minimum; moved to %d vCPUs", c, ceiling, rules.MinRAMMB, next)
+		c = next
+		ram = sizeRAM(c)
+	}
+	ex := res.explainer()
+	ex.add("round-ram", float64(ram) == float64(c)*target*1024, "%d vCPUs × %g GB/vCPU → %d MB (multiple of 256, %s)", c, target, ram, roundedBy(mode))
+	ram = min(ram, roundDown256(rules.maxRAMFor(c)))
+	ram = max(ram, rules.MinRAMMB)
+	if minRAM := roundUp256(rules.minRAMFor(c)); ram < minRAM {
+		// Rounding down a target at the GB/vCPU floor can land below it
+		res.warnf(CodeRAMRoundedUp, "memory %s to %d MB makes %s invalid; rounded up to %d MB instead", roundedBy(mode), ram, Tier{CPUs: c, RAMMB: ram}, minRAM)
+		ex.add("round-ram-up", false, "%d MB → %d MB (%s leaves the valid range)", ram, minRAM, roundedBy(mode))
+		ram = minRAM
+	}
+	newTier := Tier{CPUs: c, RAMMB: ram}
+	if ram <= r {
+		res.Message = "already at or above the target ratio"
+		res.printf("Tier %s already meets the target of %g GB/vCPU.\n", input, target)
+		res.printf("  Current: %d vCPUs, %d MB (%.2f GB) [%.2f GB/vCPU]\n", t.CPUs, r, t.RAMGB(), t.Ratio())
+		if r > rules.maxRAMFor(c) {
+			res.printf("  It exceeds the maximum of %g GB/vCPU (%d MB for %d vCPUs).\n", rules.MaxGBPerCPU, rules.maxRAMFor(c), c)
+		}
+		return res, nil
+	}
+	if err := newTier.Validate(); err != nil {
+		return res, fmt.Errorf("cannot bump memory for tier %s: the result %s is not valid (%v); the nearest valid tier is %s", input, newTier, err, nearestValidTier(newTier))
 	}
+	res.suggest(newTier)
+	res.printf("Bumping memory for tier %s to %g GB/vCPU:\n", input, target)
+	res.printf("  Current: %d vCPUs, %d MB (%.2f GB) [%.2f GB/vCPU]\n", t.CPUs, r, t.RAMGB(), t.Ratio())
+	res.printf("  New: %d vCPUs, %d MB (%.2f GB) [%.2f GB/vCPU]\n", c, ram, newTier.RAMGB(), newTier.Ratio())
+	res.printf("  New Tier: %s\n", newTier)
+	return res, nil
 }
 
-func validateTier(cpu, ram int) bool {
-	// vCPUs must be 1 or an even number between 2 and 96
-	if cpu < 1 || cpu > 96 {
-		return false
+// runBumpCPU raises a tier to the next legal vCPU count, keeping its memory
+// unless the memory-per-vCPU floor forces it up as well.
+func runBumpCPU(input string) (*Result, error) {
+	res := newResult("bump-cpu")
+	res.InputTier = input
+	t, err := ParseTier(input)
+	if err != nil {
+		return res, fmt.Errorf("invalid tier: %w", err)
 	}
-	if cpu != 1 && cpu%2 != 0 {
-		return false
+	res.TierInfo = describe(t)
+	res.noteEquivalent(input, t)
+	c, r := t.CPUs, t.RAMMB
+	if c >= rules.MaxCPUs {
+		res.Message = "already at maximum vCPUs"
+		res.printf("Tier %s is already at the maximum of %d vCPUs for %s %s.\n", input, rules.MaxCPUs, rules.Name, rules.editionName())
+		return res, nil
 	}
-	// Memory must be a multiple of 256 MB and at least 3840 MB
-	if ram%256 != 0 || ram < 3840 {
-		return false
+	newC := rules.legalCPUAtLeast(c + 1)
+	// Keep RAM unless it falls below the floor for the new vCPU count
+	ram := max(r, rules.MinRAMMB, roundUp256(rules.minRAMFor(newC)))
+	newTier := Tier{CPUs: newC, RAMMB: ram}
+	res.suggest(newTier)
+	res.printf("Bumping vCPUs for tier %s:\n", input)
+	res.printf("  Current: %g vCPUs, %d MB (%.2f GB) [%.2f GB/vCPU]\n", t.VCPUs(), r, t.RAMGB(), t.Ratio())
+	res.printf("  New: %d vCPUs, %d MB (%.2f GB) [%.2f GB/vCPU]\n", newC, ram, newTier.RAMGB(), newTier.Ratio())
+	switch {
+	case ram == r:
+	case r < rules.MinRAMMB:
+		res.Message = "memory raised to the minimum"
+		res.printf("  Memory raised by %d MB to the minimum of %d MB for %s.\n", ram-r, rules.MinRAMMB, rules.Name)
+	default:
+		res.Message = "memory raised to stay within the memory-per-vCPU range"
+		res.printf("  Memory raised by %d MB to meet the minimum of %g GB/vCPU for %d vCPUs.\n", ram-r, rules.MinGBPerCPU, newC)
 	}
-	// Memory must be 0.9 to 6.5 GB per vCPU
-	minRam := int(0.9 * float64(cpu) * 1024)
-	maxRam := int(6.5 * float64(cpu) * 1024)
-	return ram >= minRam && ram <= maxRam
+	res.printf("  New Tier: %s\n", newTier)
+	return res, nil
 }
 
-func suggestNextTier(_ int, ram int) (int, int) {
-	cpusNeeded := float64(ram) / 1.5 / 1024
-	cpusNext := int(math.Ceil(cpusNeeded))
-	ramNext := int(float64(cpusNext) * 1.5 * 1024)
-	// Ensure multiple of 256
-	ramNext = ((ramNext + 255) / 256) * 256
-	if ramNext < 3840 {
-		ramNext = 3840
-	}
-	return cpusNext, ramNext
+func runCheckDowngrade(current, recommended string) (*Result, error) {
+	return runCheckChange(current, recommended, false)
 }
 
-var knownTiers = []struct {
-	cpu int
-	ram int
-}{
-	{1, 3840},
-	{2, 7680},
-	{2, 13312},
-	{4, 15360},
-	{4, 26624},
-	{6, 23040},
-	{6, 39936},
-	{8, 30720},
-	{8, 53248},
-	{10, 38400},
-	{10, 66560},
-	{12, 46080},
-	{12, 79872},
-	{16, 61440},
-	{16, 106496},
-	{24, 92160},
-	{24, 159744},
-	{32, 122880},
-	{32, 212992},
-	{48, 184320},
-	{48, 319488},
-	{64, 245760},
-	{64, 425984},
-	{80, 307200},
-	{80, 532480},
-	{96, 368640},
-	{96, 638976},
+func runCheckUpgrade(current, recommended string) (*Result, error) {
+	return runCheckChange(current, recommended, true)
 }
 
-func findNextKnownTier(cpu int, ram int) (int, int, bool) {
-	for _, t := range knownTiers {
-		if t.cpu > cpu || (t.cpu == cpu && t.ram > ram) {
-			return t.cpu, t.ram, true
+// runCheckPair runs a downgrade (or, when upgrade is set, an upgrade) check on
+// a "current recommended" pair. The tiers may be separated by any run of
+// spaces, tabs, and commas.
+func runCheckPair(input string, upgrade bool) (*Result, error) {
+	parts := strings.FieldsFunc(input, func(r rune) bool { return unicode.IsSpace(r) || r == ',' })
+	if len(parts) != 2 {
+		mode, flagName := "check-downgrade", "-check-downgrade"
+		if upgrade {
+			mode, flagName = "check-upgrade", "-check-upgrade"
 		}
+		return newResult(mode), fmt.Errorf("usage: %s '<current-tier> <recommended-tier>'", flagName)
 	}
-	return 0, 0, false
+	return runCheckChange(parts[0], parts[1], upgrade)
 }
 
-func findPreviousKnownTier(cpu int, ram int) (int, int, bool) {
-	for i := len(knownTiers) - 1; i >= 0; i-- {
-		t := knownTiers[i]
-		if t.cpu < cpu || (t.cpu == cpu && t.ram < ram) {
-			return t.cpu, t.ram, true
+// runCheckChange checks whether the recommended tier is a valid downgrade
+// (or, when upgrade is set, a valid upgrade) from the current tier.
+func runCheckChange(current, recommended string, upgrade bool) (*Result, error) {
+	direction, comparative, code := "downgrade", "lower", CodeNotADowngrade
+	if upgrade {
+		direction, comparative, code = "upgrade", "higher", CodeNotAnUpgrade
+	}
+	res := newResult("check-" + direction)
+	res.InputTier = current
+	curr, err := ParseTier(current)
+	if err != nil {
+		return res, fmt.Errorf("invalid current tier: %w", err)
+	}
+	rec, err := ParseTier(recommended)
+	if err != nil {
+		return res, fmt.Errorf("invalid recommended tier: %w", err)
+	}
+	currErr := curr.Validate()
+	recErr := rec.Validate()
+	isValidRec := recErr == nil
+	if currErr != nil {
+		fixed := nearestValidTierExplained(curr, res.explainer())
+		res.CorrectedCurrent = describe(fixed)
+		if !opts.assumeCorrected {
+			return invalidCurrent(res, direction, current, recommended, curr, fixed, rec, recErr), nil
 		}
+		res.warnf(CodeCurrentCorrected, "current tier %s is not valid (%v); checking against its nearest valid tier %s (-assume-corrected)", current, currErr, fixed)
+		curr, current, currErr = fixed, fixed.String(), nil
 	}
-	return 0, 0, false
-}
+	// A change is in direction when neither resource moves the other way,
+	// or, with -allow-mixed, when at least one resource moves this way.
+	inDirection := func(t Tier) bool {
+		want := "lower"
+		if upgrade {
+			want = "higher"
+		}
+		v := compareTiers(curr, t).verdict()
+		return v.Change == want || opts.allowMixed && v.Change == "mixed"
+	}
+	isInDirection := inDirection(rec)
+	res.TierInfo = describe(curr)
+	res.Recommended = describe(rec)
+	res.noteEquivalent(current, curr)
+	res.noteEquivalent(recommended, rec)
 
-func nearestValidTier(cpu, ram int) (int, int) {
-	// Fix vCPU: must be 1 or even 2-96
-	if cpu < 1 {
-		cpu = 1
-	} else if cpu > 96 {
-		cpu = 96
-	} else if cpu != 1 && cpu%2 != 0 {
-		cpu = cpu + 1
-	}
-	// Round RAM up to nearest multiple of 256
-	ram = ((ram + 255) / 256) * 256
-	if ram < 3840 {
-		ram = 3840
-	}
-	// Clamp to valid range for this CPU count
-	minRAM := ((int(0.9*float64(cpu)*1024) + 255) / 256) * 256
-	maxRAM := (int(6.5*float64(cpu)*1024) / 256) * 256
-	if ram < minRAM {
-		ram = minRAM
+	res.printf("Checking %s from %s to %s:\n", direction, current, recommended)
+	res.printf("  Current: %g vCPUs, %d MB (%.2f GB) - Valid: %t\n", curr.VCPUs(), curr.RAMMB, curr.RAMGB(), currErr == nil)
+	if currErr != nil {
+		res.printf("    Reason: %v\n", currErr)
 	}
-	if ram > maxRAM {
-		ram = maxRAM
+	res.printf("  Recommended: %g vCPUs, %d MB (%.2f GB) - Valid: %t\n", rec.VCPUs(), rec.RAMMB, rec.RAMGB(), isValidRec)
+	if recErr != nil {
+		res.printf("    Reason: %v\n", recErr)
 	}
-	return cpu, ram
-}
 
-func main() {
-	cpu := flag.Float64("cpu", 0, "Number of vCPUs (e.g., 24, 48, 64)")
-	mem := flag.String("mem", "", "Memory (e.g., 6G, 6144M, 6144)")
-	tier := flag.String("t", "", "CloudSQL custom tier string (e.g., db-custom-1-3840)")
-	bumpMem := flag.String("bump-mem", "", "Bump memory for existing tier (e.g., db-custom-4-3840)")
-	checkDowngrade := flag.String("check-downgrade", "", "Check if recommended tier is a valid downgrade from current (format: 'current recommended')")
-	downgrade := flag.String("downgrade", "", "Suggest the next valid downgrade tier from current (e.g., db-custom-8-53248)")
-	flag.Parse()
-
-	if *bumpMem != "" {
-		c, r, err := parseTier(*bumpMem)
-		if err != nil {
-			fmt.Println("Invalid tier format. Use: db-custom-<cpus>-<ram_mb>")
-			os.Exit(1)
+	delta := compareTiers(curr, rec)
+	res.Delta = &delta
+	verdict := delta.verdict()
+	res.Verdict = &verdict
+	res.printf("  Change: %s\n", delta)
+	res.printf("  Verdict: %s (vCPUs %s, memory %s)\n", verdict.Change, verdict.CPU, verdict.RAM)
+	if verdict.Change == "mixed" && opts.allowMixed {
+		res.warnf(CodeMixedChange, "mixed change accepted by -allow-mixed: vCPUs %s, memory %s", verdict.CPU, verdict.RAM)
+	}
+	if v := policy.haViolation(rec); !upgrade && opts.ha && v != "" {
+		res.warnf(CodeHAViolation, "HA: %s", v)
+	}
+	if !upgrade && delta.maxDropPct() > opts.maxStepPct {
+		res.Aggressive = true
+		res.warnf(CodeAggressiveDowngrade, "aggressive downgrade: drops %.0f%% in a single step (threshold %g%%)", delta.maxDropPct(), opts.maxStepPct)
+	}
+
+	valid := isValidRec && isInDirection
+	if upgrade {
+		res.ValidUpgrade = &valid
+	} else {
+		res.ValidDowngrade = &valid
+	}
+	if valid {
+		res.printf("  Valid %s: Yes\n", direction)
+	} else {
+		res.Code = code
+		res.printf("  Valid %s: No\n", direction)
+		if !isValidRec {
+			adj := nearestValidTierExplained(rec, res.explainer())
+			res.NearestValid = describe(adj)
+			res.printf("  Nearest valid tier: %s (%d vCPUs, %d MB, %.2f GB)\n",
+				adj, adj.CPUs, adj.RAMMB, adj.RAMGB())
+			if inDirection(adj) {
+				res.printf("  This adjusted tier is a valid %s.\n", direction)
+			}
+		}
+		if !isInDirection && verdict.Change == "mixed" {
+			res.Message = fmt.Sprintf("recommended tier is a mixed change: vCPUs %s, memory %s", verdict.CPU, verdict.RAM)
+			res.printf("  Recommended tier is not %s than the current tier: vCPUs %s but memory %s (use -allow-mixed to accept).\n", comparative, verdict.CPU, verdict.RAM)
+		} else if !isInDirection {
+			res.Message = fmt.Sprintf("recommended tier is not %s than the current tier", comparative)
+			res.printf("  Recommended tier is not %s than the current tier.\n", comparative)
 		}
-		// Keep CPUs, calculate max RAM at 6.5 GB/vCPU
-		ramMB := float64(c) * 6.5 * 1024
-		ramMB = float64((int(ramMB) / 256) * 256) // round down to stay within 6.5 GB/vCPU
-		if ramMB < 3840 {
-			ramMB = 3840
+		find := findPreviousKnownTier
+		if upgrade {
+			find = findNextKnownTier
 		}
-		newTier := fmt.Sprintf("db-custom-%d-%d", c, int(ramMB))
-		if int(ramMB) == r {
-			fmt.Printf("Tier %s is already at the maximum memory level of %.2f GB (6.5 GB/vCPU).\n", *bumpMem, ramMB/1024)
-		} else if int(ramMB) < r {
-			fmt.Printf("Tier %s already exceeds the maximum standard memory.\n", *bumpMem)
-			fmt.Printf("  Current: %d vCPUs, %d MB (%.2f GB) [%.2f GB/vCPU]\n", c, r, float64(r)/1024, float64(r)/1024/float64(c))
-			fmt.Printf("  Max at 6.5 GB/vCPU: %d vCPUs, %.0f MB (%.2f GB)\n", c, ramMB, ramMB/1024)
+		known, found := find(curr)
+		for found && !inDirection(known) {
+			known, found = find(known)
+		}
+		if found && (policy.below(known) || policy.above(known)) {
+			res.printf("  No %s known tier within policy: %s.\n", comparative, strings.Join(policy.violations(known), "; "))
+		} else if found {
+			res.suggest(known)
+			res.printf("  Suggested known %s tier: %s (%d vCPUs, %d MB, %.2f GB)\n",
+				comparative, known, known.CPUs, known.RAMMB, known.RAMGB())
 		} else {
-			fmt.Printf("Bumping memory for tier %s:\n", *bumpMem)
-			fmt.Printf("  Current: %d vCPUs, %d MB (%.2f GB) [%.2f GB/vCPU]\n", c, r, float64(r)/1024, float64(r)/1024/float64(c))
-			fmt.Printf("  New: %d vCPUs, %.0f MB (%.2f GB) [%.2f GB/vCPU]\n", c, ramMB, ramMB/1024, ramMB/1024/float64(c))
-			fmt.Printf("  New Tier: %s\n", newTier)
+			res.printf("  No %s tier found in known list.\n", comparative)
 		}
-		return
 	}
+	return res, nil
+}
+
+// invalidCurrent reports a change check whose current tier is not valid:
+// there is no sound baseline to check rec against, so it stops at naming
+// the nearest valid tier fixed, which -assume-corrected checks against.
+func invalidCurrent(res *Result, direction, current, recommended string, curr, fixed, rec Tier, recErr error) *Result {
+	res.TierInfo = describe(curr)
+	res.Recommended = describe(rec)
+	res.Message, res.Code = "current tier is not valid", CodeCurrentInvalid
+	res.printf("Checking %s from %s to %s:\n", direction, current, recommended)
+	res.printf("  Current: %g vCPUs, %d MB (%.2f GB) - Valid: false\n", curr.VCPUs(), curr.RAMMB, curr.RAMGB())
+	res.printf("    Reason: %v\n", curr.Validate())
+	res.printf("  Recommended: %g vCPUs, %d MB (%.2f GB) - Valid: %t\n", rec.VCPUs(), rec.RAMMB, rec.RAMGB(), recErr == nil)
+	if recErr != nil {
+		res.printf("    Reason: %v\n", recErr)
+	}
+	res.printf("  Current tier is not valid, so the %s was not checked.\n", direction)
+	res.printf("  Nearest valid current tier: %s (%d vCPUs, %d MB, %.2f GB)\n", fixed, fixed.CPUs, fixed.RAMMB, fixed.RAMGB())
+	res.printf("  Use -assume-corrected to check the %s from %s instead.\n", direction, fixed)
+	return res
+}
+
+func runDowngrade(input string) (*Result, error) {
+	res := newResult("downgrade")
+	res.InputTier = input
+	curr, err := ParseTier(input)
+	if err != nil {
+		return res, fmt.Errorf("invalid tier: %w", err)
+	}
+	res.TierInfo = describe(curr)
+	res.noteEquivalent(input, curr)
+	currErr := curr.Validate()
+	res.printf("Current tier: %s\n", input)
+	res.printf("  CPUs: %d, RAM: %d MB (%.2f GB) - Valid: %t\n", curr.CPUs, curr.RAMMB, curr.RAMGB(), currErr == nil)
+	if currErr != nil {
+		res.printf("  Reason: %v\n", currErr)
+	}
+	res.printf("  Memory per vCPU: %.2f GB (valid range: %s)\n", curr.Ratio(), rules.ratioRange())
 
-	if *checkDowngrade != "" {
-		parts := strings.Split(*checkDowngrade, " ")
-		if len(parts) != 2 {
-			fmt.Println("Usage: -check-downgrade '<current-tier> <recommended-tier>'")
-			os.Exit(1)
+	if opts.savings.TargetPct != 0 {
+		if err := runTargetSavings(res, curr, opts.savings); err != nil {
+			return res, err
 		}
-		currCPU, currRAM, err1 := parseTier(parts[0])
-		recCPU, recRAM, err2 := parseTier(parts[1])
-		if err1 != nil || err2 != nil {
-			fmt.Println("Invalid tier format. Use: db-custom-<cpus>-<ram_mb>")
-			os.Exit(1)
+	} else if opts.strategy == "all" {
+		res.Strategies = []*StrategyCandidate{}
+		res.println("Downgrade candidates:")
+		for _, st := range downgradeStrategies {
+			prev, found := st.next(curr)
+			if vs := policy.violations(prev); found && len(vs) > 0 {
+				res.printf("  %s (%s): none within policy (%s)\n", st.name, st.desc, strings.Join(vs, "; "))
+			} else if found {
+				c := &StrategyCandidate{Strategy: st.name, TierInfo: describe(prev)}
+				res.Strategies = append(res.Strategies, c)
+				res.printf("  %s (%s): %s\n", st.name, st.desc, c.summary())
+			} else {
+				res.printf("  %s (%s): none\n", st.name, st.desc)
+			}
 		}
-		isValidCurr := validateTier(currCPU, currRAM)
-		isValidRec := validateTier(recCPU, recRAM)
-		isLower := (recCPU < currCPU) || (recCPU == currCPU && recRAM < currRAM)
+	} else if prev, found := downgradeStrategy(opts.strategy)(curr); found && len(policy.violations(prev)) > 0 {
+		res.Message = "no downgrade within policy"
+		res.printf("No downgrade within policy: %s.\n", strings.Join(policy.violations(prev), "; "))
+	} else if found {
+		res.suggest(prev)
+		res.printf("Suggested downgrade tier: %s\n", prev)
+		res.printf("  CPUs: %d, RAM: %d MB (%.2f GB)\n", prev.CPUs, prev.RAMMB, prev.RAMGB())
+		res.printf("  Memory per vCPU: %.2f GB\n", prev.Ratio())
+		if opts.strategy != "balanced" {
+			res.printf("  Strategy: %s\n", opts.strategy)
+		}
+	} else if opts.strategy == "balanced" {
+		res.Message = "already at the lowest known tier"
+		res.println("Already at the lowest known tier.")
+	} else {
+		res.Message = "no " + opts.strategy + " downgrade available"
+		res.printf("No %s downgrade available.\n", opts.strategy)
+	}
+	if opts.strategy == "balanced" && opts.savings.TargetPct == 0 {
+		res.addMemFirstCandidate(curr)
+	}
+	if opts.steps > 0 {
+		res.addSteps(curr, opts.steps, true)
+	}
+	return res, nil
+}
 
-		fmt.Printf("Checking downgrade from %s to %s:\n", parts[0], parts[1])
-		fmt.Printf("  Current: %d vCPUs, %d MB (%.2f GB) - Valid: %t\n", currCPU, currRAM, float64(currRAM)/1024, isValidCurr)
-		fmt.Printf("  Recommended: %d vCPUs, %d MB (%.2f GB) - Valid: %t\n", recCPU, recRAM, float64(recRAM)/1024, isValidRec)
+// addMemFirstCandidate lists the "reduce memory, keep vCPUs" downgrade
+// next to the previous known tier, since keeping vCPUs and dropping to the
+// standard ratio is often the better move. Both candidates go in Strategies.
+func (r *Result) addMemFirstCandidate(curr Tier) {
+	r.Strategies = []*StrategyCandidate{}
+	if r.Suggested != nil {
+		r.Strategies = append(r.Strategies, &StrategyCandidate{Strategy: "balanced", TierInfo: r.Suggested})
+	}
+	label := "Reduce memory, keep vCPUs"
+	mem, found := memFirstDowngrade(curr)
+	floor := nearestValidTier(Tier{CPUs: curr.CPUs}).RAMMB
+	switch {
+	case curr.Shared():
+		r.printf("%s: not available for shared-core tiers.\n", label)
+	case !found && curr.RAMMB == floor:
+		r.printf("%s: none, %d MB is already the minimum for %d vCPUs.\n", label, curr.RAMMB, curr.CPUs)
+	case !found && curr.RAMMB < floor:
+		r.printf("%s: none, %d MB is already below the %d MB minimum for %d vCPUs.\n", label, curr.RAMMB, floor, curr.CPUs)
+	case !found:
+		r.printf("%s: none.\n", label)
+	case len(policy.violations(mem)) > 0:
+		r.printf("%s: none within policy (%s).\n", label, strings.Join(policy.violations(mem), "; "))
+	default:
+		c := &StrategyCandidate{Strategy: "mem-first", TierInfo: describe(mem)}
+		r.Strategies = append(r.Strategies, c)
+		r.printf("%s: %s\n", label, mem)
+		r.printf("  CPUs: %d, RAM: %d MB (%.2f GB)\n", mem.CPUs, mem.RAMMB, mem.RAMGB())
+		r.printf("  Memory per vCPU: %.2f GB\n", mem.Ratio())
+	}
+}
 
-		if isValidRec && isLower {
-			fmt.Println("  Valid downgrade: Yes")
+func runTier(input string) (*Result, error) {
+	if opts.scale != 0 {
+		return runScale(input, opts.scale)
+	}
+	res := newResult("tier")
+	res.InputTier = input
+	t, err := ParseTier(input)
+	if err != nil {
+		return res, fmt.Errorf("invalid tier: %w", err)
+	}
+	res.TierInfo = describe(t)
+	res.explainer().checkTier(t)
+	res.noteEquivalent(input, t)
+	res.printf("Parsed tier: CPUs=%d, RAM=%d MB\n", t.CPUs, t.RAMMB)
+	if err := t.Validate(); err != nil {
+		res.printf("Tier is not valid: %v\n", err)
+	}
+	if !t.Shared() {
+		res.addNeighbours(t)
+	}
+	if next, found := findLargerKnownTier(t); found && policy.above(next) {
+		res.noneWithinPolicy(next)
+	} else if found {
+		res.suggest(next)
+		res.printf("Next known working custom tier: %s\n", next)
+		res.printf("  CPUs: %d\n  RAM: %d MB (%.2f GB)\n", next.CPUs, next.RAMMB, next.RAMGB())
+	} else {
+		next, ok := suggestNextTier(t)
+		if !ok {
+			res.Message = "at maximum tier"
+			res.printf("At maximum tier: no valid tier above %s under %s %s.\n", t, rules.Name, rules.editionName())
+		} else if policy.above(next) {
+			res.noneWithinPolicy(next)
 		} else {
-			fmt.Println("  Valid downgrade: No")
-			if !isValidRec {
-				adjCPU, adjRAM := nearestValidTier(recCPU, recRAM)
-				adjLower := (adjCPU < currCPU) || (adjCPU == currCPU && adjRAM < currRAM)
-				fmt.Printf("  Nearest valid tier: db-custom-%d-%d (%d vCPUs, %d MB, %.2f GB)\n",
-					adjCPU, adjRAM, adjCPU, adjRAM, float64(adjRAM)/1024)
-				if adjLower {
-					fmt.Println("  This adjusted tier is a valid downgrade.")
-				}
-			}
-			if !isLower {
-				fmt.Println("  Recommended tier is not lower than the current tier.")
-			}
-			if nextCPU, nextRAM, found := findPreviousKnownTier(currCPU, currRAM); found {
-				fmt.Printf("  Suggested known lower tier: db-custom-%d-%d (%d vCPUs, %d MB, %.2f GB)\n",
-					nextCPU, nextRAM, nextCPU, nextRAM, float64(nextRAM)/1024)
-			} else {
-				fmt.Println("  No lower tier found in known list.")
-			}
+			res.suggest(next)
+			res.sizeAt(opts.ratio)
+			res.printf("Next valid custom tier at %g GB/vCPU: %s\n", opts.ratio, next)
+			res.printf("  CPUs: %d\n  RAM: %d MB (%.2f GB)\n", next.CPUs, next.RAMMB, next.RAMGB())
 		}
-		return
 	}
+	if opts.steps > 0 {
+		res.addSteps(t, opts.steps, false)
+	}
+	if opts.nearest > 0 {
+		res.addNearest(t, opts.nearest)
+	}
+	return res, nil
+}
 
-	if *downgrade != "" {
-		currCPU, currRAM, err := parseTier(*downgrade)
-		if err != nil {
-			fmt.Println("Invalid tier format. Use: db-custom-<cpus>-<ram_mb>")
-			os.Exit(1)
+// runValidate checks a tier and, when it is not valid, suggests the nearest
+// valid tier.
+func runValidate(input string) (*Result, error) {
+	res := newResult("validate")
+	res.InputTier = input
+	t, err := ParseTier(input)
+	if err != nil {
+		return res, fmt.Errorf("invalid tier: %w", err)
+	}
+	res.TierInfo = describe(t)
+	ex := res.explainer()
+	ex.checkTier(t)
+	res.noteEquivalent(input, t)
+	if res.Valid {
+		res.printf("%s is a valid %s %s tier: %g vCPUs, %d MB (%.2f GB), %.2f GB/vCPU\n",
+			t, rules.Name, rules.editionName(), t.VCPUs(), t.RAMMB, t.RAMGB(), t.Ratio())
+		return res, nil
+	}
+	res.printf("%s is not a valid %s %s tier:\n", t, rules.Name, rules.editionName())
+	for _, reason := range res.Reasons {
+		res.printf("  - %s\n", reason)
+	}
+	adj := nearestValidTierExplained(t, ex)
+	res.suggest(adj)
+	ex.checkTier(adj)
+	res.printf("Nearest valid tier: %s (%d vCPUs, %d MB, %.2f GB)\n", adj, adj.CPUs, adj.RAMMB, adj.RAMGB())
+	return res, nil
+}
+
+// runSuggest sizes a tier from -cpu, -mem, both, or -data-size, plus the
+// memory of any -connections. With -connections, -cpu alone is sized as
+// -cpu with -mem at -ratio, so the connection memory can raise both.
+func runSuggest(cpu float64, mem string) (*Result, error) {
+	switch {
+	case opts.dataSize != "" && (cpu > 0 || mem != ""):
+		return newResult("suggest"), fmt.Errorf("-data-size cannot be combined with -cpu or -mem")
+	case opts.dataSize != "" && opts.cheapest:
+		return newResult("suggest"), fmt.Errorf("-data-size cannot be combined with -cheapest")
+	case opts.dataSize != "":
+		return runDataSize(opts.dataSize)
+	case opts.cheapest:
+		return runCheapest(cpu, mem)
+	case cpu > 0 && mem != "":
+		return runCPUMem(cpu, mem)
+	case cpu > 0 && opts.connections > 0:
+		return runCPUMem(cpu, fmt.Sprintf("%dM", ratioMBCeil(opts.ratio, int(math.Ceil(cpu)))))
+	case cpu > 0:
+		return runCPU(cpu)
+	case mem != "" || opts.connections > 0:
+		return runMem(mem)
+	}
+	return newResult("suggest"), fmt.Errorf("give -cpu, -mem, both, -data-size, or -connections")
+}
+
+func runCPU(cpu float64) (*Result, error) {
+	res := newResult("cpu")
+	res.RequestedCPUs = cpu
+	res.sizeAt(opts.ratio)
+	cpu = res.padCPU(cpu)
+	if cpu < 1 {
+		if sc, ok := smallestSharedCore(cpu, 0); ok {
+			return sharedCoreResult(res, sc, fmt.Sprintf("%g vCPUs", cpu)), nil
 		}
-		isValidCurr := validateTier(currCPU, currRAM)
-		fmt.Printf("Current tier: %s\n", *downgrade)
-		fmt.Printf("  CPUs: %d, RAM: %d MB (%.2f GB) - Valid: %t\n", currCPU, currRAM, float64(currRAM)/1024, isValidCurr)
-		fmt.Printf("  Memory per vCPU: %.2f GB (valid range: 0.9-6.5 GB)\n", float64(currRAM)/1024/float64(currCPU))
-
-		if nextCPU, nextRAM, found := findPreviousKnownTier(currCPU, currRAM); found {
-			fmt.Printf("Suggested downgrade tier: db-custom-%d-%d\n", nextCPU, nextRAM)
-			fmt.Printf("  CPUs: %d, RAM: %d MB (%.2f GB)\n", nextCPU, nextRAM, float64(nextRAM)/1024)
-			fmt.Printf("  Memory per vCPU: %.2f GB\n", float64(nextRAM)/1024/float64(nextCPU))
-		} else {
-			fmt.Println("Already at the lowest known tier.")
+	}
+	ex := res.explainer()
+	// Fractional, millicore, and odd requests round up to a whole, legal
+	// count, or as -round says. A whole count above the cap is left for
+	// correctTier, which warns that no tier meets it
+	var snapped string
+	mode := rounding(roundUp)
+	legal := rules.legalCPUAtLeast(wholeCPUs(cpu, mode))
+	if float64(legal) != cpu && (float64(legal) > cpu || math.Ceil(cpu) != cpu || opts.round != "") {
+		ex.add("round-vcpus", false, "%g vCPUs → %d (whole, then 1 or even, %s)", cpu, legal, roundedBy(mode))
+		snapped = fmt.Sprintf("%g vCPUs, %s %d (vCPUs are whole and 1 or even)", cpu, roundedTo(mode), legal)
+		if opts.cpuInput != "" && opts.cpuInput != fmt.Sprint(cpu) && res.Padding == nil {
+			snapped = opts.cpuInput + " = " + snapped
 		}
-		return
+		cpu = float64(legal)
+	}
+	ramMB := cpu * opts.ratio * 1024
+	ex.add("size-ram", true, "%g vCPUs × %g GB/vCPU × 1024 = %.0f MB", cpu, opts.ratio, ramMB)
+	ramMB = res.padMem(ramMB)
+	ramMB = float64(res.snapMem(ramMB, int(cpu), mode, ex))
+	if ramMB < float64(rules.MinRAMMB) {
+		ex.add("clamp-ram-floor", false, "%.0f MB → %d MB (floor)", ramMB, rules.MinRAMMB)
+		res.warnf(CodeRAMRaised, "memory raised from %.0f MB to the %d MB minimum", ramMB, rules.MinRAMMB)
+		ramMB = float64(rules.MinRAMMB)
+	}
+	need := int(cpu)
+	tier, err := res.correctTier(Tier{CPUs: need, RAMMB: int(ramMB)}, ex, func(t Tier) bool { return t.CPUs >= need })
+	if err != nil {
+		return res, err
+	}
+	res.TierInfo = describe(tier)
+	ex.checkTier(tier)
+	res.printf("Recommended CloudSQL %s tier for %.0f vCPUs:\n", rules.Name, cpu)
+	if snapped != "" {
+		res.printf("  - Requested: %s\n", snapped)
 	}
+	res.printPadding()
+	if tier.CPUs != need {
+		res.printf("  - vCPUs: %d\n", tier.CPUs)
+	}
+	res.printf("  - Memory: %d MB (%.2f GB)\n", tier.RAMMB, tier.RAMGB())
+	res.printf("  - Tier: %s\n", tier)
+	res.printf("  - Memory per vCPU: %.2f GB (valid range: %s)\n", tier.Ratio(), rules.ratioRange())
+	res.printf("  - Sized at: %g GB/vCPU\n", opts.ratio)
+	res.printRaw()
+	return res, nil
+}
 
-	if *tier != "" {
-		c, r, err := parseTier(*tier)
+// runMem sizes a tier from memStr of memory, at -ratio GB/vCPU. An empty
+// memStr sizes from -connections alone.
+func runMem(mem string) (*Result, error) {
+	res := newResult("mem")
+	res.sizeAt(opts.ratio)
+	var memMB float64
+	if mem == "" {
+		res.Mode = "connections"
+	} else if parsed, err := parseMem(mem); errors.Is(err, ErrRAMTooHigh) {
+		return res, err
+	} else if err != nil {
+		return res, fmt.Errorf("invalid mem format: %w", err)
+	} else {
+		memMB = parsed
+		res.RequestedMemMB = memMB
+	}
+	if memMB = res.padMem(res.addConnections(memMB)); memMB > float64(rules.MaxRAMMB) {
+		with := fmt.Sprintf("with %d connections", opts.connections)
+		if res.Padding != nil {
+			with = fmt.Sprintf("with %g%% headroom", opts.headroom)
+		}
+		return res, newTierError(ErrRAMTooHigh, fmt.Sprintf("%.0f MB", memMB), "memory %.0f MB %s exceeds the Cloud SQL maximum of %d MB for %s %s", memMB, with, rules.MaxRAMMB, rules.Name, rules.editionName())
+	}
+	if memMB < float64(rules.MinRAMMB) {
+		if sc, ok := smallestSharedCore(0, memMB); ok {
+			return sharedCoreResult(res, sc, fmt.Sprintf("%.0f MB RAM", memMB)), nil
+		}
+	}
+	ex := res.explainer()
+	// Memory is checked against the vCPUs once they are known, below
+	mode := rounding(roundUp)
+	rounded := float64(roundMem(memMB, mode))
+	ex.add("round-ram", rounded == memMB, "%.0f MB → %.0f MB (multiple of 256, %s)", memMB, rounded, roundedBy(mode))
+	var snapped string
+	if rounded != memMB && mem != "" && res.Connections == nil && res.Padding == nil {
+		snapped = fmt.Sprintf("%s = %g MB, %s %.0f MB (multiple of 256)", strings.TrimSpace(mem), memMB, roundedTo(mode), rounded)
+	}
+	memMB = rounded
+	if memMB < float64(rules.MinRAMMB) {
+		ex.add("clamp-ram-floor", false, "%.0f MB → %d MB (floor)", memMB, rules.MinRAMMB)
+		res.warnf(CodeRAMRaised, "memory raised from %.0f MB to the %d MB minimum", memMB, rules.MinRAMMB)
+		memMB = float64(rules.MinRAMMB)
+	}
+	cpus := memMB / opts.ratio / 1024
+	cpuMode := rounding(roundNearest)
+	cpusRounded := float64(wholeCPUs(cpus, cpuMode))
+	ex.add("size-vcpus", true, "%.0f MB / %g GB/vCPU / 1024 = %.2f vCPUs, %s %.0f", memMB, opts.ratio, cpus, roundedTo(cpuMode), cpusRounded)
+	// vCPUs must be 1 or even; snap to a legal count and re-check the
+	// memory-per-vCPU range, which may move memory as well.
+	need := int(memMB)
+	tier, err := res.correctTier(Tier{CPUs: int(cpusRounded), RAMMB: need}, ex, func(t Tier) bool { return t.RAMMB >= need })
+	if err != nil {
+		return res, err
+	}
+	res.TierInfo = describe(tier)
+	ex.checkTier(tier)
+	res.printf("Recommended CloudSQL %s tier for %.0f MB RAM:\n", rules.Name, memMB)
+	if snapped != "" {
+		res.printf("  - Requested: %s\n", snapped)
+	}
+	res.printConnections()
+	res.printPadding()
+	res.checkConnections(tier)
+	res.printf("  - vCPUs: %d\n", tier.CPUs)
+	res.printf("  - Memory: %d MB (%.2f GB)\n", tier.RAMMB, tier.RAMGB())
+	res.printf("  - Tier: %s\n", tier)
+	res.printf("  - Memory per vCPU: %.2f GB (valid range: %s)\n", tier.Ratio(), rules.ratioRange())
+	res.printf("  - Sized at: %g GB/vCPU\n", opts.ratio)
+	res.printRaw()
+	return res, nil
+}
+
+// correctTier returns the nearest valid tier to the computed raw tier,
+// keeping raw for the footnote of printRaw when it is not valid itself. A
+// correction that is still invalid, or that no longer meets the request, is
+// a warning, or an error under -strict.
+func (r *Result) correctTier(raw Tier, ex *explanation, meets func(Tier) bool) (Tier, error) {
+	if raw.Validate() == nil {
+		return raw, nil
+	}
+	r.Raw = describe(raw)
+	tier := nearestValidTierExplained(raw, ex)
+	if err := tier.Validate(); err != nil || !meets(tier) {
+		msg := fmt.Sprintf("no valid %s %s tier meets the request: the nearest to the computed %s is %s", rules.Name, rules.editionName(), raw, tier)
 		if err != nil {
-			fmt.Println("Invalid tier format. Use: db-custom-<cpus>-<ram_mb>")
-			os.Exit(1)
+			msg += fmt.Sprintf(", which is not valid either (%v)", err)
 		}
-		fmt.Printf("Parsed tier: CPUs=%d, RAM=%d MB\n", c, r)
-		if nextCPU, nextRAM, found := findNextKnownTier(c, r); found {
-			fmt.Printf("Next known working custom tier: db-custom-%d-%d\n", nextCPU, nextRAM)
-			fmt.Printf("  CPUs: %d\n  RAM: %d MB (%.2f GB)\n", nextCPU, nextRAM, float64(nextRAM)/1024)
-		} else {
-			cpusNext, ramNext := suggestNextTier(c, r)
-			if cpusNext == c && ramNext == r {
-				fmt.Println("This is already a valid custom tier.")
-			} else {
-				fmt.Printf("Next valid custom tier: db-custom-%d-%d\n", cpusNext, ramNext)
-				fmt.Printf("  CPUs: %d\n  RAM: %d MB (%.2f GB)\n", cpusNext, ramNext, float64(ramNext)/1024)
-			}
+		if opts.strict {
+			return tier, errors.New(msg)
 		}
-		return
+		r.warnf(CodeNoValidTier, "%s", msg)
 	}
+	return tier, nil
+}
 
-	if (*cpu == 0 && *mem == "") || (*cpu != 0 && *mem != "") {
-		fmt.Println("Usage: go-calc -cpu <vCPUs> OR -mem <memory> OR -t <tier> OR -bump-mem <tier> OR -check-downgrade '<current> <recommended>' OR -downgrade <current>")
-		fmt.Println("  -mem examples: 6G, 6144M, 6144")
-		fmt.Println("  -bump-mem: Increase memory to standard level for the given tier")
-		fmt.Println("  -check-downgrade: Validate if recommended is a valid downgrade from current")
-		fmt.Println("  -downgrade: Suggest the next valid downgrade tier from current")
-		os.Exit(1)
+// printRaw adds the footnote of a computed tier correctTier replaced.
+func (r *Result) printRaw() {
+	if r.Raw != nil {
+		r.printf("  Note: the computation gave %s, which is not valid (%s); the nearest valid tier is shown instead.\n",
+			r.Raw.Tier, strings.Join(r.Raw.Reasons, "; "))
 	}
+}
 
-	if *cpu > 0 {
-		ramMB := *cpu * 1.5 * 1024
-		ramMB = float64(((int(ramMB) + 255) / 256) * 256)
-		if ramMB < 3840 {
-			ramMB = 3840
+// runCPUMem finds the smallest valid tier with at least cpu vCPUs and memStr
+// of memory. The memory-per-vCPU band may force one side above its request;
+// the side that decided the result is reported as binding.
+func runCPUMem(cpu float64, memStr string) (*Result, error) {
+	res := newResult("cpu-mem")
+	res.RequestedCPUs = cpu
+	memMB, err := parseMem(memStr)
+	if errors.Is(err, ErrRAMTooHigh) {
+		return res, err
+	}
+	if err != nil {
+		return res, fmt.Errorf("invalid mem format: %w", err)
+	}
+	res.RequestedMemMB = memMB
+	memMB = res.padMem(res.addConnections(memMB))
+	cpu = res.padCPU(cpu)
+	request := fmt.Sprintf("%g vCPUs and %.0f MB RAM", cpu, memMB)
+	if cpu < 1 && memMB < float64(rules.MinRAMMB) {
+		if sc, ok := smallestSharedCore(cpu, memMB); ok {
+			return sharedCoreResult(res, sc, request), nil
 		}
-		tier := fmt.Sprintf("db-custom-%d-%d", int(*cpu), int(ramMB))
-		fmt.Printf("Recommended CloudSQL MySQL tier for %.0f vCPUs:\n", *cpu)
-		fmt.Printf("  - Memory: %.0f MB (%.2f GB)\n", ramMB, ramMB/1024)
-		fmt.Printf("  - Tier: %s\n", tier)
-		fmt.Printf("  - Memory per vCPU: %.2f GB (valid range: 0.9-6.5 GB)\n", ramMB/1024 / *cpu)
-	} else {
-		memMB, err := parseMem(*mem)
-		if err != nil {
-			fmt.Println("Invalid mem format:", err)
-			os.Exit(1)
+	}
+	tier, binding, err := smallestTierFor(cpu, memMB)
+	if err != nil {
+		return res, fmt.Errorf("cannot satisfy %s: %w", request, err)
+	}
+	res.TierInfo = describe(tier)
+	res.Binding = binding
+	res.Spare = &Spare{CPUs: float64(tier.CPUs) - cpu, MemMB: float64(tier.RAMMB) - memMB}
+	res.printf("Smallest CloudSQL %s tier with at least %s:\n", rules.Name, request)
+	res.printConnections()
+	res.printPadding()
+	res.printf("  - Tier: %s\n", tier)
+	res.printf("  - vCPUs: %d (spare %+g)\n", tier.CPUs, res.Spare.CPUs)
+	res.printf("  - Memory: %d MB (%.2f GB, spare %+.0f MB)\n", tier.RAMMB, tier.RAMGB(), res.Spare.MemMB)
+	res.printf("  - Memory per vCPU: %.2f GB (valid range: %s)\n", tier.Ratio(), rules.ratioRange())
+	res.printf("  - Binding constraint: %s\n", binding)
+	res.checkConnections(tier)
+	return res, nil
+}
+
+// sharedCoreResult fills res with a shared-core recommendation for a request
+// that falls below the custom tier minimums.
+func sharedCoreResult(res *Result, sc Tier, request string) *Result {
+	res.TierInfo = describe(sc)
+	res.Message = "shared-core tiers are not recommended for production"
+	res.printf("Recommended CloudSQL %s tier for %s:\n", rules.Name, request)
+	res.printf("  - Tier: %s (shared core, %g vCPU)\n", sc, sc.VCPUs())
+	res.printf("  - Memory: %d MB (%.2f GB)\n", sc.RAMMB, sc.RAMGB())
+	res.warnf(CodeSharedCoreNoSLA, "shared-core tiers have no SLA and are not recommended for production")
+	res.printf("  - Smallest custom tier: %s\n", knownTiers[0])
+	res.checkConnections(sc)
+	return res
+}
+
+// options holds the flags that apply across modes.
+type options struct {
+	output     string
+	quiet      bool
+	format     string
+	gcloud     bool
+	instance   string
+	project    string
+	maxRetries int
+	noCache    bool
+	apply      bool
+	yes        bool
+	cacheTTL   time.Duration
+	engine     string
+	edition    string
+	cost       bool
+	ha         bool
+	region     string
+	prices     string
+
+	commitment     string
+	committedCPUs  int
+	committedRAM   string
+	committedRAMMB int
+
+	cpu                float64
+	cpuInput           string // -cpu as given, e.g. 2500m
+	k8s                bool
+	equivalents        bool
+	toRDS              bool
+	k8sOverhead        string
+	k8sOverheadMB      int
+	mem                string
+	ratio              float64
+	toRatio            float64
+	strategy           string
+	steps              int
+	scale              float64
+	clamp              bool
+	normalize          bool
+	nearest            int
+	cpuWeight          float64
+	memWeight          float64
+	maxStepPct         float64
+	allowMixed         bool
+	assumeCorrected    bool
+	savings            Savings
+	dataSize           string
+	workingSet         float64
+	bufferPoolFraction float64
+	connections        int
+	cheapest           bool
+	replicaOffset      int
+	replicaTier        string
+	maxShrinkPct       float64
+	maxFactor          float64
+	headroom           float64
+	cpuHeadroom        float64
+	round              string
+	usable             bool
+	report             string
+	notifyURL          string
+	reportOut          string
+	overheadMB         int
+	overheadPct        float64
+	connMemKB          float64
+	strict             bool
+	explain            bool
+
+	config        string
+	tiersFile     string
+	tiersFrom     string
+	tiersMerge    bool
+	ratioNote     string // why the default -ratio was adjusted, if it was
+	usage         Usage
+	monitor       bool
+	window        string
+	percentile    float64
+	cpuPercentile float64
+	memPercentile float64
+	usageCSV      string
+	concurrency   int
+	desired       string
+	interval      time.Duration
+	once          bool
+	listen        string
+	metrics       bool
+	growth        Growth
+	filter        TierFilter
+	minMem        string
+	maxMem        string
+
+	tfPlaceholders bool
+
+	mysqlConfig   bool
+	bufferPoolPct float64
+	perConnKB     float64
+	flagsFile     string
+	memBudgetPct  float64
+}
+
+var opts options
+
+// annotate adds the cross-mode extras requested by flags to a result.
+func annotate(res *Result) error {
+	if rules.Edition != defaultEdition {
+		res.printf("Rules: %s, %s edition\n", rules.Name, rules.editionName())
+	}
+	addPolicyCheck(res)
+	target := res.targetTier()
+	if opts.gcloud && target != "" && len(res.PolicyViolations) == 0 {
+		res.GcloudCommand = gcloudPatchCommand(opts.instance, opts.project, target)
+		res.printf("gcloud command:\n  %s\n", res.GcloudCommand)
+	}
+	if opts.usable {
+		addUsable(res)
+	}
+	if opts.mysqlConfig {
+		if err := addMySQLConfig(res); err != nil {
+			return err
+		}
+	}
+	if opts.equivalents {
+		if err := addEquivalents(res); err != nil {
+			return err
+		}
+	}
+	if opts.toRDS {
+		if err := addRDSEquivalents(res); err != nil {
+			return err
+		}
+	}
+	if opts.flagsFile != "" {
+		if err := addFlagsCheck(res); err != nil {
+			return err
 		}
-		memMB = float64(((int(memMB) + 255) / 256) * 256)
-		if memMB < 3840 {
-			memMB = 3840
+	}
+	checkCommitment(res)
+	if opts.cost {
+		if err := addCosts(res); err != nil {
+			return err
+		}
+	}
+	if opts.apply {
+		return addApply(res)
+	}
+	return nil
+}
+
+// gcloudPatchCommand builds the command that moves an instance to tier.
+func gcloudPatchCommand(instance, project, tier string) string {
+	instance, project = patchTarget(instance, project)
+	cmd := fmt.Sprintf("gcloud sql instances patch %s --tier=%s", instance, tier)
+	if project != "" {
+		cmd += " --project=" + project
+	}
+	return cmd
+}
+
+// patchTarget returns the instance and project a gcloud command names: an
+// -instance of project:instance is split, since gcloud takes the project
+// as --project. An empty instance becomes a <INSTANCE> placeholder.
+func patchTarget(instance, project string) (string, string) {
+	if instance == "" {
+		return "<INSTANCE>", project
+	}
+	if p, name, err := splitInstance(instance, project); err == nil {
+		return name, p
+	}
+	return instance, project
+}
+
+func usage() {
+	w := flag.CommandLine.Output()
+	fmt.Fprintln(w, "Usage: go-calc <command> [flags] [args]")
+	fmt.Fprintln(w, "       go-calc [flags] <tier>...  (same as -t; several tiers are checked like -batch)")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Commands:")
+	for _, c := range commands {
+		fmt.Fprintf(w, "  %-16s %s\n", c.name, c.summary)
+	}
+	fmt.Fprintln(w, "Run 'go-calc help <command>' for the flags of a command.")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Deprecated flag form: go-calc -cpu <vCPUs> OR -mem <memory> (or both) OR -t <tier> OR -bump-mem <tier> OR -bump-cpu <tier> OR -check-downgrade '<current> <recommended>' OR -check-upgrade '<current> <recommended>' OR -diff '<tier-a> <tier-b>' OR -plan '<current> <target>' OR -downgrade <current>")
+	fmt.Fprintln(w, "  -mem examples: 6G, 6144M, 6144 (MB), 1.5T, 6442450944B (bytes)")
+	fmt.Fprintln(w, "  -cpu with -mem: Find the smallest tier with at least both")
+	fmt.Fprintln(w, "  -headroom, -cpu-headroom: Add this percentage to the memory or vCPU requirement before sizing (-headroom is 20 for -rightsize)")
+	fmt.Fprintln(w, "  -round up|down|nearest: How -cpu, -mem, -bump-mem, and -scale snap to whole vCPUs and 256 MB steps (default: up, but down for -bump-mem memory and nearest for -mem vCPUs)")
+	fmt.Fprintln(w, "  -cheapest: With -cpu and/or -mem, find the lowest-cost valid tier (by resources without -cost) and 5 alternatives")
+	fmt.Fprintln(w, "  -bump-mem: Increase memory for the given tier to -to-ratio GB/vCPU (default: the maximum)")
+	fmt.Fprintln(w, "  -bump-cpu: Increase vCPUs to the next legal count for the given tier, keeping memory")
+	fmt.Fprintln(w, "  -rightsize: Recommend the smallest tier for the observed -cpu-util and -mem-util with -headroom")
+	fmt.Fprintln(w, "  -monitor: With -rightsize, read the -percentile utilization over -window from Cloud Monitoring for -instance; with -instances, for each instance")
+	fmt.Fprintln(w, "  -usage-csv: With -rightsize, size from the -percentile (a number or max) of a CSV of timestamped CPU percent and memory bytes samples")
+	fmt.Fprintln(w, "  -cpu-percentile, -mem-percentile: With -monitor or -usage-csv, size vCPUs and memory at their own percentiles instead of -percentile (e.g. CPU at 99 for bursts, memory at 50)")
+	fmt.Fprintln(w, "  -concurrency: With -instances and -monitor, instances to look up at once (default 4)")
+	fmt.Fprintln(w, "  -growth: Project the tier needed every -every months as load grows by -mem-growth/-cpu-growth percent a month")
+	fmt.Fprintln(w, "  -check-downgrade: Validate if recommended is a valid downgrade from current")
+	fmt.Fprintln(w, "  -check-upgrade: Validate if recommended is a valid upgrade from current")
+	fmt.Fprintln(w, "  -diff: Compare two tiers side by side, with deltas and a verdict (upgrade, downgrade, sideways, or mixed)")
+	fmt.Fprintln(w, "  -plan: Plan the resizes from current to target through known and standard tiers, none more than -max-factor (default 2) times the vCPUs or memory")
+	fmt.Fprintln(w, "  -downgrade: Suggest the next valid downgrade tier from current")
+	fmt.Fprintln(w, "  -cpu-range: Show the smallest and largest legal memory, and the 3.75 and 6.5 GB/vCPU tiers, of each vCPU count (e.g. 8,16,32)")
+	fmt.Fprintln(w, "  -mem-range: List every legal vCPU count for an amount of memory (e.g. 200G), with its ratio and whether it is a known tier")
+	fmt.Fprintln(w, "  -matrix: List every valid tier at 3.75 and 6.5 GB/vCPU (with -cost, its price) and report where the known tiers differ")
+	fmt.Fprintln(w, "  -list-tiers: List the known tiers (filter with -min-cpu, -max-cpu, -min-mem, -max-mem, -ratio-class)")
+	fmt.Fprintln(w, "  -instances: Report on every instance in a gcloud instance list JSON file")
+	fmt.Fprintln(w, "  -recommender: Check every tier change in a Cloud SQL rightsizing recommender JSON export (valid, nearest valid, -cost savings)")
+	fmt.Fprintln(w, "  -strategy: With -downgrade, reduce memory (mem-first), vCPUs (cpu-first), or both (balanced, default); all compares them")
+	fmt.Fprintln(w, "  -batch: Validate every tier in a file, one per line (- or -t - reads stdin)")
+	fmt.Fprintln(w, "  -normalize: With -batch, print the canonical form of each tier instead of validating it")
+	fmt.Fprintln(w, "  -report html: With -instances or -batch, also write a self-contained HTML report to -report-out (default report.html)")
+	fmt.Fprintln(w, "  -engine: Apply the tier rules of mysql (default), postgres, sqlserver, or sqlserver-enterprise")
+	fmt.Fprintln(w, "  -edition: Apply enterprise (default, up to 96 vCPUs) or enterprise-plus (up to 128 vCPUs) limits")
+	fmt.Fprintln(w, "  -connections: Add the memory of N connections (-conn-mem-kb each) to a suggest request")
+	fmt.Fprintln(w, "  -ratio: Memory per vCPU used for sizing (default 1.5 GB, within the engine's range)")
+	fmt.Fprintln(w, "  -steps: With -t or -downgrade, list the next N known tiers")
+	fmt.Fprintln(w, "  -scale: With -t, multiply vCPUs and memory by this factor and snap to the nearest valid tier (-clamp to stop at the limits)")
+	fmt.Fprintln(w, "  -nearest: With -t, list the N closest known tiers (weighted by -cpu-weight, -mem-weight)")
+	fmt.Fprintln(w, "  -target-savings: With -downgrade, pick the tier saving closest to this percentage (-tolerance, -any-shape)")
+	fmt.Fprintln(w, "  -max-step-pct: Warn when -check-downgrade drops more than this percentage (default 50)")
+	fmt.Fprintln(w, "  -allow-mixed: Accept a check where one resource moves the wrong way")
+	fmt.Fprintln(w, "  -assume-corrected: Check against the nearest valid current tier when the current tier is not valid")
+	fmt.Fprintln(w, "  -strict: Treat warnings as failures")
+	fmt.Fprintln(w, "  -tiers-file: Use the known tiers in this CSV or JSON file of cpus,ram_mb pairs (with -tiers-merge, add them to the built-in list)")
+	fmt.Fprintln(w, "  -tiers-from: Use the custom tiers of a 'gcloud sql tiers list --format=json' file as the known tiers ('go-calc tiers export' writes them out)")
+	fmt.Fprintln(w, "  -config: Read flag defaults and tier policy from this file (default $XDG_CONFIG_HOME/go-calc/config.yaml)")
+	fmt.Fprintln(w, "  -min-tier, -max-tier: Policy floor and ceiling of suggested tiers, overriding the config min_tier and max_tier")
+	fmt.Fprintln(w, "  -explain: Show each rule check (pass/fail) and the rounding steps behind a suggestion")
+	fmt.Fprintln(w, "  -equivalents: List the GCE machine types closest to the resulting tier")
+	fmt.Fprintln(w, "  -to-rds: List the AWS RDS instance classes closest to the resulting tier")
+	fmt.Fprintln(w, "  -usable: Show the memory of the resulting tier left after the OS and agents (-overhead-mb, -overhead-pct), and size -data-size and -mysql-config from it")
+	fmt.Fprintln(w, "  -mysql-config: Recommend MySQL memory settings for the resulting tier (with -buffer-pool-pct, -per-conn-kb)")
+	fmt.Fprintln(w, "  -flags-file: Check database flags against the resulting tier's memory (with -mem-budget-pct)")
+	fmt.Fprintln(w, "  -cost: Estimate monthly cost (with -region, -prices)")
+	fmt.Fprintln(w, "  -commitment: With -cost, price at a 1yr or 3yr committed use discount and compare every level")
+	fmt.Fprintln(w, "  -committed-cpus, -committed-ram: Warn when a downgrade leaves less than is already committed")
+	fmt.Fprintln(w, "  -ha: Regional (HA) instance: double the compute cost and check the policy ha_min_tier")
+	fmt.Fprintln(w, "  -o: Output format: text (default), json, yaml, terraform, csv, k8s, gha (GitHub Actions annotations, nothing for valid results), slack (Block Kit payload; -notify-url posts it), or ndjson (batch and instances records streamed one per line, then a summary)")
+	fmt.Fprintln(w, "  -k8s: Print Kubernetes resource requests for the resulting tiers (with -k8s-overhead)")
+	fmt.Fprintln(w, "  -q, -quiet: Print only the resulting tier (exit code 2 when there is none)")
+	fmt.Fprintln(w, "  -format: Go text/template for the output (fields .Tier, .CPUs, .RAMMB, .RAMGB, .Ratio, .Valid, .Suggested.Tier, ...) or @tier-only, @oneline")
+	fmt.Fprintln(w, "  -gcloud: Print the 'gcloud sql instances patch' command for the resulting tier (with -instance, -project)")
+	fmt.Fprintln(w, "  -instance <project>:<instance>: Read the current tier from the Cloud SQL Admin API when the tier is left out or given as @instance")
+	fmt.Fprintln(w, "  watch -desired <file>: Report drift of instances from their desired tiers every -interval (default 10m), or a single pass with -once")
+	fmt.Fprintln(w, "  serve -listen <addr>: Answer validate, next, suggest, and check-downgrade as a JSON HTTP API (default :8080), with Prometheus metrics on /metrics unless -metrics=false")
+	fmt.Fprintln(w, "  -apply: Move -instance to the resulting tier with the Cloud SQL Admin API; a dry run unless -yes is given or the change is confirmed on the terminal")
+	fmt.Fprintln(w, "  -no-cache, -cache-ttl: Bypass the cache of -instance lookups, or how long to use them for (default 1h); 'go-calc cache clear' empties it")
+	fmt.Fprintln(w, "  -max-retries: Retries of a rate-limited or failing Cloud SQL Admin or Cloud Monitoring API call, with backoff (default 3)")
+	fmt.Fprintln(w, "  -version: Print the build version, commit, date, and tier rules revision")
+	fmt.Fprintln(w, "  -i, -interactive: Read commands (next, prev, mem, ratio, ...) from stdin, one result per line")
+	fmt.Fprintln(w)
+	printExitCodes(w)
+}
+
+// legacyModes maps the deprecated mode flags to the command replacing them.
+var legacyModes = []struct{ flag, command string }{
+	{"list-tiers", "list-tiers"},
+	{"cpu-range", "cpu-range <vCPUs>..."},
+	{"mem-range", "mem-range <memory>"},
+	{"matrix", "matrix"},
+	{"instances", "instances <file>"},
+	{"recommender", "recommender <file>"},
+	{"batch", "batch <file>"},
+	{"t", "next <tier>"},
+	{"bump-mem", "bump-mem <tier>"},
+	{"bump-cpu", "bump-cpu <tier>"},
+	{"rightsize", "rightsize <tier>"},
+	{"growth", "growth <tier>"},
+	{"check-downgrade", "check-downgrade <current> <recommended>"},
+	{"check-upgrade", "check-upgrade <current> <recommended>"},
+	{"diff", "diff <tier-a> <tier-b>"},
+	{"plan", "plan <current> <target>"},
+	{"downgrade", "prev <tier>"},
+	{"cpu", "suggest -cpu <vCPUs>"},
+	{"mem", "suggest -mem <memory>"},
+}
+
+// legacyFlags holds the mode flags of the deprecated flag form, which are
+// registered along with the option flags of every command.
+type legacyFlags struct {
+	tier, bumpMem, bumpCPU, rightsize, growth string
+	checkDowngrade, checkUpgrade, downgrade   string
+	diff, plan, cpuRange, memRange            string
+	instances, recommender, batch             string
+	listTiers, matrix, version, interactive   bool
+}
+
+func (l *legacyFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&l.tier, "t", "", "CloudSQL tier string (e.g., db-custom-1-3840 or db-n1-standard-4)")
+	fs.StringVar(&l.bumpMem, "bump-mem", "", "Bump memory for existing tier (e.g., db-custom-4-3840)")
+	fs.StringVar(&l.bumpCPU, "bump-cpu", "", "Bump vCPUs for existing tier, keeping memory where possible (e.g., db-custom-4-26624)")
+	fs.StringVar(&l.rightsize, "rightsize", "", "Recommend a tier for the observed load of an existing tier (with -cpu-util, -mem-util, -headroom)")
+	fs.StringVar(&l.growth, "growth", "", "Project the tier needed as an existing tier's load grows (with -mem-growth, -cpu-growth, -months, -every)")
+	fs.StringVar(&l.checkDowngrade, "check-downgrade", "", "Check if recommended tier is a valid downgrade from current (format: 'current recommended')")
+	fs.StringVar(&l.checkUpgrade, "check-upgrade", "", "Check if recommended tier is a valid upgrade from current (format: 'current recommended')")
+	fs.StringVar(&l.diff, "diff", "", "Compare two tiers side by side (format: 'tier-a tier-b')")
+	fs.StringVar(&l.plan, "plan", "", "Plan the resizes from current to target, at most -max-factor times per step (format: 'current target')")
+	fs.StringVar(&l.downgrade, "downgrade", "", "Suggest the next valid downgrade tier from current (e.g., db-custom-8-53248)")
+	fs.StringVar(&l.cpuRange, "cpu-range", "", "Show the legal memory range of each vCPU count (e.g., 8,16,32)")
+	fs.StringVar(&l.memRange, "mem-range", "", "Show the vCPU counts that can carry an amount of memory (e.g., 200G)")
+	fs.BoolVar(&l.matrix, "matrix", false, "List every valid tier at 3.75 and 6.5 GB/vCPU and check the known tiers against them")
+	fs.BoolVar(&l.listTiers, "list-tiers", false, "List the known tiers valid under the selected rules")
+	fs.StringVar(&l.instances, "instances", "", "Analyse every instance in a 'gcloud sql instances list --format=json' file (use - for stdin)")
+	fs.StringVar(&l.recommender, "recommender", "", "Check every tier change in a 'gcloud recommender recommendations list --format=json' export of Cloud SQL rightsizing recommendations (use - for stdin)")
+	fs.StringVar(&l.batch, "batch", "", "Validate one tier per line from a file (use - for stdin)")
+	fs.BoolVar(&l.version, "version", false, "Print the build version and the tier rules revision")
+	fs.BoolVar(&l.interactive, "i", false, "Read commands from stdin interactively (type help for the commands)")
+	fs.BoolVar(&l.interactive, "interactive", false, "Same as -i")
+	for _, register := range []func(*flag.FlagSet){suggestFlags, commonFlags, batchFlags, reportFlags, stepsFlags, nearestFlags, scaleFlags, strategyFlags, bumpMemFlags, checkFlags, mixedFlags, correctedFlags, planFlags, rightsizeFlags, fleetFlags, watchFlags, serveFlags, growthFlags, listFlags} {
+		register(fs)
+	}
+}
+
+func main() {
+	if len(os.Args) > 1 {
+		if name := os.Args[1]; name == "help" || lookupCommand(name) != nil {
+			runCommand(name, os.Args[2:])
 		}
-		cpus := memMB / 1.5 / 1024
-		cpusRounded := math.Round(cpus)
-		if cpusRounded < 1 {
-			cpusRounded = 1
+	}
+
+	var lf legacyFlags
+	flag.CommandLine.Init(os.Args[0], flag.ContinueOnError)
+	lf.register(flag.CommandLine)
+	flag.Usage = usage
+	args, err := parseInterspersed(flag.CommandLine, os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		os.Exit(exitOK)
+	}
+	if err != nil {
+		os.Exit(exitUsage)
+	}
+	setup(flag.CommandLine)
+	tierArgs := []*string{&lf.tier, &lf.downgrade, &lf.bumpMem, &lf.bumpCPU, &lf.rightsize, &lf.growth, &lf.checkDowngrade, &lf.checkUpgrade}
+	for i := range args {
+		tierArgs = append(tierArgs, &args[i])
+	}
+	for _, arg := range tierArgs {
+		if err := resolveInstanceRef(flag.CommandLine, arg); err != nil {
+			finish(newResult("instance"), err)
 		}
-		tier := fmt.Sprintf("db-custom-%d-%d", int(cpusRounded), int(memMB))
-		if !validateTier(int(cpusRounded), int(memMB)) {
-			fmt.Println("Warning: The calculated tier may not be valid. Please check the constraints.")
+	}
+
+	if lf.version {
+		finish(runVersion())
+	}
+	if lf.interactive {
+		runREPL(os.Stdin, os.Stdout)
+		os.Exit(exitOK)
+	}
+
+	var modes []string
+	for _, m := range legacyModes {
+		if flagSet(flag.CommandLine, m.flag) {
+			modes = append(modes, m.flag)
+		}
+	}
+	if len(args) > 0 && len(modes) > 0 {
+		fmt.Printf("Tier arguments cannot be combined with -%s\n", strings.Join(modes, ", -"))
+		os.Exit(exitUsage)
+	}
+	if len(modes) > 1 && !(len(modes) == 2 && modes[0] == "cpu" && modes[1] == "mem") {
+		fmt.Printf("Only one mode may be given, got -%s\n", strings.Join(modes, ", -"))
+		os.Exit(exitUsage)
+	}
+	if len(modes) == 0 && len(args) == 0 {
+		usage()
+		os.Exit(exitUsage)
+	}
+	for _, m := range legacyModes {
+		if len(modes) > 0 && m.flag == modes[0] {
+			fmt.Fprintf(os.Stderr, "Note: -%s is deprecated; use 'go-calc %s'\n", m.flag, m.command)
 		}
-		fmt.Printf("Recommended CloudSQL MySQL tier for %.0f MB RAM:\n", memMB)
-		fmt.Printf("  - vCPUs: %.0f\n", cpusRounded)
-		fmt.Printf("  - Memory: %.0f MB (%.2f GB)\n", memMB, memMB/1024)
-		fmt.Printf("  - Tier: %s\n", tier)
-		fmt.Printf("  - Memory per vCPU: %.2f GB (valid range: 0.9-6.5 GB)\n", memMB/1024/cpusRounded)
 	}
+
+	var res report
+	switch {
+	case len(args) == 1 && args[0] == "-":
+		res, err = runBatchMode("-")
+	case len(args) == 1:
+		res, err = runTier(args[0])
+	case len(args) > 1:
+		res, err = runTierArgs(args)
+	case lf.listTiers:
+		res, err = runListTiers(opts.filter)
+	case lf.matrix:
+		res, err = runMatrix()
+	case lf.cpuRange != "":
+		res, err = runCPURange([]string{lf.cpuRange})
+	case lf.memRange != "":
+		res, err = runMemRange(lf.memRange)
+	case lf.instances != "":
+		res, err = runFleet(lf.instances)
+	case lf.recommender != "":
+		res, err = runRecommender(lf.recommender)
+	case lf.batch != "":
+		res, err = runBatchMode(lf.batch)
+	case lf.tier == "-":
+		res, err = runBatchMode("-")
+	case lf.bumpMem != "":
+		res, err = runBumpMem(lf.bumpMem)
+	case lf.bumpCPU != "":
+		res, err = runBumpCPU(lf.bumpCPU)
+	case lf.rightsize != "":
+		res, err = runRightsize(lf.rightsize, opts.usage)
+	case lf.growth != "":
+		res, err = runGrowth(lf.growth, opts.growth)
+	case lf.checkDowngrade != "":
+		res, err = runCheckPair(lf.checkDowngrade, false)
+	case lf.checkUpgrade != "":
+		res, err = runCheckPair(lf.checkUpgrade, true)
+	case lf.diff != "":
+		res, err = runDiffPair(lf.diff)
+	case lf.plan != "":
+		res, err = runPlanPair(lf.plan)
+	case lf.downgrade != "":
+		res, err = runDowngrade(lf.downgrade)
+	case lf.tier != "":
+		res, err = runTier(lf.tier)
+	default:
+		res, err = runSuggest(opts.cpu, opts.mem)
+	}
+	finish(res, err)
 }
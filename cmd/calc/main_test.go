@@ -0,0 +1,288 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// binary is the go-calc binary built for the tests that run it.
+var binary string
+
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "go-calc-test")
+	if err != nil {
+		panic(err)
+	}
+	binary = filepath.Join(dir, "go-calc")
+	if out, err := exec.Command("go", "build", "-o", binary, ".").CombinedOutput(); err != nil {
+		os.RemoveAll(dir)
+		panic(string(out))
+	}
+	// Keep the user's config file and cache out of the tests, but pin Go's
+	// build cache, which fuzz workers use to build the binary again.
+	if cache, err := exec.Command("go", "env", "GOCACHE").Output(); err == nil {
+		os.Setenv("GOCACHE", strings.TrimSpace(string(cache)))
+	}
+	os.Setenv("XDG_CONFIG_HOME", filepath.Join(dir, "config"))
+	os.Setenv("XDG_CACHE_HOME", filepath.Join(dir, "cache"))
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+// run runs the binary with args and returns its stdout and exit code.
+func run(t *testing.T, args ...string) (string, int) {
+	t.Helper()
+	stdout, _, code := runStreams(t, args...)
+	return stdout, code
+}
+
+// runStreams runs the binary with args and returns its stdout, stderr, and
+// exit code.
+func runStreams(t *testing.T, args ...string) (stdout, stderr string, code int) {
+	t.Helper()
+	var out, errOut strings.Builder
+	cmd := exec.Command(binary, args...)
+	cmd.Stdout, cmd.Stderr = &out, &errOut
+	err := cmd.Run()
+	var ee *exec.ExitError
+	if errors.As(err, &ee) {
+		return out.String(), errOut.String(), ee.ExitCode()
+	}
+	if err != nil {
+		t.Fatalf("go-calc %q: %v", args, err)
+	}
+	return out.String(), errOut.String(), 0
+}
+
+func TestExitCodes(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		want int
+	}{
+		{"valid tier", []string{"-t", "db-custom-4-16384"}, exitOK},
+		{"valid tier json", []string{"-o", "json", "-t", "db-custom-4-16384"}, exitOK},
+		{"odd vCPUs", []string{"-t", "db-custom-3-16384"}, exitInvalid},
+		{"memory below minimum", []string{"-t", "db-custom-4-1024"}, exitInvalid},
+		{"memory not aligned", []string{"-t", "db-custom-4-16000"}, exitInvalid},
+		{"unparseable tier", []string{"-t", "db-custom-four"}, exitParse},
+		{"unparseable tier json", []string{"-o", "json", "-t", "nonsense"}, exitParse},
+		{"bad memory unit", []string{"-mem", "6X"}, exitParse},
+		{"bad memory syntax", []string{"-mem", "lots"}, exitParse},
+		{"vCPUs above the edition maximum", []string{"-t", "db-custom-97-16384"}, exitInvalid},
+		{"memory above the edition maximum", []string{"-t", "db-custom-4-638977"}, exitInvalid},
+		{"requested memory above the maximum", []string{"-mem", "1.5T"}, exitInvalid},
+		{"mem-range below the minimum", []string{"mem-range", "100M"}, exitInvalid},
+		{"valid downgrade", []string{"-check-downgrade", "db-custom-8-53248 db-custom-4-16384"}, exitOK},
+		{"downgrade to invalid tier", []string{"-check-downgrade", "db-custom-8-53248 db-custom-3-16384"}, exitInvalid},
+		{"downgrade that is not lower", []string{"-check-downgrade", "db-custom-4-16384 db-custom-8-53248"}, exitInvalid},
+		{"no mode", nil, exitUsage},
+		{"unknown output format", []string{"-o", "xml", "-t", "db-custom-4-16384"}, exitUsage},
+		{"unknown flag", []string{"-bogus", "db-custom-4-16384"}, exitUsage},
+		{"flag without its value", []string{"-t"}, exitUsage},
+		{"unknown subcommand flag", []string{"next", "-bogus", "db-custom-4-16384"}, exitUsage},
+		{"help", []string{"-h"}, exitOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, got := run(t, tt.args...); got != tt.want {
+				t.Errorf("go-calc %q exited %d, want %d", tt.args, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRatio(t *testing.T) {
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"-cpu", "4", "-ratio", "0.9"}, "db-custom-4-3840"},
+		{[]string{"-cpu", "6", "-ratio", "0.9"}, "db-custom-6-5632"},
+		{[]string{"-cpu", "8", "-ratio", "0.9"}, "db-custom-8-7424"},
+		{[]string{"-cpu", "8", "-ratio", "6.5"}, "db-custom-8-53248"},
+		{[]string{"-cpu", "4", "-ratio", "2.7"}, "db-custom-4-11264"},
+		{[]string{"-mem", "52G", "-ratio", "6.5"}, "db-custom-8-53248"},
+		{[]string{"-mem", "7424", "-ratio", "0.9"}, "db-custom-8-7424"},
+	}
+	for _, tt := range tests {
+		out, code := run(t, append([]string{"-o", "json"}, tt.args...)...)
+		var res struct {
+			Tier  string `json:"tier"`
+			RAMMB int    `json:"ram_mb"`
+		}
+		if err := json.Unmarshal([]byte(out), &res); err != nil {
+			t.Fatalf("go-calc %q: %v\n%s", tt.args, err, out)
+		}
+		if res.Tier != tt.want {
+			t.Errorf("go-calc %q = %s (exit %d), want %s", tt.args, res.Tier, code, tt.want)
+		}
+		if res.RAMMB%256 != 0 {
+			t.Errorf("go-calc %q = %d MB, not a multiple of 256 MB", tt.args, res.RAMMB)
+		}
+	}
+	for _, ratio := range []string{"0.89", "6.51", "0", "-1"} {
+		if _, code := run(t, "-cpu", "4", "-ratio", ratio); code != exitUsage {
+			t.Errorf("-ratio %s exited %d, want %d", ratio, code, exitUsage)
+		}
+	}
+}
+
+func TestCheckDowngradeSeparators(t *testing.T) {
+	const curr, rec = "db-custom-8-53248", "db-custom-4-16384"
+	tests := []struct {
+		name string
+		args []string
+		want int
+	}{
+		{"two arguments", []string{"check-downgrade", curr, rec}, exitOK},
+		{"two arguments with padding", []string{"check-downgrade", " " + curr, rec + "\t"}, exitOK},
+		{"trailing comma on the first argument", []string{"check-downgrade", curr + ",", rec}, exitOK},
+		{"upgrade as two arguments", []string{"check-upgrade", rec, curr}, exitOK},
+		{"space", []string{"-check-downgrade", curr + " " + rec}, exitOK},
+		{"two spaces", []string{"-check-downgrade", curr + "  " + rec}, exitOK},
+		{"comma", []string{"-check-downgrade", curr + "," + rec}, exitOK},
+		{"comma and space", []string{"-check-downgrade", curr + ", " + rec}, exitOK},
+		{"space and comma", []string{"-check-downgrade", curr + " ," + rec}, exitOK},
+		{"tab", []string{"-check-downgrade", curr + "\t" + rec}, exitOK},
+		{"newline", []string{"-check-downgrade", curr + "\n" + rec}, exitOK},
+		{"surrounding spaces", []string{"-check-downgrade", "  " + curr + "   " + rec + "  "}, exitOK},
+		{"doubled commas", []string{"-check-downgrade", curr + " ,, " + rec}, exitOK},
+		{"upgrade with comma", []string{"-check-upgrade", rec + "," + curr}, exitOK},
+		{"one tier", []string{"-check-downgrade", curr}, exitUsage},
+		{"one tier and a comma", []string{"-check-downgrade", curr + ","}, exitUsage},
+		{"three tiers", []string{"-check-downgrade", curr + "," + rec + "," + rec}, exitUsage},
+		{"one argument", []string{"check-downgrade", curr}, exitUsage},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if out, got := run(t, tt.args...); got != tt.want {
+				t.Errorf("go-calc %q exited %d, want %d\n%s", tt.args, got, tt.want, out)
+			}
+		})
+	}
+}
+
+func TestWarningStreams(t *testing.T) {
+	aggressive := []string{"check-downgrade", "db-custom-16-106496", "db-custom-2-7680"}
+	sharedCore := []string{"suggest", "-mem", "1G"}
+	for _, args := range [][]string{aggressive, sharedCore} {
+		stdout, stderr, code := runStreams(t, args...)
+		if code != exitOK {
+			t.Errorf("go-calc %q exited %d, want %d", args, code, exitOK)
+		}
+		if stdout == "" || strings.Contains(stdout, "Warning") {
+			t.Errorf("go-calc %q stdout = %q, want the report without warnings", args, stdout)
+		}
+		if !strings.HasPrefix(stderr, "Warning: ") {
+			t.Errorf("go-calc %q stderr = %q, want a warning", args, stderr)
+		}
+
+		stdout, stderr, code = runStreams(t, append(args, "-strict")...)
+		if code != exitInvalid {
+			t.Errorf("go-calc %q -strict exited %d, want %d", args, code, exitInvalid)
+		}
+		if strings.Contains(stdout, "Error") || !strings.HasPrefix(stderr, "Error: ") {
+			t.Errorf("go-calc %q -strict stdout = %q, stderr = %q, want the error on stderr", args, stdout, stderr)
+		}
+
+		stdout, stderr, _ = runStreams(t, append(args, "-o", "json")...)
+		var res struct {
+			Warnings []string `json:"warnings"`
+		}
+		if err := json.Unmarshal([]byte(stdout), &res); err != nil {
+			t.Fatalf("go-calc %q -o json: %v\n%s", args, err, stdout)
+		}
+		if len(res.Warnings) == 0 || stderr != "" {
+			t.Errorf("go-calc %q -o json warnings = %q, stderr = %q, want the warnings in JSON only", args, res.Warnings, stderr)
+		}
+	}
+
+	stdout, stderr, code := runStreams(t, append(sharedCore, "-q")...)
+	if code != exitOK || strings.Count(stdout, "\n") != 1 || !strings.HasPrefix(stderr, "Warning: ") {
+		t.Errorf("-q: exit %d, stdout %q, stderr %q, want one tier on stdout and the warning on stderr", code, stdout, stderr)
+	}
+	stdout, stderr, code = runStreams(t, append(sharedCore, "-q", "-strict")...)
+	if code != exitInvalid || stdout != "" || !strings.HasPrefix(stderr, "Error: ") {
+		t.Errorf("-q -strict: exit %d, stdout %q, stderr %q, want nothing on stdout and the error on stderr", code, stdout, stderr)
+	}
+}
+
+func TestBumpMem(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		want    string // suggested tier
+		warning string
+		message string
+		code    int
+		wantErr string
+	}{
+		{"1 vCPU", []string{"db-custom-1-3840"}, "db-custom-2-13312", "1 vCPU tiers allow at most 3840 MB; moved to 2 vCPUs", "", exitOK, ""},
+		{"1 vCPU, Enterprise Plus", []string{"-edition", "enterprise-plus", "db-custom-1-3840"}, "db-custom-2-16384", "moved to 2 vCPUs to reach 8 GB/vCPU", "", exitOK, ""},
+		{"1 vCPU below the memory floor", []string{"-engine", "sqlserver-enterprise", "db-custom-1-3840"}, "db-custom-2-13312", "1 vCPU tiers allow at most 6656 MB, below the 10240 MB minimum; moved to 2 vCPUs", "", exitInvalid, ""},
+		{"1 vCPU without 1 vCPU tiers", []string{"-engine", "sqlserver", "db-custom-1-3840"}, "", "", "", exitInvalid, "the result db-custom-1-6656 is not valid (vCPUs must be between 2 and 96 for SQL Server, got 1); the nearest valid tier is db-custom-2-6656"},
+		{"2 vCPUs", []string{"db-custom-2-7680"}, "db-custom-2-13312", "", "", exitOK, ""},
+		{"2 vCPUs at the minimum", []string{"db-custom-2-4096"}, "db-custom-2-13312", "", "", exitOK, ""},
+		{"2 vCPUs to a ratio", []string{"-to-ratio", "5", "db-custom-2-7680"}, "db-custom-2-10240", "", "", exitOK, ""},
+		{"4 vCPUs to an uneven ratio", []string{"-to-ratio", "4.1", "db-custom-4-15360"}, "db-custom-4-16640", "", "", exitOK, ""},
+		{"4 vCPUs to an uneven ratio, rounded up", []string{"-to-ratio", "4.1", "-round", "up", "db-custom-4-15360"}, "db-custom-4-16896", "", "", exitOK, ""},
+		{"2 vCPUs maxed", []string{"db-custom-2-13312"}, "", "", "already at or above the target ratio", exitOK, ""},
+		{"4 vCPUs maxed", []string{"db-custom-4-26624"}, "", "", "already at or above the target ratio", exitOK, ""},
+		{"96 vCPUs maxed", []string{"db-custom-96-638976"}, "", "", "already at or above the target ratio", exitOK, ""},
+		{"above the target", []string{"-to-ratio", "3", "db-custom-1-3840"}, "", "", "already at or above the target ratio", exitOK, ""},
+	}
+	for _, tt := range tests {
+		args := append([]string{"bump-mem", "-o", "json"}, tt.args...)
+		out, code := run(t, args...)
+		var res struct {
+			Suggested string   `json:"suggested_tier"`
+			Message   string   `json:"message"`
+			Warnings  []string `json:"warnings"`
+			Error     string   `json:"error"`
+		}
+		if err := json.Unmarshal([]byte(out), &res); err != nil {
+			t.Fatalf("%s: %v\n%s", tt.name, err, out)
+		}
+		if tt.wantErr != "" {
+			if code == exitOK || !strings.Contains(res.Error, tt.wantErr) {
+				t.Errorf("%s: exit %d, error %q, want %q", tt.name, code, res.Error, tt.wantErr)
+			}
+			continue
+		}
+		if code != tt.code || res.Suggested != tt.want || res.Message != tt.message {
+			t.Errorf("%s = %s, %q (exit %d), want %s, %q (exit %d)", tt.name, res.Suggested, res.Message, code, tt.want, tt.message, tt.code)
+		}
+		if warned := strings.Join(res.Warnings, "\n"); !strings.Contains(warned, tt.warning) || tt.warning == "" && warned != "" {
+			t.Errorf("%s warnings = %q, want %q", tt.name, res.Warnings, tt.warning)
+		}
+	}
+}
+
+// Every tier -bump-mem suggests is valid, under every engine and edition.
+func TestBumpMemAlwaysValid(t *testing.T) {
+	defer func(r float64) { opts.toRatio = r }(opts.toRatio)
+	for _, engine := range sortedKeys(engineRules) {
+		for _, edition := range sortedKeys(editions) {
+			useRules(t, engine, edition)
+			for _, opts.toRatio = range []float64{rules.MinGBPerCPU, defaultGBPerCPU, rules.MaxGBPerCPU} {
+				for cpu := 1; cpu <= rules.MaxCPUs; cpu++ {
+					in := nearestValidTier(Tier{CPUs: cpu, RAMMB: rules.MinRAMMB})
+					res, err := runBumpMem(in.String())
+					if err != nil || res.SuggestedTier == "" {
+						continue
+					}
+					if got, _ := ParseTier(res.SuggestedTier); got.Validate() != nil || got.RAMMB <= in.RAMMB {
+						t.Errorf("%s %s: bump-mem %s to %g GB/vCPU = %s: %v", engine, edition, in, opts.toRatio, got, got.Validate())
+					}
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+// MatrixTier is a tier of the standard-ratio matrix and whether the known
+// tier catalog has it.
+type MatrixTier struct {
+	*TierInfo
+	Known bool `json:"known"`
+}
+
+// MatrixResult is the outcome of matrix: every valid tier at the ratio
+// classes for every legal vCPU count, and where the known tier catalog
+// disagrees with it.
+type MatrixResult struct {
+	Mode          string        `json:"mode"`
+	Engine        string        `json:"engine"`
+	Edition       string        `json:"edition"`
+	Tiers         []*MatrixTier `json:"tiers"`
+	Discrepancies []string      `json:"discrepancies"`
+	Error         string        `json:"error,omitempty"`
+	Version       *BuildInfo    `json:"version,omitempty"`
+}
+
+func (m *MatrixResult) setError(err error) {
+	m.Error = err.Error()
+}
+
+func (m *MatrixResult) setVersion(bi *BuildInfo) {
+	m.Version = bi
+}
+
+// exitCode is exitInvalid when the catalog disagrees with the matrix.
+func (m *MatrixResult) exitCode() int {
+	if len(m.Discrepancies) > 0 {
+		return exitInvalid
+	}
+	return exitOK
+}
+
+func (m *MatrixResult) humanText() string {
+	var sb strings.Builder
+	tw := tabwriter.NewWriter(&sb, 0, 0, 2, ' ', 0)
+	header := "Tier\tvCPUs\tRAM GB\tGB/vCPU\tClass\tKnown"
+	if prices != nil {
+		header += "\tCost/mo"
+	}
+	fmt.Fprintln(tw, header)
+	known := 0
+	for _, t := range m.Tiers {
+		mark := ""
+		if t.Known {
+			mark = "yes"
+			known++
+		}
+		fmt.Fprintf(tw, "%s\t%d\t%.2f\t%.2f\t%s\t%s", t.Tier, t.CPUs, t.RAMGB, t.Ratio, t.Class, mark)
+		if t.Cost != nil {
+			fmt.Fprintf(tw, "\t%s", prices.money(t.Cost.Monthly))
+		}
+		fmt.Fprintln(tw)
+	}
+	tw.Flush()
+	fmt.Fprintf(&sb, "%d tiers at %g and %g GB/vCPU for %s %s, %d in the known tier catalog\n",
+		len(m.Tiers), ratioClasses["standard"], ratioClasses["highmem"], rules.Name, rules.editionName(), known)
+	if len(m.Discrepancies) == 0 {
+		sb.WriteString("Catalog check: every known tier matches the matrix\n")
+		return sb.String()
+	}
+	fmt.Fprintf(&sb, "Catalog check: %d discrepancies:\n", len(m.Discrepancies))
+	for _, d := range m.Discrepancies {
+		fmt.Fprintf(&sb, "  - %s\n", d)
+	}
+	return sb.String()
+}
+
+func (m *MatrixResult) csvRecords() [][]string {
+	records := [][]string{{"tier", "cpus", "ram_mb", "ram_gb", "ratio_gb_per_cpu", "class", "known", "monthly_cost"}}
+	for _, t := range m.Tiers {
+		cost := ""
+		if t.Cost != nil {
+			cost = strconv.FormatFloat(t.Cost.Monthly, 'f', 2, 64)
+		}
+		records = append(records, []string{
+			t.Tier,
+			strconv.Itoa(t.CPUs),
+			strconv.Itoa(t.RAMMB),
+			strconv.FormatFloat(t.RAMGB, 'f', 2, 64),
+			strconv.FormatFloat(t.Ratio, 'f', 2, 64),
+			t.Class,
+			strconv.FormatBool(t.Known),
+			cost,
+		})
+	}
+	return records
+}
+
+// standardMatrix returns every valid tier at the ratio classes for every
+// legal vCPU count, sorted like knownTiers.
+func standardMatrix() []Tier {
+	var ts []Tier
+	for c := rules.MinCPUs; c <= rules.MaxCPUs; c++ {
+		if !rules.cpuAllowed(c) {
+			continue
+		}
+		for _, class := range []string{"standard", "highmem"} {
+			if t := (Tier{CPUs: c, RAMMB: ratioMBFloor(ratioClasses[class], c)}); t.Valid() {
+				ts = append(ts, t)
+			}
+		}
+	}
+	return ts
+}
+
+// matrixDiscrepancies checks the known tier catalog against the matrix:
+// every catalog tier valid under the rules must be in it, and a vCPU count
+// the catalog lists must have every ratio class the matrix has for it.
+func matrixDiscrepancies(matrix []Tier) []string {
+	out := []string{}
+	counts := map[int]bool{}
+	for _, t := range knownTiers {
+		if !t.Valid() {
+			continue
+		}
+		counts[t.CPUs] = true
+		if !slices.Contains(matrix, t) {
+			out = append(out, fmt.Sprintf("known tier %s is not at a standard ratio (%.2f GB/vCPU)", t, t.Ratio()))
+		}
+	}
+	for _, t := range matrix {
+		if counts[t.CPUs] && !slices.Contains(knownTiers, t) {
+			out = append(out, fmt.Sprintf("known tiers list %d vCPUs but not the %s tier %s", t.CPUs, ratioClass(t), t))
+		}
+	}
+	return out
+}
+
+// runMatrix generates the standard-ratio matrix and checks the known tier
+// catalog against it.
+func runMatrix() (*MatrixResult, error) {
+	res := &MatrixResult{Mode: "matrix", Engine: rules.Engine, Edition: rules.Edition, Tiers: []*MatrixTier{}}
+	matrix := standardMatrix()
+	for _, t := range matrix {
+		res.Tiers = append(res.Tiers, &MatrixTier{TierInfo: describe(t), Known: slices.Contains(knownTiers, t)})
+	}
+	res.Discrepancies = matrixDiscrepancies(matrix)
+	return res, nil
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestStandardMatrix(t *testing.T) {
+	for _, engine := range sortedKeys(engineRules) {
+		for _, edition := range sortedKeys(editions) {
+			useRules(t, engine, edition)
+			matrix := standardMatrix()
+			if len(matrix) == 0 {
+				t.Fatalf("%s %s: empty matrix", engine, edition)
+			}
+			for i, tier := range matrix {
+				if !tier.Valid() || ratioClass(tier) == "" {
+					t.Errorf("%s %s: matrix has %s, not a valid tier at a ratio class", engine, edition, tier)
+				}
+				if i > 0 && (tier.CPUs < matrix[i-1].CPUs || tier.CPUs == matrix[i-1].CPUs && tier.RAMMB <= matrix[i-1].RAMMB) {
+					t.Errorf("%s %s: matrix has %s after %s", engine, edition, tier, matrix[i-1])
+				}
+			}
+			if got := matrixDiscrepancies(matrix); len(got) != 0 {
+				t.Errorf("%s %s: the built-in catalog disagrees with the matrix: %q", engine, edition, got)
+			}
+		}
+	}
+}
+
+func TestMatrixDiscrepancies(t *testing.T) {
+	useRules(t, "mysql", "enterprise")
+	saved := knownTiers
+	t.Cleanup(func() { knownTiers = saved })
+	knownTiers = []Tier{{CPUs: 4, RAMMB: 15360}, {CPUs: 8, RAMMB: 32768}}
+	got := matrixDiscrepancies(standardMatrix())
+	want := []string{"db-custom-8-32768 is not at a standard ratio", "not the highmem tier db-custom-4-26624", "not the standard tier db-custom-8-30720", "not the highmem tier db-custom-8-53248"}
+	if len(got) != len(want) {
+		t.Fatalf("matrixDiscrepancies = %q, want %d discrepancies", got, len(want))
+	}
+	for _, w := range want {
+		if !slices.ContainsFunc(got, func(d string) bool { return strings.Contains(d, w) }) {
+			t.Errorf("matrixDiscrepancies = %q, want one mentioning %q", got, w)
+		}
+	}
+	res, _ := runMatrix()
+	if res.exitCode() != exitInvalid {
+		t.Errorf("matrix with discrepancies exits %d, want %d", res.exitCode(), exitInvalid)
+	}
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// memUnits maps a lowercased memory suffix to its size in MB. Every unit is
+// binary: G is treated as GiB (1024 MB), matching the tier naming in
+// CloudSQL, and T as 1024 G. A bare number is MB; B marks bytes, as some
+// monitoring systems export them. Ki, Mi, Gi, and Ti are the Kubernetes
+// spellings.
+var memUnits = map[string]float64{
+	"":    1,
+	"b":   1.0 / (1 << 20),
+	"k":   1.0 / 1024,
+	"kb":  1.0 / 1024,
+	"ki":  1.0 / 1024,
+	"kib": 1.0 / 1024,
+	"m":   1,
+	"mb":  1,
+	"mi":  1,
+	"mib": 1,
+	"g":   1024,
+	"gb":  1024,
+	"gi":  1024,
+	"gib": 1024,
+	"t":   1 << 20,
+	"tb":  1 << 20,
+	"ti":  1 << 20,
+	"tib": 1 << 20,
+}
+
+// parseMem parses a memory amount such as 6G, 6 GB, 6144MiB, 1.5T,
+// 68719476736B, or 6144 and returns it in MB. Units are case-insensitive and
+// may be separated from the number by whitespace. More than the selected
+// edition's memory ceiling is an error.
+func parseMem(memStr string) (float64, error) {
+	mb, err := parseSize(memStr)
+	if err != nil {
+		return 0, err
+	}
+	if math.IsInf(mb, 0) || mb > float64(rules.MaxRAMMB) {
+		return 0, newTierError(ErrRAMTooHigh, memStr, "memory %s exceeds the Cloud SQL maximum of %d MB (%g GB) for %s %s", memStr, rules.MaxRAMMB, float64(rules.MaxRAMMB)/1024, rules.Name, rules.editionName())
+	}
+	return mb, nil
+}
+
+// parseSize parses a size in the units parseMem accepts and returns it in
+// MB, with no ceiling, for amounts such as data volumes. A number too large
+// for a float64 is +Inf.
+func parseSize(memStr string) (float64, error) {
+	s := strings.TrimSpace(memStr)
+	if s == "" {
+		return 0, newTierError(ErrBadMemSyntax, memStr, "empty memory string")
+	}
+	i := strings.IndexFunc(s, func(r rune) bool {
+		return (r < '0' || r > '9') && r != '.'
+	})
+	if i < 0 {
+		i = len(s)
+	}
+	value, err := strconv.ParseFloat(s[:i], 64)
+	if err != nil || value < 0 {
+		return 0, newTierError(ErrBadMemSyntax, memStr, "invalid memory format %q", memStr)
+	}
+	unit := strings.TrimSpace(s[i:])
+	mult, ok := memUnits[strings.ToLower(unit)]
+	if !ok {
+		return 0, newTierError(ErrBadMemUnit, unit, "invalid unit: %s", unit)
+	}
+	return value * mult, nil
+}
+
+// parseCPU parses a vCPU count such as 4 or 2.5, or Kubernetes millicores
+// such as 4000m, and returns it in vCPUs.
+func parseCPU(s string) (float64, error) {
+	s = strings.TrimSpace(s)
+	// Millicores are divided rather than multiplied by 0.001, which would
+	// print 1001m as 1.0010000000000001 vCPUs
+	num, div := s, 1.0
+	if n, ok := strings.CutSuffix(s, "m"); ok {
+		num, div = n, 1000
+	}
+	cpu, err := strconv.ParseFloat(num, 64)
+	if err != nil || cpu < 0 || math.IsInf(cpu, 0) || math.IsNaN(cpu) {
+		return 0, fmt.Errorf("invalid vCPUs %q: use a count such as 4 or millicores such as 4000m", s)
+	}
+	return cpu / div, nil
+}
+
+// cpuFlag is the -cpu flag: parseCPU's input, kept as given for output.
+type cpuFlag struct {
+	cpu   *float64
+	input *string
+}
+
+func (f cpuFlag) String() string {
+	if f.input == nil {
+		return ""
+	}
+	return *f.input
+}
+
+func (f cpuFlag) Set(s string) error {
+	cpu, err := parseCPU(s)
+	if err != nil {
+		return err
+	}
+	*f.cpu, *f.input = cpu, s
+	return nil
+}
+
+// parseOptionalMem is parseMem for optional flags: "" means 0.
+func parseOptionalMem(memStr string) (float64, error) {
+	if memStr == "" {
+		return 0, nil
+	}
+	return parseMem(memStr)
+}
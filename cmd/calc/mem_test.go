@@ -0,0 +1,267 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestParseMem(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    float64
+		wantErr error
+	}{
+		{"6144", 6144, nil},
+		{"6144M", 6144, nil},
+		{"6144m", 6144, nil},
+		{"6144MB", 6144, nil},
+		{"6144mb", 6144, nil},
+		{"6144MiB", 6144, nil},
+		{"6144mib", 6144, nil},
+		{"6G", 6144, nil},
+		{"6g", 6144, nil},
+		{"6GB", 6144, nil},
+		{"6Gb", 6144, nil},
+		{"6GiB", 6144, nil},
+		{"6gib", 6144, nil},
+		{"6 GB", 6144, nil},
+		{" 6G ", 6144, nil},
+		{"1.5G", 1536, nil},
+		{"0.5 GiB", 512, nil},
+		{"", 0, ErrBadMemSyntax},
+		{"  ", 0, ErrBadMemSyntax},
+		{"G", 0, ErrBadMemSyntax},
+		{"lots", 0, ErrBadMemSyntax},
+		{"-6G", 0, ErrBadMemSyntax},
+		{"6.5.1G", 0, ErrBadMemSyntax},
+		{"6X", 0, ErrBadMemUnit},
+		{"6 GBs", 0, ErrBadMemUnit},
+		{"6 G B", 0, ErrBadMemUnit},
+		{"6Gi B", 0, ErrBadMemUnit},
+	}
+	for _, tt := range tests {
+		got, err := parseMem(tt.in)
+		if !errors.Is(err, tt.wantErr) || tt.wantErr == nil && err != nil {
+			t.Errorf("parseMem(%q) error = %v, want %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("parseMem(%q) = %g, want %g", tt.in, got, tt.want)
+		}
+	}
+}
+
+// Memory whose 1.5 GB/vCPU count rounds to an odd number must still give a
+// valid tier.
+func TestMemSnapsToLegalVCPUs(t *testing.T) {
+	for _, mem := range []string{"4608", "4.5G", "7680", "7.5G", "10752", "10.5G", "5000", "11000"} {
+		out, code := run(t, "-o", "json", "-mem", mem)
+		var res struct {
+			Tier  string `json:"tier"`
+			Valid bool   `json:"valid"`
+		}
+		if err := json.Unmarshal([]byte(out), &res); err != nil {
+			t.Fatalf("-mem %s: %v\n%s", mem, err, out)
+		}
+		tier, err := ParseTier(res.Tier)
+		if err != nil {
+			t.Fatalf("-mem %s: %v", mem, err)
+		}
+		if !res.Valid || code != exitOK || tier.Validate() != nil {
+			t.Errorf("-mem %s = %s (valid %t, exit %d), want a valid tier", mem, res.Tier, res.Valid, code)
+		}
+		if tier.CPUs != 1 && tier.CPUs%2 != 0 {
+			t.Errorf("-mem %s = %s, want 1 or an even number of vCPUs", mem, res.Tier)
+		}
+	}
+}
+
+func TestParseMemUnits(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    float64
+		wantErr error
+	}{
+		{"6291456K", 6144, nil},
+		{"6291456 KB", 6144, nil},
+		{"6291456kib", 6144, nil},
+		{"512K", 0.5, nil},
+		{"6144M", 6144, nil},
+		{"6G", 6144, nil},
+		{"1.5G", 1536, nil},
+		{"0.5T", 524288, nil},
+		{"0.5 TiB", 524288, nil},
+		{"0.25tb", 262144, nil},
+		{"68719476736B", 65536, nil},
+		{"6442450944 b", 6144, nil},
+		{"1073741824.5B", 1024 + 0.5/(1<<20), nil},
+		{"52Gi", 53248, nil},
+		{"6144Mi", 6144, nil},
+		{"6291456Ki", 6144, nil},
+		{"0.5Ti", 524288, nil},
+		{"1.5 gi", 1536, nil},
+		{"1.5T", 0, ErrRAMTooHigh},
+		{"1Ti", 0, ErrRAMTooHigh},
+		{"1T", 0, ErrRAMTooHigh},
+		{"638977", 0, ErrRAMTooHigh},
+		{"1e400G", 0, ErrBadMemUnit},
+		{"6 PB", 0, ErrBadMemUnit},
+		{"6 bytes", 0, ErrBadMemUnit},
+		{"T", 0, ErrBadMemSyntax},
+	}
+	for _, tt := range tests {
+		got, err := parseMem(tt.in)
+		if !errors.Is(err, tt.wantErr) || tt.wantErr == nil && err != nil {
+			t.Errorf("parseMem(%q) error = %v, want %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("parseMem(%q) = %g, want %g", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestParseMemCeilingFollowsEdition(t *testing.T) {
+	useRules(t, "mysql", "enterprise-plus")
+	if got, err := parseMem("864G"); err != nil || got != 884736 {
+		t.Errorf("Enterprise Plus parseMem(864G) = %g, %v, want 884736", got, err)
+	}
+	if _, err := parseMem("865G"); !errors.Is(err, ErrRAMTooHigh) {
+		t.Errorf("Enterprise Plus parseMem(865G) error = %v, want %v", err, ErrRAMTooHigh)
+	}
+}
+
+func TestParseCPU(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    float64
+		wantErr bool
+	}{
+		{"4", 4, false},
+		{"2.5", 2.5, false},
+		{"4000m", 4, false},
+		{"2500m", 2.5, false},
+		{"500m", 0.5, false},
+		{" 1500m ", 1.5, false},
+		{"0", 0, false},
+		{"m", 0, true},
+		{"-4", 0, true},
+		{"4 vCPUs", 0, true},
+		{"4000M", 0, true},
+		{"Inf", 0, true},
+		{"NaN", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := parseCPU(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseCPU(%q) error = %v, want error %t", tt.in, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("parseCPU(%q) = %g, want %g", tt.in, got, tt.want)
+		}
+	}
+	// The error quotes the input as given, millicore suffix and all
+	for _, in := range []string{"4xm", "m"} {
+		if _, err := parseCPU(in); err == nil || !strings.Contains(err.Error(), fmt.Sprintf("invalid vCPUs %q", in)) {
+			t.Errorf("parseCPU(%q) error = %v, want it to quote %q", in, err, in)
+		}
+	}
+}
+
+// Fractional millicores and odd counts round up to the next legal vCPU count,
+// and the report shows the requested value next to the rounded one.
+func TestMillicoreRounding(t *testing.T) {
+	tests := []struct {
+		cpu  string
+		want string
+		note string
+	}{
+		{"2500m", "db-custom-4-6144", "Requested: 2500m = 2.5 vCPUs, rounded up to 4"},
+		{"3001m", "db-custom-4-6144", "Requested: 3001m = 3.001 vCPUs, rounded up to 4"},
+		{"3000m", "db-custom-4-6144", "Requested: 3000m = 3 vCPUs, rounded up to 4"},
+		{"3", "db-custom-4-6144", "Requested: 3 vCPUs, rounded up to 4"},
+		{"5", "db-custom-6-9216", "Requested: 5 vCPUs, rounded up to 6"},
+		{"1500m", "db-custom-2-3840", "Requested: 1500m = 1.5 vCPUs, rounded up to 2"},
+		{"4000m", "db-custom-4-6144", ""},
+		{"1000m", "db-custom-1-3840", ""},
+		{"1001m", "db-custom-2-3840", "Requested: 1001m = 1.001 vCPUs, rounded up to 2"},
+	}
+	for _, tt := range tests {
+		out, code := run(t, "suggest", "-cpu", tt.cpu)
+		if code != exitOK || !strings.Contains(out, "Tier: "+tt.want+"\n") {
+			t.Errorf("suggest -cpu %s (exit %d) = %q, want %s", tt.cpu, code, out, tt.want)
+		}
+		if tt.note == "" && strings.Contains(out, "Requested:") {
+			t.Errorf("suggest -cpu %s = %q, want no rounding note", tt.cpu, out)
+		}
+		if tt.note != "" && !strings.Contains(out, tt.note) {
+			t.Errorf("suggest -cpu %s = %q, want %q", tt.cpu, out, tt.note)
+		}
+	}
+	out, code := run(t, "suggest", "-cpu", "4000m", "-mem", "52Gi")
+	if code != exitOK || !strings.Contains(out, "Tier: db-custom-8-53248\n") {
+		t.Errorf("suggest -cpu 4000m -mem 52Gi (exit %d) = %q, want db-custom-8-53248", code, out)
+	}
+}
+
+// Memory and vCPU requests whose computed tier is invalid recommend the
+// nearest valid tier, with the computed one kept as a footnote.
+func TestInvalidComputationCorrected(t *testing.T) {
+	tests := []struct {
+		args    []string
+		want    string
+		raw     string
+		warning string
+	}{
+		{[]string{"-mem", "5000"}, "db-custom-4-5120", "db-custom-3-5120", ""},
+		{[]string{"-mem", "4608"}, "db-custom-4-4608", "db-custom-3-4608", ""},
+		{[]string{"-mem", "11000"}, "db-custom-8-11008", "db-custom-7-11008", ""},
+		{[]string{"-mem", "638976"}, "db-custom-96-638976", "db-custom-416-638976", ""},
+		{[]string{"-mem", "600000", "-ratio", "0.9"}, "db-custom-96-600064", "db-custom-651-600064", ""},
+		{[]string{"-cpu", "3"}, "db-custom-4-6144", "", ""},
+		{[]string{"-cpu", "100"}, "db-custom-96-153600", "db-custom-100-153600", "no valid MySQL Enterprise tier meets the request"},
+		{[]string{"-mem", "6144"}, "db-custom-4-6144", "", ""},
+		{[]string{"-cpu", "96", "-ratio", "6.5"}, "db-custom-96-638976", "", ""},
+	}
+	for _, tt := range tests {
+		args := append([]string{"suggest", "-o", "json"}, tt.args...)
+		out, code := run(t, args...)
+		var res struct {
+			Tier     string    `json:"tier"`
+			Valid    bool      `json:"valid"`
+			Raw      *TierInfo `json:"raw"`
+			Warnings []string  `json:"warnings"`
+		}
+		if err := json.Unmarshal([]byte(out), &res); err != nil {
+			t.Fatalf("go-calc %q: %v\n%s", args, err, out)
+		}
+		if res.Tier != tt.want || !res.Valid || code != exitOK {
+			t.Errorf("go-calc %q = %s (valid %t, exit %d), want valid %s", args, res.Tier, res.Valid, code, tt.want)
+		}
+		raw := ""
+		if res.Raw != nil && !res.Raw.Valid {
+			raw = res.Raw.Tier
+		}
+		if raw != tt.raw {
+			t.Errorf("go-calc %q computed %+v, want the invalid %q", args, res.Raw, tt.raw)
+		}
+		if warned := strings.Join(res.Warnings, "\n"); tt.warning == "" && strings.Contains(warned, "not valid") || !strings.Contains(warned, tt.warning) {
+			t.Errorf("go-calc %q warnings = %q, want %q", args, res.Warnings, tt.warning)
+		}
+
+		text, _ := run(t, append([]string{"suggest"}, tt.args...)...)
+		note := "Note: the computation gave " + tt.raw + ", which is not valid"
+		if strings.Contains(text, note) != (tt.raw != "") || !strings.Contains(text, "Tier: "+tt.want+"\n") {
+			t.Errorf("go-calc suggest %q = %q, want %s with the footnote only for a corrected tier", tt.args, text, tt.want)
+		}
+	}
+
+	out, code := run(t, "suggest", "-cpu", "100", "-strict")
+	if code == exitOK || strings.Contains(out, "Tier:") || !strings.Contains(out, "no valid MySQL Enterprise tier meets the request") {
+		t.Errorf("-cpu 100 -strict (exit %d) = %q, want an error and no tier", code, out)
+	}
+}
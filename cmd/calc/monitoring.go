@@ -0,0 +1,221 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Cloud Monitoring metrics -monitor reads. CPU utilization is a fraction of
+// the instance vCPUs; memory usage is in bytes.
+const (
+	cpuUtilMetric  = "cloudsql.googleapis.com/database/cpu/utilization"
+	memUsageMetric = "cloudsql.googleapis.com/database/memory/total_usage"
+)
+
+// monitorAlign is the period each sample covers. Samples are the peak of the
+// period, so short bursts are not averaged away.
+const monitorAlign = 5 * time.Minute
+
+// metricsSource returns the samples of a Cloud SQL metric for an instance
+// between start and end. monitoringAPI is the real one.
+type metricsSource interface {
+	samples(ctx context.Context, project, instance, metric string, start, end time.Time) ([]float64, error)
+}
+
+// metrics is the source -monitor uses.
+var metrics metricsSource = &monitoringAPI{client: http.DefaultClient, base: "https://monitoring.googleapis.com/v3"}
+
+// monitoringAPI reads time series from the Cloud Monitoring API with
+// Application Default Credentials.
+type monitoringAPI struct {
+	client *http.Client
+	base   string
+}
+
+func (m *monitoringAPI) samples(ctx context.Context, project, instance, metric string, start, end time.Time) ([]float64, error) {
+	client, err := adcClient(ctx, m.client)
+	if err != nil {
+		return nil, err
+	}
+	q := url.Values{}
+	q.Set("filter", fmt.Sprintf("metric.type=%q AND resource.labels.database_id=%q", metric, project+":"+instance))
+	q.Set("interval.startTime", start.UTC().Format(time.RFC3339))
+	q.Set("interval.endTime", end.UTC().Format(time.RFC3339))
+	q.Set("aggregation.alignmentPeriod", fmt.Sprintf("%ds", int(monitorAlign.Seconds())))
+	q.Set("aggregation.perSeriesAligner", "ALIGN_MAX")
+	var values []float64
+	for {
+		u := fmt.Sprintf("%s/projects/%s/timeSeries?%s", m.base, url.PathEscape(project), q.Encode())
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+		if err != nil {
+			return nil, err
+		}
+		page, err := m.page(client, req)
+		if err != nil {
+			return nil, err
+		}
+		for _, ts := range page.TimeSeries {
+			for _, p := range ts.Points {
+				if v, ok := p.Value.number(); ok {
+					values = append(values, v)
+				}
+			}
+		}
+		if page.NextPageToken == "" {
+			return values, nil
+		}
+		q.Set("pageToken", page.NextPageToken)
+	}
+}
+
+// timeSeriesPage is one page of a timeSeries.list response.
+type timeSeriesPage struct {
+	TimeSeries []struct {
+		Points []struct {
+			Value typedValue `json:"value"`
+		} `json:"points"`
+	} `json:"timeSeries"`
+	NextPageToken string `json:"nextPageToken"`
+}
+
+// typedValue is a Cloud Monitoring point value. int64Value is a JSON string.
+type typedValue struct {
+	DoubleValue *float64 `json:"doubleValue"`
+	Int64Value  *string  `json:"int64Value"`
+}
+
+func (v typedValue) number() (float64, bool) {
+	switch {
+	case v.DoubleValue != nil:
+		return *v.DoubleValue, true
+	case v.Int64Value != nil:
+		n, err := strconv.ParseInt(*v.Int64Value, 10, 64)
+		return float64(n), err == nil
+	}
+	return 0, false
+}
+
+func (m *monitoringAPI) page(client *http.Client, req *http.Request) (*timeSeriesPage, error) {
+	resp, err := callAPI(client, "Cloud Monitoring API", req, opts.maxRetries)
+	if err != nil {
+		return nil, err
+	}
+	switch resp.status {
+	case http.StatusOK:
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return nil, newAPIError(ErrAPIAuth, "Cloud Monitoring API: permission denied (needs monitoring.timeSeries.list): %s; %s", apiMessage(resp.body), authHint)
+	case http.StatusNotFound:
+		return nil, newAPIError(ErrAPINotFound, "Cloud Monitoring API: project not found: %s", apiMessage(resp.body))
+	default:
+		return nil, resp.failure("Cloud Monitoring API")
+	}
+	var page timeSeriesPage
+	if err := json.Unmarshal(resp.body, &page); err != nil {
+		return nil, fmt.Errorf("Cloud Monitoring API: invalid response: %w", err)
+	}
+	return &page, nil
+}
+
+// Monitoring is the observed utilization -monitor fed into rightsizing.
+type Monitoring struct {
+	Instance      string    `json:"instance"`
+	Window        string    `json:"window"`
+	Start         time.Time `json:"start"`
+	End           time.Time `json:"end"`
+	Percentile    float64   `json:"percentile"`
+	CPUPercentile float64   `json:"cpu_percentile"`
+	MemPercentile float64   `json:"mem_percentile"`
+	CPUSamples    int       `json:"cpu_samples"`
+	MemSamples    int       `json:"mem_samples"`
+	CPUPct        float64   `json:"cpu_util_pct"`
+	MemMB         float64   `json:"mem_used_mb"`
+	MemPct        float64   `json:"mem_util_pct"`
+}
+
+// percentiles describes the percentiles m was read at: p95, or CPU p99 and
+// memory p50 when they differ.
+func (m *Monitoring) percentiles() string {
+	if m.CPUPercentile == m.MemPercentile {
+		return percentileName(m.CPUPercentile)
+	}
+	return fmt.Sprintf("CPU %s and memory %s", percentileName(m.CPUPercentile), percentileName(m.MemPercentile))
+}
+
+// parseWindow parses a -window such as 14d, 36h, or 90m.
+func parseWindow(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.ParseFloat(days, 64)
+		if err != nil || n <= 0 {
+			return 0, fmt.Errorf("invalid -window %q: use a duration such as 14d or 36h", s)
+		}
+		return time.Duration(n * float64(24*time.Hour)), nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid -window %q: use a duration such as 14d or 36h", s)
+	}
+	return d, nil
+}
+
+// percentile returns the p-th percentile of values by the nearest-rank
+// method. values must not be empty.
+func percentile(values []float64, p float64) float64 {
+	sorted := slices.Clone(values)
+	slices.Sort(sorted)
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	return sorted[max(rank, 1)-1]
+}
+
+// observeUsage reads the -cpu-percentile CPU and -mem-percentile memory
+// utilization of the -instance over -window from Cloud Monitoring, as
+// percentages of tier t.
+func observeUsage(t Tier) (*Monitoring, error) {
+	if opts.instance == "" {
+		return nil, fmt.Errorf("-monitor needs -instance <project>:<instance>")
+	}
+	project, name, err := splitInstance(opts.instance, opts.project)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), apiTimeout)
+	defer cancel()
+	return readUsage(ctx, project, name, t)
+}
+
+// readUsage reads the -cpu-percentile CPU and -mem-percentile memory
+// utilization of an instance over -window, as percentages of its tier t.
+// setup has checked -window and the percentiles.
+func readUsage(ctx context.Context, project, name string, t Tier) (*Monitoring, error) {
+	window, _ := parseWindow(opts.window)
+	m := &Monitoring{Instance: project + ":" + name, Window: opts.window, Percentile: opts.percentile}
+	m.CPUPercentile, m.MemPercentile = usagePercentiles()
+	m.End = time.Now().UTC().Truncate(time.Minute)
+	m.Start = m.End.Add(-window)
+	cpu, err := metrics.samples(ctx, project, name, cpuUtilMetric, m.Start, m.End)
+	if err != nil {
+		return nil, err
+	}
+	mem, err := metrics.samples(ctx, project, name, memUsageMetric, m.Start, m.End)
+	if err != nil {
+		return nil, err
+	}
+	if len(cpu) == 0 || len(mem) == 0 {
+		return nil, fmt.Errorf("no utilization samples for %s in the last %s: check the instance name and -window", m.Instance, opts.window)
+	}
+	m.CPUSamples, m.MemSamples = len(cpu), len(mem)
+	// Rounded to 0.1%, and at least that so an idle instance still sizes;
+	// usage can briefly read above the tier memory.
+	m.CPUPct = max(math.Round(percentile(cpu, m.CPUPercentile)*1000)/10, 0.1)
+	m.MemMB = math.Round(percentile(mem, m.MemPercentile) / (1 << 20))
+	m.MemPct = min(max(math.Round(m.MemMB/float64(t.RAMMB)*1000)/10, 0.1), 100)
+	return m, nil
+}
@@ -0,0 +1,244 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeMetrics is a metricsSource of canned series keyed by metric type. It
+// records the window it was asked for.
+type fakeMetrics struct {
+	series     map[string][]float64
+	err        error
+	start, end time.Time
+}
+
+func (f *fakeMetrics) samples(ctx context.Context, project, instance, metric string, start, end time.Time) ([]float64, error) {
+	f.start, f.end = start, end
+	if _, ok := ctx.Deadline(); !ok {
+		return nil, errors.New("no deadline on the metrics context")
+	}
+	return f.series[metric], f.err
+}
+
+// useMetrics makes f the -monitor source for the rest of the test.
+func useMetrics(t *testing.T, f metricsSource) {
+	saved, savedOpts := metrics, opts
+	metrics = f
+	t.Cleanup(func() { metrics, opts = saved, savedOpts })
+}
+
+// series returns n samples of low followed by spikes samples of high.
+func series(n int, low float64, spikes int, high float64) []float64 {
+	s := slices.Repeat([]float64{low}, n)
+	return append(s, slices.Repeat([]float64{high}, spikes)...)
+}
+
+func TestObserveUsage(t *testing.T) {
+	const gb = 1 << 30
+	canned := map[string][]float64{
+		cpuUtilMetric:  series(90, 0.2, 10, 0.9),
+		memUsageMetric: series(96, 15*gb, 4, 29*gb),
+	}
+	tests := []struct {
+		percentile     float64
+		cpuPct, memPct float64
+		memMB          float64
+	}{
+		{95, 90, 50, 15360},
+		{90, 20, 50, 15360},
+		{97, 90, 96.7, 29696},
+		{100, 90, 96.7, 29696},
+		{50, 20, 50, 15360},
+	}
+	for _, tt := range tests {
+		f := &fakeMetrics{series: canned}
+		useMetrics(t, f)
+		opts.instance, opts.window, opts.percentile = "prod:orders", "14d", tt.percentile
+		m, err := observeUsage(Tier{CPUs: 8, RAMMB: 30720})
+		if err != nil {
+			t.Fatalf("p%g: %v", tt.percentile, err)
+		}
+		if m.CPUPct != tt.cpuPct || m.MemPct != tt.memPct || m.MemMB != tt.memMB {
+			t.Errorf("p%g = CPU %g%%, memory %g MB (%g%%), want CPU %g%%, memory %g MB (%g%%)", tt.percentile, m.CPUPct, m.MemMB, m.MemPct, tt.cpuPct, tt.memMB, tt.memPct)
+		}
+		if m.CPUSamples != 100 || m.MemSamples != 100 || m.Instance != "prod:orders" {
+			t.Errorf("p%g = %d CPU and %d memory samples for %s, want 100 each for prod:orders", tt.percentile, m.CPUSamples, m.MemSamples, m.Instance)
+		}
+		if got := f.end.Sub(f.start); got != 14*24*time.Hour || !m.Start.Equal(f.start) || !m.End.Equal(f.end) {
+			t.Errorf("p%g read %s to %s, reported %s to %s, want the 14d window", tt.percentile, f.start, f.end, m.Start, m.End)
+		}
+	}
+}
+
+func TestObserveUsageErrors(t *testing.T) {
+	idle := map[string][]float64{cpuUtilMetric: {0, 0}, memUsageMetric: {0, 40 << 30}}
+	tests := []struct {
+		name       string
+		f          *fakeMetrics
+		instance   string
+		window     string
+		percentile float64
+		wantErr    string
+	}{
+		{"no instance", &fakeMetrics{series: idle}, "", "14d", 95, "needs -instance"},
+		{"no project", &fakeMetrics{series: idle}, "orders", "14d", 95, "needs a project"},
+		{"no samples", &fakeMetrics{series: map[string][]float64{cpuUtilMetric: {0.5}}}, "prod:orders", "14d", 95, "no utilization samples"},
+		{"api error", &fakeMetrics{err: errors.New("Cloud Monitoring API: permission denied")}, "prod:orders", "14d", 95, "permission denied"},
+	}
+	for _, tt := range tests {
+		useMetrics(t, tt.f)
+		opts.instance, opts.project, opts.window, opts.percentile = tt.instance, "", tt.window, tt.percentile
+		if _, err := observeUsage(Tier{CPUs: 8, RAMMB: 30720}); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("%s: error = %v, want %q", tt.name, err, tt.wantErr)
+		}
+	}
+
+	// An idle instance still sizes, and usage above the tier memory is capped.
+	useMetrics(t, &fakeMetrics{series: idle})
+	opts.instance, opts.window, opts.percentile = "prod:orders", "1h", 100
+	m, err := observeUsage(Tier{CPUs: 8, RAMMB: 30720})
+	if err != nil || m.CPUPct != 0.1 || m.MemPct != 100 {
+		t.Errorf("idle instance = %+v, %v, want CPU 0.1%% and memory 100%%", m, err)
+	}
+}
+
+func TestRightsizeFromMonitoring(t *testing.T) {
+	useMetrics(t, &fakeMetrics{series: map[string][]float64{
+		cpuUtilMetric:  series(95, 0.2, 5, 0.9),
+		memUsageMetric: series(100, 15<<30, 0, 0),
+	}})
+	opts.monitor, opts.instance, opts.window, opts.percentile = true, "prod:orders", "7d", 95
+	res, err := runRightsize("db-custom-8-30720", Usage{HeadroomPct: 20})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Usage.CPUPct != 20 || res.Usage.MemPct != 50 || res.RequestedCPUs != 2 || res.RequestedMemMB != 19200 {
+		t.Errorf("rightsize from monitoring used %+v and required %g vCPUs, %g MB, want 20%% CPU, 50%% memory, 2 vCPUs, 19200 MB", *res.Usage, res.RequestedCPUs, res.RequestedMemMB)
+	}
+	out := res.humanText()
+	for _, want := range []string{"p95 of 100 CPU and 100 memory samples", "prod:orders over 7d", "CPU utilization: 20%, memory used: 15360 MB (50%)"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("rightsize from monitoring = %q, want %q", out, want)
+		}
+	}
+}
+
+// TestRightsizeSplitPercentiles sizes a spiky workload, with CPU bursts and
+// a few memory peaks, at one percentile and at separate ones.
+func TestRightsizeSplitPercentiles(t *testing.T) {
+	canned := map[string][]float64{
+		cpuUtilMetric:  series(90, 0.2, 10, 0.9),
+		memUsageMetric: series(96, 15<<30, 4, 29<<30),
+	}
+	tests := []struct {
+		percentile, cpuP, memP float64
+		want, read             string
+	}{
+		{99, 0, 0, "db-custom-10-37376", "p99 of 100 CPU"},
+		{50, 0, 0, "db-custom-4-19200", "p50 of 100 CPU"},
+		{95, 99, 50, "db-custom-10-19200", "CPU p99 and memory p50 of 100 CPU"},
+		{99, 0, 50, "db-custom-10-19200", "CPU p99 and memory p50 of 100 CPU"},
+		{50, 100, 0, "db-custom-10-19200", "CPU max and memory p50 of 100 CPU"},
+		{95, 99, 99, "db-custom-10-37376", "p99 of 100 CPU"},
+	}
+	for _, tt := range tests {
+		useMetrics(t, &fakeMetrics{series: canned})
+		opts.monitor, opts.instance, opts.window = true, "prod:orders", "14d"
+		opts.percentile, opts.cpuPercentile, opts.memPercentile = tt.percentile, tt.cpuP, tt.memP
+		res, err := runRightsize("db-custom-8-30720", Usage{HeadroomPct: 20})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.SuggestedTier != tt.want || !strings.Contains(res.humanText(), tt.read) {
+			t.Errorf("-percentile %g -cpu-percentile %g -mem-percentile %g = %s, %q, want %s read at %q", tt.percentile, tt.cpuP, tt.memP, res.SuggestedTier, res.humanText(), tt.want, tt.read)
+		}
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	values := []float64{15, 20, 35, 40, 50}
+	tests := []struct {
+		p, want float64
+	}{
+		{5, 15}, {20, 15}, {30, 20}, {40, 20}, {50, 35}, {95, 50}, {100, 50},
+	}
+	for _, tt := range tests {
+		if got := percentile(values, tt.p); got != tt.want {
+			t.Errorf("percentile(%v, %g) = %g, want %g", values, tt.p, got, tt.want)
+		}
+	}
+	if got := percentile([]float64{3, 1, 2}, 50); got != 2 {
+		t.Errorf("percentile of unsorted values = %g, want 2", got)
+	}
+}
+
+func TestParseWindow(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    time.Duration
+		wantErr bool
+	}{
+		{"14d", 14 * 24 * time.Hour, false},
+		{"0.5d", 12 * time.Hour, false},
+		{"36h", 36 * time.Hour, false},
+		{"90m", 90 * time.Minute, false},
+		{" 7d ", 7 * 24 * time.Hour, false},
+		{"0d", 0, true},
+		{"-1h", 0, true},
+		{"d", 0, true},
+		{"2w", 0, true},
+		{"", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := parseWindow(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("parseWindow(%q) = %s, %v, want %s, error %t", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+// TestMonitoringAPI reads a paged timeSeries.list response with double and
+// int64 points from a fake Cloud Monitoring API.
+func TestMonitoringAPI(t *testing.T) {
+	pages := map[string]string{
+		"":   `{"timeSeries":[{"points":[{"value":{"doubleValue":0.5}},{"value":{"doubleValue":0.25}}]}],"nextPageToken":"p2"}`,
+		"p2": `{"timeSeries":[{"points":[{"value":{"int64Value":"1073741824"}}]},{"points":[{"value":{}}]}],"nextPageToken":"p3"}`,
+		"p3": `{}`,
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/token" {
+			fmt.Fprint(w, `{"access_token":"fake-token","token_type":"Bearer","expires_in":3600}`)
+			return
+		}
+		q := r.URL.Query()
+		if strings.Contains(q.Get("filter"), "forbidden") {
+			http.Error(w, `{"error":{"message":"caller lacks permission"}}`, http.StatusForbidden)
+			return
+		}
+		if r.URL.Path != "/projects/prod/timeSeries" || !strings.Contains(q.Get("filter"), `resource.labels.database_id="prod:orders"`) || q.Get("aggregation.perSeriesAligner") != "ALIGN_MAX" {
+			http.Error(w, `{"error":{"message":"bad request"}}`, http.StatusBadRequest)
+			return
+		}
+		fmt.Fprint(w, pages[q.Get("pageToken")])
+	}))
+	defer srv.Close()
+	useFakeCredentials(t, srv.URL+"/token")
+
+	api := &monitoringAPI{client: srv.Client(), base: srv.URL}
+	end := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	got, err := api.samples(context.Background(), "prod", "orders", cpuUtilMetric, end.Add(-time.Hour), end)
+	if want := []float64{0.5, 0.25, 1 << 30}; err != nil || !slices.Equal(got, want) {
+		t.Errorf("samples = %v, %v, want %v", got, err, want)
+	}
+	if _, err := api.samples(context.Background(), "prod", "forbidden", cpuUtilMetric, end.Add(-time.Hour), end); err == nil || !strings.Contains(err.Error(), "permission denied (needs monitoring.timeSeries.list): caller lacks permission") {
+		t.Errorf("samples without permission: error = %v, want permission denied", err)
+	}
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	osReserveMB       = 1024   // memory left to the OS and the Cloud SQL agents
+	minConnections    = 100    // floor for max_connections on small tiers
+	maxConnections    = 100000 // Cloud SQL upper limit for max_connections
+	bufferPoolChunkMB = 128    // innodb_buffer_pool_chunk_size default
+)
+
+// MySQLConfig is a set of recommended MySQL memory settings for a tier.
+type MySQLConfig struct {
+	Tier              string `json:"tier"`
+	BufferPoolBytes   int64  `json:"innodb_buffer_pool_size"`
+	LogFileBytes      int64  `json:"innodb_log_file_size"`
+	MaxConnections    int    `json:"max_connections"`
+	DatabaseFlagsArgs string `json:"database_flags"`
+}
+
+// recommendMySQLConfig sizes the buffer pool at bufferPoolPct of the tier's
+// memory and gives the rest, less an OS reserve, to connections at perConnKB
+// each. With usable, the memory is what the overhead model leaves and the
+// reserve is part of it. On tiers too small for that split the buffer pool
+// shrinks so that minConnections still fit. A positive conns fixes
+// max_connections instead, and the buffer pool shrinks if needed to leave
+// room for them.
+func recommendMySQLConfig(t Tier, bufferPoolPct, perConnKB float64, conns int, usable bool) *MySQLConfig {
+	ram, reserve := float64(t.RAMMB), float64(osReserveMB)
+	if usable {
+		ram, reserve = rules.Overhead.usable(ram), 0
+	}
+	bp := ram * bufferPoolPct / 100
+	connMB := ram - bp - reserve
+	if conns > 0 {
+		conns = min(max(conns, minConnections), maxConnections)
+		bp = min(bp, ram-reserve-float64(conns)*perConnKB/1024)
+	} else {
+		if minMB := minConnections * perConnKB / 1024; connMB < minMB {
+			connMB = minMB
+			bp = ram - reserve - connMB
+		}
+		conns = min(int(connMB*1024/perConnKB), maxConnections)
+	}
+	bpMB := max(int(bp)/bufferPoolChunkMB*bufferPoolChunkMB, bufferPoolChunkMB)
+	logMB := min(max(bpMB/8, 256), 4096)
+	c := &MySQLConfig{
+		Tier:            t.String(),
+		BufferPoolBytes: int64(bpMB) << 20,
+		LogFileBytes:    int64(logMB) << 20,
+		MaxConnections:  conns,
+	}
+	c.DatabaseFlagsArgs = fmt.Sprintf("--database-flags=innodb_buffer_pool_size=%d,innodb_log_file_size=%d,max_connections=%d",
+		c.BufferPoolBytes, c.LogFileBytes, c.MaxConnections)
+	return c
+}
+
+// addMySQLConfig recommends MySQL settings for the tier the result resolves
+// to.
+func addMySQLConfig(res *Result) error {
+	if rules.Engine != "mysql" {
+		return fmt.Errorf("-mysql-config applies to the mysql engine only, not %s", rules.Engine)
+	}
+	t, err := ParseTier(res.resolvedTier())
+	if err != nil {
+		return fmt.Errorf("-mysql-config needs a resolved tier")
+	}
+	if t.Shared() {
+		return fmt.Errorf("-mysql-config does not support shared-core tier %s", t)
+	}
+	// With -connections the settings match the sizing request: its
+	// connection count and the memory reserved for each connection.
+	perConnKB := opts.perConnKB
+	if opts.connections > 0 {
+		perConnKB = opts.connMemKB
+	}
+	c := recommendMySQLConfig(t, opts.bufferPoolPct, perConnKB, opts.connections, opts.usable)
+	res.MySQLConfig = c
+	instance, project := patchTarget(opts.instance, opts.project)
+	if project != "" {
+		project = " --project=" + project
+	}
+	var sb strings.Builder
+	of := ""
+	if opts.usable {
+		of = " of usable memory"
+	}
+	fmt.Fprintf(&sb, "MySQL settings for %s (buffer pool %g%%%s, %g KB per connection):\n", t, opts.bufferPoolPct, of, perConnKB)
+	fmt.Fprintf(&sb, "  innodb_buffer_pool_size  %d (%d MB)\n", c.BufferPoolBytes, c.BufferPoolBytes>>20)
+	fmt.Fprintf(&sb, "  innodb_log_file_size     %d (%d MB)\n", c.LogFileBytes, c.LogFileBytes>>20)
+	fmt.Fprintf(&sb, "  max_connections          %d\n", c.MaxConnections)
+	fmt.Fprintf(&sb, "  gcloud sql instances patch %s %s%s\n", instance, c.DatabaseFlagsArgs, project)
+	sb.WriteString("  Note: --database-flags replaces every flag set on the instance; include any others you rely on.\n")
+	res.printf("%s", sb.String())
+	return nil
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"time"
+)
+
+// streamOut is where -o ndjson writes batch records and fleet instances as
+// they are processed.
+var streamOut io.Writer = os.Stdout
+
+// streaming reports whether batch and fleet runs write each record as it is
+// processed instead of keeping them for the result, as -o ndjson does.
+func streaming() bool {
+	return opts.output == "ndjson"
+}
+
+// ndjsonRecord is a batch record line of a -o ndjson stream.
+type ndjsonRecord struct {
+	Type string `json:"type"` // "record"
+	*Result
+}
+
+// ndjsonInstance is a fleet instance line of a -o ndjson stream.
+type ndjsonInstance struct {
+	Type string `json:"type"` // "instance"
+	*FleetInstance
+}
+
+// ndjsonSummary is the last line of a -o ndjson stream: the batch summary or
+// the fleet totals, and the error that ended the run early, if any.
+type ndjsonSummary struct {
+	Type    string        `json:"type"` // "summary"
+	Mode    string        `json:"mode"`
+	Source  string        `json:"source"`
+	Summary *BatchSummary `json:"summary,omitempty"`
+	Totals  *FleetTotals  `json:"totals,omitempty"`
+	Error   string        `json:"error,omitempty"`
+	Version *BuildInfo    `json:"version,omitempty"`
+}
+
+// writeJSONLine writes v as a single line of JSON.
+func writeJSONLine(w io.Writer, v any) error {
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	return enc.Encode(v)
+}
+
+// writeNDJSON closes a -o ndjson stream with the summary of r. Other modes
+// have a single result, written as one line.
+func writeNDJSON(w io.Writer, r report) error {
+	switch r := r.(type) {
+	case *BatchResult:
+		return writeJSONLine(w, ndjsonSummary{Type: "summary", Mode: r.Mode, Source: r.Source,
+			Summary: &r.Summary, Error: r.Error, Version: r.Version})
+	case *FleetResult:
+		return writeJSONLine(w, ndjsonSummary{Type: "summary", Mode: r.Mode, Source: r.Source,
+			Totals: &r.Totals, Error: r.Error, Version: r.Version})
+	case *WatchResult:
+		// The events and pass summaries are already written
+		if r.Error == "" {
+			return nil
+		}
+		return writeJSONLine(w, &WatchEvent{Type: "error", Time: time.Now().UTC(), Error: r.Error})
+	}
+	return writeJSONLine(w, r)
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// ndjsonLine is the part of a -o ndjson line the tests read.
+type ndjsonLine struct {
+	Type    string `json:"type"`
+	Line    int    `json:"line"`
+	Name    string `json:"name"`
+	Tier    string `json:"tier"`
+	Error   string `json:"error"`
+	Summary *struct {
+		Total, Valid, Invalid, Errors int
+	} `json:"summary"`
+	Totals *struct {
+		Instances int `json:"instances"`
+	} `json:"totals"`
+}
+
+// readNDJSON decodes each line of out, failing on a line that is not a
+// single JSON object.
+func readNDJSON(t *testing.T, out string) []ndjsonLine {
+	t.Helper()
+	var lines []ndjsonLine
+	for _, text := range strings.Split(strings.TrimSuffix(out, "\n"), "\n") {
+		var l ndjsonLine
+		if err := json.Unmarshal([]byte(text), &l); err != nil {
+			t.Fatalf("line %q: %v", text, err)
+		}
+		lines = append(lines, l)
+	}
+	return lines
+}
+
+func TestNDJSONBatch(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tiers.txt")
+	input := "db-custom-4-16384\n# comment\n\ndb-custom-3-16384\nnonsense\ndb-n1-standard-2\n"
+	if err := os.WriteFile(path, []byte(input), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	out, code := run(t, "batch", "-o", "ndjson", path)
+	lines := readNDJSON(t, out)
+	want := []ndjsonLine{
+		{Type: "record", Line: 1, Tier: "db-custom-4-16384"},
+		{Type: "record", Line: 4, Tier: "db-custom-3-16384"},
+		{Type: "record", Line: 5},
+		{Type: "record", Line: 6, Tier: "db-custom-2-7680"},
+		{Type: "summary"},
+	}
+	if len(lines) != len(want) {
+		t.Fatalf("batch -o ndjson wrote %d lines, want %d:\n%s", len(lines), len(want), out)
+	}
+	for i, w := range want {
+		if l := lines[i]; l.Type != w.Type || l.Line != w.Line || l.Tier != w.Tier {
+			t.Errorf("line %d = %s line %d %s, want %s line %d %s", i+1, l.Type, l.Line, l.Tier, w.Type, w.Line, w.Tier)
+		}
+	}
+	if lines[2].Error == "" {
+		t.Errorf("record for nonsense has no error")
+	}
+	if s := lines[4].Summary; s == nil || s.Total != 4 || s.Valid != 2 || s.Invalid != 1 || s.Errors != 1 {
+		t.Errorf("summary = %+v, want 4 tiers: 2 valid, 1 invalid, 1 error", s)
+	}
+	if code != exitParse {
+		t.Errorf("batch -o ndjson exited %d, want %d as with -o json", code, exitParse)
+	}
+
+	long := filepath.Join(t.TempDir(), "long.txt")
+	if err := os.WriteFile(long, []byte("db-custom-4-16384\n"+strings.Repeat("x", maxLineBytes+1)+"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	out, _ = run(t, "batch", "-o", "ndjson", long)
+	if lines := readNDJSON(t, out); len(lines) != 2 || lines[1].Type != "summary" {
+		t.Errorf("batch -o ndjson with a long line = %q, want the record and a summary", out)
+	}
+}
+
+func TestNDJSONInstances(t *testing.T) {
+	out, _ := run(t, "instances", "-o", "ndjson", filepath.Join("testdata", "report-instances.json"))
+	lines := readNDJSON(t, out)
+	if len(lines) != 6 {
+		t.Fatalf("instances -o ndjson wrote %d lines, want 5 instances and a summary:\n%s", len(lines), out)
+	}
+	for _, l := range lines[:5] {
+		if l.Type != "instance" || l.Name == "" {
+			t.Errorf("instance line = %+v, want an instance", l)
+		}
+	}
+	if s := lines[5]; s.Type != "summary" || s.Totals == nil || s.Totals.Instances != 5 {
+		t.Errorf("summary line = %+v, want totals of 5 instances", s)
+	}
+}
+
+// writeTierLines writes n generated tier lines to a file in dir.
+func writeTierLines(tb testing.TB, dir string, n int) string {
+	path := filepath.Join(dir, fmt.Sprintf("tiers-%d.txt", n))
+	f, err := os.Create(path)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	w := bufio.NewWriter(f)
+	for i := range n {
+		cpu := 2 * (1 + i%48)
+		fmt.Fprintf(w, "db-custom-%d-%d\n", cpu, cpu*3840)
+	}
+	if err := w.Flush(); err != nil {
+		tb.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		tb.Fatal(err)
+	}
+	return path
+}
+
+// BenchmarkBatchNDJSON streams generated batches of up to a million lines.
+// The heap-MB left once a run ends stays flat as the input grows, since no
+// record is kept.
+func BenchmarkBatchNDJSON(b *testing.B) {
+	defer func(output string, w io.Writer) { opts.output, streamOut = output, w }(opts.output, streamOut)
+	opts.output, streamOut = "ndjson", io.Discard
+	dir := b.TempDir()
+	for _, n := range []int{10_000, 100_000, 1_000_000} {
+		path := writeTierLines(b, dir, n)
+		b.Run(fmt.Sprint(n), func(b *testing.B) {
+			var heap uint64
+			for range b.N {
+				res, err := runBatch(path)
+				if err != nil || res.Summary.Total != n || len(res.Records) != 0 {
+					b.Fatalf("runBatch = %d records of %d, %v", len(res.Records), res.Summary.Total, err)
+				}
+				runtime.GC()
+				var m runtime.MemStats
+				runtime.ReadMemStats(&m)
+				heap = max(heap, m.HeapAlloc)
+				runtime.KeepAlive(res)
+			}
+			b.ReportMetric(float64(heap)/(1<<20), "heap-MB")
+		})
+	}
+}
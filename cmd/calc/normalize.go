@@ -0,0 +1,115 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// NormalizedTier is one input of normalize and its canonical form.
+type NormalizedTier struct {
+	Line  int    `json:"line"`
+	Input string `json:"input"`
+	Tier  string `json:"tier,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+// NormalizeResult is the outcome of normalize or -batch -normalize.
+type NormalizeResult struct {
+	Mode    string            `json:"mode"`
+	Source  string            `json:"source"` // file name, "-" for stdin, or "arguments"
+	Records []*NormalizedTier `json:"records"`
+	Errors  int               `json:"errors"`
+	Error   string            `json:"error,omitempty"`
+	Version *BuildInfo        `json:"version,omitempty"`
+}
+
+func (n *NormalizeResult) setError(err error) {
+	n.Error = err.Error()
+}
+
+func (n *NormalizeResult) setVersion(bi *BuildInfo) {
+	n.Version = bi
+}
+
+// humanText prints one canonical tier per line, so the output can replace
+// the input. Inputs that could not be normalized are reported on stderr.
+func (n *NormalizeResult) humanText() string {
+	var sb strings.Builder
+	for _, r := range n.Records {
+		if r.Error == "" {
+			fmt.Fprintln(&sb, r.Tier)
+		}
+	}
+	return sb.String()
+}
+
+func (n *NormalizeResult) csvRecords() [][]string {
+	records := [][]string{{"line", "input", "tier", "error"}}
+	for _, r := range n.Records {
+		records = append(records, []string{strconv.Itoa(r.Line), r.Input, r.Tier, r.Error})
+	}
+	return records
+}
+
+// warnings returns why each failed input could not be normalized.
+func (n *NormalizeResult) warnings() []string {
+	label := "line"
+	if n.Source == argsSource {
+		label = "arg"
+	}
+	var ws []string
+	for _, r := range n.Records {
+		if r.Error != "" {
+			ws = append(ws, fmt.Sprintf("%s %d: %s: %s", label, r.Line, r.Input, r.Error))
+		}
+	}
+	return ws
+}
+
+// exitCode is exitParse if any input could not be normalized.
+func (n *NormalizeResult) exitCode() int {
+	if n.Errors > 0 {
+		return exitParse
+	}
+	return exitOK
+}
+
+// add normalizes one tier. The canonical form must parse back to the same
+// tier; anything that does not is an error rather than a guess.
+func (n *NormalizeResult) add(line int, text string) {
+	r := &NormalizedTier{Line: line, Input: text}
+	t, err := ParseTier(text)
+	if err == nil {
+		if back, berr := ParseTier(t.String()); berr != nil || back != t {
+			err = fmt.Errorf("%q has no canonical form", text)
+		}
+	}
+	if err != nil {
+		r.Error = err.Error()
+		n.Errors++
+	} else {
+		r.Tier = t.String()
+	}
+	n.Records = append(n.Records, r)
+}
+
+// runNormalize prints the canonical form of each tier argument, or of each
+// line of stdin when the only argument is "-".
+func runNormalize(args []string) (*NormalizeResult, error) {
+	if len(args) == 1 && args[0] == "-" {
+		return runNormalizeFile("-")
+	}
+	n := &NormalizeResult{Mode: "normalize", Source: argsSource, Records: []*NormalizedTier{}}
+	for i, arg := range args {
+		n.add(i+1, arg)
+	}
+	return n, nil
+}
+
+// runNormalizeFile prints the canonical form of each tier in path, one per
+// line, or of stdin when path is "-".
+func runNormalizeFile(path string) (*NormalizeResult, error) {
+	n := &NormalizeResult{Mode: "normalize", Source: path, Records: []*NormalizedTier{}}
+	return n, readLines(path, n.add)
+}
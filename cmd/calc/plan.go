@@ -0,0 +1,153 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// PlanStep is one resize of a plan: the tier moved to, from the tier before.
+type PlanStep struct {
+	From string `json:"from"`
+	*TierInfo
+	Delta         Delta  `json:"delta"`
+	GcloudCommand string `json:"gcloud_command,omitempty"`
+}
+
+// between reports whether v lies between a and b, inclusive, in either order.
+func between(v, a, b int) bool {
+	return min(a, b) <= v && v <= max(a, b)
+}
+
+// withinFactor reports whether a and b differ by at most factor times.
+func withinFactor(a, b int, factor float64) bool {
+	return float64(max(a, b)) <= float64(min(a, b))*factor
+}
+
+// planStep reports whether a plan may move from a to b: each resource moves
+// toward target without passing it, by at most factor times.
+func planStep(a, b, target Tier, factor float64) bool {
+	return between(b.CPUs, a.CPUs, target.CPUs) && between(b.RAMMB, a.RAMMB, target.RAMMB) &&
+		withinFactor(a.CPUs, b.CPUs, factor) && withinFactor(a.RAMMB, b.RAMMB, factor)
+}
+
+// planTiers returns the fewest resizes from current to target, through the
+// valid known tiers and standard-ratio shapes, where no step changes vCPUs
+// or memory by more than factor times. Of plans with as few steps, the one
+// taking the largest steps first wins. ok is false when there is none.
+func planTiers(current, target Tier, factor float64) (path []Tier, ok bool) {
+	var nodes []Tier
+	for _, t := range slices.Concat(knownTiers, standardMatrix(), []Tier{target}) {
+		if t.Valid() && t != current && !slices.Contains(nodes, t) {
+			nodes = append(nodes, t)
+		}
+	}
+	// Breadth-first from current, trying the furthest tiers first.
+	slices.SortFunc(nodes, func(a, b Tier) int {
+		da, db := compareTiers(current, a).distance(1, 1), compareTiers(current, b).distance(1, 1)
+		switch {
+		case da > db:
+			return -1
+		case da < db:
+			return 1
+		}
+		return 0
+	})
+	prev := map[Tier]Tier{}
+	queue := []Tier{current}
+	for len(queue) > 0 {
+		at := queue[0]
+		queue = queue[1:]
+		if at == target {
+			for t := target; t != current; t = prev[t] {
+				path = append(path, t)
+			}
+			slices.Reverse(path)
+			return path, true
+		}
+		for _, t := range nodes {
+			if _, seen := prev[t]; !seen && planStep(at, t, target, factor) {
+				prev[t] = at
+				queue = append(queue, t)
+			}
+		}
+	}
+	return nil, false
+}
+
+// runPlanPair runs plan on a "current target" pair.
+func runPlanPair(input string) (*Result, error) {
+	parts := splitList([]string{input})
+	if len(parts) != 2 {
+		return newResult("plan"), fmt.Errorf("usage: -plan '<current-tier> <target-tier>'")
+	}
+	return runPlan(parts[0], parts[1])
+}
+
+// runPlan plans the resizes from current to target, up or down, without any
+// step changing vCPUs or memory by more than -max-factor times.
+func runPlan(current, target string) (*Result, error) {
+	res := newResult("plan")
+	res.InputTier = current
+	if opts.maxFactor <= 1 {
+		return res, fmt.Errorf("-max-factor must be above 1, got %g", opts.maxFactor)
+	}
+	curr, err := ParseTier(current)
+	if err != nil {
+		return res, fmt.Errorf("invalid current tier: %w", err)
+	}
+	tgt, err := ParseTier(target)
+	if err != nil {
+		return res, fmt.Errorf("invalid target tier: %w", err)
+	}
+	if curr.Shared() || tgt.Shared() {
+		return res, fmt.Errorf("plan needs db-custom tiers; resize a shared-core tier in one step")
+	}
+	if err := tgt.Validate(); err != nil {
+		return res, fmt.Errorf("target tier %s is not valid: %w", tgt, err)
+	}
+	res.TierInfo = describe(curr)
+	res.Compared = describe(tgt)
+	res.noteEquivalent(current, curr)
+	res.noteEquivalent(target, tgt)
+	delta := compareTiers(curr, tgt)
+	res.Delta = &delta
+	res.Plan = []*PlanStep{}
+	if curr == tgt {
+		res.Message = "already at the target tier"
+		res.printf("%s is already the target tier.\n", curr)
+		return res, nil
+	}
+	path, ok := planTiers(curr, tgt, opts.maxFactor)
+	if !ok {
+		return res, fmt.Errorf("no plan from %s to %s changes vCPUs and memory by at most %gx per step: allow larger steps with -max-factor", curr, tgt, opts.maxFactor)
+	}
+	direction := delta.direction()
+	if direction == "sideways" {
+		direction = "resize"
+	}
+	steps := fmt.Sprintf("%d steps", len(path))
+	if len(path) == 1 {
+		steps = "one step"
+	}
+	res.printf("Plan to %s from %s to %s in %s (at most %gx vCPUs and memory per step):\n", direction, curr, tgt, steps, opts.maxFactor)
+	from, fromInfo := curr, res.TierInfo
+	for i, t := range path {
+		step := &PlanStep{From: from.String(), TierInfo: describe(t), Delta: compareTiers(from, t)}
+		line := fmt.Sprintf("  %d. %s -> %s: %s", i+1, from, t, step.Delta)
+		if step.Cost != nil && fromInfo.Cost != nil {
+			line += ", " + prices.deltaText(step.Cost.Monthly-fromInfo.Cost.Monthly)
+		}
+		res.println(line)
+		if opts.gcloud {
+			step.GcloudCommand = gcloudPatchCommand(opts.instance, opts.project, t.String())
+			res.printf("     %s\n", step.GcloudCommand)
+		}
+		res.Plan = append(res.Plan, step)
+		from, fromInfo = t, step.TierInfo
+	}
+	if !res.Valid {
+		res.warnf(CodeCurrentInvalid, "current tier %s is not valid: %s", curr, strings.Join(res.Reasons, "; "))
+	}
+	return res, nil
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestPlanTiers(t *testing.T) {
+	useRules(t, "mysql", "enterprise")
+	tests := []struct {
+		current, target Tier
+		factor          float64
+		steps           int
+	}{
+		{Tier{CPUs: 2, RAMMB: 7680}, Tier{CPUs: 16, RAMMB: 61440}, 2, 3},
+		{Tier{CPUs: 16, RAMMB: 61440}, Tier{CPUs: 2, RAMMB: 7680}, 2, 3},
+		{Tier{CPUs: 16, RAMMB: 61440}, Tier{CPUs: 2, RAMMB: 7680}, 4, 2},
+		{Tier{CPUs: 16, RAMMB: 61440}, Tier{CPUs: 2, RAMMB: 7680}, 8, 1},
+		{Tier{CPUs: 8, RAMMB: 30720}, Tier{CPUs: 8, RAMMB: 53248}, 2, 1},
+		{Tier{CPUs: 2, RAMMB: 7680}, Tier{CPUs: 2, RAMMB: 7936}, 1.01, 0},
+	}
+	for _, tt := range tests {
+		path, ok := planTiers(tt.current, tt.target, tt.factor)
+		if ok != (tt.steps > 0) || len(path) != tt.steps {
+			t.Errorf("planTiers(%s, %s, %g) = %v (%t), want %d steps", tt.current, tt.target, tt.factor, path, ok, tt.steps)
+			continue
+		}
+		from := tt.current
+		for _, step := range path {
+			if !step.Valid() || !planStep(from, step, tt.target, tt.factor) {
+				t.Errorf("planTiers(%s, %s, %g) steps from %s to %s", tt.current, tt.target, tt.factor, from, step)
+			}
+			from = step
+		}
+		if ok && from != tt.target {
+			t.Errorf("planTiers(%s, %s, %g) ends at %s", tt.current, tt.target, tt.factor, from)
+		}
+	}
+}
+
+func TestPlanCommand(t *testing.T) {
+	tests := []struct {
+		args  []string
+		steps int
+		code  int
+	}{
+		{[]string{"db-custom-2-7680", "db-custom-16-61440"}, 3, exitOK},
+		{[]string{"db-custom-16-61440", "db-custom-2-7680", "-max-factor", "4"}, 2, exitOK},
+		{[]string{"db-custom-4-15360", "db-custom-4-15360"}, 0, exitOK},
+		{[]string{"db-custom-2-7680", "db-custom-3-7680"}, 0, exitInvalid},
+		{[]string{"db-custom-2-7680", "db-custom-2-7936", "-max-factor", "1.01"}, 0, exitUsage},
+		{[]string{"db-custom-2-7680", "db-custom-4-15360", "-max-factor", "1"}, 0, exitUsage},
+	}
+	for _, tt := range tests {
+		out, code := run(t, append([]string{"plan", "-o", "json"}, tt.args...)...)
+		var res struct {
+			Plan []*PlanStep `json:"plan"`
+		}
+		if err := json.Unmarshal([]byte(out), &res); err != nil {
+			t.Fatalf("plan %q: %v\n%s", tt.args, err, out)
+		}
+		if len(res.Plan) != tt.steps || code != tt.code {
+			t.Errorf("plan %q = %d steps (exit %d), want %d (exit %d)", tt.args, len(res.Plan), code, tt.steps, tt.code)
+		}
+	}
+}
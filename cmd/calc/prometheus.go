@@ -0,0 +1,71 @@
+package main
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// serveMetrics are the Prometheus metrics serve exports on /metrics.
+type serveMetrics struct {
+	registry *prometheus.Registry
+	requests *prometheus.CounterVec
+	latency  *prometheus.HistogramVec
+}
+
+// newServeMetrics registers the request metrics and the gauges describing
+// the loaded tier catalog.
+func newServeMetrics() *serveMetrics {
+	m := &serveMetrics{
+		registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "gocalc_requests_total",
+			Help: "Requests answered, by operation and result: valid, invalid, or error.",
+		}, []string{"operation", "result"}),
+		latency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "gocalc_request_duration_seconds",
+			Help:    "Time taken to answer a request, by operation.",
+			Buckets: prometheus.ExponentialBuckets(0.0001, 4, 8),
+		}, []string{"operation"}),
+	}
+	tiers := prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "gocalc_known_tiers",
+		Help: "Tiers in the loaded known tier catalog.",
+	}, func() float64 { return float64(len(knownTiers)) })
+	catalog := prometheus.NewGauge(prometheus.GaugeOpts{
+		Name:        "gocalc_tier_data_info",
+		Help:        "The revision of the tier rules and where the known tier catalog came from; always 1.",
+		ConstLabels: prometheus.Labels{"tier_rules": rulesVersion, "catalog": catalogSource()},
+	})
+	catalog.Set(1)
+	m.registry.MustRegister(m.requests, m.latency, tiers, catalog)
+	return m
+}
+
+// catalogSource names where the known tier catalog came from: the embedded
+// one, -tiers-file, or -tiers-from.
+func catalogSource() string {
+	switch {
+	case opts.tiersFile != "":
+		return "tiers-file"
+	case opts.tiersFrom != "":
+		return "tiers-from"
+	}
+	return "embedded"
+}
+
+// observe records a request to operation that took since start. A nil m
+// records nothing.
+func (m *serveMetrics) observe(operation, result string, start time.Time) {
+	if m == nil {
+		return
+	}
+	m.requests.WithLabelValues(operation, result).Inc()
+	m.latency.WithLabelValues(operation).Observe(time.Since(start).Seconds())
+}
+
+func (m *serveMetrics) handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// scrape returns the /metrics exposition of srv.
+func scrape(t *testing.T, srv *httptest.Server) string {
+	t.Helper()
+	resp, err := srv.Client().Get(srv.URL + "/metrics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil || resp.StatusCode != http.StatusOK {
+		t.Fatalf("GET /metrics = %s, %v", resp.Status, err)
+	}
+	return string(body)
+}
+
+func TestServeMetrics(t *testing.T) {
+	srv := httptest.NewServer(newServeMux(newServeMetrics()))
+	defer srv.Close()
+	for _, path := range []string{
+		"/v1/validate?tier=db-custom-4-16384",
+		"/v1/validate?tier=db-custom-8-53248",
+		"/v1/validate?tier=db-custom-3-16384",
+		"/v1/next?tier=nonsense",
+		"/v1/suggest?cpu=4",
+	} {
+		resp, err := srv.Client().Get(srv.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+	out := scrape(t, srv)
+	for _, want := range []string{
+		`gocalc_requests_total{operation="validate",result="valid"} 2`,
+		`gocalc_requests_total{operation="validate",result="invalid"} 1`,
+		`gocalc_requests_total{operation="next",result="error"} 1`,
+		`gocalc_requests_total{operation="suggest",result="valid"} 1`,
+		`gocalc_request_duration_seconds_count{operation="validate"} 3`,
+		`gocalc_request_duration_seconds_bucket{operation="next",le="+Inf"} 1`,
+		fmt.Sprintf("gocalc_known_tiers %d", len(knownTiers)),
+		fmt.Sprintf(`gocalc_tier_data_info{catalog="embedded",tier_rules=%q} 1`, rulesVersion),
+	} {
+		if !strings.Contains(out, want+"\n") {
+			t.Errorf("/metrics has no %s:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, `operation="check-downgrade"`) {
+		t.Errorf("/metrics counts check-downgrade, which had no requests:\n%s", out)
+	}
+
+	// A second scrape counts nothing new.
+	if again := scrape(t, srv); !strings.Contains(again, `gocalc_requests_total{operation="validate",result="valid"} 2`+"\n") {
+		t.Errorf("second scrape changed the counts:\n%s", again)
+	}
+}
+
+func TestServeWithoutMetrics(t *testing.T) {
+	srv := httptest.NewServer(newServeMux(nil))
+	defer srv.Close()
+	resp, err := srv.Client().Get(srv.URL + "/metrics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("GET /metrics with -metrics=false = %s, want 404", resp.Status)
+	}
+}
+
+func TestCatalogSource(t *testing.T) {
+	defer func(file, from string) { opts.tiersFile, opts.tiersFrom = file, from }(opts.tiersFile, opts.tiersFrom)
+	tests := []struct{ file, from, want string }{
+		{"", "", "embedded"},
+		{"tiers.json", "", "tiers-file"},
+		{"", "gcloud", "tiers-from"},
+	}
+	for _, tt := range tests {
+		opts.tiersFile, opts.tiersFrom = tt.file, tt.from
+		if got := catalogSource(); got != tt.want {
+			t.Errorf("catalogSource with -tiers-file %q -tiers-from %q = %s, want %s", tt.file, tt.from, got, tt.want)
+		}
+	}
+}
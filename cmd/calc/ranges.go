@@ -0,0 +1,247 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"unicode"
+)
+
+// CPURange is the legal memory of one vCPU count: the smallest and largest
+// valid tiers, and the tiers at the standard and highmem ratios when those
+// are valid.
+type CPURange struct {
+	CPUs     int       `json:"cpus"`
+	MinRAMMB int       `json:"min_ram_mb,omitempty"`
+	MaxRAMMB int       `json:"max_ram_mb,omitempty"`
+	Min      *TierInfo `json:"min,omitempty"`
+	Max      *TierInfo `json:"max,omitempty"`
+	Standard *TierInfo `json:"standard,omitempty"`
+	Highmem  *TierInfo `json:"highmem,omitempty"`
+	Note     string    `json:"note,omitempty"`
+}
+
+// CPURangeResult is the outcome of cpu-range.
+type CPURangeResult struct {
+	Mode    string      `json:"mode"`
+	Engine  string      `json:"engine"`
+	Edition string      `json:"edition"`
+	Ranges  []*CPURange `json:"ranges"`
+	Error   string      `json:"error,omitempty"`
+	Version *BuildInfo  `json:"version,omitempty"`
+}
+
+func (r *CPURangeResult) setError(err error) {
+	r.Error = err.Error()
+}
+
+func (r *CPURangeResult) setVersion(bi *BuildInfo) {
+	r.Version = bi
+}
+
+// exitCode is exitInvalid when a vCPU count is not legal.
+func (r *CPURangeResult) exitCode() int {
+	for _, cr := range r.Ranges {
+		if cr.Note != "" {
+			return exitInvalid
+		}
+	}
+	return exitOK
+}
+
+// tierName returns the tier of info, or "-" when there is none.
+func tierName(info *TierInfo) string {
+	if info == nil {
+		return "-"
+	}
+	return info.Tier
+}
+
+func (r *CPURangeResult) humanText() string {
+	var sb strings.Builder
+	tw := tabwriter.NewWriter(&sb, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "vCPUs\tMin GB\tMax GB\tMin tier\tMax tier\tStandard (%g GB/vCPU)\tHighmem (%g GB/vCPU)\n", ratioClasses["standard"], ratioClasses["highmem"])
+	var notes []string
+	for _, cr := range r.Ranges {
+		if cr.Note != "" {
+			fmt.Fprintf(tw, "%d\t-\t-\t-\t-\t-\t-\n", cr.CPUs)
+			notes = append(notes, fmt.Sprintf("%d vCPUs: %s\n", cr.CPUs, cr.Note))
+			continue
+		}
+		fmt.Fprintf(tw, "%d\t%.2f\t%.2f\t%s\t%s\t%s\t%s\n", cr.CPUs, float64(cr.MinRAMMB)/1024, float64(cr.MaxRAMMB)/1024,
+			tierName(cr.Min), tierName(cr.Max), tierName(cr.Standard), tierName(cr.Highmem))
+	}
+	tw.Flush()
+	sb.WriteString(strings.Join(notes, ""))
+	fmt.Fprintf(&sb, "Memory is %s per vCPU in %d MB steps, at least %d MB and at most %d MB for %s %s\n",
+		rules.ratioRange(), rules.RAMStepMB, rules.MinRAMMB, rules.MaxRAMMB, rules.Name, rules.editionName())
+	return sb.String()
+}
+
+func (r *CPURangeResult) csvRecords() [][]string {
+	records := [][]string{{"cpus", "min_ram_mb", "max_ram_mb", "min_tier", "max_tier", "standard_tier", "highmem_tier", "note"}}
+	name := func(info *TierInfo) string {
+		if info == nil {
+			return ""
+		}
+		return info.Tier
+	}
+	for _, cr := range r.Ranges {
+		records = append(records, []string{
+			strconv.Itoa(cr.CPUs),
+			strconv.Itoa(cr.MinRAMMB),
+			strconv.Itoa(cr.MaxRAMMB),
+			name(cr.Min), name(cr.Max), name(cr.Standard), name(cr.Highmem),
+			cr.Note,
+		})
+	}
+	return records
+}
+
+// cpuRange returns the legal memory range of cpu vCPUs.
+func cpuRange(cpu int) *CPURange {
+	cr := &CPURange{CPUs: cpu}
+	if !rules.cpuAllowed(cpu) {
+		cr.Note = fmt.Sprintf("not a legal vCPU count (1 or even, %d-%d)", rules.MinCPUs, rules.MaxCPUs)
+		return cr
+	}
+	lo := max(roundUp256(rules.minRAMFor(cpu)), roundUp256(rules.MinRAMMB))
+	hi := roundDown256(rules.maxRAMFor(cpu))
+	if lo > hi {
+		cr.Note = "no valid memory size"
+		return cr
+	}
+	cr.MinRAMMB, cr.MaxRAMMB = lo, hi
+	cr.Min, cr.Max = describe(Tier{CPUs: cpu, RAMMB: lo}), describe(Tier{CPUs: cpu, RAMMB: hi})
+	if t := (Tier{CPUs: cpu, RAMMB: ratioMBFloor(ratioClasses["standard"], cpu)}); t.Valid() {
+		cr.Standard = describe(t)
+	}
+	if t := (Tier{CPUs: cpu, RAMMB: ratioMBFloor(ratioClasses["highmem"], cpu)}); t.Valid() {
+		cr.Highmem = describe(t)
+	}
+	return cr
+}
+
+// splitList splits arguments such as "8,16,32" or "8 16" into their items.
+func splitList(args []string) []string {
+	return strings.FieldsFunc(strings.Join(args, " "), func(r rune) bool { return unicode.IsSpace(r) || r == ',' })
+}
+
+// runCPURange reports the legal memory range of each vCPU count in args.
+func runCPURange(args []string) (*CPURangeResult, error) {
+	res := &CPURangeResult{Mode: "cpu-range", Engine: rules.Engine, Edition: rules.Edition, Ranges: []*CPURange{}}
+	items := splitList(args)
+	if len(items) == 0 {
+		return res, fmt.Errorf("usage: cpu-range <vCPUs>[,<vCPUs>...]")
+	}
+	for _, item := range items {
+		cpu, err := strconv.Atoi(item)
+		if err != nil || cpu <= 0 {
+			return res, fmt.Errorf("invalid vCPU count %q: use a positive whole number", item)
+		}
+		res.Ranges = append(res.Ranges, cpuRange(cpu))
+	}
+	return res, nil
+}
+
+// MemRangeTier is a tier that carries the memory of a mem-range query.
+type MemRangeTier struct {
+	*TierInfo
+	Known bool `json:"known"`
+}
+
+// MemRangeResult is the outcome of mem-range: every vCPU count that can
+// legally carry the memory.
+type MemRangeResult struct {
+	Mode        string          `json:"mode"`
+	Engine      string          `json:"engine"`
+	Edition     string          `json:"edition"`
+	RequestedMB float64         `json:"requested_mem_mb"`
+	RAMMB       int             `json:"ram_mb"`
+	Tiers       []*MemRangeTier `json:"tiers"`
+	Message     string          `json:"message,omitempty"`
+	Error       string          `json:"error,omitempty"`
+	Version     *BuildInfo      `json:"version,omitempty"`
+}
+
+func (r *MemRangeResult) setError(err error) {
+	r.Error = err.Error()
+}
+
+func (r *MemRangeResult) setVersion(bi *BuildInfo) {
+	r.Version = bi
+}
+
+func (r *MemRangeResult) exitCode() int {
+	if len(r.Tiers) == 0 {
+		return exitInvalid
+	}
+	return exitOK
+}
+
+func (r *MemRangeResult) humanText() string {
+	var sb strings.Builder
+	if r.Message != "" {
+		fmt.Fprintf(&sb, "Note: %s\n", r.Message)
+	}
+	if len(r.Tiers) == 0 {
+		fmt.Fprintf(&sb, "No legal vCPU count carries %d MB under %s %s\n", r.RAMMB, rules.Name, rules.editionName())
+		return sb.String()
+	}
+	lo, hi := r.Tiers[0].CPUs, r.Tiers[len(r.Tiers)-1].CPUs
+	fmt.Fprintf(&sb, "%d MB (%.2f GB) is legal on %d vCPU counts, %d to %d vCPUs:\n", r.RAMMB, float64(r.RAMMB)/1024, len(r.Tiers), lo, hi)
+	tw := tabwriter.NewWriter(&sb, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "Tier\tvCPUs\tGB/vCPU\tClass\tKnown")
+	for _, t := range r.Tiers {
+		known := ""
+		if t.Known {
+			known = "yes"
+		}
+		fmt.Fprintf(tw, "%s\t%d\t%.2f\t%s\t%s\n", t.Tier, t.CPUs, t.Ratio, t.Class, known)
+	}
+	tw.Flush()
+	return sb.String()
+}
+
+func (r *MemRangeResult) csvRecords() [][]string {
+	records := [][]string{{"tier", "cpus", "ram_mb", "ratio_gb_per_cpu", "class", "known"}}
+	for _, t := range r.Tiers {
+		records = append(records, []string{
+			t.Tier,
+			strconv.Itoa(t.CPUs),
+			strconv.Itoa(t.RAMMB),
+			strconv.FormatFloat(t.Ratio, 'f', 2, 64),
+			t.Class,
+			strconv.FormatBool(t.Known),
+		})
+	}
+	return records
+}
+
+// runMemRange lists the legal vCPU counts for mem, rounded up to the memory
+// step, with the tier each makes.
+func runMemRange(mem string) (*MemRangeResult, error) {
+	res := &MemRangeResult{Mode: "mem-range", Engine: rules.Engine, Edition: rules.Edition, Tiers: []*MemRangeTier{}}
+	memMB, err := parseMem(mem)
+	if err != nil {
+		return res, err
+	}
+	res.RequestedMB = memMB
+	if memMB < float64(rules.MinRAMMB) {
+		return res, newTierError(ErrRAMTooLow, mem, "memory %s is below the %s minimum of %d MB", mem, rules.Name, rules.MinRAMMB)
+	}
+	res.RAMMB = roundUp256(int(math.Ceil(memMB)))
+	if float64(res.RAMMB) != memMB {
+		res.Message = fmt.Sprintf("%g MB rounded up to %d MB, the next %d MB step", memMB, res.RAMMB, rules.RAMStepMB)
+	}
+	for c := rules.MinCPUs; c <= rules.MaxCPUs; c++ {
+		t := Tier{CPUs: c, RAMMB: res.RAMMB}
+		if rules.cpuAllowed(c) && t.Valid() {
+			res.Tiers = append(res.Tiers, &MemRangeTier{TierInfo: describe(t), Known: slices.Contains(knownTiers, t)})
+		}
+	}
+	return res, nil
+}
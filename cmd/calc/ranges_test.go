@@ -0,0 +1,96 @@
+package main
+
+import "testing"
+
+func TestCPURange(t *testing.T) {
+	useRules(t, "mysql", "enterprise")
+	tests := []struct {
+		cpu               int
+		min, max          string
+		standard, highmem string
+		note              bool
+	}{
+		{1, "db-custom-1-3840", "db-custom-1-3840", "db-custom-1-3840", "-", false},
+		{2, "db-custom-2-3840", "db-custom-2-13312", "db-custom-2-7680", "db-custom-2-13312", false},
+		{8, "db-custom-8-7424", "db-custom-8-53248", "db-custom-8-30720", "db-custom-8-53248", false},
+		{96, "db-custom-96-88576", "db-custom-96-638976", "db-custom-96-368640", "db-custom-96-638976", false},
+		{3, "-", "-", "-", "-", true},
+		{98, "-", "-", "-", "-", true},
+	}
+	for _, tt := range tests {
+		cr := cpuRange(tt.cpu)
+		if got := [4]string{tierName(cr.Min), tierName(cr.Max), tierName(cr.Standard), tierName(cr.Highmem)}; got != [4]string{tt.min, tt.max, tt.standard, tt.highmem} {
+			t.Errorf("cpuRange(%d) = %v, want min %s, max %s, standard %s, highmem %s", tt.cpu, got, tt.min, tt.max, tt.standard, tt.highmem)
+		}
+		if (cr.Note != "") != tt.note {
+			t.Errorf("cpuRange(%d) note = %q, want a note: %t", tt.cpu, cr.Note, tt.note)
+		}
+		for _, info := range []*TierInfo{cr.Min, cr.Max, cr.Standard, cr.Highmem} {
+			if info != nil && !info.Valid {
+				t.Errorf("cpuRange(%d) lists invalid tier %s", tt.cpu, info.Tier)
+			}
+		}
+		if cr.Min != nil && (cr.MinRAMMB != cr.Min.RAMMB || cr.MaxRAMMB != cr.Max.RAMMB) {
+			t.Errorf("cpuRange(%d) = %d-%d MB, but tiers %s and %s", tt.cpu, cr.MinRAMMB, cr.MaxRAMMB, cr.Min.Tier, cr.Max.Tier)
+		}
+	}
+}
+
+func TestCPURangeCommand(t *testing.T) {
+	tests := []struct {
+		args []string
+		want int
+	}{
+		{[]string{"cpu-range", "2,8"}, exitOK},
+		{[]string{"cpu-range", "2", "8"}, exitOK},
+		{[]string{"cpu-range", "2,3"}, exitInvalid},
+		{[]string{"cpu-range", "x"}, exitUsage},
+		{[]string{"cpu-range", "0"}, exitUsage},
+	}
+	for _, tt := range tests {
+		if _, code := run(t, tt.args...); code != tt.want {
+			t.Errorf("go-calc %q exited %d, want %d", tt.args, code, tt.want)
+		}
+	}
+}
+
+func TestMemRange(t *testing.T) {
+	useRules(t, "mysql", "enterprise")
+	tests := []struct {
+		mem         string
+		ramMB       int
+		first, last string
+		n           int
+		rounded     bool
+	}{
+		{"52G", 53248, "db-custom-8-53248", "db-custom-56-53248", 25, false},
+		{"20000", 20224, "db-custom-4-20224", "db-custom-20-20224", 9, true},
+		{"3840", 3840, "db-custom-1-3840", "db-custom-4-3840", 3, false},
+	}
+	for _, tt := range tests {
+		res, err := runMemRange(tt.mem)
+		if err != nil {
+			t.Fatalf("runMemRange(%s): %v", tt.mem, err)
+		}
+		if res.RAMMB != tt.ramMB || len(res.Tiers) != tt.n || (res.Message != "") != tt.rounded {
+			t.Errorf("runMemRange(%s) = %d MB on %d tiers, message %q, want %d MB on %d tiers, rounded %t", tt.mem, res.RAMMB, len(res.Tiers), res.Message, tt.ramMB, tt.n, tt.rounded)
+			continue
+		}
+		if first, last := res.Tiers[0].Tier, res.Tiers[len(res.Tiers)-1].Tier; first != tt.first || last != tt.last {
+			t.Errorf("runMemRange(%s) = %s to %s, want %s to %s", tt.mem, first, last, tt.first, tt.last)
+		}
+		for _, tier := range res.Tiers {
+			if !tier.Valid || tier.RAMMB != tt.ramMB {
+				t.Errorf("runMemRange(%s) lists %s", tt.mem, tier.Tier)
+			}
+		}
+	}
+	for _, mem := range []string{"100M", "700G"} {
+		if _, err := runMemRange(mem); exitCodeFor(err) != exitInvalid {
+			t.Errorf("runMemRange(%s) = %v, want a tier error", mem, err)
+		}
+	}
+	if _, err := runMemRange("lots"); exitCodeFor(err) != exitParse {
+		t.Errorf("runMemRange(lots) = %v, want a syntax error", err)
+	}
+}
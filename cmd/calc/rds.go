@@ -0,0 +1,79 @@
+package main
+
+import (
+	_ "embed"
+	"fmt"
+	"strings"
+)
+
+// rdsClassData is the embedded table of AWS RDS instance classes, as
+// class,cpus,ram_mb rows.
+//
+//go:embed rdsclasses.csv
+var rdsClassData []byte
+
+// rdsClasses are the RDS instance classes from-rds maps and -to-rds matches
+// against, in table order.
+var rdsClasses = mustParseMachineTypes(rdsClassData, "rdsclasses.csv")
+
+// lookupRDSClass returns the RDS instance class named name, or an error
+// listing the supported families.
+func lookupRDSClass(name string) (MachineType, error) {
+	n := strings.ToLower(strings.TrimSpace(name))
+	for _, c := range rdsClasses {
+		if c.Name == n {
+			return c, nil
+		}
+	}
+	return MachineType{}, fmt.Errorf("unknown RDS instance class %q: use a class such as db.r6g.2xlarge from the %s families", name, strings.Join(rdsFamilies(), ", "))
+}
+
+// rdsFamilies returns the class families in the table, e.g. db.r6g.
+func rdsFamilies() []string {
+	var fams []string
+	for _, c := range rdsClasses {
+		fam := c.Name[:strings.LastIndex(c.Name, ".")]
+		if len(fams) == 0 || fams[len(fams)-1] != fam {
+			fams = append(fams, fam)
+		}
+	}
+	return fams
+}
+
+// runFromRDS finds the smallest valid custom tier with at least the vCPUs
+// and memory of an RDS instance class.
+func runFromRDS(class string) (*Result, error) {
+	res := newResult("from-rds")
+	res.InputTier = class
+	c, err := lookupRDSClass(class)
+	if err != nil {
+		return res, err
+	}
+	res.RequestedCPUs, res.RequestedMemMB = float64(c.CPUs), float64(c.RAMMB)
+	tier, binding, err := smallestTierFor(float64(c.CPUs), float64(c.RAMMB))
+	if err != nil {
+		return res, fmt.Errorf("cannot match %s: %w", c.Name, err)
+	}
+	res.TierInfo = describe(tier)
+	res.Binding = binding
+	res.Spare = &Spare{CPUs: float64(tier.CPUs - c.CPUs), MemMB: float64(tier.RAMMB - c.RAMMB)}
+	res.printf("Smallest CloudSQL %s tier for RDS %s (%d vCPUs, %d MB):\n", rules.Name, c.Name, c.CPUs, c.RAMMB)
+	res.printf("  - Tier: %s\n", tier)
+	res.printf("  - vCPUs: %d (spare %+g)\n", tier.CPUs, res.Spare.CPUs)
+	res.printf("  - Memory: %d MB (%.2f GB, spare %+.0f MB)\n", tier.RAMMB, tier.RAMGB(), res.Spare.MemMB)
+	res.printf("  - Memory per vCPU: %.2f GB (valid range: %s)\n", tier.Ratio(), rules.ratioRange())
+	res.printf("  - Binding constraint: %s\n", binding)
+	return res, nil
+}
+
+// addRDSEquivalents lists the RDS instance classes closest to the resolved
+// tier.
+func addRDSEquivalents(res *Result) error {
+	t, err := matchTier(res, "to-rds")
+	if err != nil {
+		return err
+	}
+	res.RDSEquivalents = closestMachines(rdsClasses, t, 3)
+	res.printMatches("RDS instance classes closest to "+t.String(), res.RDSEquivalents)
+	return nil
+}
@@ -0,0 +1,325 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+// recommendation is the subset of a Google Cloud Recommender recommendation
+// (`gcloud recommender recommendations list --format=json`) that -recommender
+// reads. A Cloud SQL rightsizing recommendation changes /settings/tier of the
+// instance: a "test" operation holds the current tier and a "replace"
+// operation the recommended one.
+type recommendation struct {
+	Name               string `json:"name"`
+	Description        string `json:"description"`
+	RecommenderSubtype string `json:"recommenderSubtype"`
+	Content            struct {
+		OperationGroups []struct {
+			Operations []struct {
+				Action   string `json:"action"`
+				Resource string `json:"resource"`
+				Path     string `json:"path"`
+				Value    any    `json:"value"`
+			} `json:"operations"`
+		} `json:"operationGroups"`
+		Overview map[string]any `json:"overview"`
+	} `json:"content"`
+}
+
+// tiers returns the instance, current tier, and recommended tier of the
+// recommendation. The tiers are empty when it does not change the tier.
+func (rc *recommendation) tiers() (instance, current, recommended string) {
+	for _, g := range rc.Content.OperationGroups {
+		for _, op := range g.Operations {
+			if _, name, ok := strings.Cut(op.Resource, "/instances/"); ok && instance == "" {
+				instance = name
+			}
+			v, ok := op.Value.(string)
+			if op.Path != "/settings/tier" || !ok {
+				continue
+			}
+			switch op.Action {
+			case "test":
+				current = v
+			case "replace", "add":
+				recommended = v
+			}
+		}
+	}
+	overview := func(key string) string {
+		s, _ := rc.Content.Overview[key].(string)
+		return s
+	}
+	if instance == "" {
+		instance = overview("instanceName")
+	}
+	if current == "" {
+		current = overview("currentTier")
+	}
+	if recommended == "" {
+		recommended = overview("recommendedTier")
+	}
+	return instance, current, recommended
+}
+
+// RecommenderItem is the evaluation of one recommendation. The embedded
+// Result is the -check-downgrade (or -check-upgrade) of the recommended tier
+// against the current one.
+type RecommenderItem struct {
+	Instance       string   `json:"instance"`
+	Recommendation string   `json:"recommendation"`
+	Current        string   `json:"current_tier"`
+	Change         string   `json:"change,omitempty"` // "downgrade" or "upgrade"
+	Valid          bool     `json:"valid"`
+	MonthlySavings *float64 `json:"monthly_savings,omitempty"`
+	Skipped        string   `json:"skipped,omitempty"`
+	*Result
+}
+
+// RecommenderTotals sums the evaluated recommendations.
+type RecommenderTotals struct {
+	Recommendations int      `json:"recommendations"`
+	Valid           int      `json:"valid"`
+	Invalid         int      `json:"invalid"`
+	Skipped         int      `json:"skipped"`
+	Errors          int      `json:"errors"`
+	MonthlySavings  *float64 `json:"monthly_savings,omitempty"`
+}
+
+// RecommenderResult is the outcome of -recommender.
+type RecommenderResult struct {
+	Mode            string             `json:"mode"`
+	Source          string             `json:"source"`
+	Recommendations []*RecommenderItem `json:"recommendations"`
+	Totals          RecommenderTotals  `json:"totals"`
+	Error           string             `json:"error,omitempty"`
+	Version         *BuildInfo         `json:"version,omitempty"`
+}
+
+func (r *RecommenderResult) setError(err error) {
+	r.Error = err.Error()
+}
+
+func (r *RecommenderResult) setVersion(bi *BuildInfo) {
+	r.Version = bi
+}
+
+// exitCode follows -instances: exitParse if any tier failed to parse,
+// exitInvalid if any recommendation was not valid, and exitOK otherwise.
+// Skipped recommendations do not count.
+func (r *RecommenderResult) exitCode() int {
+	switch {
+	case r.Totals.Errors > 0:
+		return exitParse
+	case r.Totals.Invalid > 0:
+		return exitInvalid
+	}
+	return exitOK
+}
+
+func (r *RecommenderResult) humanText() string {
+	var sb strings.Builder
+	tw := tabwriter.NewWriter(&sb, 0, 0, 2, ' ', 0)
+	fmt.Fprint(tw, "Instance\tCurrent\tRecommended\tChange\tValid\tNearest valid")
+	if prices != nil {
+		fmt.Fprint(tw, "\tCost")
+	}
+	fmt.Fprintln(tw, "\tNote")
+	for _, it := range r.Recommendations {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s", it.Instance, it.Current, it.recommendedTier(), it.Change, it.validity(), it.nearestValid())
+		if prices != nil {
+			fmt.Fprintf(tw, "\t%s", it.savings())
+		}
+		fmt.Fprintf(tw, "\t%s\n", it.note())
+	}
+	tw.Flush()
+	t := r.Totals
+	fmt.Fprintf(&sb, "%d recommendations: %d valid, %d invalid, %d skipped, %d errors",
+		t.Recommendations, t.Valid, t.Invalid, t.Skipped, t.Errors)
+	if t.MonthlySavings != nil {
+		fmt.Fprintf(&sb, "; applying the valid ones %s", prices.deltaText(-*t.MonthlySavings))
+	}
+	sb.WriteString("\n")
+	return sb.String()
+}
+
+func (r *RecommenderResult) csvRecords() [][]string {
+	records := [][]string{{"instance", "recommendation", "current_tier", "recommended_tier", "change", "valid", "nearest_valid", "monthly_savings", "note"}}
+	for _, it := range r.Recommendations {
+		valid := ""
+		if it.Skipped == "" && it.Error == "" {
+			valid = strconv.FormatBool(it.Valid)
+		}
+		savings := ""
+		if it.MonthlySavings != nil {
+			savings = strconv.FormatFloat(*it.MonthlySavings, 'f', 2, 64)
+		}
+		records = append(records, []string{it.Instance, it.Recommendation, it.Current, it.recommendedTier(), it.Change, valid, it.nearestValid(), savings, it.note()})
+	}
+	return records
+}
+
+func (it *RecommenderItem) recommendedTier() string {
+	if it.Recommended != nil {
+		return it.Recommended.Tier
+	}
+	return ""
+}
+
+func (it *RecommenderItem) validity() string {
+	switch {
+	case it.Error != "":
+		return "error"
+	case it.Skipped != "":
+		return "skipped"
+	case it.Valid:
+		return "yes"
+	}
+	return "no"
+}
+
+// nearestValid is the valid alternative to an invalid recommendation: the
+// nearest valid tier when the recommended tier breaks the rules, otherwise
+// the first known tier in the direction of the change.
+func (it *RecommenderItem) nearestValid() string {
+	switch {
+	case it.Valid || it.Skipped != "":
+		return ""
+	case it.NearestValid != nil:
+		return it.NearestValid.Tier
+	}
+	return it.SuggestedTier
+}
+
+func (it *RecommenderItem) savings() string {
+	if it.MonthlySavings == nil {
+		return ""
+	}
+	return prices.deltaText(-*it.MonthlySavings)
+}
+
+func (it *RecommenderItem) note() string {
+	switch {
+	case it.Error != "":
+		return it.Error
+	case it.Skipped != "":
+		return it.Skipped
+	case it.Message == "" && it.Recommended != nil && len(it.Recommended.Reasons) > 0:
+		return it.Recommended.Reasons[0]
+	}
+	return it.Message
+}
+
+// readRecommendations decodes a recommender export: the JSON array gcloud
+// prints, or the {"recommendations": [...]} object of the REST API.
+func readRecommendations(in io.Reader) ([]recommendation, error) {
+	data, err := io.ReadAll(in)
+	if err != nil {
+		return nil, err
+	}
+	var list []recommendation
+	if err := json.Unmarshal(data, &list); err == nil {
+		return list, nil
+	}
+	var page struct {
+		Recommendations []recommendation `json:"recommendations"`
+	}
+	if err := json.Unmarshal(data, &page); err != nil {
+		return nil, err
+	}
+	return page.Recommendations, nil
+}
+
+// runRecommender evaluates every recommendation in a Cloud SQL rightsizing
+// recommender export, or stdin when path is "-". Each tier change is checked
+// as a downgrade, or as an upgrade when it grows both vCPUs and memory.
+func runRecommender(path string) (*RecommenderResult, error) {
+	r := &RecommenderResult{Mode: "recommender", Source: path, Recommendations: []*RecommenderItem{}}
+	var in io.Reader = os.Stdin
+	if path != "-" {
+		file, err := os.Open(path)
+		if err != nil {
+			return r, err
+		}
+		defer file.Close()
+		in = file
+	}
+	list, err := readRecommendations(in)
+	if err != nil {
+		return r, fmt.Errorf("invalid recommender export %s: %w", path, err)
+	}
+	for i := range list {
+		it := evaluateRecommendation(&list[i])
+		r.Recommendations = append(r.Recommendations, it)
+		r.Totals.Recommendations++
+		switch {
+		case it.Error != "":
+			r.Totals.Errors++
+		case it.Skipped != "":
+			r.Totals.Skipped++
+		case it.Valid:
+			r.Totals.Valid++
+			if s := it.MonthlySavings; s != nil {
+				total := *s
+				if r.Totals.MonthlySavings != nil {
+					total += *r.Totals.MonthlySavings
+				}
+				r.Totals.MonthlySavings = &total
+			}
+		default:
+			r.Totals.Invalid++
+		}
+	}
+	return r, nil
+}
+
+// evaluateRecommendation checks the tier change of one recommendation.
+// Recommendations that do not change the tier, or that involve a shared-core
+// tier, are skipped rather than judged.
+func evaluateRecommendation(rc *recommendation) *RecommenderItem {
+	instance, current, recommended := rc.tiers()
+	it := &RecommenderItem{Instance: instance, Recommendation: rc.Name, Current: current, Result: newResult("check-downgrade")}
+	if current == "" || recommended == "" {
+		it.Skipped = "not a tier change"
+		if rc.RecommenderSubtype != "" {
+			it.Skipped += " (" + rc.RecommenderSubtype + ")"
+		}
+		return it
+	}
+	curr, err := ParseTier(current)
+	if err != nil {
+		it.setError(fmt.Errorf("invalid current tier: %w", err))
+		return it
+	}
+	rec, err := ParseTier(recommended)
+	if err != nil {
+		it.setError(fmt.Errorf("invalid recommended tier: %w", err))
+		return it
+	}
+	upgrade := compareTiers(curr, rec).verdict().Change == "higher"
+	if it.Result, err = runCheckChange(current, recommended, upgrade); err != nil {
+		it.setError(err)
+		return it
+	}
+	if curr.Shared() || rec.Shared() {
+		it.Skipped = "shared-core tier: check by hand"
+		return it
+	}
+	it.Change = "downgrade"
+	it.Valid = it.ValidDowngrade != nil && *it.ValidDowngrade
+	if upgrade {
+		it.Change = "upgrade"
+		it.Valid = it.ValidUpgrade != nil && *it.ValidUpgrade
+	}
+	if from, to := it.TierInfo.Cost, it.Recommended.Cost; from != nil && to != nil {
+		s := from.Monthly - to.Monthly
+		it.MonthlySavings = &s
+	}
+	return it
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// replHelp lists the interactive commands. A tier argument defaults to the
+// tier the previous command resulted in.
+const replHelp = `Commands:
+  next [tier]        next known tier up (a bare tier does the same)
+  prev [tier]        suggested downgrade (also: downgrade)
+  validate [tier]    check a tier
+  bump-mem [tier]    raise memory to -to-ratio GB/vCPU
+  bump-cpu [tier]    raise vCPUs to the next legal count
+  cpu <vCPUs>        size a tier for vCPUs
+  mem <memory>       size a tier for memory, e.g. 52G
+  ratio [GB/vCPU]    show or set the sizing ratio
+  help               show this help
+  quit               leave (also: exit, Ctrl-D)
+`
+
+// repl is an interactive session. last is the tier the previous command
+// resulted in, used when a command is given without a tier.
+type repl struct {
+	out  io.Writer
+	last string
+}
+
+// runREPL reads commands from in, one per line, and writes a single line of
+// output for each. Errors are reported and the session continues. The prompt
+// is only shown when in is a terminal.
+func runREPL(in *os.File, out io.Writer) {
+	r := &repl{out: out}
+	prompt := isTerminal(in)
+	scanner := bufio.NewScanner(in)
+	for {
+		if prompt {
+			fmt.Fprint(out, "go-calc> ")
+		}
+		if !scanner.Scan() {
+			break
+		}
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		if fields[0] == "quit" || fields[0] == "exit" {
+			return
+		}
+		if err := r.exec(fields[0], fields[1:]); err != nil {
+			fmt.Fprintf(out, "error: %v\n", err)
+		}
+	}
+	if prompt {
+		fmt.Fprintln(out)
+	}
+}
+
+func isTerminal(f *os.File) bool {
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
+// exec runs one command.
+func (r *repl) exec(name string, args []string) error {
+	var run func(string) (*Result, error)
+	switch name {
+	case "help", "?":
+		fmt.Fprint(r.out, replHelp)
+		return nil
+	case "ratio":
+		return r.setRatio(args)
+	case "cpu":
+		if len(args) != 1 {
+			return fmt.Errorf("usage: cpu <vCPUs>")
+		}
+		cpu, err := parseCPU(args[0])
+		if err != nil || cpu <= 0 {
+			return fmt.Errorf("invalid vCPUs %q", args[0])
+		}
+		return r.show(runCPU(cpu))
+	case "mem":
+		if len(args) != 1 {
+			return fmt.Errorf("usage: mem <memory>")
+		}
+		return r.show(runMem(args[0]))
+	case "next", "tier":
+		run = runTier
+	case "prev", "downgrade":
+		run = runDowngrade
+	case "validate":
+		run = runValidate
+	case "bump-mem":
+		run = runBumpMem
+	case "bump-cpu":
+		run = runBumpCPU
+	default:
+		if _, err := ParseTier(name); err != nil {
+			return fmt.Errorf("unknown command %q (try help)", name)
+		}
+		run, args = runTier, []string{name}
+	}
+	tier, err := r.tierArg(name, args)
+	if err != nil {
+		return err
+	}
+	return r.show(run(tier))
+}
+
+// tierArg returns the tier argument, or the last resulting tier if none is
+// given.
+func (r *repl) tierArg(name string, args []string) (string, error) {
+	switch {
+	case len(args) > 1:
+		return "", fmt.Errorf("usage: %s [tier]", name)
+	case len(args) == 1:
+		return args[0], nil
+	case r.last == "":
+		return "", fmt.Errorf("%s needs a tier: no previous result", name)
+	}
+	return r.last, nil
+}
+
+func (r *repl) setRatio(args []string) error {
+	if len(args) == 0 {
+		fmt.Fprintf(r.out, "ratio %g GB/vCPU (range %s)\n", opts.ratio, rules.ratioRange())
+		return nil
+	}
+	ratio, err := strconv.ParseFloat(strings.TrimSuffix(strings.ToUpper(args[0]), "G"), 64)
+	if err != nil {
+		return fmt.Errorf("invalid ratio %q", args[0])
+	}
+	if err := rules.checkRatio(ratio); err != nil {
+		return err
+	}
+	opts.ratio = ratio
+	fmt.Fprintf(r.out, "ratio %g GB/vCPU\n", ratio)
+	return nil
+}
+
+// show prints the result of a command as one line: the tier it moves to, an
+// invalid input tier with its reasons and suggestion, or the message of a
+// command with nothing to move to. The tier shown becomes the context for
+// the next command.
+func (r *repl) show(res *Result, err error) error {
+	if err != nil {
+		return err
+	}
+	keep := func(tier string) string {
+		r.last = tier
+		t, _ := ParseTier(tier)
+		return describe(t).summary()
+	}
+	line := res.input() + ": "
+	tier := res.targetTier()
+	switch {
+	case res.TierInfo != nil && !res.Valid:
+		line += "invalid (" + strings.Join(res.Reasons, "; ") + ")"
+		if tier != "" {
+			line += "; suggested " + keep(tier)
+		}
+	case tier != "":
+		line += keep(tier)
+	case res.Message != "":
+		line += res.Message
+		if res.TierInfo != nil {
+			r.last = res.Tier
+		}
+	case res.TierInfo != nil:
+		line += keep(res.Tier)
+	default:
+		line += "no tier"
+	}
+	for _, w := range res.Warnings {
+		line += " [warning: " + w + "]"
+	}
+	fmt.Fprintln(r.out, line)
+	return nil
+}
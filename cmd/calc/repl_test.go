@@ -0,0 +1,53 @@
+package main
+
+import (
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+// A session piped in, as a script would: no prompt, one line per command,
+// errors that do not end it, and nothing read after quit.
+func TestREPL(t *testing.T) {
+	cmd := exec.Command(binary, "-i")
+	cmd.Stdin = strings.NewReader(`next
+# sized from memory, then stepped up from the result
+mem 52G
+next
+bogus
+validate db-custom-3-4000
+prev db-custom-1-3840
+ratio
+ratio 5
+ratio 9
+cpu 4
+quit
+next
+`)
+	var out strings.Builder
+	cmd.Stdout = &out
+	if err := cmd.Run(); err != nil {
+		t.Fatalf("go-calc -i: %v\n%s", err, out.String())
+	}
+	want := []string{
+		"error: next needs a tier: no previous result",
+		"53248 MB: db-custom-36-53248: 36 vCPUs, 53248 MB (52.00 GB), 1.44 GB/vCPU",
+		"db-custom-36-53248: db-custom-48-184320: 48 vCPUs, 184320 MB (180.00 GB), 3.75 GB/vCPU",
+		`error: unknown command "bogus" (try help)`,
+		"db-custom-3-4000: invalid (vCPUs must be 1 or an even number, got 3; memory must be a multiple of 256 MB, got 4000 MB); suggested db-custom-4-4096: 4 vCPUs, 4096 MB (4.00 GB), 1.00 GB/vCPU",
+		"db-custom-1-3840: already at the lowest known tier",
+		"ratio 1.5 GB/vCPU (range 0.9-6.5 GB)",
+		"ratio 5 GB/vCPU",
+		"error: ",
+		"4 vCPUs: db-custom-4-20480: 4 vCPUs, 20480 MB (20.00 GB), 5.00 GB/vCPU",
+	}
+	got := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	if len(got) != len(want) {
+		t.Fatalf("session printed %d lines, want %d:\n%s", len(got), len(want), out.String())
+	}
+	for i := range want {
+		if !strings.HasPrefix(got[i], want[i]) {
+			t.Errorf("line %d = %q, want %q", i+1, got[i], want[i])
+		}
+	}
+}
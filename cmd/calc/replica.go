@@ -0,0 +1,108 @@
+package main
+
+import "fmt"
+
+// ReplicaPair is the combined size of a primary and its read replica.
+type ReplicaPair struct {
+	CPUs    float64  `json:"cpus"`
+	RAMMB   int      `json:"ram_mb"`
+	RAMGB   float64  `json:"ram_gb"`
+	Monthly *float64 `json:"monthly_cost,omitempty"`
+}
+
+// runReplica sizes a read replica for primary: -replica-offset known tiers
+// down, each smaller in both vCPUs and memory, or the -replica-tier given.
+// The replica must be a valid tier and no more than -max-shrink-pct smaller
+// than the primary in vCPUs or memory, so it can take the primary's traffic
+// on failover.
+func runReplica(primary string) (*Result, error) {
+	res := newResult("replica")
+	res.InputTier = primary
+	prim, err := ParseTier(primary)
+	if err != nil {
+		return res, fmt.Errorf("invalid primary tier: %w", err)
+	}
+	if opts.replicaOffset < 0 {
+		return res, fmt.Errorf("-replica-offset must not be negative")
+	}
+	res.TierInfo = describe(prim)
+	res.noteEquivalent(primary, prim)
+	primErr := prim.Validate()
+	res.printf("Primary: %s (%g vCPUs, %d MB, %.2f GB) - Valid: %t\n", prim, prim.VCPUs(), prim.RAMMB, prim.RAMGB(), primErr == nil)
+	if primErr != nil {
+		res.printf("  Reason: %v\n", primErr)
+	}
+
+	var rep Tier
+	var how string
+	if opts.replicaTier != "" {
+		if rep, err = ParseTier(opts.replicaTier); err != nil {
+			return res, fmt.Errorf("invalid replica tier: %w", err)
+		}
+		res.noteEquivalent(opts.replicaTier, rep)
+		how = "proposed"
+	} else {
+		rep = prim
+		steps := 0
+		for ; steps < opts.replicaOffset; steps++ {
+			// A step down must not grow either resource, so skip the
+			// known tiers that trade vCPUs for memory.
+			prev, found := findPreviousKnownTier(rep)
+			for found && compareTiers(rep, prev).verdict().Change != "lower" {
+				prev, found = findPreviousKnownTier(prev)
+			}
+			if !found {
+				break
+			}
+			rep = prev
+		}
+		how = fmt.Sprintf("%d known tiers down", steps)
+		switch {
+		case steps == 0:
+			how = "same as the primary"
+		case steps == 1:
+			how = "one known tier down"
+		}
+		if steps < opts.replicaOffset {
+			res.warnf(CodeReplicaOffsetShort, "only %d known tiers below %s; -replica-offset %d stops at %s", steps, prim, opts.replicaOffset, rep)
+		}
+	}
+	res.Recommended = describe(rep)
+	repErr := rep.Validate()
+	res.printf("Replica: %s (%g vCPUs, %d MB, %.2f GB), %s - Valid: %t\n", rep, rep.VCPUs(), rep.RAMMB, rep.RAMGB(), how, repErr == nil)
+	if repErr != nil {
+		res.printf("  Reason: %v\n", repErr)
+	}
+
+	delta := compareTiers(prim, rep)
+	res.Delta = &delta
+	res.printf("  Change: %s\n", delta)
+	valid := repErr == nil
+	if drop := delta.maxDropPct(); drop > opts.maxShrinkPct {
+		valid = false
+		res.Code = CodeReplicaTooSmall
+		res.Message = fmt.Sprintf("replica is %.0f%% smaller than the primary, more than -max-shrink-pct %g%%", drop, opts.maxShrinkPct)
+		res.printf("  Replica is %.0f%% smaller than the primary (limit %g%%) and may not absorb failover traffic.\n", drop, opts.maxShrinkPct)
+	}
+	if delta.direction() == "upgrade" {
+		res.warnf(CodeReplicaLarger, "replica %s is larger than the primary %s", rep, prim)
+	}
+	res.ValidReplica = &valid
+	if valid {
+		res.println("  Valid replica: Yes")
+	} else {
+		res.println("  Valid replica: No")
+	}
+
+	pair := &ReplicaPair{CPUs: prim.VCPUs() + rep.VCPUs(), RAMMB: prim.RAMMB + rep.RAMMB}
+	pair.RAMGB = float64(pair.RAMMB) / 1024
+	total := fmt.Sprintf("%g vCPUs, %d MB (%.2f GB)", pair.CPUs, pair.RAMMB, pair.RAMGB)
+	if pc, rc := res.TierInfo.Cost, res.Recommended.Cost; pc != nil && rc != nil {
+		monthly := pc.Monthly + rc.Monthly
+		pair.Monthly = &monthly
+		total += fmt.Sprintf(", ~%s/mo", prices.money(monthly))
+	}
+	res.Pair = pair
+	res.printf("Pair total: %s\n", total)
+	return res, nil
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	_ "embed"
+	"flag"
+	"fmt"
+	"html/template"
+	"io"
+	"os"
+	"slices"
+	"strings"
+)
+
+//go:embed report.html
+var reportTemplateText string
+
+// reportTemplate renders the -report html page. It is self-contained: the
+// styles and the table sorting script are inline.
+var reportTemplate = template.Must(template.New("report").Parse(reportTemplateText))
+
+// ReportRow is one tier of a report page.
+type ReportRow struct {
+	Name      string
+	Region    string
+	Tier      string
+	CPUs      float64
+	RAMGB     float64
+	Ratio     float64
+	Status    string // valid, invalid, or error
+	Detail    string // the reasons a tier is invalid, or the error
+	Known     bool
+	Suggested string
+}
+
+// RatioBucket counts the rows whose GB/vCPU falls in one band.
+type RatioBucket struct {
+	Label string
+	Count int
+	Pct   float64
+}
+
+// ReportPage is the data of a -report html page.
+type ReportPage struct {
+	Title      string
+	Source     string
+	Version    string
+	NameColumn string
+	NextColumn string
+	Regions    bool
+	Rows       []ReportRow
+	Ratios     []RatioBucket
+	Count      int
+	Valid      int
+	Invalid    int
+	Errors     int
+	VCPUs      float64
+	RAMGB      float64
+}
+
+// pager is a result that can be rendered as a report page.
+type pager interface {
+	reportPage() *ReportPage
+}
+
+// ratioBands are the upper bounds of the GB/vCPU bands in the ratio
+// distribution; the last band is open.
+var ratioBands = []float64{1, 2, 3.75, 5, 6.5}
+
+// newReportPage returns an empty report page with its ratio bands.
+func newReportPage(title, source, nameColumn, nextColumn string) *ReportPage {
+	p := &ReportPage{Title: title, Source: source, NameColumn: nameColumn, NextColumn: nextColumn}
+	lo := 0.0
+	for _, hi := range ratioBands {
+		label := fmt.Sprintf("%g-%g GB/vCPU", lo, hi)
+		if lo == 0 {
+			label = fmt.Sprintf("under %g GB/vCPU", hi)
+		}
+		p.Ratios = append(p.Ratios, RatioBucket{Label: label})
+		lo = hi
+	}
+	p.Ratios = append(p.Ratios, RatioBucket{Label: fmt.Sprintf("%g GB/vCPU and over", lo)})
+	return p
+}
+
+// addRow adds row to the page totals and ratio distribution.
+func (p *ReportPage) addRow(row ReportRow) {
+	p.Rows = append(p.Rows, row)
+	p.Count++
+	switch row.Status {
+	case "error":
+		p.Errors++
+		return
+	case "invalid":
+		p.Invalid++
+	default:
+		p.Valid++
+	}
+	p.VCPUs += row.CPUs
+	p.RAMGB += row.RAMGB
+	i := 0
+	for i < len(ratioBands) && row.Ratio >= ratioBands[i] {
+		i++
+	}
+	p.Ratios[i].Count++
+}
+
+// finishRatios sets the share of each ratio band.
+func (p *ReportPage) finishRatios() {
+	n := p.Valid + p.Invalid
+	if n == 0 {
+		return
+	}
+	for i := range p.Ratios {
+		p.Ratios[i].Pct = float64(p.Ratios[i].Count) * 100 / float64(n)
+	}
+}
+
+// resultRow is the report row of a -t or batch record.
+func resultRow(name string, r *Result) ReportRow {
+	row := ReportRow{Name: name, Tier: r.InputTier, Status: "error", Detail: r.Error, Suggested: r.SuggestedTier}
+	if r.Error != "" || r.TierInfo == nil {
+		return row
+	}
+	row.CPUs, row.RAMGB, row.Ratio = r.TierInfo.tier().VCPUs(), r.RAMGB, r.Ratio
+	row.Status, row.Detail = "valid", ""
+	if !r.Valid {
+		row.Status, row.Detail = "invalid", strings.Join(r.Reasons, "; ")
+	}
+	return row
+}
+
+func (f *FleetResult) reportPage() *ReportPage {
+	p := newReportPage("Cloud SQL fleet report", f.Source, "Instance", "Next tier")
+	p.Regions = true
+	for _, in := range f.Instances {
+		row := resultRow(in.Name, in.Result)
+		row.Region, row.Known = in.Region, in.Known
+		p.addRow(row)
+	}
+	return p
+}
+
+func (b *BatchResult) reportPage() *ReportPage {
+	column := "Line"
+	if b.Source == argsSource {
+		column = "Arg"
+	}
+	p := newReportPage("Cloud SQL tier report", b.Source, column, "Suggested")
+	for _, r := range b.Records {
+		row := resultRow(fmt.Sprint(r.Line), r)
+		if t, err := ParseTier(r.InputTier); err == nil {
+			row.Known = t.Shared() || slices.Contains(knownTiers, t)
+		}
+		// As in the text output, only invalid tiers get a suggestion
+		if row.Status != "invalid" {
+			row.Suggested = ""
+		}
+		p.addRow(row)
+	}
+	return p
+}
+
+// writeReport renders the report page of pr to w.
+func writeReport(w io.Writer, pr pager) error {
+	p := pr.reportPage()
+	p.finishRatios()
+	p.Version = buildInfo().Version
+	return reportTemplate.Execute(w, p)
+}
+
+// saveReport writes the -report page of res to -report-out.
+func saveReport(res report) error {
+	pr, ok := res.(pager)
+	if !ok {
+		return fmt.Errorf("-report applies to the instances and batch modes")
+	}
+	f, err := os.Create(opts.reportOut)
+	if err != nil {
+		return fmt.Errorf("-report-out: %w", err)
+	}
+	if err := writeReport(f, pr); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func reportFlags(fs *flag.FlagSet) {
+	fs.StringVar(&opts.report, "report", "", "Also write the results as a report page: html")
+	fs.StringVar(&opts.reportOut, "report-out", "report.html", "With -report, the file to write")
+}
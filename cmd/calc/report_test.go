@@ -0,0 +1,86 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestReportGolden compares the -report html pages of an instance list and
+// a batch of tiers with their golden files. Run go test -run Report -update
+// after changing the template.
+func TestReportGolden(t *testing.T) {
+	fleet, err := runFleet(filepath.Join("testdata", "report-instances.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	batch, err := runTierArgs([]string{"db-custom-4-16384", "db-custom-6-39936", "db-custom-3-16384", "db-custom-four", "db-f1-micro"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, pr := range map[string]pager{"instances": fleet, "batch": batch} {
+		t.Run(name, func(t *testing.T) {
+			var out strings.Builder
+			if err := writeReport(&out, pr); err != nil {
+				t.Fatal(err)
+			}
+			golden := filepath.Join("testdata", "report-"+name+".html")
+			if *update {
+				if err := os.WriteFile(golden, []byte(out.String()), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if out.String() != string(want) {
+				t.Errorf("%s report differs from %s; rerun with -update if the change is intended", name, golden)
+			}
+		})
+	}
+}
+
+func TestReportEscapesValues(t *testing.T) {
+	fleet, err := runFleet(filepath.Join("testdata", "report-instances.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out strings.Builder
+	if err := writeReport(&out, fleet); err != nil {
+		t.Fatal(err)
+	}
+	page := out.String()
+	if strings.Contains(page, `<script>alert`) || !strings.Contains(page, "&lt;script&gt;alert(&#34;x&#34;)&lt;/script&gt;") {
+		t.Errorf("the instance name is not escaped in the report:\n%s", page)
+	}
+	for _, external := range []string{`src="http`, `href="http`, "@import"} {
+		if strings.Contains(page, external) {
+			t.Errorf("the report loads %s, want it self-contained", external)
+		}
+	}
+}
+
+func TestReportTotals(t *testing.T) {
+	fleet, err := runFleet(filepath.Join("testdata", "report-instances.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := fleet.reportPage()
+	p.finishRatios()
+	if p.Count != 5 || p.Valid != 3 || p.Invalid != 1 || p.Errors != 1 {
+		t.Errorf("report counts %d rows: %d valid, %d invalid, %d errors; want 5: 3, 1, 1", p.Count, p.Valid, p.Invalid, p.Errors)
+	}
+	if p.VCPUs != 27.5 || p.RAMGB != 30+104+16000.0/1024+1741.0/1024 {
+		t.Errorf("report totals %g vCPUs, %g GB", p.VCPUs, p.RAMGB)
+	}
+	sum, pct := 0, 0.0
+	for _, b := range p.Ratios {
+		sum += b.Count
+		pct += b.Pct
+	}
+	if sum != p.Valid+p.Invalid || pct < 99.99 || pct > 100.01 {
+		t.Errorf("ratio bands hold %d rows and %g%%, want %d and 100%%", sum, pct, p.Valid+p.Invalid)
+	}
+}
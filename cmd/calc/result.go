@@ -0,0 +1,496 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// TierInfo is the structured description of a single tier.
+type TierInfo struct {
+	Tier       string   `json:"tier"`
+	SharedCore bool     `json:"shared_core,omitempty"`
+	CPUs       int      `json:"cpus"`
+	RAMMB      int      `json:"ram_mb"`
+	RAMGB      float64  `json:"ram_gb"`
+	Ratio      float64  `json:"ratio_gb_per_cpu"`
+	Class      string   `json:"class,omitempty"`
+	Valid      bool     `json:"valid"`
+	Reasons    []string `json:"reasons,omitempty"`
+	Codes      []string `json:"reason_codes,omitempty"`
+	Cost       *Cost    `json:"cost,omitempty"`
+	UsableMB   int      `json:"usable_mb,omitempty"`
+}
+
+// tier returns the tier info describes, without parsing its name under
+// whatever rules are selected now.
+func (info *TierInfo) tier() Tier {
+	return Tier{CPUs: info.CPUs, RAMMB: info.RAMMB}
+}
+
+func describe(t Tier) *TierInfo {
+	info := &TierInfo{
+		Tier:       t.String(),
+		SharedCore: t.Shared(),
+		CPUs:       t.CPUs,
+		RAMMB:      t.RAMMB,
+		RAMGB:      t.RAMGB(),
+		Ratio:      t.Ratio(),
+		Class:      ratioClass(t),
+		Valid:      true,
+	}
+	for _, v := range t.violations() {
+		info.Valid = false
+		info.Reasons = append(info.Reasons, v.Error())
+		info.Codes = append(info.Codes, reasonCode(v))
+	}
+	if opts.usable {
+		info.UsableMB = int(rules.Overhead.usable(float64(t.RAMMB)))
+	}
+	if prices != nil {
+		info.Cost, _ = prices.estimate(info, opts.region)
+	}
+	return info
+}
+
+// summary formats a one-line description of the tier, including the
+// estimated monthly cost when prices are loaded.
+func (info *TierInfo) summary() string {
+	s := fmt.Sprintf("%s: %d vCPUs, %d MB (%.2f GB), %.2f GB/vCPU", info.Tier, info.CPUs, info.RAMMB, info.RAMGB, info.Ratio)
+	if opts.usable {
+		s += fmt.Sprintf(", ~%d MB usable", info.UsableMB)
+	}
+	if info.Cost != nil {
+		s += fmt.Sprintf(", ~%s/mo", prices.money(info.Cost.Monthly))
+	}
+	return s
+}
+
+// Result is the outcome of one invocation. The embedded TierInfo describes
+// the primary tier of the mode: the parsed input for tier-based modes, or
+// the recommendation for -cpu/-mem.
+type Result struct {
+	Mode      string `json:"mode"`
+	Engine    string `json:"engine"`
+	Edition   string `json:"edition"`
+	HA        bool   `json:"ha,omitempty"`
+	Line      int    `json:"line,omitempty"`
+	InputTier string `json:"input_tier,omitempty"`
+	*TierInfo
+	RequestedCPUs    float64              `json:"requested_cpus,omitempty"`
+	RequestedMemMB   float64              `json:"requested_mem_mb,omitempty"`
+	SizingRatio      float64              `json:"sizing_ratio,omitempty"`
+	Raw              *TierInfo            `json:"raw,omitempty"`
+	Usage            *Usage               `json:"usage,omitempty"`
+	Monitoring       *Monitoring          `json:"monitoring,omitempty"`
+	Series           *UsageSeries         `json:"usage_series,omitempty"`
+	Growth           *Growth              `json:"growth,omitempty"`
+	Scaling          *Scaling             `json:"scaling,omitempty"`
+	Data             *DataSizing          `json:"data_sizing,omitempty"`
+	Connections      *Connections         `json:"connections,omitempty"`
+	Padding          *Padding             `json:"padding,omitempty"`
+	Usable           *UsableMemory        `json:"usable,omitempty"`
+	Timeline         []*Milestone         `json:"timeline,omitempty"`
+	Binding          string               `json:"binding,omitempty"`
+	Spare            *Spare               `json:"spare,omitempty"`
+	SuggestedTier    string               `json:"suggested_tier,omitempty"`
+	Suggested        *TierInfo            `json:"suggested,omitempty"`
+	Known            *TierInfo            `json:"known,omitempty"`
+	Steps            []*TierInfo          `json:"steps,omitempty"`
+	Strategies       []*StrategyCandidate `json:"strategies,omitempty"`
+	Cheapest         *Cheapest            `json:"cheapest,omitempty"`
+	Nearest          []*Neighbour         `json:"nearest,omitempty"`
+	Neighbours       *KnownNeighbours     `json:"neighbours,omitempty"`
+	Recommended      *TierInfo            `json:"recommended,omitempty"`
+	Compared         *TierInfo            `json:"compared,omitempty"`
+	Plan             []*PlanStep          `json:"plan,omitempty"`
+	NearestValid     *TierInfo            `json:"nearest_valid,omitempty"`
+	CorrectedCurrent *TierInfo            `json:"corrected_current,omitempty"`
+	ValidDowngrade   *bool                `json:"valid_downgrade,omitempty"`
+	ValidUpgrade     *bool                `json:"valid_upgrade,omitempty"`
+	ValidReplica     *bool                `json:"valid_replica,omitempty"`
+	Pair             *ReplicaPair         `json:"pair,omitempty"`
+	Delta            *Delta               `json:"delta,omitempty"`
+	Verdict          *Verdict             `json:"verdict,omitempty"`
+	Direction        string               `json:"direction,omitempty"`
+	Savings          *Savings             `json:"savings,omitempty"`
+	Aggressive       bool                 `json:"aggressive,omitempty"`
+	MonthlyDelta     *float64             `json:"monthly_cost_delta,omitempty"`
+	CommitmentCosts  []*CommitmentCost    `json:"commitment_costs,omitempty"`
+	FlagViolations   []FlagViolation      `json:"flag_violations,omitempty"`
+	PolicyViolations []string             `json:"policy_violations,omitempty"`
+	PolicyCodes      []string             `json:"policy_violation_codes,omitempty"`
+	MySQLConfig      *MySQLConfig         `json:"mysql_config,omitempty"`
+	Equivalents      []*MachineMatch      `json:"gce_equivalents,omitempty"`
+	RDSEquivalents   []*MachineMatch      `json:"rds_equivalents,omitempty"`
+	GcloudCommand    string               `json:"gcloud_command,omitempty"`
+	Apply            *Apply               `json:"apply,omitempty"`
+	Warnings         []string             `json:"warnings,omitempty"`
+	WarningCodes     []string             `json:"warning_codes,omitempty"`
+	Explanation      explanation          `json:"explanation,omitempty"`
+	Message          string               `json:"message,omitempty"`
+	Code             string               `json:"code,omitempty"`
+	Error            string               `json:"error,omitempty"`
+	ErrorCode        string               `json:"error_code,omitempty"`
+	Version          *BuildInfo           `json:"version,omitempty"`
+
+	text strings.Builder
+}
+
+// StrategyCandidate is the tier one -downgrade strategy suggests.
+type StrategyCandidate struct {
+	Strategy string `json:"strategy"`
+	*TierInfo
+}
+
+// Spare is how far a tier exceeds the requested vCPUs and memory. It is not
+// -headroom, which pads the request before sizing.
+type Spare struct {
+	CPUs  float64 `json:"cpus"`
+	MemMB float64 `json:"mem_mb"`
+}
+
+func newResult(mode string) *Result {
+	return &Result{Mode: mode, Engine: rules.Engine, Edition: rules.Edition, HA: opts.ha}
+}
+
+// suggest records t as the suggested tier.
+func (r *Result) suggest(t Tier) {
+	r.Suggested = describe(t)
+	r.SuggestedTier = r.Suggested.Tier
+}
+
+// noteEquivalent tells the user the canonical form of an input that was
+// given under another name, such as a legacy db-n1 tier.
+func (r *Result) noteEquivalent(input string, t Tier) {
+	if strings.TrimSpace(input) != t.String() {
+		r.printf("%s is equivalent to %s\n", strings.TrimSpace(input), t)
+	}
+}
+
+// printf appends human-readable output.
+func (r *Result) printf(format string, a ...any) {
+	fmt.Fprintf(&r.text, format, a...)
+}
+
+// println appends a human-readable line.
+func (r *Result) println(a ...any) {
+	fmt.Fprintln(&r.text, a...)
+}
+
+// warnf records a warning and its Code* code. Warnings are kept out of the report text and
+// written to stderr, so they do not mix with results piped from stdout.
+func (r *Result) warnf(code, format string, a ...any) {
+	r.Warnings = append(r.Warnings, fmt.Sprintf(format, a...))
+	r.WarningCodes = append(r.WarningCodes, code)
+}
+
+// sizeAt records the ratio a tier was sized at, warning when the default
+// ratio had to be moved into the engine's band or above the policy minimum.
+func (r *Result) sizeAt(ratio float64) {
+	r.SizingRatio = ratio
+	if opts.ratioNote != "" && ratio == opts.ratio {
+		r.warnf(CodeRatioAdjusted, "%s", opts.ratioNote)
+	}
+}
+
+// quietTier returns the single tier -q prints: a valid -t or validate tier
+// itself in canonical form, otherwise the target tier, or the primary tier when the
+// mode keeps it unchanged. It is "" when there is no valid answer.
+func (r *Result) quietTier() string {
+	keeps := r.TierInfo != nil && r.Valid
+	if (r.Mode == "tier" || r.Mode == "validate") && keeps {
+		return r.Tier
+	}
+	if t := r.targetTier(); t != "" {
+		return t
+	}
+	switch r.Mode {
+	case "bump-mem", "bump-cpu", "rightsize":
+		if keeps {
+			return r.Tier
+		}
+	}
+	return ""
+}
+
+// targetTier returns the tier this result recommends moving to, or "" when
+// there is none. For check modes that is the recommended tier, and only when
+// the change is valid.
+func (r *Result) targetTier() string {
+	switch {
+	case r.ValidDowngrade != nil:
+		if *r.ValidDowngrade {
+			return r.Recommended.Tier
+		}
+		return ""
+	case r.ValidUpgrade != nil:
+		if *r.ValidUpgrade {
+			return r.Recommended.Tier
+		}
+		return ""
+	case r.ValidReplica != nil:
+		if *r.ValidReplica {
+			return r.Recommended.Tier
+		}
+		return ""
+	case r.Mode == "cpu" || r.Mode == "mem" || r.Mode == "cpu-mem" || r.Mode == "from-rds" || r.Mode == "data-size" || r.Mode == "connections" || r.Mode == "cheapest":
+		if r.TierInfo != nil && r.Valid {
+			return r.Tier
+		}
+		return ""
+	}
+	return r.SuggestedTier
+}
+
+// resolvedTier returns the tier settings should be sized for: the
+// recommended tier in check modes, the parsed tier itself for a valid -t,
+// otherwise the target tier, or else the primary tier.
+func (r *Result) resolvedTier() string {
+	switch {
+	case r.Recommended != nil:
+		return r.Recommended.Tier
+	case r.TierInfo != nil && r.Mode == "tier" && r.Valid:
+		return r.Tier
+	case r.targetTier() != "":
+		return r.targetTier()
+	case r.TierInfo != nil:
+		return r.Tier
+	}
+	return ""
+}
+
+// comparisonTier returns the tier this result is weighed against the primary
+// tier with: the recommendation in check modes, the second tier of diff,
+// otherwise the suggestion.
+func (r *Result) comparisonTier() *TierInfo {
+	if r.Recommended != nil {
+		return r.Recommended
+	}
+	if r.Compared != nil {
+		return r.Compared
+	}
+	return r.Suggested
+}
+
+// addSteps lists up to n known tiers above t (or below it when down is set).
+func (r *Result) addSteps(t Tier, n int, down bool) {
+	direction, find := "higher", findNextKnownTier
+	if down {
+		direction, find = "lower", findPreviousKnownTier
+	}
+	r.Steps = []*TierInfo{}
+	r.printf("Next %d %s known tiers:\n", n, direction)
+	for i := 1; i <= n; i++ {
+		next, ok := find(t)
+		if ok && !down && policy.above(next) {
+			r.printf("  At the policy max_tier %s: no known tier above %s within policy.\n", policy.MaxTier, t)
+			return
+		}
+		if ok && down && policy.below(next) {
+			r.printf("  At the policy min_tier %s: no known tier below %s within policy.\n", policy.MinTier, t)
+			return
+		}
+		if !ok && !down {
+			r.printf("  At maximum tier: no known tier above %s under %s %s (largest valid tier %s).\n", t, rules.Name, rules.editionName(), rules.maxTier())
+			return
+		}
+		if !ok {
+			r.printf("  Only %d %s known tiers exist.\n", i-1, direction)
+			return
+		}
+		info := describe(next)
+		r.Steps = append(r.Steps, info)
+		r.printf("  %d. %s\n", i, info.summary())
+		t = next
+	}
+}
+
+// addNearest lists the n known tiers closest to t.
+func (r *Result) addNearest(t Tier, n int) {
+	r.Nearest = nearestKnownTiers(t, n, opts.cpuWeight, opts.memWeight)
+	r.printf("Nearest %d known tiers (vCPU weight %g, memory weight %g):\n", n, opts.cpuWeight, opts.memWeight)
+	for i, nb := range r.Nearest {
+		r.printf("  %d. %s [%s, distance %.3f]\n", i+1, nb.summary(), nb.Direction, nb.Distance)
+	}
+}
+
+// addNeighbours records how t compares with the known tiers on either side.
+func (r *Result) addNeighbours(t Tier) {
+	ns := knownNeighbours(t)
+	r.Neighbours = ns
+	if ns.Exact {
+		r.printf("%s is a known tier.\n", t)
+	}
+	if ns.Below != nil {
+		r.printf("Known tier below: %s\n", ns.Below.text())
+	}
+	if ns.Above != nil {
+		r.printf("Known tier above: %s\n", ns.Above.text())
+	}
+}
+
+// setError records a failure that prevented the result from being computed.
+func (r *Result) setError(err error) {
+	r.Error, r.ErrorCode = err.Error(), reasonCode(err)
+}
+
+func (r *Result) setVersion(bi *BuildInfo) {
+	r.Version = bi
+}
+
+// humanText returns the accumulated human-readable output.
+func (r *Result) humanText() string {
+	if len(r.Explanation) > 0 {
+		return r.text.String() + r.Explanation.text()
+	}
+	return r.text.String()
+}
+
+// exitCode reports the process exit code for a successfully computed result.
+func (r *Result) exitCode() int {
+	if r.CorrectedCurrent != nil && r.ValidDowngrade == nil && r.ValidUpgrade == nil {
+		return exitCurrent
+	}
+	if r.ValidDowngrade != nil && !*r.ValidDowngrade {
+		return exitInvalid
+	}
+	if r.ValidUpgrade != nil && !*r.ValidUpgrade {
+		return exitInvalid
+	}
+	if r.ValidReplica != nil && !*r.ValidReplica {
+		return exitInvalid
+	}
+	if r.TierInfo != nil && !r.Valid {
+		return exitInvalid
+	}
+	if r.Compared != nil && !r.Compared.Valid {
+		return exitInvalid
+	}
+	if opts.output == "quiet" && r.quietTier() == "" {
+		return exitInvalid
+	}
+	if len(r.FlagViolations) > 0 {
+		return exitInvalid
+	}
+	if len(r.PolicyViolations) > 0 {
+		return exitPolicy
+	}
+	if r.Apply != nil && r.Apply.Refused != "" {
+		return exitInvalid
+	}
+	if opts.strict && len(r.Warnings) > 0 {
+		return exitInvalid
+	}
+	return exitOK
+}
+
+// report is the common interface of everything main can emit: a single
+// Result or an aggregate such as a batch run.
+type report interface {
+	humanText() string
+	exitCode() int
+	setError(err error)
+}
+
+// resultCSVHeader is the CSV header for single results and batch records.
+var resultCSVHeader = []string{"input", "cpus", "ram_mb", "ram_gb", "ratio", "valid", "reason", "code", "suggested_tier"}
+
+// input describes what the result was computed from: the input tier, or the
+// requested vCPUs and memory.
+func (r *Result) input() string {
+	if r.InputTier != "" {
+		return r.InputTier
+	}
+	var parts []string
+	if r.RequestedCPUs != 0 {
+		parts = append(parts, fmt.Sprintf("%g vCPUs", r.RequestedCPUs))
+	}
+	if r.RequestedMemMB != 0 {
+		parts = append(parts, fmt.Sprintf("%.0f MB", r.RequestedMemMB))
+	}
+	return strings.Join(parts, " ")
+}
+
+// csvRow returns the result as a row under resultCSVHeader.
+func (r *Result) csvRow() []string {
+	row := []string{r.input(), "", "", "", "", "", r.Error, r.ErrorCode, r.SuggestedTier}
+	if r.TierInfo != nil {
+		row[1] = strconv.Itoa(r.CPUs)
+		row[2] = strconv.Itoa(r.RAMMB)
+		row[3] = strconv.FormatFloat(r.RAMGB, 'f', 2, 64)
+		row[4] = strconv.FormatFloat(r.Ratio, 'f', 2, 64)
+		row[5] = strconv.FormatBool(r.Valid)
+		if r.Error == "" {
+			row[6], row[7] = strings.Join(r.Reasons, "; "), strings.Join(r.Codes, ";")
+		}
+	}
+	return row
+}
+
+func (r *Result) csvRecords() [][]string {
+	return [][]string{resultCSVHeader, r.csvRow()}
+}
+
+// writeQuiet prints only the resulting tier, or nothing when there is none
+// (or, with -strict, when there are warnings).
+func writeQuiet(w io.Writer, r report) error {
+	res, ok := r.(*Result)
+	if !ok {
+		return fmt.Errorf("-q is only supported for single-tier modes")
+	}
+	if opts.strict && len(res.Warnings) > 0 {
+		return nil
+	}
+	if t := res.quietTier(); t != "" {
+		_, err := fmt.Fprintln(w, t)
+		return err
+	}
+	return nil
+}
+
+// tabular is implemented by reports that can be written as CSV.
+type tabular interface {
+	csvRecords() [][]string
+}
+
+// emit writes the report to w in the selected output format.
+func emit(w io.Writer, format string, r report) error {
+	switch format {
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		enc.SetEscapeHTML(false)
+		return enc.Encode(r)
+	case "yaml":
+		return writeYAML(w, r)
+	case "template":
+		return writeTemplate(w, r)
+	case "quiet":
+		return writeQuiet(w, r)
+	case "gha":
+		return writeGHA(w, r)
+	case "slack":
+		return writeSlack(w, r)
+	case "ndjson":
+		return writeNDJSON(w, r)
+	case "terraform":
+		return writeTerraform(w, r)
+	case "k8s":
+		return writeK8s(w, r)
+	case "csv":
+		t, ok := r.(tabular)
+		if !ok {
+			return fmt.Errorf("csv output is not supported for this mode")
+		}
+		cw := csv.NewWriter(w)
+		cw.WriteAll(t.csvRecords())
+		return cw.Error()
+	default:
+		_, err := io.WriteString(w, r.humanText())
+		return err
+	}
+}
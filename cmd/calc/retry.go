@@ -0,0 +1,118 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+)
+
+// Backoff between attempts of an API call: retryBase, doubled for each
+// retry up to retryMax, of which a random half is waited. A Retry-After
+// from the server is honoured up to retryAfterMax.
+const (
+	retryBase     = 500 * time.Millisecond
+	retryMax      = 8 * time.Second
+	retryAfterMax = 30 * time.Second
+)
+
+// authHint is how to fix an authentication or permission failure.
+const authHint = "run 'gcloud auth application-default login' (with an account that has the permission)"
+
+// apiResponse is the last response of an API call, with its body read.
+type apiResponse struct {
+	status   int
+	header   http.Header
+	body     []byte
+	attempts int
+	text     string // e.g. "503 Service Unavailable"
+}
+
+// retryable reports whether a response status may succeed on a retry: rate
+// limiting and server errors.
+func retryable(status int) bool {
+	return status == http.StatusTooManyRequests || status >= 500
+}
+
+// retryWait is how long to wait before retry n of a call that got resp, or
+// failed without one.
+var retryWait = func(n int, resp *apiResponse) time.Duration {
+	if resp != nil {
+		if s, err := strconv.Atoi(resp.header.Get("Retry-After")); err == nil && s > 0 {
+			return min(time.Duration(s)*time.Second, retryAfterMax)
+		}
+	}
+	d := min(retryBase<<(n-1), retryMax)
+	return d/2 + rand.N(d/2)
+}
+
+// callAPI sends req with client and reads the response. Rate limiting,
+// server errors, and failures to connect are retried up to retries times
+// (-max-retries for API calls) with backoff, noting each retry on stderr;
+// other responses, such as permission denied or not found, are returned as
+// they are. Only a failure to get any response is an error.
+func callAPI(client *http.Client, api string, req *http.Request, retries int) (*apiResponse, error) {
+	ctx := req.Context()
+	for attempt := 1; ; attempt++ {
+		r := req.Clone(ctx)
+		if req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			r.Body = body
+		}
+		var res *apiResponse
+		resp, err := client.Do(r)
+		if err == nil {
+			var body []byte
+			body, err = io.ReadAll(resp.Body)
+			resp.Body.Close()
+			if err == nil {
+				res = &apiResponse{status: resp.StatusCode, header: resp.Header, body: body, attempts: attempt, text: resp.Status}
+				if !retryable(res.status) {
+					return res, nil
+				}
+			}
+		}
+		if attempt > retries || ctx.Err() != nil {
+			if res != nil {
+				return res, nil
+			}
+			return nil, newAPIError(ErrAPITransient, "%s: %v%s", api, err, attempts(attempt))
+		}
+		failure := fmt.Sprint(err)
+		if res != nil {
+			failure = res.text
+		}
+		wait := retryWait(attempt, res)
+		fmt.Fprintf(os.Stderr, "Note: %s: %s; retrying in %s (retry %d of %d)\n", api, failure, wait.Round(100*time.Millisecond), attempt, retries)
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return nil, newAPIError(ErrAPITransient, "%s: %v%s", api, ctx.Err(), attempts(attempt))
+		}
+	}
+}
+
+// attempts notes how many attempts a failed call took, when it was retried.
+func attempts(n int) string {
+	if n < 2 {
+		return ""
+	}
+	return fmt.Sprintf(" (after %d attempts)", n)
+}
+
+// failure is the error of a response no caller case handled: transient for
+// rate limiting and server errors, which were retried, a rejected request
+// otherwise.
+func (r *apiResponse) failure(api string) error {
+	kind := ErrAPIRequest
+	if retryable(r.status) {
+		kind = ErrAPITransient
+	}
+	return newAPIError(kind, "%s: %s: %s%s", api, r.text, apiMessage(r.body), attempts(r.attempts))
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// scriptedTransport answers each request with the next status of its
+// script, where 0 stands for a failure to connect. It records the bodies it
+// was sent.
+type scriptedTransport struct {
+	script []int
+	header http.Header
+	bodies []string
+}
+
+func (s *scriptedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		body, _ := io.ReadAll(req.Body)
+		s.bodies = append(s.bodies, string(body))
+	}
+	status := s.script[0]
+	s.script = s.script[1:]
+	if status == 0 {
+		return nil, errors.New("connection refused")
+	}
+	return &http.Response{
+		StatusCode: status, Status: fmt.Sprintf("%d %s", status, http.StatusText(status)), Header: s.header,
+		Body: io.NopCloser(strings.NewReader(`{"error":{"message":"try later"}}`)), Request: req,
+	}, nil
+}
+
+// noRetryWait retries at once for the rest of the test, recording the waits
+// asked for.
+func noRetryWait(t *testing.T) *[]time.Duration {
+	saved := retryWait
+	var waits []time.Duration
+	retryWait = func(n int, resp *apiResponse) time.Duration {
+		waits = append(waits, saved(n, resp))
+		return 0
+	}
+	t.Cleanup(func() { retryWait = saved })
+	return &waits
+}
+
+func TestCallAPI(t *testing.T) {
+	tests := []struct {
+		name       string
+		script     []int
+		retries    int
+		wantStatus int
+		attempts   int
+		wantErr    error
+	}{
+		{"ok", []int{200}, 3, 200, 1, nil},
+		{"server errors then ok", []int{503, 500, 200}, 3, 200, 3, nil},
+		{"rate limited then ok", []int{429, 200}, 3, 200, 2, nil},
+		{"connection failure then ok", []int{0, 0, 200}, 3, 200, 3, nil},
+		{"server errors throughout", []int{503, 503, 503, 503}, 3, 503, 4, nil},
+		{"no retries", []int{503}, 0, 503, 1, nil},
+		{"permission denied", []int{403}, 3, 403, 1, nil},
+		{"not found", []int{404}, 3, 404, 1, nil},
+		{"bad request", []int{400}, 3, 400, 1, nil},
+		{"connection failures throughout", []int{0, 0, 0}, 2, 0, 3, ErrAPITransient},
+	}
+	for _, tt := range tests {
+		noRetryWait(t)
+		tr := &scriptedTransport{script: tt.script}
+		req, _ := http.NewRequest(http.MethodPost, "https://example.com/api", strings.NewReader("grant_type=x"))
+		resp, err := callAPI(&http.Client{Transport: tr}, "Test API", req, tt.retries)
+		if tt.wantErr != nil {
+			if !errors.Is(err, tt.wantErr) || !strings.Contains(err.Error(), fmt.Sprintf("after %d attempts", tt.attempts)) {
+				t.Errorf("%s: error = %v, want %v after %d attempts", tt.name, err, tt.wantErr, tt.attempts)
+			}
+			continue
+		}
+		if err != nil || resp.status != tt.wantStatus || resp.attempts != tt.attempts {
+			t.Errorf("%s = %+v, %v, want %d after %d attempts", tt.name, resp, err, tt.wantStatus, tt.attempts)
+			continue
+		}
+		if len(tr.script) != 0 {
+			t.Errorf("%s left %d responses unused", tt.name, len(tr.script))
+		}
+		for _, body := range tr.bodies {
+			if body != "grant_type=x" {
+				t.Errorf("%s sent body %q on a retry, want it resent", tt.name, body)
+			}
+		}
+	}
+}
+
+func TestAPIFailure(t *testing.T) {
+	tests := []struct {
+		status, attempts int
+		want             error
+		text             string
+	}{
+		{503, 4, ErrAPITransient, "Test API: 503 Service Unavailable: try later (after 4 attempts)"},
+		{429, 1, ErrAPITransient, "Test API: 429 Too Many Requests: try later"},
+		{400, 1, ErrAPIRequest, "Test API: 400 Bad Request: try later"},
+	}
+	for _, tt := range tests {
+		r := &apiResponse{status: tt.status, text: fmt.Sprintf("%d %s", tt.status, http.StatusText(tt.status)), body: []byte(`{"error":{"message":"try later"}}`), attempts: tt.attempts}
+		err := r.failure("Test API")
+		if !errors.Is(err, tt.want) || err.Error() != tt.text {
+			t.Errorf("failure of %d after %d attempts = %v, want %v: %q", tt.status, tt.attempts, err, tt.want, tt.text)
+		}
+	}
+}
+
+func TestRetryWait(t *testing.T) {
+	if got := retryWait(1, &apiResponse{header: http.Header{"Retry-After": {"120"}}}); got != retryAfterMax {
+		t.Errorf("Retry-After 120 waited %s, want %s", got, retryAfterMax)
+	}
+	waits := noRetryWait(t)
+	tr := &scriptedTransport{script: []int{429, 429, 503, 503, 503, 503, 200}, header: http.Header{}}
+	tr.header.Set("Retry-After", "5")
+	req, _ := http.NewRequest(http.MethodGet, "https://example.com/api", nil)
+	client := &http.Client{Transport: tr}
+	if _, err := callAPI(client, "Test API", req, 1); err != nil {
+		t.Fatal(err)
+	}
+	tr.header.Del("Retry-After")
+	if _, err := callAPI(client, "Test API", req, 5); err != nil {
+		t.Fatal(err)
+	}
+	// Retry-After is honoured up to its cap; otherwise the backoff doubles
+	// from retryBase up to retryMax, of which half or more is waited.
+	if got := *waits; len(got) != 5 || got[0] != 5*time.Second {
+		t.Fatalf("waits = %v, want 5s then four backoffs", got)
+	}
+	for i, w := range (*waits)[1:] {
+		d := min(retryBase<<i, retryMax)
+		if w < d/2 || w >= d {
+			t.Errorf("backoff before retry %d = %s, want in [%s, %s)", i+1, w, d/2, d)
+		}
+	}
+}
+
+// A call cancelled while waiting to retry gives up at once.
+func TestCallAPICancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	saved := retryWait
+	retryWait = func(int, *apiResponse) time.Duration {
+		cancel()
+		return time.Hour
+	}
+	t.Cleanup(func() { retryWait = saved })
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, "https://example.com/api", nil)
+	_, err := callAPI(&http.Client{Transport: &scriptedTransport{script: []int{503, 200}}}, "Test API", req, 3)
+	if !errors.Is(err, ErrAPITransient) || !strings.Contains(err.Error(), "context canceled") {
+		t.Errorf("cancelled call error = %v, want a transient failure from the cancellation", err)
+	}
+}
+
+func TestMaxRetriesFlag(t *testing.T) {
+	if _, code := run(t, "next", "-max-retries", "-1", "db-custom-4-15360"); code != exitUsage {
+		t.Errorf("-max-retries -1 exited %d, want %d", code, exitUsage)
+	}
+}
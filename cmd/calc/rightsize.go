@@ -0,0 +1,129 @@
+package main
+
+import "fmt"
+
+// Usage is the observed peak utilization of a tier and the headroom to keep,
+// all in percent. CPUHeadroomPct, set by -cpu-headroom, replaces
+// HeadroomPct for vCPUs.
+type Usage struct {
+	CPUPct         float64  `json:"cpu_util_pct"`
+	MemPct         float64  `json:"mem_util_pct"`
+	HeadroomPct    float64  `json:"headroom_pct"`
+	CPUHeadroomPct *float64 `json:"cpu_headroom_pct,omitempty"`
+}
+
+// cpuHeadroom returns the headroom to keep on vCPUs.
+func (u Usage) cpuHeadroom() float64 {
+	if u.CPUHeadroomPct != nil {
+		return *u.CPUHeadroomPct
+	}
+	return u.HeadroomPct
+}
+
+// validate reports an error if a percentage is out of range. The samples of
+// a series were checked as it was read, with their lines.
+func (u Usage) validate(series *UsageSeries) error {
+	cpuOK := u.CPUPct > 0 && u.CPUPct <= 100 || series != nil
+	memOK := u.MemPct > 0 && u.MemPct <= 100 || series != nil
+	if !cpuOK || !memOK {
+		return fmt.Errorf("-cpu-util and -mem-util must be percentages above 0 and at most 100")
+	}
+	if u.HeadroomPct < 0 || u.HeadroomPct >= 100 || u.cpuHeadroom() < 0 || u.cpuHeadroom() >= 100 {
+		return fmt.Errorf("-headroom and -cpu-headroom must be percentages from 0 to below 100")
+	}
+	return nil
+}
+
+// required returns the vCPUs and memory in MB that keep the observed load of
+// t below (100 - headroom)% utilization.
+func (u Usage) required(t Tier) (cpu, memMB float64) {
+	return t.VCPUs() * u.CPUPct / 100 / (1 - u.cpuHeadroom()/100), float64(t.RAMMB) * u.MemPct / 100 / (1 - u.HeadroomPct/100)
+}
+
+// runRightsize recommends the smallest valid tier that carries the observed
+// load of input with the requested headroom.
+func runRightsize(input string, u Usage) (*Result, error) {
+	res := newResult("rightsize")
+	res.InputTier = input
+	res.Usage = &u
+	curr, err := ParseTier(input)
+	if err != nil {
+		return res, fmt.Errorf("invalid tier: %w", err)
+	}
+	if opts.monitor {
+		if res.Monitoring, err = observeUsage(curr); err != nil {
+			return res, err
+		}
+		u.CPUPct, u.MemPct = res.Monitoring.CPUPct, res.Monitoring.MemPct
+	}
+	if opts.usageCSV != "" {
+		cpuP, memP := usagePercentiles()
+		if res.Series, err = readUsageCSV(opts.usageCSV, cpuP, memP); err != nil {
+			return res, err
+		}
+		u.CPUPct, u.MemPct = res.Series.usagePcts(curr)
+	}
+	if err := u.validate(res.Series); err != nil {
+		return res, err
+	}
+	res.TierInfo = describe(curr)
+	res.noteEquivalent(input, curr)
+	cpu, memMB := u.required(curr)
+	observedMB := float64(curr.RAMMB) * u.MemPct / 100
+	if s := res.Series; s != nil {
+		// A dimension without samples keeps its current size. Memory is
+		// sized from the sampled MB rather than from a rounded percentage
+		if s.CPU == nil {
+			cpu = curr.VCPUs()
+		}
+		memMB = float64(curr.RAMMB)
+		if s.Mem != nil {
+			observedMB = s.Mem.Value
+			memMB = observedMB / (1 - u.HeadroomPct/100)
+		}
+	}
+	res.RequestedCPUs, res.RequestedMemMB = cpu, memMB
+
+	res.printf("Rightsizing %s:\n", input)
+	if m := res.Monitoring; m != nil {
+		res.printf("  Cloud Monitoring: %s of %d CPU and %d memory samples (%g-minute peaks) for %s over %s (%s to %s UTC)\n",
+			m.percentiles(), m.CPUSamples, m.MemSamples, monitorAlign.Minutes(), m.Instance, m.Window, m.Start.Format("2006-01-02 15:04"), m.End.Format("2006-01-02 15:04"))
+		res.printf("    CPU utilization: %g%%, memory used: %.0f MB (%g%%)\n", m.CPUPct, m.MemMB, m.MemPct)
+	}
+	if res.Series != nil {
+		res.printSeries(res.Series)
+	}
+	res.printf("  Observed peak: %g%% of %g vCPUs = %.2f vCPUs, %g%% of %d MB = %.0f MB\n",
+		u.CPUPct, curr.VCPUs(), curr.VCPUs()*u.CPUPct/100, u.MemPct, curr.RAMMB, observedMB)
+	if u.cpuHeadroom() == u.HeadroomPct {
+		res.printf("  Required at %g%% headroom (utilization at most %g%%): %.2f vCPUs, %.0f MB (%.2f GB)\n",
+			u.HeadroomPct, 100-u.HeadroomPct, cpu, memMB, memMB/1024)
+	} else {
+		res.printf("  Required at %g%% vCPU and %g%% memory headroom: %.2f vCPUs, %.0f MB (%.2f GB)\n",
+			u.cpuHeadroom(), u.HeadroomPct, cpu, memMB, memMB/1024)
+	}
+	res.println("  Assumes load scales linearly with vCPUs and memory.")
+
+	// Shared-core tiers have no SLA, so rightsizing stays on custom tiers
+	rec, binding, err := smallestTierFor(cpu, memMB)
+	if err != nil {
+		return res, fmt.Errorf("cannot fit the observed load: %w", err)
+	}
+	res.Binding = binding
+	if rec == curr {
+		res.Message = "no change recommended"
+		res.println("No change recommended: the current tier is already the best fit.")
+		return res, nil
+	}
+	res.suggest(rec)
+	res.Delta = new(Delta)
+	*res.Delta = compareTiers(curr, rec)
+	res.printf("Recommended tier: %s (%g vCPUs, %d MB, %.2f GB/vCPU; binding: %s)\n",
+		rec, rec.VCPUs(), rec.RAMMB, rec.Ratio(), res.Binding)
+	res.printf("  Change: %s\n", res.Delta)
+	if known, ok := smallestKnownTierFor(cpu, memMB); ok && known != rec {
+		res.Known = describe(known)
+		res.printf("  Smallest known tier that fits: %s\n", known)
+	}
+	return res, nil
+}
@@ -0,0 +1,84 @@
+package main
+
+import "math"
+
+// Rounding modes of -round. Without it each path keeps its own default:
+// memory rounds up for -cpu, -mem, and -scale and down for -bump-mem, and
+// vCPUs round up for -cpu and -scale and to the nearest count for -mem.
+const (
+	roundUp      = "up"
+	roundDown    = "down"
+	roundNearest = "nearest"
+)
+
+// rounding returns the -round mode, or def when -round is not given.
+func rounding(def string) string {
+	if opts.round != "" {
+		return opts.round
+	}
+	return def
+}
+
+// roundedBy describes rounding by mode, as "rounded up", "rounded down", or
+// "rounded to nearest".
+func roundedBy(mode string) string {
+	if mode == roundNearest {
+		return "rounded to nearest"
+	}
+	return "rounded " + mode
+}
+
+// roundedTo is roundedBy before a value: "rounded up to", or just "rounded
+// to" for nearest.
+func roundedTo(mode string) string {
+	if mode == roundNearest {
+		return "rounded to"
+	}
+	return roundedBy(mode) + " to"
+}
+
+// roundMem rounds mb to the 256 MB memory step by mode.
+func roundMem(mb float64, mode string) int {
+	switch mode {
+	case roundDown:
+		return roundDown256(int(math.Floor(mb)))
+	case roundNearest:
+		return int(math.Round(mb/256)) * 256
+	}
+	return roundUp256(int(math.Ceil(mb)))
+}
+
+// wholeCPUs rounds cpu to a whole vCPU count by mode, at least 1. Rounding
+// down also steps an odd count down to the even one below it; callers snap
+// other counts up to a legal one.
+func wholeCPUs(cpu float64, mode string) int {
+	switch mode {
+	case roundDown:
+		n := int(math.Floor(cpu))
+		if n > 1 && n%2 != 0 {
+			n--
+		}
+		return max(n, 1)
+	case roundNearest:
+		return max(int(math.Round(cpu)), 1)
+	}
+	return max(int(math.Ceil(cpu)), 1)
+}
+
+// snapMem rounds mb for cpu vCPUs by mode. When rounding down or to the
+// nearest step leaves the memory outside what cpu vCPUs allow and rounding up
+// would not, it rounds up instead with a warning, so rounding never makes a
+// tier invalid.
+func (r *Result) snapMem(mb float64, cpu int, mode string, ex *explanation) int {
+	rounded := roundMem(mb, mode)
+	ex.add("round-ram", float64(rounded) == mb, "%.0f MB → %d MB (multiple of 256, %s)", mb, rounded, roundedBy(mode))
+	if mode == roundUp {
+		return rounded
+	}
+	if t, up := (Tier{CPUs: cpu, RAMMB: rounded}), roundMem(mb, roundUp); !t.Valid() && (Tier{CPUs: cpu, RAMMB: up}).Valid() {
+		r.warnf(CodeRAMRoundedUp, "memory %s to %d MB makes %s invalid; rounded up to %d MB instead", roundedBy(mode), rounded, t, up)
+		ex.add("round-ram-up", false, "%d MB → %d MB (%s leaves the valid range)", rounded, up, roundedBy(mode))
+		return up
+	}
+	return rounded
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"encoding/json"
+	"slices"
+	"testing"
+)
+
+func TestRoundMem(t *testing.T) {
+	tests := []struct {
+		mb                float64
+		up, down, nearest int
+	}{
+		{3840, 3840, 3840, 3840},
+		{3841, 4096, 3840, 3840},
+		{3967.9, 4096, 3840, 3840},
+		{3968, 4096, 3840, 4096},
+		{20000, 20224, 19968, 19968},
+	}
+	for _, tt := range tests {
+		for mode, want := range map[string]int{roundUp: tt.up, roundDown: tt.down, roundNearest: tt.nearest} {
+			if got := roundMem(tt.mb, mode); got != want {
+				t.Errorf("roundMem(%g, %s) = %d, want %d", tt.mb, mode, got, want)
+			}
+		}
+	}
+}
+
+func TestWholeCPUs(t *testing.T) {
+	tests := []struct {
+		cpu               float64
+		up, down, nearest int
+	}{
+		{0.2, 1, 1, 1},
+		{1, 1, 1, 1},
+		{1.5, 2, 1, 2},
+		{4.4, 5, 4, 4},
+		{5, 5, 4, 5},
+		{5.6, 6, 4, 6},
+	}
+	for _, tt := range tests {
+		for mode, want := range map[string]int{roundUp: tt.up, roundDown: tt.down, roundNearest: tt.nearest} {
+			if got := wholeCPUs(tt.cpu, mode); got != want {
+				t.Errorf("wholeCPUs(%g, %s) = %d, want %d", tt.cpu, mode, got, want)
+			}
+		}
+	}
+}
+
+func TestRoundFlag(t *testing.T) {
+	tests := []struct {
+		args    []string
+		want    string
+		warning string
+	}{
+		{[]string{"-cpu", "2", "-ratio", "3.3"}, "db-custom-2-6912", ""},
+		{[]string{"-cpu", "2", "-ratio", "3.3", "-round", "down"}, "db-custom-2-6656", ""},
+		{[]string{"-cpu", "2", "-ratio", "3.3", "-round", "nearest"}, "db-custom-2-6656", ""},
+		{[]string{"-cpu", "4.4"}, "db-custom-6-9216", ""},
+		{[]string{"-cpu", "4.4", "-round", "nearest"}, "db-custom-4-6144", ""},
+		{[]string{"-cpu", "5", "-round", "down"}, "db-custom-4-6144", ""},
+		{[]string{"-cpu", "4", "-ratio", "0.9", "-round", "down"}, "db-custom-4-3840", CodeRAMRoundedUp},
+		{[]string{"-mem", "20000"}, "db-custom-14-20224", ""},
+		{[]string{"-mem", "20000", "-round", "down"}, "db-custom-12-19968", ""},
+	}
+	for _, tt := range tests {
+		out, code := run(t, append([]string{"suggest", "-o", "json"}, tt.args...)...)
+		var res struct {
+			Tier     string   `json:"tier"`
+			Warnings []string `json:"warning_codes"`
+		}
+		if err := json.Unmarshal([]byte(out), &res); err != nil {
+			t.Fatalf("suggest %q: %v\n%s", tt.args, err, out)
+		}
+		if res.Tier != tt.want || code != exitOK {
+			t.Errorf("suggest %q = %s (exit %d), want %s", tt.args, res.Tier, code, tt.want)
+		}
+		if tt.warning != "" && !slices.Contains(res.Warnings, tt.warning) {
+			t.Errorf("suggest %q warned %q, want %s", tt.args, res.Warnings, tt.warning)
+		}
+	}
+	if _, code := run(t, "suggest", "-cpu", "5", "-round", "sideways"); code != exitUsage {
+		t.Errorf("-round sideways exited %d, want %d", code, exitUsage)
+	}
+}
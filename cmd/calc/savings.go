@@ -0,0 +1,109 @@
+package main
+
+import (
+	"fmt"
+	"math"
+)
+
+// Savings is a -target-savings request and how close the chosen downgrade
+// came to it.
+type Savings struct {
+	Basis        string  `json:"basis"` // "resources" or "cost"
+	TargetPct    float64 `json:"target_pct"`
+	TolerancePct float64 `json:"tolerance_pct"`
+	AnyShape     bool    `json:"any_shape"`
+	AchievedPct  float64 `json:"achieved_pct"`
+}
+
+func (s Savings) validate() error {
+	if s.TargetPct <= 0 || s.TargetPct >= 100 {
+		return fmt.Errorf("-target-savings must be between 0 and 100")
+	}
+	if s.TolerancePct < 0 {
+		return fmt.Errorf("-tolerance must not be negative")
+	}
+	return nil
+}
+
+// savingsPct is the reduction from curr to t in percent: the monthly cost
+// reduction when prices are loaded, otherwise the mean of the vCPU and
+// memory reductions.
+func savingsPct(curr, t Tier) float64 {
+	if prices != nil {
+		from, to := describe(curr).Cost, describe(t).Cost
+		return (from.Monthly - to.Monthly) / from.Monthly * 100
+	}
+	d := compareTiers(curr, t)
+	return -(d.CPUPct + d.RAMPct) / 2
+}
+
+// savingsCandidates returns the valid tiers within policy that are clean
+// downgrades from curr: the known tiers, or with anyShape every custom shape
+// on the 256 MB memory step.
+func savingsCandidates(curr Tier, anyShape bool) []Tier {
+	var ts []Tier
+	add := func(t Tier) {
+		if t.Valid() && compareTiers(curr, t).verdict().Change == "lower" && len(policy.violations(t)) == 0 {
+			ts = append(ts, t)
+		}
+	}
+	if !anyShape {
+		for _, k := range knownTiers {
+			add(k)
+		}
+		return ts
+	}
+	for cpu := rules.MinCPUs; cpu <= curr.CPUs; cpu++ {
+		if cpu != 1 && cpu%2 != 0 {
+			continue
+		}
+		for ram := roundUp256(rules.minRAMFor(cpu)); ram <= min(rules.maxRAMFor(cpu), curr.RAMMB); ram += 256 {
+			add(Tier{CPUs: cpu, RAMMB: ram})
+		}
+	}
+	return ts
+}
+
+// runTargetSavings picks the downgrade of curr whose savings are closest to
+// s.TargetPct, failing when none is within s.TolerancePct of it.
+func runTargetSavings(res *Result, curr Tier, s Savings) error {
+	if err := s.validate(); err != nil {
+		return err
+	}
+	if curr.Shared() {
+		return fmt.Errorf("-target-savings does not support shared-core tier %s", curr)
+	}
+	s.Basis = "resources"
+	if prices != nil {
+		s.Basis = "cost"
+	}
+	res.Savings = &s
+	var best Tier
+	bestPct, found := 0.0, false
+	for _, t := range savingsCandidates(curr, s.AnyShape) {
+		pct := savingsPct(curr, t)
+		if !found || math.Abs(pct-s.TargetPct) < math.Abs(bestPct-s.TargetPct) {
+			best, bestPct, found = t, pct, true
+		}
+	}
+	scope := "known tier"
+	if s.AnyShape {
+		scope = "valid custom shape"
+	}
+	if !found {
+		return fmt.Errorf("no %s is a downgrade from %s", scope, curr)
+	}
+	if math.Abs(bestPct-s.TargetPct) > s.TolerancePct {
+		return fmt.Errorf("no %s saves within %g%% of %g%% by %s: the closest, %s, saves %.1f%% (widen -tolerance or try -any-shape)",
+			scope, s.TolerancePct, s.TargetPct, s.Basis, best, bestPct)
+	}
+	s.AchievedPct = bestPct
+	res.suggest(best)
+	d := compareTiers(curr, best)
+	res.printf("Target savings: %g%% of %s (tolerance %g%%)\n", s.TargetPct, s.Basis, s.TolerancePct)
+	res.printf("Suggested downgrade tier: %s\n", best)
+	res.printf("  CPUs: %d, RAM: %d MB (%.2f GB)\n", best.CPUs, best.RAMMB, best.RAMGB())
+	res.printf("  Memory per vCPU: %.2f GB\n", best.Ratio())
+	res.printf("  Achieved savings: %.1f%% (%s)\n", s.AchievedPct, d)
+	return nil
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"fmt"
+	"math"
+)
+
+// Scaling is how -scale sized a tier: the multiplied resources before
+// snapping, and each adjustment made to reach a valid shape.
+type Scaling struct {
+	Factor      float64  `json:"factor"`
+	RawCPUs     float64  `json:"raw_cpus"`
+	RawRAMMB    float64  `json:"raw_ram_mb"`
+	Clamped     bool     `json:"clamped,omitempty"`
+	Adjustments []string `json:"adjustments,omitempty"`
+}
+
+// scaleBounds returns why cpus and ramMB are outside what any valid tier can
+// have, as the tier error of the limit they cross, or nil when they are not.
+func scaleBounds(cpus, ramMB float64) *TierError {
+	switch {
+	case cpus > float64(rules.MaxCPUs):
+		return newTierError(ErrCPUCount, cpus, "%g vCPUs, above the %s %s maximum of %d", cpus, rules.Name, rules.editionName(), rules.MaxCPUs).withCode(CodeCPURange)
+	case ramMB > float64(rules.MaxRAMMB):
+		return newTierError(ErrRAMTooHigh, ramMB, "%.0f MB, above the %s %s maximum of %d MB", ramMB, rules.Name, rules.editionName(), rules.MaxRAMMB)
+	case cpus < float64(rules.MinCPUs):
+		return newTierError(ErrCPUCount, cpus, "%g vCPUs, below the %s minimum of %d", cpus, rules.Name, rules.MinCPUs).withCode(CodeCPURange)
+	case ramMB < float64(rules.MinRAMMB):
+		return newTierError(ErrRAMTooLow, ramMB, "%.0f MB, below the %s minimum of %d MB", ramMB, rules.Name, rules.MinRAMMB)
+	}
+	return nil
+}
+
+// runScale multiplies the vCPUs and memory of a tier by factor and snaps the
+// result to the nearest valid shape. A result beyond the smallest or largest
+// shape is a *TierError unless -clamp is set.
+func runScale(input string, factor float64) (*Result, error) {
+	res := newResult("scale")
+	res.InputTier = input
+	t, err := ParseTier(input)
+	if err != nil {
+		return res, fmt.Errorf("invalid tier: %w", err)
+	}
+	if t.Shared() {
+		return res, fmt.Errorf("cannot scale shared-core tier %s: scale a db-custom tier", t)
+	}
+	res.TierInfo = describe(t)
+	res.noteEquivalent(input, t)
+	sc := &Scaling{Factor: factor, RawCPUs: float64(t.CPUs) * factor, RawRAMMB: float64(t.RAMMB) * factor}
+	res.Scaling = sc
+	cpus, ramMB := sc.RawCPUs, sc.RawRAMMB
+	if why := scaleBounds(cpus, ramMB); why != nil {
+		if !opts.clamp {
+			why.Msg = fmt.Sprintf("scaling %s by %g needs %s: use -clamp to stop at the limit", t, factor, why.Msg)
+			return res, why
+		}
+		sc.Clamped = true
+		cpus = min(max(cpus, float64(rules.MinCPUs)), float64(rules.MaxCPUs))
+		ramMB = min(max(ramMB, float64(rules.MinRAMMB)), float64(rules.MaxRAMMB))
+		sc.Adjustments = append(sc.Adjustments, fmt.Sprintf("clamped to %g vCPUs, %.0f MB (-clamp)", cpus, ramMB))
+		res.warnf(CodeScaleClamped, "scaling %s by %g needs %s; clamped to the limit", t, factor, why)
+	}
+	// Snapping to a valid shape rounds up; -round down or nearest goes to
+	// the 256 MB step first so that only an invalid shape moves up
+	mode := rounding(roundUp)
+	raw := Tier{CPUs: wholeCPUs(cpus, mode), RAMMB: int(math.Ceil(ramMB))}
+	step := "whole MB"
+	if mode != roundUp {
+		raw.RAMMB, step = roundMem(ramMB, mode), "multiple of 256"
+	}
+	if float64(raw.CPUs) != cpus {
+		sc.Adjustments = append(sc.Adjustments, fmt.Sprintf("%g vCPUs → %d (whole vCPUs, %s)", cpus, raw.CPUs, roundedBy(mode)))
+	}
+	if float64(raw.RAMMB) != ramMB {
+		sc.Adjustments = append(sc.Adjustments, fmt.Sprintf("%g MB → %d MB (%s, %s)", ramMB, raw.RAMMB, step, roundedBy(mode)))
+	}
+	var steps explanation
+	scaled := nearestValidTierExplained(raw, &steps)
+	for _, c := range steps {
+		if !c.Passed {
+			sc.Adjustments = append(sc.Adjustments, c.Detail)
+		}
+	}
+	if ex := res.explainer(); ex != nil {
+		*ex = append(*ex, steps...)
+	}
+	res.suggest(scaled)
+	delta := compareTiers(t, scaled)
+	res.Delta = &delta
+	res.printf("Scaling %s by %g:\n", t, factor)
+	res.printf("  Raw: %g vCPUs, %.0f MB (%.2f GB)\n", sc.RawCPUs, sc.RawRAMMB, sc.RawRAMMB/1024)
+	if len(sc.Adjustments) == 0 {
+		res.println("  No rounding needed")
+	}
+	for _, a := range sc.Adjustments {
+		res.printf("  Adjusted: %s\n", a)
+	}
+	res.printf("  Scaled tier: %s\n", res.Suggested.summary())
+	res.printf("  Change: %s\n", delta)
+	return res, nil
+}
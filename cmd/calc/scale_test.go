@@ -0,0 +1,62 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestScale(t *testing.T) {
+	tests := []struct {
+		args    []string
+		want    string
+		clamped bool
+	}{
+		{[]string{"db-custom-4-15360", "-scale", "2"}, "db-custom-8-30720", false},
+		{[]string{"db-custom-4-15360", "-scale", "1.5"}, "db-custom-6-23040", false},
+		{[]string{"db-custom-6-23040", "-scale", "1.5"}, "db-custom-10-34560", false},
+		{[]string{"db-custom-4-26624", "-scale", "0.3"}, "db-custom-2-8192", false},
+		{[]string{"db-custom-4-26624", "-scale", "0.3", "-round", "down"}, "db-custom-2-7936", false},
+		{[]string{"db-custom-64-245760", "-scale", "2", "-clamp"}, "db-custom-96-491520", true},
+	}
+	for _, tt := range tests {
+		out, code := run(t, append([]string{"next", "-o", "json"}, tt.args...)...)
+		var res struct {
+			Mode      string   `json:"mode"`
+			Suggested string   `json:"suggested_tier"`
+			Scaling   *Scaling `json:"scaling"`
+		}
+		if err := json.Unmarshal([]byte(out), &res); err != nil {
+			t.Fatalf("go-calc %q: %v\n%s", tt.args, err, out)
+		}
+		if code != exitOK || res.Mode != "scale" || res.Suggested != tt.want {
+			t.Errorf("go-calc %q = %s %s (exit %d), want scale %s", tt.args, res.Mode, res.Suggested, code, tt.want)
+		}
+		if res.Scaling == nil || res.Scaling.Clamped != tt.clamped {
+			t.Errorf("go-calc %q scaling = %+v, want clamped %t", tt.args, res.Scaling, tt.clamped)
+		}
+	}
+}
+
+func TestScaleOutOfRange(t *testing.T) {
+	tests := []struct {
+		args []string
+		code string
+	}{
+		{[]string{"db-custom-64-245760", "-scale", "2"}, CodeCPURange},
+		{[]string{"db-custom-2-7680", "-scale", "0.25"}, CodeCPURange},
+		{[]string{"db-custom-4-3840", "-scale", "0.5"}, CodeBelowMinRAM},
+	}
+	for _, tt := range tests {
+		out, code := run(t, append([]string{"next", "-o", "json"}, tt.args...)...)
+		var res struct {
+			Error     string `json:"error"`
+			ErrorCode string `json:"error_code"`
+		}
+		if err := json.Unmarshal([]byte(out), &res); err != nil {
+			t.Fatalf("go-calc %q: %v\n%s", tt.args, err, out)
+		}
+		if code != exitInvalid || res.ErrorCode != tt.code {
+			t.Errorf("go-calc %q = %s %q (exit %d), want %s (exit %d)", tt.args, res.ErrorCode, res.Error, code, tt.code, exitInvalid)
+		}
+	}
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// shutdownTimeout is how long serve waits for requests in flight to finish
+// once it is told to stop.
+const shutdownTimeout = 10 * time.Second
+
+// serveMu runs one calculation at a time: the modes read and switch the
+// package-level options and rules.
+var serveMu sync.Mutex
+
+// ServeResult is the outcome of serve: the address it listened on.
+type ServeResult struct {
+	Mode   string `json:"mode"`
+	Listen string `json:"listen"`
+	Error  string `json:"error,omitempty"`
+}
+
+func (s *ServeResult) humanText() string  { return "" }
+func (s *ServeResult) exitCode() int      { return exitOK }
+func (s *ServeResult) setError(err error) { s.Error = err.Error() }
+
+// errorBody is the response of a request that could not be answered: the
+// error, and its reason code.
+type errorBody struct {
+	Error string `json:"error"`
+	Code  string `json:"code"`
+}
+
+// checkRequest is the body of POST /v1/check-downgrade.
+type checkRequest struct {
+	Current     string `json:"current"`
+	Recommended string `json:"recommended"`
+	AllowMixed  bool   `json:"allow_mixed"`
+}
+
+// operation computes the result of an API request.
+type operation func(r *http.Request) (*Result, error)
+
+// newServeMux routes the HTTP API. Each calculation answers with the result
+// -o json would print for the same mode. With m, the requests are counted
+// and timed, and /metrics exports them.
+func newServeMux(m *serveMetrics) *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+	})
+	if m != nil {
+		mux.Handle("GET /metrics", m.handler())
+	}
+	mux.Handle("GET /v1/validate", answer(m, "validate", func(r *http.Request) (*Result, error) {
+		tier, err := tierParam(r)
+		if err != nil {
+			return nil, err
+		}
+		return runValidate(tier)
+	}))
+	mux.Handle("GET /v1/next", answer(m, "next", func(r *http.Request) (*Result, error) {
+		tier, err := tierParam(r)
+		if err != nil {
+			return nil, err
+		}
+		return runTier(tier)
+	}))
+	mux.Handle("GET /v1/suggest", answer(m, "suggest", func(r *http.Request) (*Result, error) {
+		q := r.URL.Query()
+		if q.Get("cpu") == "" && q.Get("mem") == "" {
+			return nil, fmt.Errorf("missing cpu or mem parameter")
+		}
+		var cpu float64
+		if s := q.Get("cpu"); s != "" {
+			var err error
+			if cpu, err = parseCPU(s); err != nil {
+				return nil, err
+			}
+		}
+		return runSuggest(cpu, q.Get("mem"))
+	}))
+	mux.Handle("POST /v1/check-downgrade", answer(m, "check-downgrade", func(r *http.Request) (*Result, error) {
+		var req checkRequest
+		dec := json.NewDecoder(r.Body)
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&req); err != nil {
+			return nil, fmt.Errorf("invalid request body: %w", err)
+		}
+		if req.Current == "" || req.Recommended == "" {
+			return nil, fmt.Errorf("request body needs current and recommended tiers")
+		}
+		defer func(allow bool) { opts.allowMixed = allow }(opts.allowMixed)
+		opts.allowMixed = opts.allowMixed || req.AllowMixed
+		return runCheckDowngrade(req.Current, req.Recommended)
+	}))
+	return mux
+}
+
+// tierParam returns the tier query parameter.
+func tierParam(r *http.Request) (string, error) {
+	tier := r.URL.Query().Get("tier")
+	if tier == "" {
+		return "", fmt.Errorf("missing tier parameter")
+	}
+	return tier, nil
+}
+
+// answer runs op and writes its annotated result, or 400 with the error and
+// its reason code. A tier that fails validation is a result, not an error:
+// its valid field is false, and the request counts as invalid.
+func answer(m *serveMetrics, name string, op operation) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		r.Body = http.MaxBytesReader(w, r.Body, 1<<16)
+		serveMu.Lock()
+		defer serveMu.Unlock()
+		selected := rules
+		defer func() { rules = selected }()
+		res, err := op(r)
+		if err == nil {
+			err = annotate(res)
+		}
+		if err != nil {
+			badRequest(w, err)
+			m.observe(name, "error", start)
+			return
+		}
+		res.setVersion(buildInfo())
+		writeJSON(w, http.StatusOK, res)
+		result := "valid"
+		if res.exitCode() != exitOK {
+			result = "invalid"
+		}
+		m.observe(name, result, start)
+	})
+}
+
+func badRequest(w http.ResponseWriter, err error) {
+	code := reasonCode(err)
+	if code == "" {
+		code = "BAD_REQUEST"
+	}
+	writeJSON(w, http.StatusBadRequest, errorBody{Error: err.Error(), Code: code})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	enc.Encode(v)
+}
+
+// runServe answers the HTTP API on -listen until SIGINT or SIGTERM, then
+// lets the requests in flight finish.
+func runServe() (*ServeResult, error) {
+	s := &ServeResult{Mode: "serve", Listen: opts.listen}
+	if opts.output != "text" {
+		return s, fmt.Errorf("serve answers in JSON and takes no -o")
+	}
+	if opts.apply {
+		return s, fmt.Errorf("serve does not take -apply")
+	}
+	var m *serveMetrics
+	if opts.metrics {
+		m = newServeMetrics()
+	}
+	srv := &http.Server{Addr: opts.listen, Handler: newServeMux(m), ReadHeaderTimeout: 10 * time.Second}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	errc := make(chan error, 1)
+	go func() { errc <- srv.ListenAndServe() }()
+	fmt.Fprintf(os.Stderr, "Serving the %s %s rules on %s\n", rules.Name, rules.editionName(), opts.listen)
+	select {
+	case err := <-errc:
+		return s, err
+	case <-ctx.Done():
+	}
+	fmt.Fprintln(os.Stderr, "Shutting down")
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(ctx); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return s, err
+	}
+	return s, nil
+}
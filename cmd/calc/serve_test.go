@@ -0,0 +1,161 @@
+package main
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os/exec"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestServeHandlers(t *testing.T) {
+	srv := httptest.NewServer(newServeMux(nil))
+	defer srv.Close()
+	tests := []struct {
+		method, path, body string
+		status             int
+		tier               string // of a 200 answer
+		valid              bool
+		code               string // of a 400 answer
+	}{
+		{"GET", "/healthz", "", 200, "", false, ""},
+		{"GET", "/v1/validate?tier=db-custom-4-16384", "", 200, "db-custom-4-16384", true, ""},
+		{"GET", "/v1/validate?tier=db-custom-3-16384", "", 200, "db-custom-3-16384", false, ""},
+		{"GET", "/v1/validate?tier=nonsense", "", 400, "", false, "TIER_SYNTAX"},
+		{"GET", "/v1/validate", "", 400, "", false, "BAD_REQUEST"},
+		{"GET", "/v1/next?tier=db-custom-4-16384", "", 200, "db-custom-4-16384", true, ""},
+		{"GET", "/v1/suggest?cpu=4&mem=16G", "", 200, "db-custom-4-16384", true, ""},
+		{"GET", "/v1/suggest?cpu=lots", "", 400, "", false, "BAD_REQUEST"},
+		{"GET", "/v1/suggest?mem=6X", "", 400, "", false, "MEM_UNIT"},
+		{"GET", "/v1/suggest", "", 400, "", false, "BAD_REQUEST"},
+		{"POST", "/v1/check-downgrade", `{"current":"db-custom-8-53248","recommended":"db-custom-4-16384"}`, 200, "db-custom-8-53248", true, ""},
+		{"POST", "/v1/check-downgrade", `{"current":"db-custom-8-53248"}`, 400, "", false, "BAD_REQUEST"},
+		{"POST", "/v1/check-downgrade", `{"current":"db-custom-8-53248","recommended":"db-custom-4-16384","force":true}`, 400, "", false, "BAD_REQUEST"},
+		{"POST", "/v1/check-downgrade", `{"current":"db-custom-8-53248","recommended":"db-custom-four"}`, 400, "", false, "TIER_SYNTAX"},
+		{"GET", "/v1/check-downgrade", "", 405, "", false, ""},
+		{"POST", "/v1/validate?tier=db-custom-4-16384", "", 405, "", false, ""},
+		{"GET", "/v1/missing", "", 404, "", false, ""},
+	}
+	for _, tt := range tests {
+		req, err := http.NewRequest(tt.method, srv.URL+tt.path, strings.NewReader(tt.body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := srv.Client().Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var body struct {
+			Status string `json:"status"`
+			Tier   string `json:"tier"`
+			Valid  bool   `json:"valid"`
+			Error  string `json:"error"`
+			Code   string `json:"code"`
+		}
+		err = json.NewDecoder(resp.Body).Decode(&body)
+		resp.Body.Close()
+		if resp.StatusCode != tt.status {
+			t.Errorf("%s %s = %d %+v, want %d", tt.method, tt.path, resp.StatusCode, body, tt.status)
+			continue
+		}
+		switch {
+		case tt.path == "/healthz":
+			if err != nil || body.Status != "ok" {
+				t.Errorf("GET /healthz = %+v, %v, want status ok", body, err)
+			}
+		case tt.status == 200:
+			if err != nil || body.Tier != tt.tier || body.Valid != tt.valid || resp.Header.Get("Content-Type") != "application/json" {
+				t.Errorf("%s %s = %+v, %v, want %s valid %t in JSON", tt.method, tt.path, body, err, tt.tier, tt.valid)
+			}
+		case tt.status == 400:
+			if err != nil || body.Code != tt.code || body.Error == "" {
+				t.Errorf("%s %s = %+v, %v, want an error with code %s", tt.method, tt.path, body, err, tt.code)
+			}
+		}
+	}
+}
+
+func TestServeCheckDowngrade(t *testing.T) {
+	srv := httptest.NewServer(newServeMux(nil))
+	defer srv.Close()
+	tests := []struct {
+		body string
+		want bool
+	}{
+		{`{"current":"db-custom-8-53248","recommended":"db-custom-4-16384"}`, true},
+		{`{"current":"db-custom-4-16384","recommended":"db-custom-8-53248"}`, false},
+		{`{"current":"db-custom-8-30720","recommended":"db-custom-6-38912"}`, false},
+		{`{"current":"db-custom-8-30720","recommended":"db-custom-6-38912","allow_mixed":true}`, true},
+	}
+	for _, tt := range tests {
+		resp, err := srv.Client().Post(srv.URL+"/v1/check-downgrade", "application/json", strings.NewReader(tt.body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var res struct {
+			ValidDowngrade *bool `json:"valid_downgrade"`
+		}
+		err = json.NewDecoder(resp.Body).Decode(&res)
+		resp.Body.Close()
+		if err != nil || res.ValidDowngrade == nil || *res.ValidDowngrade != tt.want {
+			t.Errorf("POST /v1/check-downgrade %s = %v, %v, want valid_downgrade %t", tt.body, res.ValidDowngrade, err, tt.want)
+		}
+	}
+	if opts.allowMixed {
+		t.Error("allow_mixed in a request left -allow-mixed set")
+	}
+}
+
+// TestServeShutdown runs serve and stops it with SIGTERM, which lets it
+// shut down cleanly.
+func TestServeShutdown(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := l.Addr().String()
+	l.Close()
+
+	var stderr strings.Builder
+	cmd := exec.Command(binary, "serve", "-listen", addr)
+	cmd.Stderr = &stderr
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer cmd.Process.Kill()
+	for deadline := time.Now().Add(10 * time.Second); ; time.Sleep(20 * time.Millisecond) {
+		resp, err := http.Get("http://" + addr + "/v1/validate?tier=db-custom-4-16384")
+		if err == nil {
+			resp.Body.Close()
+			if resp.StatusCode != http.StatusOK {
+				t.Fatalf("GET /v1/validate = %s, want 200", resp.Status)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("serve not answering on %s: %v\n%s", addr, err, stderr.String())
+		}
+	}
+	if err := cmd.Process.Signal(syscall.SIGTERM); err != nil {
+		t.Fatal(err)
+	}
+	if err := cmd.Wait(); err != nil {
+		t.Errorf("serve stopped with %v, want a clean exit\n%s", err, stderr.String())
+	}
+	if !strings.Contains(stderr.String(), "Serving the MySQL Enterprise rules on "+addr) || !strings.Contains(stderr.String(), "Shutting down") {
+		t.Errorf("serve stderr = %q, want the address and the shutdown", stderr.String())
+	}
+	if _, err := http.Get("http://" + addr + "/healthz"); err == nil {
+		t.Errorf("serve still answering after shutdown")
+	}
+
+	for _, args := range [][]string{{"serve", "-o", "json"}, {"serve", "-apply"}} {
+		if _, code := run(t, args...); code == exitOK {
+			t.Errorf("go-calc %q exited %d, want a failure", args, code)
+		}
+	}
+}
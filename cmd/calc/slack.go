@@ -0,0 +1,178 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"text/template"
+	"time"
+)
+
+//go:embed slack.json
+var slackTemplateText string
+
+// slackTemplate renders -o slack payloads. -format replaces it; the template
+// gets the same slackView.
+var slackTemplate = template.Must(template.New("slack").Funcs(templateFuncs).Parse(slackTemplateText))
+
+// Slack Block Kit limits on header and section text.
+const (
+	slackHeaderMax = 150
+	slackTextMax   = 3000
+)
+
+// notifyTimeout bounds a -notify-url post.
+const notifyTimeout = 10 * time.Second
+
+// httpDoer sends HTTP requests; *http.Client is the real one.
+type httpDoer interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// notifyClient is the client -notify-url posts with.
+var notifyClient httpDoer = http.DefaultClient
+
+// SlackField is one labelled value of a Slack payload.
+type SlackField struct {
+	Title string
+	Value string
+}
+
+// slackView is the data of a Slack payload template: a header, the fields
+// of a single result, the text output, and the warnings. Result is the
+// report itself, for -format templates that want other fields.
+type slackView struct {
+	Header   string
+	Fields   []SlackField
+	Text     string
+	Warnings []string
+	Result   report
+}
+
+// newSlackView summarises r for a Slack message.
+func newSlackView(r report) *slackView {
+	v := &slackView{Result: r, Text: strings.TrimSpace(r.humanText())}
+	// The text goes in a code block, which takes 8 of the characters
+	if len(v.Text) > slackTextMax-8 {
+		v.Text = v.Text[:slackTextMax-12] + "\n..."
+	}
+	switch r := r.(type) {
+	case *Result:
+		v.Header = "go-calc " + r.Mode
+		if in := r.input(); in != "" {
+			v.Header += ": " + in
+		}
+		v.Fields = r.slackFields()
+		v.Warnings = r.Warnings
+	case *BatchResult:
+		v.Header = fmt.Sprintf("go-calc batch: %d tiers, %d invalid", r.Summary.Total, r.Summary.Invalid)
+		v.Warnings = r.warnings()
+	case *FleetResult:
+		v.Header = fmt.Sprintf("go-calc fleet: %d instances, %d invalid", r.Totals.Instances, r.Totals.Invalid)
+	default:
+		v.Header = "go-calc"
+	}
+	if len(v.Header) > slackHeaderMax {
+		v.Header = v.Header[:slackHeaderMax-3] + "..."
+	}
+	return v
+}
+
+// slackFields returns the tiers, change, and cost of a result.
+func (r *Result) slackFields() []SlackField {
+	var fs []SlackField
+	add := func(title, format string, args ...any) {
+		fs = append(fs, SlackField{title, fmt.Sprintf(format, args...)})
+	}
+	other := r.comparisonTier()
+	if r.TierInfo != nil {
+		title := "Tier"
+		if other != nil {
+			title = "Current tier"
+		}
+		valid := "valid"
+		if !r.Valid {
+			valid = "invalid"
+		}
+		add(title, "%s (%s)", r.Tier, valid)
+	}
+	switch {
+	case r.Recommended != nil:
+		add("Recommended tier", "%s", r.Recommended.Tier)
+	case r.Compared != nil:
+		add("Compared tier", "%s", r.Compared.Tier)
+	}
+	if r.Suggested != nil {
+		add("Suggested tier", "%s", r.Suggested.Tier)
+	}
+	if r.Delta != nil {
+		add("Change", "%s", r.Delta)
+	}
+	if r.TierInfo != nil && r.Cost != nil {
+		cost := prices.money(r.Cost.Monthly) + "/mo"
+		if other != nil && other.Cost != nil {
+			cost += fmt.Sprintf(" → %s/mo (%s)", prices.money(other.Cost.Monthly), prices.deltaText(other.Cost.Monthly-r.Cost.Monthly))
+		}
+		add("Estimated cost", "%s", cost)
+	}
+	return fs
+}
+
+// slackPayload renders the Slack payload of r with the -format template, or
+// the built-in one.
+func slackPayload(r report) ([]byte, error) {
+	t := slackTemplate
+	if outputTemplate != nil {
+		t = outputTemplate
+	}
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, newSlackView(r)); err != nil {
+		return nil, err
+	}
+	if !json.Valid(buf.Bytes()) {
+		return nil, fmt.Errorf("slack payload is not valid JSON; check the -format template")
+	}
+	return buf.Bytes(), nil
+}
+
+// writeSlack prints the Slack payload of r.
+func writeSlack(w io.Writer, r report) error {
+	payload, err := slackPayload(r)
+	if err != nil {
+		return err
+	}
+	if _, err = w.Write(payload); err == nil && !bytes.HasSuffix(payload, []byte("\n")) {
+		_, err = io.WriteString(w, "\n")
+	}
+	return err
+}
+
+// notifySlack posts the Slack payload of r to -notify-url.
+func notifySlack(r report) error {
+	payload, err := slackPayload(r)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), notifyTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, opts.notifyURL, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("-notify-url: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := notifyClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("-notify-url: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("-notify-url: webhook returned %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
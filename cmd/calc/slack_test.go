@@ -0,0 +1,151 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"text/template"
+)
+
+// fakeDoer is an httpDoer that records the request and answers with a canned
+// status, or fails.
+type fakeDoer struct {
+	status int
+	body   string
+	err    error
+	req    *http.Request
+	sent   []byte
+}
+
+func (f *fakeDoer) Do(req *http.Request) (*http.Response, error) {
+	f.req = req
+	f.sent, _ = io.ReadAll(req.Body)
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &http.Response{StatusCode: f.status, Status: http.StatusText(f.status), Body: io.NopCloser(strings.NewReader(f.body))}, nil
+}
+
+// slackMessage is the part of a Block Kit payload the tests read.
+type slackMessage struct {
+	Text   string `json:"text"`
+	Blocks []struct {
+		Type string `json:"type"`
+		Text struct {
+			Text string `json:"text"`
+		} `json:"text"`
+		Fields []struct {
+			Text string `json:"text"`
+		} `json:"fields"`
+		Elements []struct {
+			Text string `json:"text"`
+		} `json:"elements"`
+	} `json:"blocks"`
+}
+
+func TestSlackPayload(t *testing.T) {
+	res, err := runCheckDowngrade("db-custom-16-106496", "db-custom-2-7680")
+	if err != nil {
+		t.Fatal(err)
+	}
+	payload, err := slackPayload(res)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var msg slackMessage
+	if err := json.Unmarshal(payload, &msg); err != nil {
+		t.Fatalf("payload is not JSON: %v\n%s", err, payload)
+	}
+	var types, fields []string
+	for _, b := range msg.Blocks {
+		types = append(types, b.Type)
+		for _, f := range b.Fields {
+			fields = append(fields, f.Text)
+		}
+	}
+	if got := strings.Join(types, ","); got != "header,section,section,context" {
+		t.Errorf("payload blocks = %s, want header,section,section,context", got)
+	}
+	if msg.Text != "go-calc check-downgrade: db-custom-16-106496" || msg.Blocks[0].Text.Text != msg.Text {
+		t.Errorf("payload header = %q, %q, want the mode and current tier", msg.Text, msg.Blocks[0].Text.Text)
+	}
+	for _, want := range []string{"*Current tier*\ndb-custom-16-106496 (valid)", "*Recommended tier*\ndb-custom-2-7680", "*Change*\n"} {
+		if !strings.Contains(strings.Join(fields, "\n"), want) {
+			t.Errorf("payload fields = %q, want %q", fields, want)
+		}
+	}
+	if w := msg.Blocks[3].Elements; len(w) == 0 || !strings.HasPrefix(w[0].Text, ":warning: aggressive downgrade") {
+		t.Errorf("payload warnings = %+v, want the aggressive downgrade warning", w)
+	}
+}
+
+func TestSlackLimits(t *testing.T) {
+	res := newResult("tier")
+	res.InputTier = strings.Repeat("x", 400)
+	res.printf("%s\n", strings.Repeat("line of output\n", 400))
+	v := newSlackView(res)
+	if len(v.Header) != slackHeaderMax || !strings.HasSuffix(v.Header, "...") {
+		t.Errorf("header of %d characters, want %d ending in ...", len(v.Header), slackHeaderMax)
+	}
+	if len(v.Text) > slackTextMax-8 || !strings.HasSuffix(v.Text, "\n...") {
+		t.Errorf("text of %d characters, want at most %d ending in ...", len(v.Text), slackTextMax-8)
+	}
+}
+
+func TestSlackFormat(t *testing.T) {
+	defer func(t *template.Template) { outputTemplate = t }(outputTemplate)
+	res, err := runTier("db-custom-4-16384")
+	if err != nil {
+		t.Fatal(err)
+	}
+	outputTemplate = template.Must(template.New("format").Funcs(templateFuncs).Parse(`{"text": {{json .Header}}, "tier": {{json .Result.Tier}}}`))
+	payload, err := slackPayload(res)
+	if err != nil || string(payload) != `{"text": "go-calc tier: db-custom-4-16384", "tier": "db-custom-4-16384"}` {
+		t.Errorf("-format payload = %s, %v", payload, err)
+	}
+	outputTemplate = template.Must(template.New("format").Parse(`not json`))
+	if _, err := slackPayload(res); err == nil || !strings.Contains(err.Error(), "not valid JSON") {
+		t.Errorf("-format payload that is not JSON: error = %v", err)
+	}
+}
+
+func TestNotifySlack(t *testing.T) {
+	defer func(c httpDoer, url string) { notifyClient, opts.notifyURL = c, url }(notifyClient, opts.notifyURL)
+	opts.notifyURL = "https://hooks.slack.test/services/T/B/X"
+	res, err := runTier("db-custom-4-16384")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name    string
+		doer    *fakeDoer
+		wantErr string
+	}{
+		{"ok", &fakeDoer{status: http.StatusOK, body: "ok"}, ""},
+		{"no content", &fakeDoer{status: http.StatusNoContent}, ""},
+		{"bad token", &fakeDoer{status: http.StatusForbidden, body: "invalid_token\n"}, "-notify-url: webhook returned Forbidden: invalid_token"},
+		{"gone", &fakeDoer{status: http.StatusGone, body: "channel_is_archived"}, "channel_is_archived"},
+		{"redirect", &fakeDoer{status: http.StatusFound}, "webhook returned Found"},
+		{"unreachable", &fakeDoer{err: errors.New("dial tcp: no such host")}, "-notify-url: dial tcp: no such host"},
+	}
+	for _, tt := range tests {
+		notifyClient = tt.doer
+		err := notifySlack(res)
+		if tt.wantErr == "" && err != nil || tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+			t.Errorf("%s: error = %v, want %q", tt.name, err, tt.wantErr)
+		}
+		req := tt.doer.req
+		if req.Method != http.MethodPost || req.URL.String() != opts.notifyURL || req.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("%s: sent %s %s with Content-Type %q, want a JSON POST to -notify-url", tt.name, req.Method, req.URL, req.Header.Get("Content-Type"))
+		}
+		if _, ok := req.Context().Deadline(); !ok {
+			t.Errorf("%s: request has no deadline", tt.name)
+		}
+		if !json.Valid(tt.doer.sent) {
+			t.Errorf("%s: sent %s, want the JSON payload", tt.name, tt.doer.sent)
+		}
+	}
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"fmt"
+	"io"
+)
+
+// writeTerraform renders the result's target tier as the settings block of a
+// google_sql_database_instance resource.
+func writeTerraform(w io.Writer, r report) error {
+	res, ok := r.(*Result)
+	if !ok {
+		return fmt.Errorf("terraform output is only supported for single-tier modes")
+	}
+	tier := res.targetTier()
+	if tier == "" {
+		return fmt.Errorf("no target tier to render as terraform")
+	}
+	if !opts.tfPlaceholders {
+		_, err := fmt.Fprintf(w, "settings {\n  tier = %q\n}\n", tier)
+		return err
+	}
+	_, err := fmt.Fprintf(w, `settings {
+  tier              = %q
+  availability_type = "ZONAL" # or "REGIONAL" for HA
+  disk_size         = 100     # GB
+}
+`, tier)
+	return err
+}
@@ -0,0 +1,116 @@
+# bash completion for go-calc. Generated by 'go-calc completion bash'.
+_go_calc() {
+    local cur=${COMP_WORDS[COMP_CWORD]} prev=${COMP_WORDS[COMP_CWORD-1]}
+    local tiers="db-custom-1-3840 db-custom-2-7680 db-custom-2-13312 db-custom-4-15360 db-custom-4-26624 db-custom-6-23040 db-custom-6-39936 db-custom-8-30720 db-custom-8-53248 db-custom-10-38400 db-custom-10-66560 db-custom-12-46080 db-custom-12-79872 db-custom-16-61440 db-custom-16-106496 db-custom-24-92160 db-custom-24-159744 db-custom-32-122880 db-custom-32-212992 db-custom-48-184320 db-custom-48-319488 db-custom-64-245760 db-custom-64-425984 db-custom-80-307200 db-custom-80-532480 db-custom-96-368640 db-custom-96-638976 db-custom-128-491520 db-custom-128-851968"
+    local common="-apply -buffer-pool-pct -cache-ttl -commitment -committed-cpus -committed-ram -config -cost -cpu-headroom -edition -engine -equivalents -explain -flags-file -format -gcloud -ha -headroom -instance -k8s -k8s-overhead -max-retries -max-tier -mem-budget-pct -min-tier -mysql-config -no-cache -notify-url -o -overhead-mb -overhead-pct -per-conn-kb -prices -project -q -quiet -ratio -region -round -strict -tf-placeholders -tiers-file -tiers-from -tiers-merge -to-rds -usable -yes"
+    case $prev in
+        -edition|--edition) COMPREPLY=($(compgen -W "enterprise enterprise-plus" -- "$cur")); return ;;
+        -engine|--engine) COMPREPLY=($(compgen -W "mysql postgres sqlserver sqlserver-enterprise" -- "$cur")); return ;;
+        -format|--format) COMPREPLY=($(compgen -W "@oneline @tier-only" -- "$cur")); return ;;
+        -o|--o) COMPREPLY=($(compgen -W "text json yaml terraform csv k8s gha slack ndjson" -- "$cur")); return ;;
+        -ratio-class|--ratio-class) COMPREPLY=($(compgen -W "highmem standard" -- "$cur")); return ;;
+        -report|--report) COMPREPLY=($(compgen -W "html" -- "$cur")); return ;;
+        -strategy|--strategy) COMPREPLY=($(compgen -W "mem-first cpu-first balanced all" -- "$cur")); return ;;
+        -bump-cpu|--bump-cpu|-bump-mem|--bump-mem|-downgrade|--downgrade|-growth|--growth|-rightsize|--rightsize|-t|--t) COMPREPLY=($(compgen -W "$tiers" -- "$cur")); return ;;
+        -batch|--batch|-config|--config|-desired|--desired|-flags-file|--flags-file|-instances|--instances|-prices|--prices|-recommender|--recommender|-report-out|--report-out|-tiers-file|--tiers-file|-tiers-from|--tiers-from) COMPREPLY=($(compgen -f -- "$cur")); return ;;
+        -buffer-pool-fraction|--buffer-pool-fraction|-buffer-pool-pct|--buffer-pool-pct|-cache-ttl|--cache-ttl|-check-downgrade|--check-downgrade|-check-upgrade|--check-upgrade|-commitment|--commitment|-committed-cpus|--committed-cpus|-committed-ram|--committed-ram|-concurrency|--concurrency|-conn-mem-kb|--conn-mem-kb|-connections|--connections|-cpu|--cpu|-cpu-growth|--cpu-growth|-cpu-headroom|--cpu-headroom|-cpu-percentile|--cpu-percentile|-cpu-range|--cpu-range|-cpu-util|--cpu-util|-cpu-weight|--cpu-weight|-data-size|--data-size|-diff|--diff|-every|--every|-headroom|--headroom|-instance|--instance|-interval|--interval|-k8s-overhead|--k8s-overhead|-listen|--listen|-max-cpu|--max-cpu|-max-factor|--max-factor|-max-mem|--max-mem|-max-retries|--max-retries|-max-step-pct|--max-step-pct|-max-tier|--max-tier|-mem|--mem|-mem-budget-pct|--mem-budget-pct|-mem-growth|--mem-growth|-mem-percentile|--mem-percentile|-mem-range|--mem-range|-mem-util|--mem-util|-mem-weight|--mem-weight|-min-cpu|--min-cpu|-min-mem|--min-mem|-min-tier|--min-tier|-months|--months|-nearest|--nearest|-notify-url|--notify-url|-overhead-mb|--overhead-mb|-overhead-pct|--overhead-pct|-per-conn-kb|--per-conn-kb|-percentile|--percentile|-plan|--plan|-project|--project|-ratio|--ratio|-region|--region|-round|--round|-scale|--scale|-steps|--steps|-target-savings|--target-savings|-to-ratio|--to-ratio|-tolerance|--tolerance|-usage-csv|--usage-csv|-window|--window|-working-set|--working-set) return ;;
+    esac
+    local flags cmd
+    (( COMP_CWORD > 1 )) && cmd=${COMP_WORDS[1]}
+    case $cmd in
+        help) COMPREPLY=($(compgen -W "validate next prev bump-mem bump-cpu suggest check-downgrade check-upgrade diff plan replica rightsize growth from-rds cpu-range mem-range matrix list-tiers instances recommender batch normalize tiers watch serve cache version completion" -- "$cur")); return ;;
+        validate)
+            flags=""
+            [[ $cur == -* ]] || { COMPREPLY=($(compgen -W "$tiers" -- "$cur")); return; } ;;
+        next)
+            flags="-clamp -cpu-weight -mem-weight -nearest -scale -steps"
+            [[ $cur == -* ]] || { COMPREPLY=($(compgen -W "$tiers" -- "$cur")); return; } ;;
+        prev)
+            flags="-any-shape -steps -strategy -target-savings -tolerance"
+            [[ $cur == -* ]] || { COMPREPLY=($(compgen -W "$tiers" -- "$cur")); return; } ;;
+        bump-mem)
+            flags="-to-ratio"
+            [[ $cur == -* ]] || { COMPREPLY=($(compgen -W "$tiers" -- "$cur")); return; } ;;
+        bump-cpu)
+            flags=""
+            [[ $cur == -* ]] || { COMPREPLY=($(compgen -W "$tiers" -- "$cur")); return; } ;;
+        suggest)
+            flags="-buffer-pool-fraction -cheapest -conn-mem-kb -connections -cpu -data-size -mem -working-set"
+            ;;
+        check-downgrade)
+            flags="-allow-mixed -assume-corrected -max-step-pct"
+            [[ $cur == -* ]] || { COMPREPLY=($(compgen -W "$tiers" -- "$cur")); return; } ;;
+        check-upgrade)
+            flags="-allow-mixed -assume-corrected"
+            [[ $cur == -* ]] || { COMPREPLY=($(compgen -W "$tiers" -- "$cur")); return; } ;;
+        diff)
+            flags=""
+            ;;
+        plan)
+            flags="-max-factor"
+            [[ $cur == -* ]] || { COMPREPLY=($(compgen -W "$tiers" -- "$cur")); return; } ;;
+        replica)
+            flags="-max-shrink-pct -replica-offset -replica-tier"
+            ;;
+        rightsize)
+            flags="-cpu-percentile -cpu-util -mem-percentile -mem-util -monitor -percentile -usage-csv -window"
+            [[ $cur == -* ]] || { COMPREPLY=($(compgen -W "$tiers" -- "$cur")); return; } ;;
+        growth)
+            flags="-cpu-growth -every -mem-growth -months"
+            [[ $cur == -* ]] || { COMPREPLY=($(compgen -W "$tiers" -- "$cur")); return; } ;;
+        from-rds)
+            flags=""
+            ;;
+        cpu-range)
+            flags=""
+            ;;
+        mem-range)
+            flags=""
+            ;;
+        matrix)
+            flags=""
+            ;;
+        list-tiers)
+            flags="-max-cpu -max-mem -min-cpu -min-mem -ratio-class"
+            ;;
+        instances)
+            flags="-concurrency -cpu-percentile -mem-percentile -monitor -percentile -report -report-out -window"
+            [[ $cur == -* ]] || { COMPREPLY=($(compgen -f -- "$cur")); return; } ;;
+        recommender)
+            flags=""
+            [[ $cur == -* ]] || { COMPREPLY=($(compgen -f -- "$cur")); return; } ;;
+        batch)
+            flags="-normalize -report -report-out"
+            [[ $cur == -* ]] || { COMPREPLY=($(compgen -f -- "$cur")); return; } ;;
+        normalize)
+            flags=""
+            [[ $cur == -* ]] || { COMPREPLY=($(compgen -W "$tiers" -- "$cur")); return; } ;;
+        tiers)
+            flags=""
+            ;;
+        watch)
+            flags="-desired -interval -once"
+            ;;
+        serve)
+            flags="-allow-mixed -assume-corrected -listen -max-step-pct -metrics"
+            ;;
+        cache)
+            flags=""
+            ;;
+        version)
+            flags=""
+            ;;
+        completion)
+            flags=""
+            [[ $cur == -* ]] || { COMPREPLY=($(compgen -W "bash zsh fish" -- "$cur")); return; } ;;
+        *)
+            flags="-allow-mixed -any-shape -assume-corrected -batch -buffer-pool-fraction -bump-cpu -bump-mem -cheapest -check-downgrade -check-upgrade -clamp -concurrency -conn-mem-kb -connections -cpu -cpu-growth -cpu-percentile -cpu-range -cpu-util -cpu-weight -data-size -desired -diff -downgrade -every -growth -i -instances -interactive -interval -list-tiers -listen -matrix -max-cpu -max-factor -max-mem -max-step-pct -mem -mem-growth -mem-percentile -mem-range -mem-util -mem-weight -metrics -min-cpu -min-mem -monitor -months -nearest -normalize -once -percentile -plan -ratio-class -recommender -report -report-out -rightsize -scale -steps -strategy -t -target-savings -to-ratio -tolerance -usage-csv -version -window -working-set"
+            if [[ $cur != -* ]]; then
+                local words=$tiers
+                (( COMP_CWORD == 1 )) && words="validate next prev bump-mem bump-cpu suggest check-downgrade check-upgrade diff plan replica rightsize growth from-rds cpu-range mem-range matrix list-tiers instances recommender batch normalize tiers watch serve cache version completion help"" $tiers"
+                COMPREPLY=($(compgen -W "$words" -- "$cur")); return
+            fi ;;
+    esac
+    COMPREPLY=($(compgen -W "$flags $common" -- "$cur"))
+}
+complete -F _go_calc go-calc
@@ -0,0 +1,154 @@
+# fish completion for go-calc. Generated by 'go-calc completion fish'.
+set -l commands validate next prev bump-mem bump-cpu suggest check-downgrade check-upgrade diff plan replica rightsize growth from-rds cpu-range mem-range matrix list-tiers instances recommender batch normalize tiers watch serve cache version completion help
+set -l tiers db-custom-1-3840 db-custom-2-7680 db-custom-2-13312 db-custom-4-15360 db-custom-4-26624 db-custom-6-23040 db-custom-6-39936 db-custom-8-30720 db-custom-8-53248 db-custom-10-38400 db-custom-10-66560 db-custom-12-46080 db-custom-12-79872 db-custom-16-61440 db-custom-16-106496 db-custom-24-92160 db-custom-24-159744 db-custom-32-122880 db-custom-32-212992 db-custom-48-184320 db-custom-48-319488 db-custom-64-245760 db-custom-64-425984 db-custom-80-307200 db-custom-80-532480 db-custom-96-368640 db-custom-96-638976 db-custom-128-491520 db-custom-128-851968
+complete -c go-calc -f
+complete -c go-calc -n '__fish_use_subcommand' -a validate -d 'Validate a tier and show the nearest valid tier if it is not'
+complete -c go-calc -n '__fish_use_subcommand' -a next -d 'Show the next known tier up from a tier'
+complete -c go-calc -n '__fish_use_subcommand' -a prev -d 'Suggest a downgrade tier'
+complete -c go-calc -n '__fish_use_subcommand' -a bump-mem -d 'Raise memory to -to-ratio GB/vCPU, keeping vCPUs'
+complete -c go-calc -n '__fish_use_subcommand' -a bump-cpu -d 'Raise vCPUs to the next legal count, keeping memory'
+complete -c go-calc -n '__fish_use_subcommand' -a suggest -d 'Size a tier from -cpu, -mem, both, -data-size, or -connections'
+complete -c go-calc -n '__fish_use_subcommand' -a check-downgrade -d 'Check that recommended is a valid downgrade from current'
+complete -c go-calc -n '__fish_use_subcommand' -a check-upgrade -d 'Check that recommended is a valid upgrade from current'
+complete -c go-calc -n '__fish_use_subcommand' -a diff -d 'Compare two tiers side by side'
+complete -c go-calc -n '__fish_use_subcommand' -a plan -d 'Plan the resizes from current to target, none more than -max-factor times'
+complete -c go-calc -n '__fish_use_subcommand' -a replica -d 'Size a read replica for a primary tier and total the pair'
+complete -c go-calc -n '__fish_use_subcommand' -a rightsize -d 'Recommend a tier for observed utilization'
+complete -c go-calc -n '__fish_use_subcommand' -a growth -d 'Project the tier needed as load grows'
+complete -c go-calc -n '__fish_use_subcommand' -a from-rds -d 'Find the smallest tier with at least the vCPUs and memory of an RDS instance class'
+complete -c go-calc -n '__fish_use_subcommand' -a cpu-range -d 'Show the legal memory range of each vCPU count (e.g. 8,16,32)'
+complete -c go-calc -n '__fish_use_subcommand' -a mem-range -d 'Show the vCPU counts that can carry an amount of memory (e.g. 200G)'
+complete -c go-calc -n '__fish_use_subcommand' -a matrix -d 'List every tier at 3.75 and 6.5 GB/vCPU and check the known tiers against them'
+complete -c go-calc -n '__fish_use_subcommand' -a list-tiers -d 'List the known tiers'
+complete -c go-calc -n '__fish_use_subcommand' -a instances -d 'Report on a gcloud instance list JSON file (- for stdin)'
+complete -c go-calc -n '__fish_use_subcommand' -a recommender -d 'Check every tier change in a Cloud SQL rightsizing recommender JSON export (- for stdin)'
+complete -c go-calc -n '__fish_use_subcommand' -a batch -d 'Validate one tier per line (- for stdin)'
+complete -c go-calc -n '__fish_use_subcommand' -a normalize -d 'Print the canonical db-custom-<cpus>-<ram_mb> form of each tier (- reads stdin)'
+complete -c go-calc -n '__fish_use_subcommand' -a tiers -d 'Write the known tier catalog (from -tiers-from or -tiers-file) as a -tiers-file'
+complete -c go-calc -n '__fish_use_subcommand' -a watch -d 'Compare the live tiers of the instances in a -desired file with their desired tiers, every -interval'
+complete -c go-calc -n '__fish_use_subcommand' -a serve -d 'Serve validate, next, suggest, and check-downgrade as a JSON HTTP API on -listen'
+complete -c go-calc -n '__fish_use_subcommand' -a cache -d 'Remove the cached Cloud SQL Admin API lookups'
+complete -c go-calc -n '__fish_use_subcommand' -a version -d 'Print the build version and the tier rules revision'
+complete -c go-calc -n '__fish_use_subcommand' -a completion -d 'Print a bash, zsh, or fish completion script'
+complete -c go-calc -n '__fish_use_subcommand' -a help -d 'Show the usage of go-calc or of a command'
+complete -c go-calc -n '__fish_use_subcommand' -a "$tiers"
+complete -c go-calc -n '__fish_seen_subcommand_from help' -a "$commands"
+complete -c go-calc -n '__fish_seen_subcommand_from instances recommender batch' -F
+complete -c go-calc -n '__fish_seen_subcommand_from completion' -a 'bash zsh fish'
+complete -c go-calc -n '__fish_seen_subcommand_from validate next prev bump-mem bump-cpu check-downgrade check-upgrade plan rightsize growth normalize' -a "$tiers"
+complete -c go-calc -o apply -d 'Move -instance to the resulting tier with the Cloud SQL Admin API (a dry run without -yes or confirmation)'
+complete -c go-calc -o buffer-pool-pct -x -d 'With -mysql-config, percentage of memory for the InnoDB buffer pool'
+complete -c go-calc -o cache-ttl -x -d 'How long a cached -instance lookup is used for'
+complete -c go-calc -o commitment -x -d 'With -cost, price at this committed use discount: none, 1yr, or 3yr'
+complete -c go-calc -o committed-cpus -x -d 'vCPUs already under a commitment; warn when a downgrade leaves fewer'
+complete -c go-calc -o committed-ram -x -d 'Memory already under a commitment (e.g. 64G); warn when a downgrade leaves less'
+complete -c go-calc -o config -r -F -d 'Config file of flag defaults and policy (default $XDG_CONFIG_HOME/go-calc/config.yaml)'
+complete -c go-calc -o cost -d 'Print estimated monthly cost for the tiers involved'
+complete -c go-calc -o cpu-headroom -x -d 'Percentage to add to the vCPU requirement before sizing; for rightsize, replaces -headroom for vCPUs'
+complete -c go-calc -o edition -x -a 'enterprise enterprise-plus' -d 'CloudSQL edition whose limits apply: enterprise, enterprise-plus'
+complete -c go-calc -o engine -x -a 'mysql postgres sqlserver sqlserver-enterprise' -d 'Database engine whose tier rules apply: mysql, postgres, sqlserver, sqlserver-enterprise'
+complete -c go-calc -o equivalents -d 'List the GCE machine types closest to the resulting tier'
+complete -c go-calc -o explain -d 'Show every rule check and sizing step, with the numbers involved'
+complete -c go-calc -o flags-file -r -F -d 'Check that the memory flags in this JSON or key=value file fit the resulting tier'
+complete -c go-calc -o format -x -a '@oneline @tier-only' -d 'Go text/template for the output, or @tier-only / @oneline (overrides -o)'
+complete -c go-calc -o gcloud -d 'Also print the gcloud command that applies the resulting tier'
+complete -c go-calc -o ha -d 'Treat the instance as regional (HA): double the -cost compute and check the policy ha_min_tier'
+complete -c go-calc -o headroom -x -d 'Percentage to add to the memory requirement before sizing; for rightsize, percentage of capacity to keep free (default 20 there)'
+complete -c go-calc -o instance -x -d 'Instance name used in generated commands; as <project>:<instance>, or with -project, stands for its current tier when the tier is left out or given as @instance'
+complete -c go-calc -o k8s -d 'Print Kubernetes resource requests for the resulting tiers (same as -o k8s)'
+complete -c go-calc -o k8s-overhead -x -d 'With -k8s, memory to subtract for the OS and agents (e.g., 1Gi)'
+complete -c go-calc -o max-retries -x -d 'Times to retry a Cloud SQL Admin or Cloud Monitoring API call that was rate limited or failed on the server side'
+complete -c go-calc -o max-tier -x -d 'Policy ceiling: never suggest a tier with more vCPUs or memory than this (config max_tier)'
+complete -c go-calc -o mem-budget-pct -x -d 'With -flags-file, percentage of memory the flags may use'
+complete -c go-calc -o min-tier -x -d 'Policy floor: never suggest a tier with fewer vCPUs or less memory than this (config min_tier)'
+complete -c go-calc -o mysql-config -d 'Recommend innodb_buffer_pool_size, innodb_log_file_size, and max_connections for the resulting tier'
+complete -c go-calc -o no-cache -d 'Look up -instance in the Cloud SQL Admin API even if it is cached'
+complete -c go-calc -o notify-url -x -d 'With -o slack, also post the payload to this Slack webhook URL'
+complete -c go-calc -o o -x -a 'text json yaml terraform csv k8s gha slack ndjson' -d 'Output format: text, json, yaml, terraform, csv, k8s, gha (GitHub Actions annotations), slack (Block Kit payload), or ndjson (batch and instances records streamed one per line)'
+complete -c go-calc -o overhead-mb -x -d 'With -usable, fixed overhead in MB (default: the engine\'s estimate, 1024 for MySQL and PostgreSQL, 2048 for SQL Server)'
+complete -c go-calc -o overhead-pct -x -d 'With -usable, overhead as a percentage of instance memory (default: the engine\'s estimate, 5)'
+complete -c go-calc -o per-conn-kb -x -d 'With -mysql-config, memory per connection in KB'
+complete -c go-calc -o prices -r -F -d 'Price table JSON file to use instead of the embedded one'
+complete -c go-calc -o project -x -d 'Project used in generated commands'
+complete -c go-calc -o q -d 'Print only the resulting tier; warnings and errors go to stderr'
+complete -c go-calc -o quiet -d 'Same as -q'
+complete -c go-calc -o ratio -x -d 'Memory per vCPU in GB used to size -cpu, -mem, and suggested tiers'
+complete -c go-calc -o region -x -d 'Region used for cost estimates'
+complete -c go-calc -o round -x -d 'Snap vCPUs and memory up, down, or nearest (default: each mode\'s own rounding)'
+complete -c go-calc -o strict -d 'Treat warnings (invalid or adjusted calculated tiers, clamped memory or ratio, aggressive downgrades) as errors (exit code 2)'
+complete -c go-calc -o tf-placeholders -d 'Include availability_type and disk_size placeholders in terraform output'
+complete -c go-calc -o tiers-file -r -F -d 'Known tier catalog, CSV or JSON of cpus,ram_mb pairs, to use instead of the built-in one'
+complete -c go-calc -o tiers-from -r -F -d 'Known tier catalog from a \'gcloud sql tiers list --format=json\' file (- for stdin), to use instead of the built-in one'
+complete -c go-calc -o tiers-merge -d 'Add the -tiers-file or -tiers-from tiers to the built-in catalog instead of replacing it'
+complete -c go-calc -o to-rds -d 'List the AWS RDS instance classes closest to the resulting tier'
+complete -c go-calc -o usable -d 'Show the estimated memory the engine can use, after the OS and agent overhead, and size -data-size and -mysql-config from it'
+complete -c go-calc -o yes -d 'With -apply, make the change without asking'
+complete -c go-calc -n '__fish_use_subcommand; or __fish_seen_subcommand_from check-downgrade check-upgrade serve' -o allow-mixed -d 'Accept a change where one of vCPUs and memory moves the other way'
+complete -c go-calc -n '__fish_use_subcommand; or __fish_seen_subcommand_from prev' -o any-shape -d 'Consider every valid custom shape for -target-savings, not just the known tiers'
+complete -c go-calc -n '__fish_use_subcommand; or __fish_seen_subcommand_from check-downgrade check-upgrade serve' -o assume-corrected -d 'When the current tier is not valid, check against its nearest valid tier instead of stopping'
+complete -c go-calc -n '__fish_use_subcommand' -o batch -r -F -d 'Validate one tier per line from a file (use - for stdin)'
+complete -c go-calc -n '__fish_use_subcommand; or __fish_seen_subcommand_from suggest' -o buffer-pool-fraction -x -d 'With -data-size, fraction of instance memory given to the buffer pool'
+complete -c go-calc -n '__fish_use_subcommand' -o bump-cpu -x -a "$tiers" -d 'Bump vCPUs for existing tier, keeping memory where possible (e.g., db-custom-4-26624)'
+complete -c go-calc -n '__fish_use_subcommand' -o bump-mem -x -a "$tiers" -d 'Bump memory for existing tier (e.g., db-custom-4-3840)'
+complete -c go-calc -n '__fish_use_subcommand; or __fish_seen_subcommand_from suggest' -o cheapest -d 'Find the valid tier meeting -cpu and -mem that costs least (with -cost), and the 5 next cheapest'
+complete -c go-calc -n '__fish_use_subcommand' -o check-downgrade -x -d 'Check if recommended tier is a valid downgrade from current (format: \'current recommended\')'
+complete -c go-calc -n '__fish_use_subcommand' -o check-upgrade -x -d 'Check if recommended tier is a valid upgrade from current (format: \'current recommended\')'
+complete -c go-calc -n '__fish_use_subcommand; or __fish_seen_subcommand_from next' -o clamp -d 'With -scale, stop at the smallest or largest tier instead of failing'
+complete -c go-calc -n '__fish_use_subcommand; or __fish_seen_subcommand_from instances' -o concurrency -x -d 'With -monitor, instances of an instance list to look up at once'
+complete -c go-calc -n '__fish_use_subcommand; or __fish_seen_subcommand_from suggest' -o conn-mem-kb -x -d 'With -connections, memory per connection in KB'
+complete -c go-calc -n '__fish_use_subcommand; or __fish_seen_subcommand_from suggest' -o connections -x -d 'Add the memory of this many client connections to the request (alone, or with -cpu, -mem, or -data-size)'
+complete -c go-calc -n '__fish_use_subcommand; or __fish_seen_subcommand_from suggest' -o cpu -x -d 'Number of `vCPUs` (e.g., 24, 48, or millicores such as 4000m)'
+complete -c go-calc -n '__fish_use_subcommand; or __fish_seen_subcommand_from growth' -o cpu-growth -x -d 'Monthly vCPU growth in percent'
+complete -c go-calc -n '__fish_use_subcommand; or __fish_seen_subcommand_from rightsize instances' -o cpu-percentile -x -d 'With -monitor or -usage-csv, percentile to size vCPUs for, or max, instead of -percentile (e.g. 99 for bursts)'
+complete -c go-calc -n '__fish_use_subcommand' -o cpu-range -x -d 'Show the legal memory range of each vCPU count (e.g., 8,16,32)'
+complete -c go-calc -n '__fish_use_subcommand; or __fish_seen_subcommand_from rightsize' -o cpu-util -x -d 'Observed peak CPU utilization in percent'
+complete -c go-calc -n '__fish_use_subcommand; or __fish_seen_subcommand_from next' -o cpu-weight -x -d 'Weight of the vCPU difference in the -nearest distance'
+complete -c go-calc -n '__fish_use_subcommand; or __fish_seen_subcommand_from suggest' -o data-size -x -d 'Size the tier from this data volume (e.g., 500G) instead of -cpu and -mem'
+complete -c go-calc -n '__fish_use_subcommand; or __fish_seen_subcommand_from watch' -o desired -r -F -d 'YAML file of the instances to watch and their desired tiers'
+complete -c go-calc -n '__fish_use_subcommand' -o diff -x -d 'Compare two tiers side by side (format: \'tier-a tier-b\')'
+complete -c go-calc -n '__fish_use_subcommand' -o downgrade -x -a "$tiers" -d 'Suggest the next valid downgrade tier from current (e.g., db-custom-8-53248)'
+complete -c go-calc -n '__fish_use_subcommand; or __fish_seen_subcommand_from growth' -o every -x -d 'Months between milestones'
+complete -c go-calc -n '__fish_use_subcommand' -o growth -x -a "$tiers" -d 'Project the tier needed as an existing tier\'s load grows (with -mem-growth, -cpu-growth, -months, -every)'
+complete -c go-calc -n '__fish_use_subcommand' -o i -d 'Read commands from stdin interactively (type help for the commands)'
+complete -c go-calc -n '__fish_use_subcommand' -o instances -r -F -d 'Analyse every instance in a \'gcloud sql instances list --format=json\' file (use - for stdin)'
+complete -c go-calc -n '__fish_use_subcommand' -o interactive -d 'Same as -i'
+complete -c go-calc -n '__fish_use_subcommand; or __fish_seen_subcommand_from watch' -o interval -x -d 'Time between watch passes'
+complete -c go-calc -n '__fish_use_subcommand' -o list-tiers -d 'List the known tiers valid under the selected rules'
+complete -c go-calc -n '__fish_use_subcommand; or __fish_seen_subcommand_from serve' -o listen -x -d 'Address serve listens on'
+complete -c go-calc -n '__fish_use_subcommand' -o matrix -d 'List every valid tier at 3.75 and 6.5 GB/vCPU and check the known tiers against them'
+complete -c go-calc -n '__fish_use_subcommand; or __fish_seen_subcommand_from list-tiers' -o max-cpu -x -d 'Only tiers with at most this many vCPUs'
+complete -c go-calc -n '__fish_use_subcommand; or __fish_seen_subcommand_from plan' -o max-factor -x -d 'Largest factor one step of a plan may change vCPUs or memory by'
+complete -c go-calc -n '__fish_use_subcommand; or __fish_seen_subcommand_from list-tiers' -o max-mem -x -d 'Only tiers with at most this much memory (e.g., 64G)'
+complete -c go-calc -n '__fish_use_subcommand; or __fish_seen_subcommand_from check-downgrade serve' -o max-step-pct -x -d 'Flag downgrades that drop more than this percentage of vCPUs or memory in one step'
+complete -c go-calc -n '__fish_use_subcommand; or __fish_seen_subcommand_from suggest' -o mem -x -d 'Memory (e.g., 6G, 6144M, 6144, 1.5T, 52Gi, 6442450944B)'
+complete -c go-calc -n '__fish_use_subcommand; or __fish_seen_subcommand_from growth' -o mem-growth -x -d 'Monthly memory growth in percent'
+complete -c go-calc -n '__fish_use_subcommand; or __fish_seen_subcommand_from rightsize instances' -o mem-percentile -x -d 'With -monitor or -usage-csv, percentile to size memory for, or max, instead of -percentile (e.g. 50 for the steady working set)'
+complete -c go-calc -n '__fish_use_subcommand' -o mem-range -x -d 'Show the vCPU counts that can carry an amount of memory (e.g., 200G)'
+complete -c go-calc -n '__fish_use_subcommand; or __fish_seen_subcommand_from rightsize' -o mem-util -x -d 'Observed peak memory utilization in percent'
+complete -c go-calc -n '__fish_use_subcommand; or __fish_seen_subcommand_from next' -o mem-weight -x -d 'Weight of the memory difference in the -nearest distance'
+complete -c go-calc -n '__fish_use_subcommand; or __fish_seen_subcommand_from serve' -o metrics -d 'Export Prometheus metrics of the requests and the tier catalog on /metrics'
+complete -c go-calc -n '__fish_use_subcommand; or __fish_seen_subcommand_from list-tiers' -o min-cpu -x -d 'Only tiers with at least this many vCPUs'
+complete -c go-calc -n '__fish_use_subcommand; or __fish_seen_subcommand_from list-tiers' -o min-mem -x -d 'Only tiers with at least this much memory (e.g., 16G)'
+complete -c go-calc -n '__fish_use_subcommand; or __fish_seen_subcommand_from rightsize instances' -o monitor -d 'Read -cpu-util and -mem-util from Cloud Monitoring for -instance (for each instance of an instance list)'
+complete -c go-calc -n '__fish_use_subcommand; or __fish_seen_subcommand_from growth' -o months -x -d 'Projection horizon in months'
+complete -c go-calc -n '__fish_use_subcommand; or __fish_seen_subcommand_from next' -o nearest -x -d 'List the N known tiers closest in vCPUs and memory'
+complete -c go-calc -n '__fish_use_subcommand; or __fish_seen_subcommand_from batch' -o normalize -d 'Print the canonical form of each tier instead of validating it'
+complete -c go-calc -n '__fish_use_subcommand; or __fish_seen_subcommand_from watch' -o once -d 'Run a single watch pass and exit, with exit code 2 on any finding'
+complete -c go-calc -n '__fish_use_subcommand; or __fish_seen_subcommand_from rightsize instances' -o percentile -x -d 'With -monitor or -usage-csv, percentile of the utilization samples to size for, or max'
+complete -c go-calc -n '__fish_use_subcommand' -o plan -x -d 'Plan the resizes from current to target, at most -max-factor times per step (format: \'current target\')'
+complete -c go-calc -n '__fish_use_subcommand; or __fish_seen_subcommand_from list-tiers' -o ratio-class -x -a 'highmem standard' -d 'Only standard (3.75 GB/vCPU) or highmem (6.5 GB/vCPU) tiers'
+complete -c go-calc -n '__fish_use_subcommand' -o recommender -r -F -d 'Check every tier change in a \'gcloud recommender recommendations list --format=json\' export of Cloud SQL rightsizing recommendations (use - for stdin)'
+complete -c go-calc -n '__fish_use_subcommand; or __fish_seen_subcommand_from instances batch' -o report -x -a 'html' -d 'Also write the results as a report page: html'
+complete -c go-calc -n '__fish_use_subcommand; or __fish_seen_subcommand_from instances batch' -o report-out -r -F -d 'With -report, the file to write'
+complete -c go-calc -n '__fish_use_subcommand' -o rightsize -x -a "$tiers" -d 'Recommend a tier for the observed load of an existing tier (with -cpu-util, -mem-util, -headroom)'
+complete -c go-calc -n '__fish_use_subcommand; or __fish_seen_subcommand_from next' -o scale -x -d 'Multiply the vCPUs and memory of the tier by this factor (e.g., 2 or 0.5) and snap to a valid tier'
+complete -c go-calc -n '__fish_use_subcommand; or __fish_seen_subcommand_from next prev' -o steps -x -d 'List the next N known tiers in that direction'
+complete -c go-calc -n '__fish_use_subcommand; or __fish_seen_subcommand_from prev' -o strategy -x -a 'mem-first cpu-first balanced all' -d 'Downgrade strategy: mem-first, cpu-first, balanced, or all'
+complete -c go-calc -n '__fish_use_subcommand' -o t -x -a "$tiers" -d 'CloudSQL tier string (e.g., db-custom-1-3840 or db-n1-standard-4)'
+complete -c go-calc -n '__fish_use_subcommand; or __fish_seen_subcommand_from prev' -o target-savings -x -d 'Pick the downgrade whose resource (or, with -cost, cost) savings are closest to this percentage'
+complete -c go-calc -n '__fish_use_subcommand; or __fish_seen_subcommand_from bump-mem' -o to-ratio -x -d 'Target memory per vCPU in GB for -bump-mem (default: the engine maximum)'
+complete -c go-calc -n '__fish_use_subcommand; or __fish_seen_subcommand_from prev' -o tolerance -x -d 'Largest distance in percentage points between -target-savings and the savings achieved'
+complete -c go-calc -n '__fish_use_subcommand; or __fish_seen_subcommand_from rightsize' -o usage-csv -x -d 'CSV of timestamped CPU percent and memory bytes samples to size from at -percentile (- for stdin)'
+complete -c go-calc -n '__fish_use_subcommand' -o version -d 'Print the build version and the tier rules revision'
+complete -c go-calc -n '__fish_use_subcommand; or __fish_seen_subcommand_from rightsize instances' -o window -x -d 'With -monitor, how far back to read utilization (e.g. 14d, 36h)'
+complete -c go-calc -n '__fish_use_subcommand; or __fish_seen_subcommand_from suggest' -o working-set -x -d 'With -data-size, fraction of the data that is hot and should fit in the buffer pool'
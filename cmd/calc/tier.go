@@ -0,0 +1,377 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Tier is a CloudSQL custom machine shape: a vCPU count and memory in MB.
+type Tier struct {
+	CPUs  int
+	RAMMB int
+}
+
+// legacyTiers maps the legacy db-n1 machine names to their custom tier
+// equivalents: standard is 3.75 GB/vCPU and highmem is 6.5 GB/vCPU.
+var legacyTiers = func() map[string]Tier {
+	m := map[string]Tier{}
+	for _, cpu := range []int{1, 2, 4, 8, 16, 32, 64, 96} {
+		m[fmt.Sprintf("db-n1-standard-%d", cpu)] = Tier{CPUs: cpu, RAMMB: cpu * 3840}
+	}
+	for _, cpu := range []int{2, 4, 8, 16, 32, 64, 96} {
+		m[fmt.Sprintf("db-n1-highmem-%d", cpu)] = Tier{CPUs: cpu, RAMMB: cpu * 6656}
+	}
+	return m
+}()
+
+// sharedCoreTiers are the named shared-core shapes. They are represented as
+// a Tier with zero dedicated vCPUs; vCPU is the fractional share.
+var sharedCoreTiers = []struct {
+	name string
+	vCPU float64
+	tier Tier
+}{
+	{"db-f1-micro", 0.2, Tier{0, 614}},
+	{"db-g1-small", 0.5, Tier{0, 1741}},
+}
+
+// customTierPattern matches db-custom-<cpus>-<ram_mb>. It is compiled once
+// because batch and fleet modes parse thousands of tiers.
+var customTierPattern = regexp.MustCompile(`^db-custom-(\d+)-(\d+)$`)
+
+// ParseTier parses a tier string of the form db-custom-<cpus>-<ram_mb>, a
+// legacy db-n1-standard-N / db-n1-highmem-N name, a shared-core name, or a
+// GCE machine type such as n2-standard-16, which becomes the custom tier with
+// the same vCPUs and memory, under the selected rules.
+// Case and surrounding whitespace are ignored; anything else around the tier
+// is an error. String returns the canonical form, so ParseTier(s).String()
+// normalizes s.
+func ParseTier(s string) (Tier, error) {
+	return rules.parseTier(s)
+}
+
+// parseTier is ParseTier under the rules c, for goroutines that must not
+// read the selected rules while another switches them.
+func (c Constraints) parseTier(s string) (Tier, error) {
+	name := strings.ToLower(strings.TrimSpace(s))
+	if t, ok := legacyTiers[name]; ok {
+		return t, nil
+	}
+	for _, sc := range sharedCoreTiers {
+		if name == sc.name {
+			return sc.tier, nil
+		}
+	}
+	if m, ok := lookupMachineType(name); ok {
+		return m.tier(), nil
+	}
+	matches := customTierPattern.FindStringSubmatch(name)
+	if len(matches) != 3 {
+		return Tier{}, newTierError(ErrBadTierSyntax, s, "invalid tier format %q: use db-custom-<cpus>-<ram_mb>", s)
+	}
+	cpu, err := c.parseTierNumber(s, matches[1], 1, c.MaxCPUs, "vCPU count", "", ErrCPUCount, ErrCPUCount)
+	if err != nil {
+		return Tier{}, err.withCode(CodeCPURange)
+	}
+	ram, err := c.parseTierNumber(s, matches[2], c.RAMStepMB, c.MaxRAMMB, "memory", " MB", ErrRAMTooLow, ErrRAMTooHigh)
+	if err != nil {
+		return Tier{}, err
+	}
+	return Tier{CPUs: cpu, RAMMB: ram}, nil
+}
+
+// parseTierNumber parses one number of the custom tier s, which must be
+// within lo and hi (in unit) under the rules c. Numbers too large for an int
+// are out of range rather than malformed, so they get the same error as any
+// other value above hi.
+func (c Constraints) parseTierNumber(s, num string, lo, hi int, what, unit string, below, above error) (int, *TierError) {
+	n, err := strconv.Atoi(num)
+	if err != nil || n > hi {
+		return 0, newTierError(above, num, "%s %s%s in tier %q is above the %s %s maximum of %d%s", what, num, unit, s, c.Name, c.editionName(), hi, unit)
+	}
+	if n < lo {
+		return 0, newTierError(below, n, "%s %d%s in tier %q is below the minimum of %d%s", what, n, unit, s, lo, unit)
+	}
+	return n, nil
+}
+
+// String returns the canonical db-custom-<cpus>-<ram_mb> form, or the name
+// of a shared-core tier.
+func (t Tier) String() string {
+	for _, sc := range sharedCoreTiers {
+		if t == sc.tier {
+			return sc.name
+		}
+	}
+	return fmt.Sprintf("db-custom-%d-%d", t.CPUs, t.RAMMB)
+}
+
+// Shared reports whether t is a shared-core tier.
+func (t Tier) Shared() bool {
+	for _, sc := range sharedCoreTiers {
+		if t == sc.tier {
+			return true
+		}
+	}
+	return false
+}
+
+// VCPUs returns the vCPU count, fractional for shared-core tiers.
+func (t Tier) VCPUs() float64 {
+	for _, sc := range sharedCoreTiers {
+		if t == sc.tier {
+			return sc.vCPU
+		}
+	}
+	return float64(t.CPUs)
+}
+
+// RAMGB returns the tier memory in GB.
+func (t Tier) RAMGB() float64 {
+	return float64(t.RAMMB) / 1024
+}
+
+// Ratio returns the memory per vCPU in GB.
+func (t Tier) Ratio() float64 {
+	if t.VCPUs() == 0 {
+		return 0
+	}
+	return t.RAMGB() / t.VCPUs()
+}
+
+// Validate checks the tier against the CloudSQL custom tier rules and
+// returns the first rule that fails.
+func (t Tier) Validate() error {
+	if v := t.violations(); len(v) > 0 {
+		return v[0]
+	}
+	return nil
+}
+
+// violations returns every rule the tier fails under the selected rules.
+func (t Tier) violations() []*TierError {
+	return rules.violations(t)
+}
+
+// Valid reports whether the tier passes Validate.
+func (t Tier) Valid() bool {
+	return t.Validate() == nil
+}
+
+// Less reports whether t orders before o by CPU, then RAM.
+func (t Tier) Less(o Tier) bool {
+	return t.CPUs < o.CPUs || (t.CPUs == o.CPUs && t.RAMMB < o.RAMMB)
+}
+
+// smallestSharedCore returns the smallest shared-core tier with at least
+// vCPU share and ramMB memory, if the selected rules allow shared-core tiers.
+func smallestSharedCore(vCPU, ramMB float64) (Tier, bool) {
+	if !rules.SharedCore {
+		return Tier{}, false
+	}
+	for _, sc := range sharedCoreTiers {
+		if sc.vCPU >= vCPU && float64(sc.tier.RAMMB) >= ramMB {
+			return sc.tier, true
+		}
+	}
+	return Tier{}, false
+}
+
+// suggestNextTier sizes the next tier up from t at the -ratio GB/vCPU: the
+// smallest legal vCPU count that carries t's memory at the ratio, keeping at
+// least t's vCPUs and memory. The result always passes Validate and is
+// larger than t in vCPUs, memory, or both; false means t is at the edition
+// maximum and there is no larger tier.
+func suggestNextTier(t Tier) (Tier, bool) {
+	ratio := opts.ratio
+	cpusNeeded := int(math.Ceil(float64(t.RAMMB) / ratio / 1024))
+	for c := rules.legalCPUAtLeast(max(cpusNeeded, t.CPUs)); ; c = rules.legalCPUAtLeast(c + 1) {
+		next := nearestValidTier(Tier{CPUs: c, RAMMB: max(ratioMBCeil(ratio, c), t.RAMMB, rules.MinRAMMB)})
+		if next == t && c == rules.MaxCPUs {
+			// No more vCPUs to add: the next memory step
+			next = nearestValidTier(Tier{CPUs: c, RAMMB: t.RAMMB + 256})
+		}
+		if next != t && next.CPUs >= t.CPUs && next.RAMMB >= t.RAMMB {
+			return next, true
+		}
+		if c == rules.MaxCPUs {
+			return Tier{}, false
+		}
+	}
+}
+
+// smallestTierFor returns the smallest valid custom tier with at least cpu
+// vCPUs and memMB of memory, and which requirement decided it: "cpu" when the
+// memory-per-vCPU floor raised memory, "memory" when the ceiling raised vCPUs,
+// or "cpu and memory" when neither moved.
+func smallestTierFor(cpu, memMB float64) (Tier, string, error) {
+	if cpu > float64(rules.MaxCPUs) {
+		return Tier{}, "", fmt.Errorf("%s %s allows at most %d vCPUs", rules.Name, rules.editionName(), rules.MaxCPUs)
+	}
+	if maxMB := roundDown256(rules.maxRAMFor(rules.MaxCPUs)); memMB > float64(maxMB) {
+		return Tier{}, "", fmt.Errorf("%s %s allows at most %d MB (%d vCPUs at %g GB/vCPU)", rules.Name, rules.editionName(), maxMB, rules.MaxCPUs, rules.MaxGBPerCPU)
+	}
+	c := rules.legalCPUAtLeast(int(math.Ceil(cpu)))
+	ram := max(roundUp256(int(math.Ceil(memMB))), rules.MinRAMMB)
+	binding := "cpu and memory"
+	if ram > rules.maxRAMFor(c) {
+		// Too much memory for the vCPUs: add vCPUs until the ceiling fits it
+		c = rules.legalCPUAtLeast(int(math.Ceil(float64(ram) / 1024 / rules.MaxGBPerCPU)))
+		for ram > rules.maxRAMFor(c) && c < rules.MaxCPUs {
+			c = rules.legalCPUAtLeast(c + 1)
+		}
+		binding = "memory"
+	} else if ram < rules.minRAMFor(c) {
+		// Too little memory for the vCPUs: raise memory to the band floor
+		ram = roundUp256(rules.minRAMFor(c))
+		binding = "cpu"
+	}
+	t := Tier{CPUs: c, RAMMB: ram}
+	return t, binding, t.Validate()
+}
+
+// smallestKnownTierFor returns the first valid known tier with at least cpu
+// vCPUs and memMB of memory.
+func smallestKnownTierFor(cpu, memMB float64) (Tier, bool) {
+	for _, k := range knownTiers {
+		if float64(k.CPUs) >= cpu && float64(k.RAMMB) >= memMB && k.Valid() {
+			return k, true
+		}
+	}
+	return Tier{}, false
+}
+
+// findNextKnownTier returns the first known tier above t that is valid under
+// the selected rules. knownTiers is sorted, so the search starts at the
+// first tier above t rather than scanning from the start.
+func findNextKnownTier(t Tier) (Tier, bool) {
+	i := sort.Search(len(knownTiers), func(i int) bool { return t.Less(knownTiers[i]) })
+	for _, k := range knownTiers[i:] {
+		if k.Valid() {
+			return k, true
+		}
+	}
+	return Tier{}, false
+}
+
+// findLargerKnownTier returns the first known tier above t that is valid
+// under the selected rules and has at least t's memory, so that it is larger
+// in both vCPUs and memory rather than only later in the order.
+func findLargerKnownTier(t Tier) (Tier, bool) {
+	for k, ok := findNextKnownTier(t); ok; k, ok = findNextKnownTier(k) {
+		if k.RAMMB >= t.RAMMB {
+			return k, true
+		}
+	}
+	return Tier{}, false
+}
+
+// findPreviousKnownTier returns the last known tier below t that is valid
+// under the selected rules.
+func findPreviousKnownTier(t Tier) (Tier, bool) {
+	i := sort.Search(len(knownTiers), func(i int) bool { return !knownTiers[i].Less(t) })
+	for i--; i >= 0; i-- {
+		if k := knownTiers[i]; k.Valid() {
+			return k, true
+		}
+	}
+	return Tier{}, false
+}
+
+// downgradeStrategies are the -strategy values for -downgrade, in the order
+// -strategy all lists them.
+var downgradeStrategies = []struct {
+	name string
+	desc string
+	next func(Tier) (Tier, bool)
+}{
+	{"mem-first", "less memory, same vCPUs", memFirstDowngrade},
+	{"cpu-first", "fewer vCPUs, same GB/vCPU", cpuFirstDowngrade},
+	{"balanced", "previous known tier", findPreviousKnownTier},
+}
+
+// downgradeStrategy returns the downgrade function for a -strategy name, or
+// nil if there is none.
+func downgradeStrategy(name string) func(Tier) (Tier, bool) {
+	for _, st := range downgradeStrategies {
+		if st.name == name {
+			return st.next
+		}
+	}
+	return nil
+}
+
+// memFirstDowngrade keeps the vCPUs of t and lowers its memory to the next
+// rung down of highmem, standard, the -ratio sizing ratio, and the engine's
+// minimum GB/vCPU.
+func memFirstDowngrade(t Tier) (Tier, bool) {
+	if t.Shared() {
+		return Tier{}, false
+	}
+	for _, r := range []float64{ratioClasses["highmem"], ratioClasses["standard"], opts.ratio, rules.MinGBPerCPU} {
+		next := nearestValidTier(Tier{CPUs: t.CPUs, RAMMB: ratioMBFloor(r, t.CPUs)})
+		if next.CPUs == t.CPUs && next.RAMMB < t.RAMMB && next.Valid() {
+			return next, true
+		}
+	}
+	return Tier{}, false
+}
+
+// cpuFirstDowngrade moves t to the next legal vCPU count down, keeping its
+// memory per vCPU as closely as the rules allow.
+func cpuFirstDowngrade(t Tier) (Tier, bool) {
+	cpu, ok := rules.legalCPUBelow(t.CPUs)
+	if t.Shared() || !ok {
+		return Tier{}, false
+	}
+	ram := min(max(t.RAMMB*cpu/t.CPUs, rules.minRAMFor(cpu)), rules.maxRAMFor(cpu))
+	next := nearestValidTier(Tier{CPUs: cpu, RAMMB: ram})
+	if !next.Valid() || !next.Less(t) {
+		return Tier{}, false
+	}
+	return next, true
+}
+
+func nearestValidTier(t Tier) Tier {
+	return nearestValidTierExplained(t, nil)
+}
+
+// nearestValidTierExplained is nearestValidTier, recording each adjustment
+// in ex.
+func nearestValidTierExplained(t Tier, ex *explanation) Tier {
+	// Fix vCPU: must be 1 or even, clamped to the engine's range after the
+	// parity fix so an odd count just below the cap cannot pass it
+	cpu := rules.legalCPUAtLeast(t.CPUs)
+	ex.add("adjust-vcpus", cpu == t.CPUs, "%d vCPUs → %d (1 or even, %d-%d)", t.CPUs, cpu, rules.MinCPUs, rules.MaxCPUs)
+	// Round RAM up to nearest multiple of 256
+	ram := roundUp256(t.RAMMB)
+	ex.add("round-ram", ram == t.RAMMB, "%d MB → %d MB (multiple of 256)", t.RAMMB, ram)
+	if ram < rules.MinRAMMB {
+		ex.add("clamp-ram-floor", false, "%d MB → %d MB (floor)", ram, rules.MinRAMMB)
+		ram = rules.MinRAMMB
+	}
+	// Too much memory for the vCPUs: add vCPUs rather than drop memory,
+	// until the cap leaves no choice
+	if from := cpu; ram > roundDown256(rules.maxRAMFor(cpu)) && cpu < rules.MaxCPUs {
+		for ram > roundDown256(rules.maxRAMFor(cpu)) && cpu < rules.MaxCPUs {
+			cpu = rules.legalCPUAtLeast(cpu + 1)
+		}
+		ex.add("raise-vcpus", false, "%d vCPUs → %d (%d MB needs at most %g GB/vCPU)", from, cpu, ram, rules.MaxGBPerCPU)
+	}
+	// Clamp to valid range for this CPU count
+	minRAM := roundUp256(rules.minRAMFor(cpu))
+	maxRAM := roundDown256(rules.maxRAMFor(cpu))
+	if ram < minRAM {
+		ex.add("clamp-ram-min", false, "%d MB → %d MB (%g GB × %d vCPUs, rounded up to 256)", ram, minRAM, rules.MinGBPerCPU, cpu)
+		ram = minRAM
+	}
+	if ram > maxRAM {
+		ex.add("clamp-ram-max", false, "%d MB → %d MB (%g GB × %d vCPUs, rounded down to 256)", ram, maxRAM, rules.MaxGBPerCPU, cpu)
+		ram = maxRAM
+	}
+	return Tier{CPUs: cpu, RAMMB: ram}
+}
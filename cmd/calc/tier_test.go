@@ -0,0 +1,266 @@
+package main
+
+import (
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestParseTier(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Tier
+		wantErr error
+	}{
+		{"db-custom-4-16384", Tier{CPUs: 4, RAMMB: 16384}, nil},
+		{"  db-custom-4-16384\t", Tier{CPUs: 4, RAMMB: 16384}, nil},
+		{"db-custom-4-16384\n", Tier{CPUs: 4, RAMMB: 16384}, nil},
+		{"xdb-custom-4-16384", Tier{}, ErrBadTierSyntax},
+		{"db-custom-4-16384x", Tier{}, ErrBadTierSyntax},
+		{"db-custom-4-16384-1", Tier{}, ErrBadTierSyntax},
+		{"tier=db-custom-4-16384", Tier{}, ErrBadTierSyntax},
+		{"db-custom-4-16384 db-custom-8-32768", Tier{}, ErrBadTierSyntax},
+		{"my-db-custom-4-16384-tier", Tier{}, ErrBadTierSyntax},
+		{"DB-CUSTOM-4-16384", Tier{CPUs: 4, RAMMB: 16384}, nil},
+		{"Db-Custom-4-16384", Tier{CPUs: 4, RAMMB: 16384}, nil},
+		{" DB-custom-4-16384x", Tier{}, ErrBadTierSyntax},
+		{"db-custom--4-16384", Tier{}, ErrBadTierSyntax},
+		{"db-custom-4-", Tier{}, ErrBadTierSyntax},
+		{"", Tier{}, ErrBadTierSyntax},
+	}
+	for _, tt := range tests {
+		got, err := ParseTier(tt.in)
+		if !errors.Is(err, tt.wantErr) || tt.wantErr == nil && err != nil {
+			t.Errorf("ParseTier(%q) error = %v, want %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseTier(%q) = %+v, want %+v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestSuggestNextTierAlwaysValid(t *testing.T) {
+	defer func(r float64) { opts.ratio = r }(opts.ratio)
+	for _, opts.ratio = range []float64{defaultGBPerCPU, rules.MinGBPerCPU, rules.MaxGBPerCPU} {
+		for ram := 1024; ram <= 700000; ram++ {
+			in := Tier{CPUs: 4, RAMMB: ram}
+			got, ok := suggestNextTier(in)
+			if !ok {
+				if ram < rules.MaxRAMMB {
+					t.Fatalf("at %g GB/vCPU, suggestNextTier(%s) found no larger tier", opts.ratio, in)
+				}
+				continue
+			}
+			if got == in || got.CPUs < in.CPUs || got.RAMMB < in.RAMMB {
+				t.Fatalf("at %g GB/vCPU, suggestNextTier(%s) = %s, not larger", opts.ratio, in, got)
+			}
+			if err := got.Validate(); err != nil {
+				t.Fatalf("at %g GB/vCPU, suggestNextTier(%s) = %s: %v", opts.ratio, in, got, err)
+			}
+			if got.RAMMB%256 != 0 {
+				t.Fatalf("at %g GB/vCPU, suggestNextTier(%s) = %s, not a multiple of 256 MB", opts.ratio, in, got)
+			}
+		}
+	}
+}
+
+func TestNextIsLarger(t *testing.T) {
+	tests := []struct {
+		edition, in, want string
+	}{
+		{"enterprise", "db-custom-4-15360", "db-custom-4-26624"},
+		{"enterprise", "db-custom-6-39936", "db-custom-8-53248"},
+		{"enterprise", "db-custom-95-600000", "db-custom-96-638976"},
+		{"enterprise", "db-custom-96-368640", "db-custom-96-638976"},
+		{"enterprise", "db-custom-96-147456", "db-custom-96-368640"},
+		{"enterprise", "db-custom-96-600064", "db-custom-96-638976"},
+		{"enterprise", "db-custom-96-638976", ""},
+		{"enterprise-plus", "db-custom-96-638976", "db-custom-128-851968"},
+		{"enterprise-plus", "db-custom-128-884736", ""},
+	}
+	for _, tt := range tests {
+		useRules(t, "mysql", tt.edition)
+		out, code := run(t, "next", "-edition", tt.edition, tt.in)
+		res, err := runTier(tt.in)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.SuggestedTier != tt.want {
+			t.Errorf("%s next %s = %q, want %q", tt.edition, tt.in, res.SuggestedTier, tt.want)
+		}
+		if tt.want == "" && (res.Message != "at maximum tier" || code != exitOK || !strings.Contains(out, "At maximum tier: no valid tier above "+tt.in)) {
+			t.Errorf("%s next %s = %q, exit %d, want it at the maximum tier", tt.edition, tt.in, out, code)
+		}
+	}
+}
+
+func TestParseLegacyTiers(t *testing.T) {
+	tests := map[string]string{
+		"db-n1-standard-1":  "db-custom-1-3840",
+		"db-n1-standard-2":  "db-custom-2-7680",
+		"db-n1-standard-4":  "db-custom-4-15360",
+		"db-n1-standard-8":  "db-custom-8-30720",
+		"db-n1-standard-16": "db-custom-16-61440",
+		"db-n1-standard-32": "db-custom-32-122880",
+		"db-n1-standard-64": "db-custom-64-245760",
+		"db-n1-standard-96": "db-custom-96-368640",
+		"db-n1-highmem-2":   "db-custom-2-13312",
+		"db-n1-highmem-4":   "db-custom-4-26624",
+		"db-n1-highmem-8":   "db-custom-8-53248",
+		"db-n1-highmem-16":  "db-custom-16-106496",
+		"db-n1-highmem-32":  "db-custom-32-212992",
+		"db-n1-highmem-64":  "db-custom-64-425984",
+		"db-n1-highmem-96":  "db-custom-96-638976",
+		" db-n1-highmem-8 ": "db-custom-8-53248",
+	}
+	for in, want := range tests {
+		got, err := ParseTier(in)
+		if err != nil {
+			t.Errorf("ParseTier(%q): %v", in, err)
+			continue
+		}
+		if got.String() != want {
+			t.Errorf("ParseTier(%q) = %s, want %s", in, got, want)
+		}
+		if err := got.Validate(); err != nil {
+			t.Errorf("ParseTier(%q) = %s is not valid: %v", in, got, err)
+		}
+	}
+	for _, in := range []string{"db-n1-standard-3", "db-n1-highmem-1", "db-n1-standard-128", "db-n1-megamem-8", "db-n1-standard-4x"} {
+		if _, err := ParseTier(in); !errors.Is(err, ErrBadTierSyntax) {
+			t.Errorf("ParseTier(%q) error = %v, want %v", in, err, ErrBadTierSyntax)
+		}
+	}
+}
+
+// regexpParseTier is the custom tier parse before the pattern was compiled
+// once, kept as the reference behavior, with case ignored since tiers are
+// parsed case-insensitively. It reports false for input it rejects.
+func regexpParseTier(s string) (Tier, bool) {
+	re := regexp.MustCompile(`^db-custom-(\d+)-(\d+)$`)
+	m := re.FindStringSubmatch(strings.ToLower(strings.TrimSpace(s)))
+	if len(m) != 3 {
+		return Tier{}, false
+	}
+	cpu, err := strconv.Atoi(m[1])
+	if err != nil {
+		return Tier{}, false
+	}
+	ram, err := strconv.Atoi(m[2])
+	if err != nil {
+		return Tier{}, false
+	}
+	return Tier{CPUs: cpu, RAMMB: ram}, true
+}
+
+// FuzzParseTier checks that ParseTier accepts the same custom tiers as the
+// reference parse within the limits of the selected rules, with the same
+// values, and rejects everything else; a well-formed tier out of the limits
+// gets a range error.
+func FuzzParseTier(f *testing.F) {
+	for _, s := range []string{
+		"db-custom-4-16384", " db-custom-4-16384 ", "db-custom-4-16384x", "xdb-custom-4-16384",
+		"db-custom-04-016384", "db-custom-4--16384", "db-custom-99999999999999999999-1",
+		"db-custom-4-16384\n", "db-custom-٤-16384", "DB-CUSTOM-4-16384", "db-custom-4-", "",
+		"db-custom-0-3840", "db-custom-97-16384", "db-custom-4-255", "db-custom-4-99999999999",
+		"db-custom-4-638977", "db-custom-96-638976", "db-custom-1-256",
+	} {
+		f.Add(s)
+	}
+	f.Fuzz(func(t *testing.T, s string) {
+		name := strings.ToLower(strings.TrimSpace(s))
+		if _, ok := legacyTiers[name]; ok {
+			return
+		}
+		for _, sc := range sharedCoreTiers {
+			if name == sc.name {
+				return
+			}
+		}
+		if _, ok := lookupMachineType(name); ok {
+			return
+		}
+		got, err := ParseTier(s)
+		want, ok := regexpParseTier(s)
+		inRange := want.CPUs >= 1 && want.CPUs <= rules.MaxCPUs && want.RAMMB >= rules.RAMStepMB && want.RAMMB <= rules.MaxRAMMB
+		switch {
+		case ok && inRange:
+			if err != nil || got != want {
+				t.Errorf("ParseTier(%q) = %+v, %v; reference = %+v", s, got, err, want)
+			}
+		case err == nil:
+			t.Errorf("ParseTier(%q) = %+v, want an error; reference = %+v, %t", s, got, want, ok)
+		case ok && !errors.Is(err, ErrCPUCount) && !errors.Is(err, ErrRAMTooLow) && !errors.Is(err, ErrRAMTooHigh):
+			t.Errorf("ParseTier(%q) error = %v, want a range error for %+v", s, err, want)
+		}
+	})
+}
+
+func TestParseTierBounds(t *testing.T) {
+	tests := []struct {
+		edition string
+		in      string
+		want    Tier
+		wantErr error
+		msg     string
+	}{
+		{"enterprise", "db-custom-1-256", Tier{CPUs: 1, RAMMB: 256}, nil, ""},
+		{"enterprise", "db-custom-96-638976", Tier{CPUs: 96, RAMMB: 638976}, nil, ""},
+		{"enterprise", "db-custom-0-3840", Tier{}, ErrCPUCount, `vCPU count 0 in tier "db-custom-0-3840" is below the minimum of 1`},
+		{"enterprise", "db-custom-97-16384", Tier{}, ErrCPUCount, `vCPU count 97 in tier "db-custom-97-16384" is above the MySQL Enterprise maximum of 96`},
+		{"enterprise", "db-custom-999999999999999999999-1", Tier{}, ErrCPUCount, "is above the MySQL Enterprise maximum of 96"},
+		{"enterprise", "db-custom-4-0", Tier{}, ErrRAMTooLow, `memory 0 MB in tier "db-custom-4-0" is below the minimum of 256 MB`},
+		{"enterprise", "db-custom-4-255", Tier{}, ErrRAMTooLow, "below the minimum of 256 MB"},
+		{"enterprise", "db-custom-4-638977", Tier{}, ErrRAMTooHigh, `memory 638977 MB in tier "db-custom-4-638977" is above the MySQL Enterprise maximum of 638976 MB`},
+		{"enterprise", "db-custom-4-99999999999", Tier{}, ErrRAMTooHigh, "above the MySQL Enterprise maximum of 638976 MB"},
+		{"enterprise", "db-custom-4-99999999999999999999999", Tier{}, ErrRAMTooHigh, "above the MySQL Enterprise maximum of 638976 MB"},
+		{"enterprise-plus", "db-custom-128-16384", Tier{CPUs: 128, RAMMB: 16384}, nil, ""},
+		{"enterprise-plus", "db-custom-129-16384", Tier{}, ErrCPUCount, "above the MySQL Enterprise Plus maximum of 128"},
+		{"enterprise-plus", "db-custom-4-884736", Tier{CPUs: 4, RAMMB: 884736}, nil, ""},
+	}
+	for _, tt := range tests {
+		useRules(t, "mysql", tt.edition)
+		got, err := ParseTier(tt.in)
+		if !errors.Is(err, tt.wantErr) || tt.wantErr == nil && err != nil {
+			t.Errorf("%s ParseTier(%q) error = %v, want %v", tt.edition, tt.in, err, tt.wantErr)
+			continue
+		}
+		if err != nil && !strings.Contains(err.Error(), tt.msg) {
+			t.Errorf("%s ParseTier(%q) error = %q, want %q", tt.edition, tt.in, err, tt.msg)
+		}
+		if got != tt.want {
+			t.Errorf("%s ParseTier(%q) = %+v, want %+v", tt.edition, tt.in, got, tt.want)
+		}
+	}
+}
+
+func BenchmarkParseTier(b *testing.B) {
+	for range b.N {
+		ParseTier("db-custom-16-106496")
+	}
+}
+
+func BenchmarkParseTierRecompiled(b *testing.B) {
+	for range b.N {
+		regexpParseTier("db-custom-16-106496")
+	}
+}
+
+func TestNearestValidTierAlwaysValid(t *testing.T) {
+	for _, engine := range sortedKeys(engineRules) {
+		for _, edition := range sortedKeys(editions) {
+			useRules(t, engine, edition)
+			for cpu := -5; cpu <= 110; cpu++ {
+				for ram := 0; ram <= 700000; ram += 256 {
+					in := Tier{CPUs: cpu, RAMMB: ram}
+					if got := nearestValidTier(in); !got.Valid() {
+						t.Fatalf("%s %s: nearestValidTier(%s) = %s: %v", engine, edition, in, got, got.Validate())
+					}
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+)
+
+// Overhead estimates the memory Cloud SQL keeps from the database engine for
+// the OS and its agents: a fixed amount plus a share of instance memory. The
+// engine defaults are rough; -overhead-mb and -overhead-pct, or the same keys
+// in the config file, calibrate them from real instances.
+type Overhead struct {
+	FixedMB int     `json:"fixed_mb"`
+	Pct     float64 `json:"pct"`
+}
+
+func (o Overhead) String() string {
+	return fmt.Sprintf("%d MB + %g%%", o.FixedMB, o.Pct)
+}
+
+// usable returns the memory of ramMB that the engine can use, at least 0.
+func (o Overhead) usable(ramMB float64) float64 {
+	return max(ramMB-float64(o.FixedMB)-ramMB*o.Pct/100, 0)
+}
+
+// raw returns the instance memory that leaves usableMB to the engine.
+func (o Overhead) raw(usableMB float64) float64 {
+	return (usableMB + float64(o.FixedMB)) / (1 - o.Pct/100)
+}
+
+// UsableMemory is the -usable view of the resulting tier.
+type UsableMemory struct {
+	Tier     string   `json:"tier"`
+	RawMB    int      `json:"raw_mb"`
+	UsableMB int      `json:"usable_mb"`
+	Overhead Overhead `json:"overhead"`
+}
+
+// calibrateOverhead replaces the overhead estimate of every engine with
+// -overhead-mb and -overhead-pct where they are given.
+func calibrateOverhead(fs *flag.FlagSet) error {
+	if opts.overheadMB < 0 || opts.overheadPct < 0 || opts.overheadPct >= 100 {
+		return fmt.Errorf("-overhead-mb must not be negative and -overhead-pct must be at least 0 and below 100")
+	}
+	for name, c := range engineRules {
+		if flagSet(fs, "overhead-mb") {
+			c.Overhead.FixedMB = opts.overheadMB
+		}
+		if flagSet(fs, "overhead-pct") {
+			c.Overhead.Pct = opts.overheadPct
+		}
+		engineRules[name] = c
+	}
+	return nil
+}
+
+// addUsable shows the raw and usable memory of the resolved tier.
+func addUsable(res *Result) {
+	t, err := ParseTier(res.resolvedTier())
+	if err != nil {
+		return
+	}
+	o := rules.Overhead
+	u := &UsableMemory{Tier: t.String(), RawMB: t.RAMMB, UsableMB: int(o.usable(float64(t.RAMMB))), Overhead: o}
+	res.Usable = u
+	res.printf("Usable memory for %s (less %s overhead):\n", t, o)
+	res.printf("  Raw: %d MB (%.2f GB)  Usable: ~%d MB (%.2f GB)\n", u.RawMB, t.RAMGB(), u.UsableMB, float64(u.UsableMB)/1024)
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+)
+
+func TestOverhead(t *testing.T) {
+	tests := []struct {
+		o           Overhead
+		ramMB, want float64
+	}{
+		{Overhead{FixedMB: 1024, Pct: 5}, 15360, 13568},
+		{Overhead{FixedMB: 512, Pct: 10}, 15360, 13312},
+		{Overhead{FixedMB: 2048, Pct: 5}, 30720, 27136},
+		{Overhead{FixedMB: 1024}, 3840, 2816},
+		{Overhead{FixedMB: 4096, Pct: 5}, 3840, 0},
+	}
+	for _, tt := range tests {
+		got := tt.o.usable(tt.ramMB)
+		if got != tt.want {
+			t.Errorf("%s usable(%g) = %g, want %g", tt.o, tt.ramMB, got, tt.want)
+		}
+		if got > 0 && math.Abs(tt.o.raw(got)-tt.ramMB) > 1e-6 {
+			t.Errorf("%s raw(%g) = %g, want %g", tt.o, got, tt.o.raw(got), tt.ramMB)
+		}
+	}
+}
+
+func TestUsableMySQLConfig(t *testing.T) {
+	useRules(t, "mysql", "enterprise")
+	tier := Tier{CPUs: 8, RAMMB: 30720}
+	raw := recommendMySQLConfig(tier, 75, 2048, 0, false)
+	usable := recommendMySQLConfig(tier, 75, 2048, 0, true)
+	if got := usable.BufferPoolBytes >> 20; got != 21120 {
+		t.Errorf("usable buffer pool of %s = %d MB, want 75%% of the 28160 MB usable, 21120 MB", tier, got)
+	}
+	if usable.BufferPoolBytes >= raw.BufferPoolBytes {
+		t.Errorf("usable buffer pool of %s = %d, not below the raw %d", tier, usable.BufferPoolBytes, raw.BufferPoolBytes)
+	}
+}
+
+func TestUsableFlag(t *testing.T) {
+	tests := []struct {
+		args   []string
+		usable int
+	}{
+		{[]string{"-usable", "db-custom-4-15360"}, 13568},
+		{[]string{"-usable", "-overhead-mb", "512", "-overhead-pct", "10", "db-custom-4-15360"}, 13312},
+		{[]string{"db-custom-4-15360"}, 0},
+	}
+	for _, tt := range tests {
+		out, code := run(t, append([]string{"next", "-o", "json"}, tt.args...)...)
+		var res struct {
+			Usable *UsableMemory `json:"usable"`
+		}
+		if err := json.Unmarshal([]byte(out), &res); err != nil {
+			t.Fatalf("next %q: %v\n%s", tt.args, err, out)
+		}
+		got := 0
+		if res.Usable != nil {
+			got = res.Usable.UsableMB
+		}
+		if got != tt.usable || code != exitOK {
+			t.Errorf("next %q = %d MB usable (exit %d), want %d", tt.args, got, code, tt.usable)
+		}
+	}
+	// Sizing from usable memory adds the overhead back on top
+	sized := map[bool]string{}
+	for _, usable := range []bool{false, true} {
+		args := []string{"suggest", "-o", "json", "-data-size", "100G"}
+		if usable {
+			args = append(args, "-usable")
+		}
+		out, _ := run(t, args...)
+		var res struct {
+			Tier string `json:"tier"`
+		}
+		if err := json.Unmarshal([]byte(out), &res); err != nil {
+			t.Fatalf("suggest %q: %v\n%s", args, err, out)
+		}
+		sized[usable] = res.Tier
+	}
+	if sized[false] != "db-custom-20-28672" || sized[true] != "db-custom-22-31232" {
+		t.Errorf("-data-size 100G = %s, with -usable %s, want db-custom-20-28672 and db-custom-22-31232", sized[false], sized[true])
+	}
+	for _, pct := range []string{"-1", "100"} {
+		if _, code := run(t, "next", "-usable", "-overhead-pct", pct, "db-custom-4-15360"); code != exitUsage {
+			t.Errorf("-overhead-pct %s exited %d, want %d", pct, code, exitUsage)
+		}
+	}
+}
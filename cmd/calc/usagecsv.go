@@ -0,0 +1,299 @@
+package main
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// UsageSeries is the utilization a -usage-csv fed into rightsizing: the
+// statistics of each dimension it has samples of, CPU in percent of the tier
+// vCPUs and memory in MB. A dimension without samples keeps its current size.
+type UsageSeries struct {
+	File    string       `json:"file"`
+	Samples int          `json:"samples"`
+	Start   *time.Time   `json:"start,omitempty"`
+	End     *time.Time   `json:"end,omitempty"`
+	CPU     *SeriesStats `json:"cpu_pct,omitempty"`
+	Mem     *SeriesStats `json:"mem_mb,omitempty"`
+}
+
+// SeriesStats summarizes the samples of one dimension. Value is the sample at
+// Percentile, taken at At when the file has timestamps; Above counts the
+// samples higher than it.
+type SeriesStats struct {
+	Samples    int        `json:"samples"`
+	Min        float64    `json:"min"`
+	Mean       float64    `json:"mean"`
+	P50        float64    `json:"p50"`
+	Max        float64    `json:"max"`
+	Percentile float64    `json:"percentile"`
+	Value      float64    `json:"value"`
+	At         *time.Time `json:"at,omitempty"`
+	Above      int        `json:"samples_above"`
+}
+
+// percentileFlag is a -percentile, -cpu-percentile, or -mem-percentile: a
+// number above 0 and at most 100, or max, which is the 100th percentile. 0 is
+// unset.
+type percentileFlag struct{ p *float64 }
+
+func (f percentileFlag) String() string {
+	if f.p == nil || *f.p == 0 {
+		return ""
+	}
+	if *f.p == 100 {
+		return "max"
+	}
+	return strconv.FormatFloat(*f.p, 'g', -1, 64)
+}
+
+func (f percentileFlag) Set(s string) error {
+	if strings.EqualFold(strings.TrimSpace(s), "max") {
+		*f.p = 100
+		return nil
+	}
+	p, err := strconv.ParseFloat(s, 64)
+	if err != nil || p <= 0 || p > 100 {
+		return fmt.Errorf("invalid percentile %q: use a number above 0 and at most 100, or max", s)
+	}
+	*f.p = p
+	return nil
+}
+
+// usagePercentiles returns the percentiles to size vCPUs and memory for:
+// -cpu-percentile and -mem-percentile, or -percentile for one not given.
+func usagePercentiles() (cpuP, memP float64) {
+	cpuP, memP = opts.cpuPercentile, opts.memPercentile
+	if cpuP == 0 {
+		cpuP = opts.percentile
+	}
+	if memP == 0 {
+		memP = opts.percentile
+	}
+	return cpuP, memP
+}
+
+// percentileName formats p as p95, or max for the 100th percentile.
+func percentileName(p float64) string {
+	if p == 100 {
+		return "max"
+	}
+	return fmt.Sprintf("p%g", p)
+}
+
+// usageColumns are the columns of a -usage-csv, -1 when absent.
+type usageColumns struct{ time, cpu, mem int }
+
+// headerColumns finds the columns of a header row by name: a time, timestamp,
+// or date column, one whose name has cpu, and one whose name has mem. It
+// reports false when the row is data rather than a header.
+func headerColumns(row []string) (usageColumns, bool) {
+	cols := usageColumns{-1, -1, -1}
+	for i, name := range row {
+		name = strings.ToLower(strings.TrimSpace(name))
+		switch {
+		case cols.time < 0 && (strings.Contains(name, "time") || name == "ts" || name == "date"):
+			cols.time = i
+		case cols.cpu < 0 && strings.Contains(name, "cpu"):
+			cols.cpu = i
+		case cols.mem < 0 && strings.Contains(name, "mem"):
+			cols.mem = i
+		}
+	}
+	return cols, cols != usageColumns{-1, -1, -1}
+}
+
+// parseSampleTime parses an RFC 3339 time, a "2006-01-02 15:04:05" UTC time,
+// or Unix seconds.
+func parseSampleTime(s string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t.UTC(), nil
+	}
+	if t, err := time.Parse(time.DateTime, s); err == nil {
+		return t, nil
+	}
+	if secs, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return time.Unix(secs, 0).UTC(), nil
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q: use RFC 3339, \"2006-01-02 15:04:05\", or Unix seconds", s)
+}
+
+// readUsageCSV reads a -usage-csv of timestamped CPU percent and memory bytes
+// samples, or stdin when path is "-". The columns are found by a header row,
+// or are time, cpu, mem in that order without one; either utilization column
+// may be missing or have empty cells. cpuP and memP are the percentiles of
+// each dimension to size for.
+func readUsageCSV(path string, cpuP, memP float64) (*UsageSeries, error) {
+	var in io.Reader = os.Stdin
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		in = f
+	}
+	r := csv.NewReader(in)
+	r.FieldsPerRecord = -1
+	r.TrimLeadingSpace = true
+	r.Comment = '#'
+	s := &UsageSeries{File: path}
+	cols := usageColumns{0, 1, 2}
+	var cpu, mem sample
+	for line := 1; ; line++ {
+		row, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		if line == 1 {
+			if h, ok := headerColumns(row); ok {
+				cols = h
+				continue
+			}
+		}
+		var at time.Time
+		if v := field(row, cols.time); v != "" {
+			if at, err = parseSampleTime(v); err != nil {
+				return nil, fmt.Errorf("%s line %d: %w", path, line, err)
+			}
+			if s.Start == nil || at.Before(*s.Start) {
+				s.Start = &at
+			}
+			if s.End == nil || at.After(*s.End) {
+				s.End = &at
+			}
+		}
+		if err := cpu.add(field(row, cols.cpu), at, 1, 100); err != nil {
+			return nil, fmt.Errorf("%s line %d: CPU: %w", path, line, err)
+		}
+		if err := mem.add(field(row, cols.mem), at, 1<<20, math.Inf(1)); err != nil {
+			return nil, fmt.Errorf("%s line %d: memory: %w", path, line, err)
+		}
+		s.Samples++
+	}
+	s.CPU, s.Mem = cpu.stats(cpuP), mem.stats(memP)
+	if s.CPU == nil && s.Mem == nil {
+		return nil, fmt.Errorf("%s: no CPU or memory samples", path)
+	}
+	return s, nil
+}
+
+// field returns column i of row, or "" when the row has no such column.
+func field(row []string, i int) string {
+	if i < 0 || i >= len(row) {
+		return ""
+	}
+	return strings.TrimSpace(row[i])
+}
+
+// sample collects the values of one dimension and when they were taken.
+type sample struct {
+	values []float64
+	times  []time.Time
+}
+
+// add records the value v, divided by unit, taken at at. An empty v is a
+// missing sample and is skipped; one above limit is an error.
+func (s *sample) add(v string, at time.Time, unit, limit float64) error {
+	if v == "" {
+		return nil
+	}
+	x, err := strconv.ParseFloat(strings.TrimSuffix(v, "%"), 64)
+	if err != nil || x < 0 || math.IsInf(x, 0) || math.IsNaN(x) {
+		return fmt.Errorf("invalid sample %q", v)
+	}
+	if x > limit {
+		return fmt.Errorf("sample %q is above %g%%", v, limit)
+	}
+	s.values = append(s.values, x/unit)
+	s.times = append(s.times, at)
+	return nil
+}
+
+// stats summarizes the samples with Value at the p-th percentile, by the
+// nearest-rank method of percentile, or returns nil when there are none.
+func (s *sample) stats(p float64) *SeriesStats {
+	n := len(s.values)
+	if n == 0 {
+		return nil
+	}
+	order := make([]int, n)
+	sum := 0.0
+	for i, v := range s.values {
+		order[i] = i
+		sum += v
+	}
+	sort.SliceStable(order, func(a, b int) bool { return s.values[order[a]] < s.values[order[b]] })
+	at := func(p float64) int { return order[max(int(math.Ceil(p/100*float64(n))), 1)-1] }
+	i := at(p)
+	st := &SeriesStats{
+		Samples:    n,
+		Min:        s.values[order[0]],
+		Mean:       sum / float64(n),
+		P50:        s.values[at(50)],
+		Max:        s.values[order[n-1]],
+		Percentile: p,
+		Value:      s.values[i],
+	}
+	if t := s.times[i]; !t.IsZero() {
+		st.At = &t
+	}
+	for _, v := range s.values {
+		if v > st.Value {
+			st.Above++
+		}
+	}
+	return st
+}
+
+// usagePcts returns the CPU and memory utilization of tier t the series
+// sizes for, rounded to 0.1% like -monitor and 0 for a dimension it has no
+// samples of. Memory is only shown as a percentage: it is sized from the
+// sampled MB, which may be above what t has.
+func (s *UsageSeries) usagePcts(t Tier) (cpuPct, memPct float64) {
+	if s.CPU != nil {
+		cpuPct = max(math.Round(s.CPU.Value*10)/10, 0.1)
+	}
+	if s.Mem != nil {
+		memPct = math.Round(s.Mem.Value/float64(t.RAMMB)*1000) / 10
+	}
+	return cpuPct, memPct
+}
+
+// printSeries adds the statistics of a -usage-csv to the report.
+func (r *Result) printSeries(s *UsageSeries) {
+	span := "no timestamps"
+	if s.Start != nil {
+		span = fmt.Sprintf("%s to %s UTC", s.Start.Format("2006-01-02 15:04"), s.End.Format("2006-01-02 15:04"))
+	}
+	r.printf("  Usage CSV %s: %d samples, %s\n", s.File, s.Samples, span)
+	line := func(name, unit string, st *SeriesStats) {
+		if st == nil {
+			r.printf("    %s: no samples, kept at the current size\n", name)
+			return
+		}
+		at := ""
+		if st.At != nil {
+			at = " at " + st.At.Format("2006-01-02 15:04")
+		}
+		r.printf("    %s (%d samples): min %.1f, mean %.1f, p50 %.1f, max %.1f%s; %s %.1f%s%s, %d samples above\n",
+			name, st.Samples, st.Min, st.Mean, st.P50, st.Max, unit, percentileName(st.Percentile), st.Value, unit, at, st.Above)
+	}
+	line("CPU", "%", s.CPU)
+	line("Memory", " MB", s.Mem)
+	if s.CPU != nil && s.Mem != nil && s.CPU.Percentile != s.Mem.Percentile {
+		r.printf("    vCPUs sized for the %s CPU sample, memory for the %s memory sample\n",
+			percentileName(s.CPU.Percentile), percentileName(s.Mem.Percentile))
+	}
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeSpikyCSV writes 100 minutes of an OLTP workload: CPU at 20% with a
+// burst to 90% every tenth minute, and memory at 15 GiB but for 29 GiB
+// peaks in the last four minutes.
+func writeSpikyCSV(t *testing.T) string {
+	t.Helper()
+	var b strings.Builder
+	b.WriteString("time,cpu,mem\n")
+	for i := range 100 {
+		cpu, mem := 20, 15<<30
+		if i%10 == 9 {
+			cpu = 90
+		}
+		if i >= 96 {
+			mem = 29 << 30
+		}
+		fmt.Fprintf(&b, "2026-10-01T%02d:%02d:00Z,%d,%d\n", i/60, i%60, cpu, mem)
+	}
+	path := filepath.Join(t.TempDir(), "usage.csv")
+	if err := os.WriteFile(path, []byte(b.String()), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestUsageCSVSplitPercentiles(t *testing.T) {
+	path := writeSpikyCSV(t)
+	tests := []struct {
+		flags      []string
+		want       string
+		cpuP, memP float64
+		explained  bool
+	}{
+		{[]string{"-percentile", "99"}, "db-custom-10-37120", 99, 99, false},
+		{[]string{"-percentile", "50"}, "db-custom-4-19200", 50, 50, false},
+		{[]string{"-cpu-percentile", "99", "-mem-percentile", "50"}, "db-custom-10-19200", 99, 50, true},
+		{[]string{"-percentile", "50", "-cpu-percentile", "max"}, "db-custom-10-19200", 100, 50, true},
+	}
+	for _, tt := range tests {
+		args := append(append([]string{"rightsize", "-usage-csv", path}, tt.flags...), "db-custom-8-30720")
+		out, code := run(t, args...)
+		explained := strings.Contains(out, fmt.Sprintf("vCPUs sized for the %s CPU sample, memory for the %s memory sample", percentileName(tt.cpuP), percentileName(tt.memP)))
+		if code != exitOK || !strings.Contains(out, "Recommended tier: "+tt.want) || explained != tt.explained {
+			t.Errorf("go-calc %q = %d %q, want %s, explained %t", args, code, out, tt.want, tt.explained)
+		}
+
+		var res struct {
+			Series struct {
+				CPU *SeriesStats `json:"cpu_pct"`
+				Mem *SeriesStats `json:"mem_mb"`
+			} `json:"usage_series"`
+		}
+		out, _ = run(t, append([]string{"rightsize", "-o", "json"}, args[1:]...)...)
+		if err := json.Unmarshal([]byte(out), &res); err != nil {
+			t.Fatalf("go-calc %q -o json: %v\n%s", args, err, out)
+		}
+		if res.Series.CPU == nil || res.Series.Mem == nil || res.Series.CPU.Percentile != tt.cpuP || res.Series.Mem.Percentile != tt.memP {
+			t.Errorf("go-calc %q -o json read CPU at %+v and memory at %+v, want p%g and p%g", args, res.Series.CPU, res.Series.Mem, tt.cpuP, tt.memP)
+		}
+	}
+
+	if _, code := run(t, "rightsize", "-cpu-percentile", "99", "-cpu-util", "50", "-mem-util", "50", "db-custom-8-30720"); code != exitUsage {
+		t.Errorf("-cpu-percentile without -monitor or -usage-csv exited %d, want %d", code, exitUsage)
+	}
+}
+
+// writeCSV writes rows under a time,cpu,mem header and returns the path.
+func writeCSV(t *testing.T, rows ...string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "usage.csv")
+	if err := os.WriteFile(path, []byte("time,cpu,mem\n"+strings.Join(rows, "\n")+"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// Memory is sized from the sampled bytes, not a percentage of the current
+// tier rounded to 0.1% and capped at 100.
+func TestUsageCSVMemoryInMB(t *testing.T) {
+	tests := []struct {
+		name, mem, tier string
+		wantMB          float64
+	}{
+		{"8 GiB of 30 GiB", "8589934592", "db-custom-8-30720", 8192 / 0.8},
+		{"above the current memory", "34359738368", "db-custom-8-30720", 32768 / 0.8},
+	}
+	for _, tt := range tests {
+		path := writeCSV(t, "2026-10-01T00:00:00Z,50,"+tt.mem)
+		out, code := run(t, "rightsize", "-o", "json", "-usage-csv", path, tt.tier)
+		var res struct {
+			RequestedMemMB float64 `json:"requested_mem_mb"`
+		}
+		if err := json.Unmarshal([]byte(out), &res); err != nil || code != exitOK {
+			t.Fatalf("%s: exit %d, %v\n%s", tt.name, code, err, out)
+		}
+		if res.RequestedMemMB != tt.wantMB {
+			t.Errorf("%s: required memory = %g MB, want %g", tt.name, res.RequestedMemMB, tt.wantMB)
+		}
+	}
+}
+
+// A sample out of range is reported with its line, not as a flag the user
+// never gave.
+func TestUsageCSVSampleOutOfRange(t *testing.T) {
+	path := writeCSV(t, "2026-10-01T00:00:00Z,50,1073741824", "2026-10-01T00:01:00Z,120,1073741824")
+	out, code := run(t, "rightsize", "-usage-csv", path, "db-custom-8-30720")
+	if code == exitOK || !strings.Contains(out, "line 3: CPU: sample \"120\" is above 100%") || strings.Contains(out, "-cpu-util") {
+		t.Errorf("CPU sample of 120%% = exit %d, %q, want the CSV line reported", code, out)
+	}
+}
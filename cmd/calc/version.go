@@ -0,0 +1,94 @@
+package main
+
+import (
+	"fmt"
+	"runtime/debug"
+	"strings"
+)
+
+// rulesVersion identifies the revision of the tier rule tables (engineRules,
+// editions, and knownTiers). Bump it whenever any of them changes.
+const rulesVersion = "2026-10"
+
+// Set with -ldflags "-X main.version=... -X main.commit=... -X main.date=...".
+// Empty values fall back to the build info Go embeds in the binary.
+var (
+	version string
+	commit  string
+	date    string
+)
+
+// BuildInfo describes the binary and the tier rules it enforces.
+type BuildInfo struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit,omitempty"`
+	Date      string `json:"date,omitempty"`
+	Modified  bool   `json:"modified,omitempty"`
+	TierRules string `json:"tier_rules"`
+}
+
+// buildInfo returns the version information, preferring the -ldflags values.
+func buildInfo() *BuildInfo {
+	b := &BuildInfo{Version: version, Commit: commit, Date: date, TierRules: rulesVersion}
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		if b.Version == "" {
+			b.Version = bi.Main.Version
+		}
+		for _, s := range bi.Settings {
+			switch s.Key {
+			case "vcs.revision":
+				if b.Commit == "" {
+					b.Commit = s.Value
+				}
+			case "vcs.time":
+				if b.Date == "" {
+					b.Date = s.Value
+				}
+			case "vcs.modified":
+				b.Modified = commit == "" && s.Value == "true"
+			}
+		}
+	}
+	if b.Version == "" {
+		b.Version = "(devel)"
+	}
+	return b
+}
+
+func (b *BuildInfo) String() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "go-calc %s\n", b.Version)
+	if b.Commit != "" {
+		fmt.Fprintf(&sb, "  commit: %s", b.Commit)
+		if b.Modified {
+			sb.WriteString(" (modified)")
+		}
+		sb.WriteString("\n")
+	}
+	if b.Date != "" {
+		fmt.Fprintf(&sb, "  date: %s\n", b.Date)
+	}
+	fmt.Fprintf(&sb, "  tier rules: %s\n", b.TierRules)
+	return sb.String()
+}
+
+// versioned is implemented by the reports that carry the build info in
+// machine-readable output.
+type versioned interface {
+	setVersion(b *BuildInfo)
+}
+
+// VersionResult is the output of -version.
+type VersionResult struct {
+	Version *BuildInfo `json:"version"`
+	Error   string     `json:"error,omitempty"`
+}
+
+func (v *VersionResult) humanText() string        { return v.Version.String() }
+func (v *VersionResult) exitCode() int            { return exitOK }
+func (v *VersionResult) setError(err error)       { v.Error = err.Error() }
+func (v *VersionResult) setVersion(bi *BuildInfo) { v.Version = bi }
+
+func runVersion() (*VersionResult, error) {
+	return &VersionResult{Version: buildInfo()}, nil
+}
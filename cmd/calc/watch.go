@@ -0,0 +1,257 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DesiredTier is one instance of a -desired file and the tier it should be
+// on. Instance is <project>:<instance>, or an instance of -project.
+type DesiredTier struct {
+	Instance string `yaml:"instance"`
+	Tier     string `yaml:"tier"`
+}
+
+// loadDesired reads a -desired file:
+//
+//	instances:
+//	  - instance: my-project:my-db
+//	    tier: db-custom-4-15360
+func loadDesired(path string) ([]DesiredTier, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var doc struct {
+		Instances []DesiredTier `yaml:"instances"`
+	}
+	dec := yaml.NewDecoder(f)
+	dec.KnownFields(true)
+	if err := dec.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("desired tiers %s: %w", path, err)
+	}
+	for i, d := range doc.Instances {
+		if d.Instance == "" || d.Tier == "" {
+			return nil, fmt.Errorf("desired tiers %s: entry %d needs an instance and a tier", path, i+1)
+		}
+	}
+	return doc.Instances, nil
+}
+
+// WatchSummary counts the outcomes of one watch pass.
+type WatchSummary struct {
+	Checked int `json:"checked"`
+	InSync  int `json:"in_sync"`
+	Drifted int `json:"drifted"`
+	Invalid int `json:"invalid"`
+	Missing int `json:"missing"`
+	Errors  int `json:"errors"`
+}
+
+// WatchEvent is a finding of a watch pass: drift of an instance from its
+// desired tier, an invalid desired tier, an instance the Admin API does not
+// have, an error, or the summary that closes the pass.
+type WatchEvent struct {
+	Type     string        `json:"type"` // drift, invalid, missing, error, or summary
+	Time     time.Time     `json:"time"`
+	Instance string        `json:"instance,omitempty"`
+	Desired  string        `json:"desired_tier,omitempty"`
+	Actual   string        `json:"actual_tier,omitempty"`
+	Change   string        `json:"change,omitempty"` // the diff verdict of moving to the desired tier
+	Delta    *Delta        `json:"delta,omitempty"`
+	Reasons  []string      `json:"reasons,omitempty"`
+	Codes    []string      `json:"reason_codes,omitempty"`
+	Error    string        `json:"error,omitempty"`
+	Summary  *WatchSummary `json:"summary,omitempty"`
+}
+
+// String is the log line of the event.
+func (e *WatchEvent) String() string {
+	var msg string
+	switch e.Type {
+	case "drift":
+		msg = fmt.Sprintf("%s is on %s, desired %s (%s: %s)", e.Instance, e.Actual, e.Desired, e.Change, e.Delta)
+	case "invalid":
+		msg = fmt.Sprintf("%s: desired %s is not valid: %s", e.Instance, e.Desired, strings.Join(e.Reasons, "; "))
+	case "missing":
+		msg = fmt.Sprintf("%s: not found in the Cloud SQL Admin API", e.Instance)
+	case "summary":
+		s := e.Summary
+		msg = fmt.Sprintf("checked %d instances: %d in sync, %d drifted, %d invalid, %d missing, %d errors",
+			s.Checked, s.InSync, s.Drifted, s.Invalid, s.Missing, s.Errors)
+	default:
+		msg = e.Error
+		if e.Instance != "" {
+			msg = e.Instance + ": " + msg
+		}
+	}
+	return fmt.Sprintf("%s %s: %s", e.Time.Format(time.RFC3339), e.Type, msg)
+}
+
+// WatchResult is the outcome of watch: the passes run and the summary of
+// the last one. The events are written as they happen.
+type WatchResult struct {
+	Mode    string       `json:"mode"`
+	Desired string       `json:"desired"`
+	Passes  int          `json:"passes"`
+	Last    WatchSummary `json:"last"`
+	Error   string       `json:"error,omitempty"`
+}
+
+func (w *WatchResult) humanText() string  { return "" }
+func (w *WatchResult) setError(err error) { w.Error = err.Error() }
+
+// exitCode is exitInvalid when a single -once pass found drift, an invalid
+// or missing instance, or an error, and exitOK otherwise.
+func (w *WatchResult) exitCode() int {
+	s := w.Last
+	if opts.once && s.Drifted+s.Invalid+s.Missing+s.Errors > 0 {
+		return exitInvalid
+	}
+	return exitOK
+}
+
+// emit writes an event as a log line, or a JSON line with -o ndjson.
+func (w *WatchResult) emit(e *WatchEvent) error {
+	if streaming() {
+		return writeJSONLine(streamOut, e)
+	}
+	_, err := fmt.Fprintln(streamOut, e)
+	return err
+}
+
+// runWatch compares the live tier of every instance in the -desired file
+// with its desired tier every -interval, or once with -once, until SIGINT or
+// SIGTERM. The file is read again for each pass.
+func runWatch() (*WatchResult, error) {
+	w := &WatchResult{Mode: "watch", Desired: opts.desired}
+	if opts.desired == "" {
+		return w, fmt.Errorf("watch needs -desired <file>")
+	}
+	if opts.output != "text" && opts.output != "ndjson" {
+		return w, fmt.Errorf("watch writes log lines, or events with -o ndjson")
+	}
+	desired, err := loadDesired(opts.desired)
+	if err != nil {
+		return w, err
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	for {
+		if err := w.pass(ctx, desired); err != nil {
+			return w, err
+		}
+		if opts.once {
+			return w, nil
+		}
+		select {
+		case <-time.After(opts.interval):
+		case <-ctx.Done():
+			return w, nil
+		}
+		// A file that no longer loads is reported, and the last one kept
+		if d, err := loadDesired(opts.desired); err != nil {
+			if err := w.emit(&WatchEvent{Type: "error", Time: time.Now().UTC(), Error: err.Error()}); err != nil {
+				return w, err
+			}
+		} else {
+			desired = d
+		}
+	}
+}
+
+// pass checks every desired tier once and ends with a summary, unless it
+// is interrupted.
+func (w *WatchResult) pass(ctx context.Context, desired []DesiredTier) error {
+	selected := rules
+	defer func() { rules = selected }()
+	var s WatchSummary
+	for _, d := range desired {
+		if ctx.Err() != nil {
+			return nil
+		}
+		s.Checked++
+		e := checkDesired(ctx, d)
+		switch e.Type {
+		case "":
+			s.InSync++
+			continue
+		case "drift":
+			s.Drifted++
+		case "invalid":
+			s.Invalid++
+		case "missing":
+			s.Missing++
+		default:
+			s.Errors++
+		}
+		if err := w.emit(e); err != nil {
+			return err
+		}
+	}
+	w.Passes++
+	w.Last = s
+	return w.emit(&WatchEvent{Type: "summary", Time: time.Now().UTC(), Summary: &s})
+}
+
+// checkDesired compares the live tier of an instance with its desired tier,
+// under the rules of the instance's engine and edition, with the comparison
+// of diff. It leaves rules set to those rules. An instance on its desired
+// tier gives an event without a type.
+func checkDesired(ctx context.Context, d DesiredTier) *WatchEvent {
+	e := &WatchEvent{Time: time.Now().UTC(), Instance: d.Instance, Desired: d.Tier}
+	fail := func(err error) *WatchEvent {
+		e.Type, e.Error = "error", err.Error()
+		return e
+	}
+	project, name, err := splitInstance(d.Instance, opts.project)
+	if err != nil {
+		return fail(err)
+	}
+	e.Instance = project + ":" + name
+	ctx, cancel := context.WithTimeout(ctx, apiTimeout)
+	defer cancel()
+	gi, err := admin.instance(ctx, project, name)
+	if errors.Is(err, ErrAPINotFound) {
+		e.Type = "missing"
+		return e
+	}
+	if err != nil {
+		return fail(err)
+	}
+	e.Actual = gi.Settings.Tier
+	if rules, err = instanceRules(*gi); err != nil {
+		return fail(err)
+	}
+	// The desired tier is only parsed now, under the instance's rules:
+	// its range depends on them
+	want, err := ParseTier(d.Tier)
+	if err != nil {
+		e.Type, e.Reasons, e.Codes = "invalid", []string{err.Error()}, []string{reasonCode(err)}
+		return e
+	}
+	if info := describe(want); !info.Valid {
+		e.Type, e.Reasons, e.Codes = "invalid", info.Reasons, info.Codes
+		return e
+	}
+	have, err := ParseTier(e.Actual)
+	if err != nil {
+		return fail(fmt.Errorf("live tier %s: %w", e.Actual, err))
+	}
+	if have == want {
+		return e
+	}
+	delta := compareTiers(have, want)
+	verdict := delta.verdict()
+	e.Type, e.Change, e.Delta = "drift", verdict.word(), &delta
+	return e
+}
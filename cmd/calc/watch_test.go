@@ -0,0 +1,32 @@
+package main
+
+import (
+	"context"
+	"testing"
+)
+
+// The desired tier is parsed under the rules of the instance it is for, not
+// those left selected by the instance checked before it.
+func TestCheckDesiredUnderInstanceRules(t *testing.T) {
+	useAdmin(t, &fakeAdmin{fakeInstances: fakeInstances{
+		"prod:big":    fakeInstance("MYSQL_8_0", "db-custom-96-638976", "ENTERPRISE_PLUS"),
+		"prod:orders": fakeInstance("MYSQL_8_0", "db-custom-4-15360", "ENTERPRISE"),
+		"prod:pg":     fakeInstance("POSTGRES_16", "db-custom-8-30720", "ENTERPRISE"),
+	}}, false, false)
+	useRules(t, "mysql", "enterprise")
+	tests := []struct {
+		instance, tier string
+		want, change   string
+	}{
+		{"prod:orders", "db-custom-4-15360", "", ""},
+		{"prod:big", "db-custom-128-851968", "drift", "upgrade"},
+		{"prod:pg", "db-custom-128-851968", "invalid", ""},
+		{"prod:big", "db-custom-96-638976", "", ""},
+	}
+	for _, tt := range tests {
+		e := checkDesired(context.Background(), DesiredTier{Instance: tt.instance, Tier: tt.tier})
+		if e.Type != tt.want || e.Change != tt.change {
+			t.Errorf("%s to %s = %q %q (%v %s), want %q %q", tt.instance, tt.tier, e.Type, e.Change, e.Reasons, e.Error, tt.want, tt.change)
+		}
+	}
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+
+	"gopkg.in/yaml.v3"
+)
+
+// writeYAML renders r as YAML. It goes through the JSON encoding so the keys,
+// their order, and omitted fields match -o json exactly.
+func writeYAML(w io.Writer, r report) error {
+	data, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return err
+	}
+	plainStyle(&doc)
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(&doc); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+// plainStyle drops the JSON flow and quoting styles so the document is
+// written in block style, quoting only where YAML needs it.
+func plainStyle(n *yaml.Node) {
+	n.Style = 0
+	for _, c := range n.Content {
+		plainStyle(c)
+	}
+}